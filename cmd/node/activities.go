@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"ideal-core/pkg/cbt/activation"
+	"ideal-core/pkg/journal"
+	"net/http"
+	"os"
+	"time"
+)
+
+// handleActivities — GET/POST /api/activities (список и планирование занятий)
+func handleActivities(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		var from, to time.Time
+		if s := q.Get("from"); s != "" {
+			t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+			if err != nil {
+				http.Error(w, "Invalid from date", http.StatusBadRequest)
+				return
+			}
+			from = t
+		}
+		if s := q.Get("to"); s != "" {
+			t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+			if err != nil {
+				http.Error(w, "Invalid to date", http.StatusBadRequest)
+				return
+			}
+			to = t
+		}
+		json.NewEncoder(w).Encode(activityStore.List(q.Get("person"), from, to))
+
+	case http.MethodPost:
+		var a activation.Activity
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		saved, err := activityStore.Plan(a)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(saved)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleActivityComplete — POST /api/activities/complete
+func handleActivityComplete(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+		activation.Completion
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a, err := activityStore.Complete(req.ID, req.Completion, time.Now())
+	writeActivityResult(w, a, err)
+}
+
+// handleActivitySkip — POST /api/activities/skip
+func handleActivitySkip(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID    string `json:"id"`
+		Notes string `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a, err := activityStore.Skip(req.ID, req.Notes)
+	writeActivityResult(w, a, err)
+}
+
+// handleActivityWeek — GET /api/activities/week?person=...&start=2006-01-02
+func handleActivityWeek(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	start := time.Now()
+	if s := q.Get("start"); s != "" {
+		t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+		if err != nil {
+			http.Error(w, "Invalid start date", http.StatusBadRequest)
+			return
+		}
+		start = t
+	} else {
+		// По умолчанию — неделя с понедельника
+		offset := (int(start.Weekday()) + 6) % 7
+		start = start.AddDate(0, 0, -offset)
+	}
+	json.NewEncoder(w).Encode(activityStore.Week(q.Get("person"), start))
+}
+
+// handleActivityAnalysis — GET /api/activities/analysis?person=...
+func handleActivityAnalysis(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	person := r.URL.Query().Get("person")
+	activities := activityStore.List(person, time.Time{}, time.Time{})
+	entries := journalInstance.GetEntries(journal.EntryFilters{PersonID: person})
+	json.NewEncoder(w).Encode(activation.Analyze(activities, entries))
+}
+
+func writeActivityResult(w http.ResponseWriter, a activation.Activity, err error) {
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(a)
+}
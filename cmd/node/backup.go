@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// stdin — общий буферизованный ввод для интерактивных команд
+var stdin = bufio.NewReader(os.Stdin)
+
+// readLine печатает приглашение и читает строку из терминала
+func readLine(prompt string) (string, error) {
+	fmt.Print(prompt)
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readBackupPassword берёт пароль из IDEAL_BACKUP_PASSWORD или спрашивает в терминале
+func readBackupPassword(prompt string) (string, error) {
+	if p := os.Getenv("IDEAL_BACKUP_PASSWORD"); p != "" {
+		return p, nil
+	}
+	password, err := readLine(prompt)
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		return "", errors.New("empty password")
+	}
+	return password, nil
+}
+
+// exportBackup сохраняет зашифрованную копию приватного ключа (Argon2id, формат v1)
+func exportBackup(kp *crypto.KeyPair, path string) error {
+	password, err := readBackupPassword("🔐 Backup password: ")
+	if err != nil {
+		return err
+	}
+	backup, err := kp.ExportEncryptedBackup(password)
+	if err != nil {
+		return err
+	}
+	if err := crypto.SaveEncryptedBackup(backup, path); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Encrypted backup saved to: %s\n", path)
+	return nil
+}
+
+// rewrapBackup проверяет бэкап и перешифровывает устаревший формат v0 в v1.
+// Файл заменяется атомарно; старая копия не сохраняется, так как защищена
+// слабым KDF.
+func rewrapBackup(path string) error {
+	backup, err := crypto.LoadEncryptedBackup(path)
+	if err != nil {
+		return err
+	}
+	info, err := crypto.ParseBackup(backup)
+	if err != nil {
+		return err
+	}
+	password, err := readBackupPassword("🔐 Backup password: ")
+	if err != nil {
+		return err
+	}
+
+	kp, err := crypto.ImportEncryptedBackup(backup, password)
+	if err != nil && !errors.Is(err, crypto.ErrLegacyBackup) {
+		return err
+	}
+	if info.Version != crypto.BackupVersionLegacy {
+		fmt.Printf("✅ Backup is already v%d (Argon2id), key %s...\n", info.Version, kp.ToHex()[:16])
+		return nil
+	}
+
+	upgraded, err := crypto.RewrapBackup(backup, password)
+	if err != nil {
+		return err
+	}
+	if err := replaceFileSynced(path, upgraded); err != nil {
+		return err
+	}
+	// Перечитываем с диска: новый файл должен открываться тем же паролем
+	written, err := crypto.LoadEncryptedBackup(path)
+	if err != nil {
+		return err
+	}
+	check, err := crypto.ImportEncryptedBackup(written, password)
+	if err != nil {
+		return fmt.Errorf("re-wrapped backup does not open: %w", err)
+	}
+	if !bytes.Equal(check.PublicKey, kp.PublicKey) {
+		return errors.New("re-wrapped backup holds a different key")
+	}
+	// Копия со слабым KDF от прежних версий -rewrap-backup больше не нужна
+	if err := os.Remove(path + ".v0"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	fmt.Printf("✅ Legacy backup re-wrapped with Argon2id: %s\n", path)
+	fmt.Println("   Delete any other copies of the old backup file: they are still protected only by the weak legacy KDF.")
+	return nil
+}
+
+// replaceFileSynced заменяет файл целиком: временный файл сбрасывается на диск,
+// переименовывается поверх path, затем сбрасывается каталог
+func replaceFileSynced(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"ideal-core/pkg/cbt/beliefs"
+	"ideal-core/pkg/journal"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// handleBeliefs — GET/POST /api/beliefs (список и добавление убеждений)
+func handleBeliefs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(beliefStore.List(r.URL.Query().Get("person")))
+
+	case http.MethodPost:
+		var req struct {
+			beliefs.Belief
+			Believability int `json:"believability"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		b, err := beliefStore.Add(req.Belief, req.Believability, time.Now())
+		writeBeliefResult(w, b, err)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleBeliefArrow — GET/POST /api/beliefs/arrow
+// GET ?depth=N возвращает вопрос следующего шага, POST выводит убеждение из цепочки
+func handleBeliefArrow(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		depth := 0
+		if d := r.URL.Query().Get("depth"); d != "" {
+			n, err := strconv.Atoi(d)
+			if err != nil {
+				http.Error(w, "Invalid depth", http.StatusBadRequest)
+				return
+			}
+			depth = n
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"depth": depth, "question": beliefs.ArrowQuestion(depth)})
+
+	case http.MethodPost:
+		var req struct {
+			PersonID      string   `json:"person_id"`
+			EntryID       string   `json:"entry_id"` // запись, с мысли которой начата стрела
+			Steps         []string `json:"steps"`
+			Believability int      `json:"believability"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		b, err := beliefStore.AddFromArrow(req.PersonID, req.Steps, req.Believability, time.Now())
+		if err == nil && req.EntryID != "" {
+			if entry, ok := findEntry(req.EntryID); ok {
+				b, err = beliefStore.LinkEntry(b.ID, entry)
+			}
+		}
+		writeBeliefResult(w, b, err)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleBeliefRate — POST /api/beliefs/rate {id, percent, note}
+func handleBeliefRate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID          string `json:"id"`
+		Percent     int    `json:"percent"`
+		Note        string `json:"note"`
+		Alternative string `json:"alternative"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b, err := beliefStore.Rate(req.ID, req.Percent, req.Note, time.Now())
+	if err == nil && req.Alternative != "" {
+		b, err = beliefStore.SetAlternative(req.ID, req.Alternative)
+	}
+	writeBeliefResult(w, b, err)
+}
+
+// handleBeliefLink — POST /api/beliefs/link {id, entry_id}
+func handleBeliefLink(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID      string `json:"id"`
+		EntryID string `json:"entry_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entry, ok := findEntry(req.EntryID)
+	if !ok {
+		http.Error(w, "Entry not found", http.StatusNotFound)
+		return
+	}
+	b, err := beliefStore.LinkEntry(req.ID, entry)
+	writeBeliefResult(w, b, err)
+}
+
+// handleBeliefSuggest — GET /api/beliefs/suggest?id=... (записи, которые могут питать убеждение)
+func handleBeliefSuggest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	b, ok := beliefStore.Get(r.URL.Query().Get("id"))
+	if !ok {
+		http.Error(w, "Belief not found", http.StatusNotFound)
+		return
+	}
+	entries := journalInstance.GetEntries(journal.EntryFilters{Type: string(journal.EntryTypeCBT)})
+	json.NewEncoder(w).Encode(beliefs.Suggest(b, entries))
+}
+
+// handleBeliefTrends — GET /api/beliefs/trends?person=...&weakening=true
+func handleBeliefTrends(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	trends := beliefStore.Trends(q.Get("person"))
+	if q.Get("weakening") == "true" {
+		weakening := []beliefs.Trend{}
+		for _, t := range trends {
+			if t.Weakening {
+				weakening = append(weakening, t)
+			}
+		}
+		trends = weakening
+	}
+	json.NewEncoder(w).Encode(trends)
+}
+
+// findEntry ищет запись дневника по ID
+func findEntry(id string) (journal.ThoughtEntry, bool) {
+	for _, e := range journalInstance.GetEntries(journal.EntryFilters{}) {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return journal.ThoughtEntry{}, false
+}
+
+func writeBeliefResult(w http.ResponseWriter, b beliefs.Belief, err error) {
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(b)
+}
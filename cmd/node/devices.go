@@ -0,0 +1,421 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/envelope"
+	"ideal-core/pkg/identity"
+	"ideal-core/pkg/peers"
+	"ideal-core/pkg/yggdrasil"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+)
+
+// pairer — активный код сопряжения этого узла (мастера)
+var pairer identity.Pairer
+
+// errNotOwnDevice — ключ нельзя записать в адресную книгу как своё устройство
+var errNotOwnDevice = errors.New("not a device of this identity")
+
+// ownIdentity — идентичность, к которой принадлежат устройства этого узла
+// (у сопряжённого устройства — идентичность мастера)
+func ownIdentity() string {
+	if self := deviceStore.Self(); self != nil {
+		return self.History.Root
+	}
+	return identityManager.ID()
+}
+
+// admitDevice проверяет ключ перед записью пира с доверием TrustDevice:
+// устройство заверено этим узлом (или это мастер этого устройства), либо
+// предъявлена цепочка, сходящаяся к нашей идентичности и не отозванная.
+func admitDevice(key string, chain *identity.DeviceChain) error {
+	if isOwnDevice(key) {
+		return nil
+	}
+	if chain == nil {
+		return fmt.Errorf("%w: trust %q needs the device chain (\"self\" in GET /api/devices on that device)", errNotOwnDevice, peers.TrustDevice)
+	}
+	if err := chain.VerifyMember(ownIdentity(), key, deviceStore.Revocations()); err != nil {
+		return fmt.Errorf("%w: %v", errNotOwnDevice, err)
+	}
+	return nil
+}
+
+// pairTimeout — сколько новое устройство ждёт ответа мастера
+const pairTimeout = 30 * time.Second
+
+// pairReply — ответ мастера на запрос сопряжения (envelope.TypeDevicePaired)
+type pairReply struct {
+	Response *identity.PairResponse `json:"response,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// pairWithMaster — флаг -pair: сопрягает этот узел как устройство мастер-идентичности.
+// Запрос идёт по транспорту пиров (сессия подтверждает ключи обеих сторон), а не
+// через веб-API мастера. master — Node ID (текущий ключ) мастер-узла, endpoint —
+// его адрес для пиров (по умолчанию Yggdrasil-адрес ключа).
+func pairWithMaster(master, endpoint, code, name string) error {
+	masterKey, err := identity.ParseKey(master)
+	if err != nil {
+		return fmt.Errorf("master node ID: %w", err)
+	}
+	if code == "" {
+		if code, err = readLine("🔗 Pairing code shown on the master device: "); err != nil {
+			return err
+		}
+	}
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+	kp := identityManager.KeyPair()
+	req, key, err := identity.NewPairRequest(kp, name, code)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	var ygg *yggdrasil.Client
+	if *peerListen == "" {
+		if ygg, err = yggdrasil.NewClient(kp.ToHex(), "/usr/bin/yggdrasil", checkYggdrasil("/usr/bin/yggdrasil")); err != nil {
+			return err
+		}
+		defer ygg.Close()
+	}
+	tr, err := newPeerTransport(ygg, *peerListen)
+	if err != nil {
+		return err
+	}
+	if tr == nil {
+		return errPeerTransportOff
+	}
+	defer tr.Close()
+
+	// Мастер — своё устройство; записывается в книгу после успешного сопряжения
+	masterPeer, err := peers.NewPeer(masterKey, "master", peers.TrustDevice, time.Now())
+	if err != nil {
+		return err
+	}
+	masterPeer.Endpoint = endpoint
+	endpoint = masterPeer.DialAddr()
+	ctx, cancel := context.WithTimeout(context.Background(), pairTimeout)
+	defer cancel()
+	got, err := tr.Dial(ctx, endpoint)
+	if err != nil {
+		return fmt.Errorf("connect to master at %s: %w", endpoint, err)
+	}
+	if !got.Equal(masterKey) {
+		return fmt.Errorf("%s answered with key %s..., expected %s...", endpoint, hex.EncodeToString(got)[:16], master[:16])
+	}
+	env, err := envelope.Seal(kp, masterKey, envelope.TypeDevicePair, data, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := tr.Send(ctx, masterKey, env.Marshal()); err != nil {
+		return err
+	}
+
+	// Ответ принимается только от мастера: адресной книги у устройства ещё нет
+	receiver := envelope.NewReceiver(identityManager.KeyPair)
+	receiver.SetPolicy(func(sender ed25519.PublicKey) error {
+		if !sender.Equal(masterKey) {
+			return errors.New("not the master being paired with")
+		}
+		return nil
+	})
+	for {
+		pkt, err := tr.Receive(ctx)
+		if err != nil {
+			return fmt.Errorf("waiting for the master: %w", err)
+		}
+		msg, err := receiver.Open(pkt.Data)
+		if err != nil || msg.Type != envelope.TypeDevicePaired {
+			continue
+		}
+		var reply pairReply
+		if err := json.Unmarshal(msg.Payload, &reply); err != nil {
+			return err
+		}
+		if reply.Error != "" || reply.Response == nil {
+			return fmt.Errorf("master refused pairing: %s", reply.Error)
+		}
+		chain, err := identity.OpenPairResponse(*reply.Response, key, kp.PublicKey)
+		if err != nil {
+			return err
+		}
+		if err := deviceStore.SetSelf(chain); err != nil {
+			return err
+		}
+		if _, err := peerBook.Add(masterPeer); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Paired as device %q of identity %s\n", chain.Certificate.Name, chain.History.Root)
+		fmt.Printf("   Device key: %s\n", chain.Certificate.DeviceKey)
+		return nil
+	}
+}
+
+// handleDevices — GET /api/devices (устройства идентичности и собственная цепочка)
+func handleDevices(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"identity": identityManager.ID(),
+		"devices":  deviceStore.List(),
+		"self":     deviceStore.Self(),
+	})
+}
+
+// handleDevicePairing — POST /api/devices/pairing (новый код), DELETE (отмена)
+func handleDevicePairing(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodPost:
+		code, expires, err := pairer.Start(identity.DefaultPairingTTL, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// node_id — что ввести на новом устройстве в -pair
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": code, "expires_at": expires, "node_id": identityManager.KeyPair().ToHex()})
+
+	case http.MethodDelete:
+		pairer.Cancel()
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleDevicePairMessage — запрос сопряжения от нового устройства по транспорту.
+// Отправитель ещё не в адресной книге (тип исключён из политики), поэтому его
+// ключ должен совпадать с ключом из запроса, а заблокированные не принимаются.
+func handleDevicePairMessage(ctx context.Context, msg envelope.Message) error {
+	from := hex.EncodeToString(msg.Sender)
+	if p, err := peerBook.Get(from); err == nil && p.Trust == peers.TrustBlocked {
+		return peers.ErrBlocked
+	}
+	var req identity.PairRequest
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		return err
+	}
+	reply := pairReply{}
+	chain, err := acceptDevice(req, from, time.Now())
+	if err != nil {
+		reply.Error = err.Error()
+	} else {
+		resp := identity.NewPairResponse(chain.chain, chain.key)
+		reply.Response = &resp
+	}
+	data, merr := json.Marshal(reply)
+	if merr != nil {
+		return merr
+	}
+	sctx, cancel := context.WithTimeout(ctx, ackTimeout)
+	defer cancel()
+	if serr := sendToPeer(sctx, msg.Sender, envelope.TypeDevicePaired, data); serr != nil {
+		return serr
+	}
+	if err == nil {
+		fmt.Printf("🔗 Paired device %q: %s...\n", req.Name, from[:16])
+	}
+	return err
+}
+
+// acceptedDevice — цепочка нового устройства и ключ для MAC ответа
+type acceptedDevice struct {
+	chain identity.DeviceChain
+	key   []byte
+}
+
+// acceptDevice проверяет запрос сопряжения, заверяет устройство и записывает
+// его в адресную книгу как своё
+func acceptDevice(req identity.PairRequest, from string, now time.Time) (acceptedDevice, error) {
+	if req.DeviceKey != from {
+		return acceptedDevice{}, errors.New("pairing request is for another key than the sender's")
+	}
+	device, key, err := pairer.Accept(req, now)
+	if err != nil {
+		return acceptedDevice{}, err
+	}
+	cert := identity.NewDeviceCertificate(identityManager.ID(), identityManager.KeyPair(), device, req.Name, now)
+	if err := deviceStore.Add(cert); err != nil {
+		return acceptedDevice{}, err
+	}
+	p, err := peers.NewPeer(device, req.Name, peers.TrustDevice, now)
+	if err == nil {
+		_, err = peerBook.Add(p)
+	}
+	if err != nil {
+		return acceptedDevice{}, err
+	}
+	chain := identity.DeviceChain{History: identityManager.History(), Certificate: cert, Revocations: deviceStore.Revocations()}
+	return acceptedDevice{chain: chain, key: key}, nil
+}
+
+// handleDeviceRevoke — POST /api/devices/revoke {"device_key": "hex", "reason": "..."}
+func handleDeviceRevoke(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		DeviceKey string `json:"device_key"`
+		Reason    string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rev, err := deviceStore.Revoke(identityManager, req.DeviceKey, req.Reason, time.Now())
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Отозванное устройство больше не своё: его сообщения не принимаются
+	if _, err := peerBook.SetTrust(rev.DeviceKey, peers.TrustBlocked); err != nil && !errors.Is(err, os.ErrNotExist) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(rev)
+}
+
+// handleDeviceRevocations — GET /api/devices/revocations
+// История ключей и отзывы устройств — пиры обновляют по ним свои проверки.
+func handleDeviceRevocations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"history":     identityManager.History(),
+		"revocations": deviceStore.Revocations(),
+	})
+}
+
+// handleDeviceVerify — POST /api/devices/verify {"chain": {...}} (проверка цепочки устройства пира)
+func handleDeviceVerify(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Chain identity.DeviceChain `json:"chain"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := req.Chain.Verify()
+	result := map[string]interface{}{
+		"valid":      err == nil,
+		"identity":   id,
+		"device_key": req.Chain.Certificate.DeviceKey,
+		"name":       req.Chain.Certificate.Name,
+	}
+	if err != nil {
+		result["error"] = err.Error()
+		result["revoked"] = errors.Is(err, identity.ErrDeviceRevoked)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// deviceChainUpdate — история ключей мастера и отзывы устройств (envelope.TypeDeviceChain)
+type deviceChainUpdate struct {
+	History     identity.KeyHistory         `json:"history"`
+	Revocations []identity.DeviceRevocation `json:"revocations"`
+}
+
+// pushDeviceChains сообщает устройствам, сопряжённым до ротации мастер-ключа,
+// новую историю ключей. Новый ключ устройствам ещё не известен, поэтому конверт
+// подписывается ключом, выдавшим сертификат устройства: его устройство знает.
+func pushDeviceChains() {
+	current := identityManager.KeyPair().ToHex()
+	data, err := json.Marshal(deviceChainUpdate{History: identityManager.History(), Revocations: deviceStore.Revocations()})
+	if err != nil {
+		log.Printf("⚠️  Device chain update: %v", err)
+		return
+	}
+	for _, d := range deviceStore.List() {
+		if d.Revocation != nil || d.Certificate.Issuer == current {
+			continue
+		}
+		if err := pushDeviceChain(d.Certificate, data); err != nil {
+			log.Printf("⚠️  Device chain update for %s failed: %v", d.Certificate.Name, err)
+		}
+	}
+}
+
+func pushDeviceChain(cert identity.DeviceCertificate, data []byte) error {
+	issuer, err := identityManager.LoadRetiredKey(cert.Issuer)
+	if err != nil {
+		return err
+	}
+	key, err := identity.ParseKey(cert.DeviceKey)
+	if err != nil {
+		return err
+	}
+	env, err := envelope.Seal(issuer, key, envelope.TypeDeviceChain, data, time.Now())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+	defer cancel()
+	return transmitToPeer(ctx, key, env.Marshal())
+}
+
+// handleDeviceChainMessage — мастер сообщил свою историю ключей: после ротации
+// цепочка устройства обновляется, и синхронизация идёт с новым мастер-ключом
+func handleDeviceChainMessage(ctx context.Context, msg envelope.Message) error {
+	self := deviceStore.Self()
+	if self == nil {
+		return errors.New("device chain update: this node is not a paired device")
+	}
+	from := hex.EncodeToString(msg.Sender)
+	if !slices.Contains(self.History.Keys(), from) {
+		return fmt.Errorf("device chain update from %s: not a key of identity %s", peerName(from), self.History.Root[:16]+"...")
+	}
+	var upd deviceChainUpdate
+	if err := json.Unmarshal(msg.Payload, &upd); err != nil {
+		return err
+	}
+	chain, changed, err := self.Refresh(upd.History, upd.Revocations)
+	if err != nil {
+		return fmt.Errorf("device chain update: %w", err)
+	}
+	if !changed {
+		return nil
+	}
+	if err := deviceStore.SetSelf(chain); err != nil {
+		return err
+	}
+	ensureDevicePeers(time.Now())
+	if head, err := chain.History.Verify(); err == nil {
+		fmt.Printf("🔄 Master key updated: %s...\n", hex.EncodeToString(head)[:16])
+	}
+	return nil
+}
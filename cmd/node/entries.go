@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"ideal-core/pkg/journal"
+	"net/http"
+	"os"
+)
+
+// handleJournalEntry — GET /api/journal/entry?id=..., POST (правка полей:
+// {"notes": "...", "intensity": 40}), DELETE
+func handleJournalEntry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := r.URL.Query().Get("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		e, err := journalInstance.Entry(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(e)
+
+	case http.MethodPost:
+		var fields map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		e, err := journalInstance.UpdateEntry(id, fields)
+		writeEntryResult(w, e, err)
+
+	case http.MethodDelete:
+		if err := journalInstance.DeleteEntry(id); err != nil {
+			writeEntryResult(w, journal.ThoughtEntry{}, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleJournalEntryTags — POST /api/journal/entry/tags?id=... {"add": ["сон"], "remove": ["утро"]}
+func handleJournalEntryTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id := r.URL.Query().Get("id")
+	e, err := journalInstance.Entry(id)
+	for _, t := range req.Add {
+		if err != nil {
+			break
+		}
+		e, err = journalInstance.AddTag(id, t)
+	}
+	for _, t := range req.Remove {
+		if err != nil {
+			break
+		}
+		e, err = journalInstance.RemoveTag(id, t)
+	}
+	writeEntryResult(w, e, err)
+}
+
+func writeEntryResult(w http.ResponseWriter, e journal.ThoughtEntry, err error) {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, journal.ErrNotEditable):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		json.NewEncoder(w).Encode(e)
+	}
+}
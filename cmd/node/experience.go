@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/db"
+	"ideal-core/pkg/envelope"
+	"ideal-core/pkg/identity"
+	"ideal-core/pkg/peers"
+	"ideal-core/pkg/rl"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Обмен опытом RL-агента: узел учится на реакциях своих клиентов, раз в
+// -rl-gossip выпускает зашумлённую статистику (ε из -rl-epsilon, не больше
+// -rl-budget за неделю) и отправляет её нескольким доверенным пирам.
+// Полученный от доверенных пиров опыт становится начальной оценкой действий.
+
+const rlAgentFile = "rl_agent.json"
+
+// gossipFanout — скольким доверенным пирам уходит один выпуск
+const gossipFanout = 3
+
+var (
+	rlAgent     *rl.Agent
+	rlAgentPath string
+	rlExchange  *rl.Exchange
+)
+
+// initExperience загружает агента и состояние обмена опытом
+func initExperience(dir string, budget float64) error {
+	rlAgent = rl.NewAgent(0.1, 0.9, 0.1)
+	rlAgentPath = filepath.Join(dir, rlAgentFile)
+	if err := rlAgent.LoadFile(rlAgentPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	var err error
+	if rlExchange, err = rl.NewExchange(dir, budget, rl.DefaultBudgetWindow); err != nil {
+		return err
+	}
+	rlAgent.SetPrior(rlExchange.Prior())
+	return nil
+}
+
+// gossipResult — итог одного выпуска
+type gossipResult struct {
+	Released int      `json:"released"`
+	Epsilon  float64  `json:"epsilon"`
+	SentTo   []string `json:"sent_to"`
+	Messages []string `json:"messages"`
+}
+
+// gossipExperience выпускает опыт и ставит его в очередь случайным доверенным пирам.
+// Без нового опыта или без пиров бюджет не тратится.
+func gossipExperience(epsilon float64, ttl time.Duration, now time.Time) (gossipResult, error) {
+	res := gossipResult{Epsilon: epsilon, SentTo: []string{}, Messages: []string{}}
+	if rlExchange.Pending(rlAgent) == 0 {
+		return res, rl.ErrNoNewExperience
+	}
+	trusted := peerBook.List(peers.TrustTrusted)
+	if len(trusted) == 0 {
+		return res, errors.New("no trusted peers to share experience with")
+	}
+	rand.Shuffle(len(trusted), func(i, j int) { trusted[i], trusted[j] = trusted[j], trusted[i] })
+	trusted = trusted[:min(len(trusted), gossipFanout)]
+
+	exps, err := rlExchange.Release(rlAgent, epsilon, now)
+	if err != nil {
+		return res, err
+	}
+	res.Released = len(exps)
+	if len(exps) == 0 {
+		// Шум скрыл всё: отправлять нечего, но бюджет уже потрачен
+		return res, nil
+	}
+	data, err := json.Marshal(rl.ExperienceMessage{Epsilon: epsilon, ReleasedAt: now, Experiences: exps})
+	if err != nil {
+		return res, err
+	}
+	for _, p := range trusted {
+		key, err := identity.ParseKey(p.Key)
+		if err != nil {
+			return res, err
+		}
+		// Выпуск не повторяется: пир, не получивший его за ttl, просто его пропустит
+		msg, err := outboxQueue.Enqueue(key, envelope.TypeExperience, data, ttl, now)
+		if err != nil {
+			return res, err
+		}
+		res.SentTo = append(res.SentTo, p.Key)
+		res.Messages = append(res.Messages, msg.ID)
+	}
+	return res, nil
+}
+
+// runExperienceGossip выпускает опыт раз в interval (0 — не выпускать)
+func runExperienceGossip(ctx context.Context, interval time.Duration, epsilon float64) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			res, err := gossipExperience(epsilon, interval, now)
+			if err != nil {
+				log.Printf("ℹ️  Experience gossip skipped: %v", err)
+				continue
+			}
+			fmt.Printf("🧠 Shared %d experience patterns with %d peers (ε=%.2f)\n", res.Released, len(res.SentTo), epsilon)
+		}
+	}
+}
+
+// handleExperienceMessage — опыт от пира; принимается только от доверенных
+func handleExperienceMessage(ctx context.Context, msg envelope.Message) error {
+	from := hex.EncodeToString(msg.Sender)
+	p, err := peerBook.Get(from)
+	if err != nil {
+		return err
+	}
+	if p.Trust != peers.TrustTrusted {
+		return fmt.Errorf("experience accepted only from trusted peers, %s is %q", peerName(from), p.Trust)
+	}
+	var m rl.ExperienceMessage
+	if err := json.Unmarshal(msg.Payload, &m); err != nil {
+		return err
+	}
+	n, err := rlExchange.Merge(from, m.Experiences, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("🧠 %d experience patterns from %s (ε=%.2f)\n", n, peerName(from), m.Epsilon)
+	return nil
+}
+
+// forgetPeerExperience убирает опыт пира, которому больше не доверяем
+func forgetPeerExperience(p peers.Peer) {
+	if p.Trust == peers.TrustTrusted {
+		return
+	}
+	if err := rlExchange.Forget(p.Key, time.Now()); err != nil {
+		log.Printf("⚠️  Forget experience of %s: %v", peerName(p.Key), err)
+	}
+}
+
+// handleExperience — GET /api/rl/experience: бюджет приватности, свой опыт и опыт пиров
+func handleExperience(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	type peerSummary struct {
+		Peer        string    `json:"peer"`
+		Name        string    `json:"name"`
+		Experiences int       `json:"experiences"`
+		ReceivedAt  time.Time `json:"received_at"`
+	}
+	now := time.Now()
+	fromPeers := []peerSummary{}
+	for _, pe := range rlExchange.Prior().Peers() {
+		fromPeers = append(fromPeers, peerSummary{
+			Peer:        pe.Peer,
+			Name:        peerName(pe.Peer),
+			Experiences: len(pe.Experiences),
+			ReceivedAt:  pe.ReceivedAt,
+		})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"budget":       rlExchange.Budget(now),
+		"epsilon":      *rlEpsilon,
+		"next_release": rlExchange.NextRelease(*rlEpsilon, now),
+		"local":        len(rlAgent.ExportExperience()),
+		"prior_pairs":  rlExchange.Prior().Size(),
+		"peers":        fromPeers,
+	})
+}
+
+// handleExperienceGossip — POST /api/rl/gossip: выпустить и разослать опыт сейчас
+func handleExperienceGossip(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ttl := *rlGossip
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	res, err := gossipExperience(*rlEpsilon, ttl, time.Now())
+	if errors.Is(err, rl.ErrBudgetExhausted) {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+// rlRequestState — состояние клиента в запросах к агенту
+type rlRequestState struct {
+	Vectors  [3][3]int `json:"vectors"`
+	Chakras  []int     `json:"chakras"`
+	Symptoms []string  `json:"symptoms"`
+}
+
+func (s rlRequestState) state() rl.State {
+	return rl.State{Vectors: s.Vectors, Chakras: s.Chakras, Symptoms: s.Symptoms}
+}
+
+// handleExperienceChoose — POST /api/rl/choose {"state": {...}, "actions": [{"id": "...", "text": "..."}]}
+func handleExperienceChoose(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		State   rlRequestState `json:"state"`
+		Actions []struct {
+			ID          string `json:"id"`
+			Text        string `json:"text"`
+			Author      string `json:"author"`
+			ChakraIndex int    `json:"chakra_index"`
+		} `json:"actions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Actions) == 0 {
+		http.Error(w, "actions are required", http.StatusBadRequest)
+		return
+	}
+	actions := make([]rl.Action, len(req.Actions))
+	for i, a := range req.Actions {
+		actions[i] = rl.Action{ID: a.ID, Text: a.Text, Author: a.Author, ChakraIndex: a.ChakraIndex}
+	}
+	act := rlAgent.ChooseAction(req.State.state(), actions)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":     act.ID,
+		"text":   act.Text,
+		"reason": act.Reason,
+	})
+}
+
+// feedbackRewards — реакция клиента → награда агента
+var feedbackRewards = map[string]rl.Reward{
+	"copied":   rl.RewardCopied,
+	"printed":  rl.RewardPrinted,
+	"returned": rl.RewardReturned,
+	"ignored":  rl.RewardIgnored,
+}
+
+// handleExperienceFeedback — POST /api/rl/feedback
+// {"person_id": "...", "state": {...}, "action_id": "...", "outcome": "copied|printed|returned|ignored"}
+func handleExperienceFeedback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		PersonID string         `json:"person_id"`
+		State    rlRequestState `json:"state"`
+		ActionID string         `json:"action_id"`
+		Outcome  string         `json:"outcome"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reward, ok := feedbackRewards[req.Outcome]
+	if !ok || req.ActionID == "" {
+		http.Error(w, "action_id and outcome (copied, printed, returned, ignored) are required", http.StatusBadRequest)
+		return
+	}
+
+	s := req.State.state()
+	rlAgent.Learn(s, rl.Action{ID: req.ActionID}, reward, s)
+	if err := rlAgent.SaveFile(rlAgentPath); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if peopleDB != nil && req.PersonID != "" {
+		err := peopleDB.AddFeedback(db.Feedback{
+			PersonID:      req.PersonID,
+			IntentionHash: req.ActionID,
+			Action:        req.Outcome,
+			Reward:        float64(reward),
+		})
+		if err != nil {
+			log.Printf("⚠️  Feedback not stored: %v", err)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
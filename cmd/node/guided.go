@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"ideal-core/pkg/cbt"
+	"net/http"
+	"os"
+)
+
+// guidedResponse — состояние сессии и следующий вопрос
+type guidedResponse struct {
+	Session *cbt.GuidedSession `json:"session"`
+	Prompt  string             `json:"prompt"`
+}
+
+func writeGuided(w http.ResponseWriter, s *cbt.GuidedSession) {
+	json.NewEncoder(w).Encode(guidedResponse{Session: s, Prompt: s.Prompt()})
+}
+
+// handleGuidedSessions — GET/POST /api/cbt/sessions
+func handleGuidedSessions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(journalInstance.ListGuidedSessions(r.URL.Query().Get("person")))
+
+	case http.MethodPost:
+		var req struct {
+			PersonID string `json:"person_id"`
+		}
+		if r.ContentLength > 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		session, err := journalInstance.StartGuidedSession(req.PersonID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeGuided(w, session)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleGuidedSession — GET /api/cbt/session?id=... (продолжить сессию)
+func handleGuidedSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	session, ok := journalInstance.GetGuidedSession(r.URL.Query().Get("id"))
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	writeGuided(w, session)
+}
+
+// handleGuidedAnswer — POST /api/cbt/session/answer {"id": "...", "text": "...", "items": [...], "intensity": 50}
+func handleGuidedAnswer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+		cbt.StepAnswer
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	session, err := journalInstance.AnswerGuidedSession(req.ID, req.StepAnswer)
+	if err != nil {
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, cbt.ErrInvalidAnswer), errors.Is(err, cbt.ErrSessionFinished):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	writeGuided(w, session)
+}
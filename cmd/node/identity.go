@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/identity"
+	"net/http"
+	"os"
+	"time"
+)
+
+// writeRevocationCert сохраняет сертификат отзыва текущего ключа (хранить офлайн)
+func writeRevocationCert(path string) error {
+	rc := identityManager.RevocationFor("key compromised or lost", time.Now())
+	data, err := json.MarshalIndent(rc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Revocation certificate for %s... saved to: %s\n", rc.Key[:16], path)
+	fmt.Println("   Keep it offline. Applying it (POST /api/identity/revoke) revokes the key.")
+	return nil
+}
+
+// handleIdentity — GET /api/identity (постоянный ID, текущий ключ, адрес)
+func handleIdentity(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	kp := identityManager.KeyPair()
+	history := identityManager.History()
+	_, err := history.Verify()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":           identityManager.ID(),
+		"current_key":  kp.ToHex(),
+		"yggdrasil_ip": identity.DeriveYggdrasilIP(kp.PublicKey),
+		"rotations":    len(history.Rotations),
+		"revoked":      errors.Is(err, identity.ErrIdentityRevoked),
+	})
+}
+
+// handleIdentityHistory — GET /api/identity/history (цепочка ключей для пиров)
+func handleIdentityHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(identityManager.History())
+}
+
+// handleIdentityVerify — POST /api/identity/verify (проверка истории ключей пира)
+// Тело: {"history": {...}, "key": "hex"} — key необязателен; если задан,
+// проверяется, что он входит в цепочку, и возвращается актуальный ключ.
+func handleIdentityVerify(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		History identity.KeyHistory `json:"history"`
+		Key     string              `json:"key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	current, err := req.History.Verify()
+	if err == nil && req.Key != "" {
+		var key []byte
+		if key, err = identity.ParseKey(req.Key); err == nil {
+			current, err = req.History.Follow(key)
+		}
+	}
+	result := map[string]interface{}{"id": req.History.Root, "valid": err == nil}
+	if current != nil {
+		result["current_key"] = fmt.Sprintf("%x", []byte(current))
+	}
+	if err != nil {
+		result["error"] = err.Error()
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleIdentityRevoke — POST /api/identity/revoke
+// Тело: {"key": "hex", "reason": "..."} — отозвать ключ цепочки текущим ключом,
+// либо {"certificate": {...}} — применить заранее сохранённый сертификат.
+func handleIdentityRevoke(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Key         string                          `json:"key"`
+		Reason      string                          `json:"reason"`
+		Certificate *identity.RevocationCertificate `json:"certificate"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var rc identity.RevocationCertificate
+	var err error
+	if req.Certificate != nil {
+		rc = *req.Certificate
+		err = identityManager.ApplyRevocation(rc)
+	} else {
+		rc, err = identityManager.Revoke(req.Key, req.Reason, time.Now())
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(rc)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"ideal-core/pkg/envelope"
+	"ideal-core/pkg/identity"
+	"ideal-core/pkg/journal"
+	"ideal-core/pkg/peers"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Синхронизация дневника между своими устройствами: мастер-узел
+// синхронизируется со всеми сопряжёнными устройствами, устройство — с мастером
+// (операции других устройств приходят через него). Сообщения идут напрямую,
+// мимо очереди: потерянный обмен повторяется через -sync-interval.
+
+var journalReplica *journal.Replica
+
+// ownDevice — устройство той же идентичности
+type ownDevice struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+// ownDevices — с кем синхронизировать дневник
+func ownDevices() []ownDevice {
+	var out []ownDevice
+	for _, d := range deviceStore.List() {
+		if d.Revocation == nil {
+			out = append(out, ownDevice{Key: d.Certificate.DeviceKey, Name: d.Certificate.Name})
+		}
+	}
+	if self := deviceStore.Self(); self != nil {
+		if master, err := self.History.Verify(); err == nil {
+			out = append(out, ownDevice{Key: hex.EncodeToString(master), Name: "master"})
+		}
+	}
+	return out
+}
+
+func isOwnDevice(key string) bool {
+	for _, d := range ownDevices() {
+		if d.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// initJournalSync открывает журнал операций дневника
+func initJournalSync(dir string) error {
+	var err error
+	node := keyPair.ToHex()[:16]
+	if journalReplica, err = journal.NewReplica(journalInstance, dir, node, time.Now()); err != nil {
+		return err
+	}
+	// Локальное изменение — сразу предложить обмен устройствам
+	journalReplica.OnRecord(func() { go offerJournalSync() })
+	return nil
+}
+
+// ensureDevicePeers добавляет свои устройства в адресную книгу (без неё
+// их сообщения не принимаются, а адрес для подключения взять неоткуда)
+func ensureDevicePeers(now time.Time) {
+	for _, d := range ownDevices() {
+		if _, err := peerBook.Get(d.Key); err == nil {
+			continue
+		}
+		key, err := identity.ParseKey(d.Key)
+		if err != nil {
+			continue
+		}
+		p, err := peers.NewPeer(key, d.Name, peers.TrustDevice, now)
+		if err == nil {
+			_, err = peerBook.Add(p)
+		}
+		if err != nil {
+			log.Printf("⚠️  Device %s not added to the contact book: %v", d.Name, err)
+		}
+	}
+}
+
+// offerJournalSync отправляет свой вектор версий всем своим устройствам
+func offerJournalSync() {
+	for _, d := range ownDevices() {
+		requestJournalSync(d.Key)
+	}
+}
+
+// requestJournalSync просит устройство прислать недостающие операции
+func requestJournalSync(peer string) error {
+	key, err := identity.ParseKey(peer)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(journal.SyncRequest{Have: journalReplica.Have()})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+	defer cancel()
+	err = sendToPeer(ctx, key, envelope.TypeJournalSync, data)
+	journalReplica.Attempt(peer, err, time.Now())
+	return err
+}
+
+// runJournalSync периодически сверяет дневник со своими устройствами (0 — только по изменениям)
+func runJournalSync(ctx context.Context, interval time.Duration) {
+	ensureDevicePeers(time.Now())
+	pushDeviceChains()
+	offerJournalSync()
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			ensureDevicePeers(now)
+			pushDeviceChains()
+			offerJournalSync()
+		}
+	}
+}
+
+// handleJournalSyncMessage — устройство сообщило свой вектор: досылаем недостающее
+func handleJournalSyncMessage(ctx context.Context, msg envelope.Message) error {
+	from := hex.EncodeToString(msg.Sender)
+	if !isOwnDevice(from) {
+		return fmt.Errorf("journal sync from %s: not a device of this identity", peerName(from))
+	}
+	var req journal.SyncRequest
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		return err
+	}
+	journalReplica.Seen(from, req.Have, time.Now())
+
+	ops, more := journalReplica.Missing(req.Have, journal.MaxSyncBatch)
+	data, err := json.Marshal(journal.SyncBatch{Ops: ops, Have: journalReplica.Have(), More: more})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, ackTimeout)
+	defer cancel()
+	if err := sendToPeer(ctx, msg.Sender, envelope.TypeJournalOps, data); err != nil {
+		return err
+	}
+	// У него есть то, чего нет у нас, — просим в ответ
+	if journalReplica.Have().Lacks(req.Have) > 0 {
+		go requestJournalSync(from)
+	}
+	return nil
+}
+
+// handleJournalOpsMessage — операции дневника со своего устройства
+func handleJournalOpsMessage(ctx context.Context, msg envelope.Message) error {
+	from := hex.EncodeToString(msg.Sender)
+	if !isOwnDevice(from) {
+		return fmt.Errorf("journal ops from %s: not a device of this identity", peerName(from))
+	}
+	var batch journal.SyncBatch
+	if err := json.Unmarshal(msg.Payload, &batch); err != nil {
+		return err
+	}
+	n, err := journalReplica.Apply(batch.Ops, time.Now())
+	if err != nil {
+		return err
+	}
+	journalReplica.Seen(from, batch.Have, time.Now())
+	if n > 0 {
+		fmt.Printf("🔄 Journal: %d changes from %s\n", n, peerName(from))
+	}
+	if batch.More || journalReplica.Have().Lacks(batch.Have) > 0 {
+		go requestJournalSync(from)
+	}
+	return nil
+}
+
+// handleJournalSync — GET /api/journal/sync (состояние синхронизации устройств),
+// POST — синхронизировать сейчас
+func handleJournalSync(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		ensureDevicePeers(time.Now())
+		for _, d := range ownDevices() {
+			requestJournalSync(d.Key)
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	type deviceStatus struct {
+		Name string `json:"name"`
+		journal.SyncStatus
+	}
+	devices := ownDevices()
+	keys := make([]string, len(devices))
+	for i, d := range devices {
+		keys[i] = d.Key
+	}
+	list := []deviceStatus{}
+	for i, st := range journalReplica.Status(keys) {
+		list = append(list, deviceStatus{Name: devices[i].Name, SyncStatus: st})
+	}
+	ops, clock := journalReplica.Stats()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"node":    journalReplica.Node(),
+		"ops":     ops,
+		"clock":   clock,
+		"have":    journalReplica.Have(),
+		"devices": list,
+	})
+}
@@ -2,18 +2,29 @@ package main
 
 import (
 	"context"
-	"crypto/ed25519"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"ideal-core/pkg/cbt/activation"
+	"ideal-core/pkg/cbt/beliefs"
 	"ideal-core/pkg/crypto"
+	"ideal-core/pkg/db"
+	"ideal-core/pkg/identity"
 	"ideal-core/pkg/journal"
+	"ideal-core/pkg/llm"
+	"ideal-core/pkg/questionnaire"
+	"ideal-core/pkg/outbox"
+	"ideal-core/pkg/peers"
+	"ideal-core/pkg/recovery"
+	"ideal-core/pkg/rl"
+	"ideal-core/pkg/transport"
 	"ideal-core/pkg/yggdrasil"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -27,12 +38,53 @@ var (
 	ollamaHost = flag.String("ollama", "http://localhost:11434", "Ollama API host")
 	ollamaModel= flag.String("ollama-model", "bge-m3", "Ollama model for embeddings")
 	useOllama  = flag.Bool("use-ollama", false, "Enable Ollama embeddings (requires running Ollama server)")
+	llmModel   = flag.String("llm-model", "", "Ollama model for text generation (default: chosen by hardware)")
+	llmCBT     = flag.Bool("llm-cbt", false, "Use local LLM for CBT rational responses (falls back to rules)")
+	backupOut  = flag.String("export-backup", "", "Write password-encrypted key backup to this path and exit")
+	rotateKey  = flag.Bool("rotate-key", false, "Replace the node key with a new one certified by the current key, then exit")
+	revocationOut = flag.String("revocation-cert", "", "Write a revocation certificate for the current key to this path (keep it offline), then exit")
+	recoverKey = flag.Bool("recover", false, "Restore the node key from a 24-word recovery phrase, then exit")
+	keyHistory = flag.String("key-history", "", "Key history for -recover: JSON from GET /api/identity/history on another device (default: key_history.json left in the data dir)")
+	mnemonic   = flag.Bool("mnemonic", false, "Show the recovery phrase for the current key and verify it was written down, then exit")
+	mnemonicLang = flag.String("lang", "ru", "Recovery phrase language: ru or en")
+	rewrapPath = flag.String("rewrap-backup", "", "Check a key backup and re-wrap legacy (v0) format with Argon2id, then exit")
+	splitKey   = flag.String("split-key", "", "Comma-separated person IDs: split the node key into encrypted shares for them (Shamir), then exit")
+	splitThreshold = flag.Int("threshold", 0, "Shares needed to recover with -split-key (default: majority)")
+	splitPassphrase = flag.Bool("split-passphrase", false, "With -split-key: split the backup password instead of the key")
+	openShare  = flag.String("open-share", "", "Decrypt a recovery share file addressed to this node and print it for the owner, then exit")
+	pairMaster = flag.String("pair", "", "Pair this node as a device of the identity whose master node has this Node ID (hex key), over the peer transport, then exit")
+	pairEndpoint = flag.String("pair-endpoint", "", "Peer address (host:port) of the master node for -pair (default: its Yggdrasil address)")
+	pairCode   = flag.String("pair-code", "", "Pairing code shown on the master device (asked interactively if empty)")
+	deviceName = flag.String("device-name", "", "Device name for -pair (default: hostname)")
+	peerListen = flag.String("peer-listen", "", "Accept peer connections on this TCP address instead of the Yggdrasil address (e.g. 127.0.0.1:9001 for local testing)")
+	combineShares = flag.Bool("combine-shares", false, "Restore the node key (or backup password) from recovery shares, then exit")
+	rlGossip   = flag.Duration("rl-gossip", 24*time.Hour, "Share differentially private RL experience with trusted peers this often (0 disables)")
+	rlEpsilon  = flag.Float64("rl-epsilon", rl.DefaultEpsilon, "Privacy parameter ε spent on each experience release")
+	rlBudget   = flag.Float64("rl-budget", rl.DefaultBudgetLimit, "Total ε the node may spend on experience releases per week")
+	syncInterval = flag.Duration("sync-interval", time.Minute, "Compare the journal with this identity's other devices this often (0: only on changes)")
+	yggGenconf = flag.String("ygg-genconf", "", "Write a yggdrasil.conf for this node to this path (\"-\": print it), showing the changes to the existing file, then exit")
+	yggConfig  = flag.String("ygg-config", "", "System yggdrasil.conf to check and compare with (default: search standard locations)")
+	yggListen  = flag.String("ygg-listen", "", "Comma-separated listen addresses for incoming Yggdrasil peers in the generated config (e.g. tls://[::]:9001)")
+	yggAdmin   = flag.String("ygg-admin", "", "Admin socket for the generated config (default: "+yggdrasil.DefaultAdminListen+")")
+	yggOwnKey  = flag.Bool("ygg-own-key", false, "Put the node key into the generated config so the app is reachable at its Node ID address")
 )
 
 // Global instances
 var (
 	journalInstance *journal.Journal
 	keyPair         *crypto.KeyPair
+	identityManager *identity.Manager
+	llmClient       *llm.Client
+	questionnaireStore *questionnaire.Store
+	activityStore   *activation.Store
+	beliefStore     *beliefs.Store
+	peopleDB        *db.Database
+	recoveryStore   *recovery.Store
+	deviceStore     *identity.DeviceStore
+	peerBook        *peers.Book
+	shareStore      *journal.Shares
+	outboxQueue     *outbox.Outbox
+	nodeTransport   transport.Transport
 )
 
 func main() {
@@ -42,58 +94,164 @@ func main() {
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		log.Fatalf("Failed to create data dir: %v", err)
 	}
+	yggKeyPath = filepath.Join(dir, yggKeyFile)
 
-	// Key management
-	keyPath := filepath.Join(dir, "private.key")
-	pubKeyPath := filepath.Join(dir, "public.key")
 	var err error
 
-	if *genKey {
-		keyPair, err = crypto.GenerateKeyPair()
+	if *rewrapPath != "" {
+		if err := rewrapBackup(*rewrapPath); err != nil {
+			log.Fatalf("Backup re-wrap failed: %v", err)
+		}
+		return
+	}
+
+	if *recoverKey {
+		identityManager, err = recoverFromMnemonic(dir, *keyHistory)
 		if err != nil {
-			log.Fatalf("Key generation failed: %v", err)
+			log.Fatalf("Recovery failed: %v", err)
 		}
-		if err := crypto.SavePrivateKey(keyPair.PrivateKey, keyPath); err != nil {
-			log.Fatalf("Failed to save private key: %v", err)
+		kp := identityManager.KeyPair()
+		fmt.Printf("✅ Identity restored: %s\n", identityManager.ID())
+		fmt.Printf("   Current key: %s\n", kp.ToHex())
+		fmt.Printf("   Yggdrasil IP: %s\n", identity.DeriveYggdrasilIP(kp.PublicKey))
+		return
+	}
+
+	if *combineShares {
+		if err := combineSharesCLI(dir); err != nil {
+			log.Fatalf("Recovery from shares failed: %v", err)
 		}
-		if err := os.WriteFile(pubKeyPath, keyPair.PublicKey, 0644); err != nil {
-			log.Fatalf("Failed to save public key: %v", err)
+		return
+	}
+
+	if *genKey {
+		identityManager, err = identity.Create(dir)
+		if err != nil {
+			log.Fatalf("Key generation failed: %v (use -rotate-key to replace an existing key)", err)
 		}
+		keyPair = identityManager.KeyPair()
 		fmt.Printf("✅ New keypair generated:\n")
 		fmt.Printf("   Public ID: %s\n", keyPair.ToHex())
-		fmt.Printf("   Yggdrasil IP: %s\n", crypto.DeriveYggdrasilIP(keyPair.PublicKey))
+		fmt.Printf("   Yggdrasil IP: %s\n", identity.DeriveYggdrasilIP(keyPair.PublicKey))
 		fmt.Printf("   ⚠️  %s", crypto.SecurityWarning())
-		fmt.Printf("   Private key saved to: %s\n", keyPath)
+		fmt.Printf("   Private key saved to: %s\n", filepath.Join(dir, "private.key"))
 		return
 	}
 
 	// Load or create key
-	if _, err := os.Stat(keyPath); os.IsNotExist(err) {
-		fmt.Println("🔑 No key found, generating new one...")
-		keyPair, err = crypto.GenerateKeyPair()
-		if err != nil {
-			log.Fatalf("Key generation failed: %v", err)
+	identityManager, err = identity.Open(dir)
+	if err != nil {
+		log.Fatalf("Failed to load identity: %v", err)
+	}
+	keyPair = identityManager.KeyPair()
+
+	peopleDB, err = openPeopleDB(dir)
+	if err != nil {
+		log.Fatalf("Failed to open people database: %v", err)
+	}
+	defer peopleDB.Close()
+
+	recoveryStore, err = recovery.NewStore(dir)
+	if err != nil {
+		log.Fatalf("Failed to initialize recovery shares: %v", err)
+	}
+
+	deviceStore, err = identity.NewDeviceStore(dir)
+	if err != nil {
+		log.Fatalf("Failed to initialize devices: %v", err)
+	}
+
+	peerBook, err = peers.NewBook(dir)
+	if err != nil {
+		log.Fatalf("Failed to open contact book: %v", err)
+	}
+
+	outboxQueue, err = outbox.New(outbox.Config{Dir: dir, Key: identityManager.KeyPair, Transmit: transmitToPeer})
+	if err != nil {
+		log.Fatalf("Failed to open outbox: %v", err)
+	}
+
+	if err := initExperience(dir, *rlBudget); err != nil {
+		log.Fatalf("Failed to load RL experience: %v", err)
+	}
+
+	if *pairMaster != "" {
+		if err := pairWithMaster(*pairMaster, *pairEndpoint, *pairCode, *deviceName); err != nil {
+			log.Fatalf("Pairing failed: %v", err)
 		}
-		if err := crypto.SavePrivateKey(keyPair.PrivateKey, keyPath); err != nil {
-			log.Fatalf("Failed to save private key: %v", err)
+		return
+	}
+
+	if *splitKey != "" {
+		if err := splitKeyCLI(dir, *splitKey, *splitThreshold, *splitPassphrase); err != nil {
+			log.Fatalf("Key split failed: %v", err)
 		}
-		if err := os.WriteFile(pubKeyPath, keyPair.PublicKey, 0644); err != nil {
-			log.Fatalf("Failed to save public key: %v", err)
+		return
+	}
+
+	if *openShare != "" {
+		if err := openShareCLI(*openShare); err != nil {
+			log.Fatalf("Failed to open share: %v", err)
 		}
-	} else {
-		priv, err := crypto.LoadPrivateKey(keyPath)
-		if err != nil {
-			log.Fatalf("Failed to load private key: %v", err)
+		return
+	}
+
+	if *mnemonic {
+		if err := showMnemonic(keyPair, identity.Language(*mnemonicLang)); err != nil {
+			log.Fatalf("Recovery phrase: %v", err)
 		}
-		pub, err := os.ReadFile(pubKeyPath)
+		return
+	}
+
+	if *rotateKey {
+		st, err := identityManager.Rotate("manual rotation", time.Now())
 		if err != nil {
-			log.Fatalf("Failed to load public key: %v", err)
+			log.Fatalf("Key rotation failed: %v", err)
+		}
+		fmt.Printf("🔄 Key rotated (#%d): %s... → %s...\n", st.Sequence, st.OldKey[:16], st.NewKey[:16])
+		fmt.Printf("   Identity ID stays: %s\n", identityManager.ID())
+		if n, err := recoveryStore.MarkKeyStale(st.OldKey); err != nil {
+			log.Printf("⚠️  Recovery share sets not updated: %v", err)
+		} else if n > 0 {
+			fmt.Printf("⚠️  %d recovery share set(s) hold the old key; split the new one again (-split-key)\n", n)
+		}
+		fmt.Println("⚠️  A recovery phrase written down before now restores the retired key, not the new one: write down the new phrase (-mnemonic)")
+		warnYggKeyRetired(st.OldKey)
+		return
+	}
+
+	if *revocationOut != "" {
+		if err := writeRevocationCert(*revocationOut); err != nil {
+			log.Fatalf("Failed to write revocation certificate: %v", err)
+		}
+		return
+	}
+
+	if *backupOut != "" {
+		if err := exportBackup(keyPair, *backupOut); err != nil {
+			log.Fatalf("Backup export failed: %v", err)
 		}
-		keyPair = &crypto.KeyPair{PrivateKey: priv, PublicKey: ed25519.PublicKey(pub)}
+		return
+	}
+
+	if *yggGenconf != "" {
+		if err := writeYggConfig(*yggGenconf); err != nil {
+			log.Fatalf("Yggdrasil config: %v", err)
+		}
+		return
 	}
 
 	fmt.Printf("🗝️  Node ID: %s\n", keyPair.ToHex()[:16]+"...")
-	fmt.Printf("🌐 App Yggdrasil IP: %s\n", crypto.DeriveYggdrasilIP(keyPair.PublicKey))
+	fmt.Printf("🌐 App Yggdrasil IP: %s\n", identity.DeriveYggdrasilIP(keyPair.PublicKey))
+
+	// Локальная LLM для ответов по дневнику (только Ollama, без облака)
+	llmCfg := llm.DefaultConfigForHardware()
+	llmCfg.Host = *ollamaHost
+	llmCfg.EmbedModel = *ollamaModel
+	if *llmModel != "" {
+		llmCfg.Model = *llmModel
+	}
+	llmClient = llm.NewClient(llmCfg)
 
 	// Initialize journal with Ollama option
 	journalCfg := journal.JournalConfig{
@@ -103,12 +261,40 @@ func main() {
 		UseOllamaEmbed: *useOllama,
 		DefaultMode:    journal.EntryTypeCBT,
 	}
+	if *llmCBT {
+		journalCfg.Generator = llmClient
+	}
 	journalInstance, err = journal.NewJournal(journalCfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize journal: %v", err)
 	}
 	fmt.Printf("📓 Journal initialized: %d entries loaded\n", len(journalInstance.GetEntries(journal.EntryFilters{})))
 
+	shareStore, err = journal.NewShares(dir)
+	if err != nil {
+		log.Fatalf("Failed to initialize journal shares: %v", err)
+	}
+	journalInstance.OnEntryAdded(shareNewEntry)
+
+	if err := initJournalSync(dir); err != nil {
+		log.Fatalf("Failed to open journal sync log: %v", err)
+	}
+
+	questionnaireStore, err = questionnaire.NewStore(dir)
+	if err != nil {
+		log.Fatalf("Failed to initialize questionnaires: %v", err)
+	}
+
+	activityStore, err = activation.NewStore(dir)
+	if err != nil {
+		log.Fatalf("Failed to initialize activities: %v", err)
+	}
+
+	beliefStore, err = beliefs.NewStore(dir)
+	if err != nil {
+		log.Fatalf("Failed to initialize core beliefs: %v", err)
+	}
+
 	// Yggdrasil client (optional)
 	yggAvailable := checkYggdrasil("/usr/bin/yggdrasil")
 	if !yggAvailable {
@@ -121,6 +307,15 @@ func main() {
 		log.Printf("⚠️  Yggdrasil client init failed: %v", err)
 	} else {
 		defer ygg.Close()
+		yggClient = ygg
+		if *bootstrap != "" && ygg.Available() {
+			if err := ygg.Bootstrap(strings.Split(*bootstrap, ",")); err != nil {
+				log.Printf("⚠️  Bootstrap: %v", err)
+			}
+		}
+	}
+	if yggAvailable || *peerListen == "" {
+		reportYggReachability()
 	}
 
 	// Start web server
@@ -139,18 +334,16 @@ func main() {
 		cancel()
 	}()
 
-	// Yggdrasil message listener (optional)
-	if ygg != nil {
-		go func() {
-			if err := ygg.Receive(ctx, func(msg []byte) error {
-				fmt.Printf("📥 Received %d bytes (encrypted)\n", len(msg))
-				// TODO: decrypt and process message
-				return nil
-			}); err != nil && err != context.Canceled {
-				log.Printf("Receive error: %v", err)
-			}
-		}()
+	// Транспорт между узлами: Yggdrasil-адрес или -peer-listen
+	if err := startTransport(ctx, ygg, *peerListen); err != nil {
+		log.Printf("⚠️  Peer transport disabled: %v", err)
+	} else if nodeTransport != nil {
+		defer nodeTransport.Close()
 	}
+	syncPendingShares()
+	go outboxQueue.Run(ctx)
+	go runExperienceGossip(ctx, *rlGossip, *rlEpsilon)
+	go runJournalSync(ctx, *syncInterval)
 
 	fmt.Printf("✅ Node + Server running at http://%s:%s\n", *bindAddr, *port)
 	fmt.Println("🔐 Security: Your private key is stored encrypted at rest.")
@@ -176,6 +369,82 @@ func startWebServer(bindAddr, port string) {
 	http.HandleFunc("/api/journal/entries", handleJournalEntries)
 	http.HandleFunc("/api/journal/search", handleJournalSearch)
 	http.HandleFunc("/api/journal/export/md", handleJournalExportMD)
+	http.HandleFunc("/api/journal/ask", handleJournalAsk)
+	http.HandleFunc("/api/journal/shares", handleJournalShares)
+	http.HandleFunc("/api/journal/shares/sync", handleJournalShareSync)
+	http.HandleFunc("/api/journal/shares/revoke", handleJournalShareRevoke)
+	http.HandleFunc("/api/journal/shared-with-me", handleSharedWithMe)
+	http.HandleFunc("/api/journal/entry", handleJournalEntry)
+	http.HandleFunc("/api/journal/entry/tags", handleJournalEntryTags)
+	http.HandleFunc("/api/journal/sync", handleJournalSync)
+
+	// Guided CBT sessions
+	http.HandleFunc("/api/cbt/sessions", handleGuidedSessions)
+	http.HandleFunc("/api/cbt/session", handleGuidedSession)
+	http.HandleFunc("/api/cbt/session/answer", handleGuidedAnswer)
+
+	// Questionnaires (PHQ-9, GAD-7, PSS-10)
+	http.HandleFunc("/api/questionnaires", handleQuestionnaires)
+	http.HandleFunc("/api/questionnaires/responses", handleQuestionnaireResponses)
+	http.HandleFunc("/api/questionnaires/alerts", handleQuestionnaireAlerts)
+	http.HandleFunc("/api/questionnaires/phases", handleQuestionnairePhases)
+
+	// Behavioral activation
+	http.HandleFunc("/api/activities", handleActivities)
+	http.HandleFunc("/api/activities/complete", handleActivityComplete)
+	http.HandleFunc("/api/activities/skip", handleActivitySkip)
+	http.HandleFunc("/api/activities/week", handleActivityWeek)
+	http.HandleFunc("/api/activities/analysis", handleActivityAnalysis)
+
+	// Core beliefs and downward arrow
+	http.HandleFunc("/api/beliefs", handleBeliefs)
+	http.HandleFunc("/api/beliefs/arrow", handleBeliefArrow)
+	http.HandleFunc("/api/beliefs/rate", handleBeliefRate)
+	http.HandleFunc("/api/beliefs/link", handleBeliefLink)
+	http.HandleFunc("/api/beliefs/suggest", handleBeliefSuggest)
+	http.HandleFunc("/api/beliefs/trends", handleBeliefTrends)
+
+	// Identity: key history, rotation and revocation
+	http.HandleFunc("/api/identity", handleIdentity)
+	http.HandleFunc("/api/identity/history", handleIdentityHistory)
+	http.HandleFunc("/api/identity/verify", handleIdentityVerify)
+	http.HandleFunc("/api/identity/revoke", handleIdentityRevoke)
+
+	// Devices of this identity (pairing, certificates, revocation)
+	http.HandleFunc("/api/devices", handleDevices)
+	http.HandleFunc("/api/devices/pairing", handleDevicePairing)
+	http.HandleFunc("/api/devices/revoke", handleDeviceRevoke)
+	http.HandleFunc("/api/devices/revocations", handleDeviceRevocations)
+	http.HandleFunc("/api/devices/verify", handleDeviceVerify)
+
+	// Peers: contact book, trust levels and active connections
+	http.HandleFunc("/api/peers", handlePeers)
+	http.HandleFunc("/api/peers/peer", handlePeer)
+	http.HandleFunc("/api/peers/verify", handlePeerVerify)
+	http.HandleFunc("/api/peers/policy", handlePeerPolicy)
+	http.HandleFunc("/api/peers/sessions", handlePeerSessions)
+
+	// Yggdrasil: system config and the address the app is reachable at
+	http.HandleFunc("/api/yggdrasil/config", handleYggConfig)
+	http.HandleFunc("/api/yggdrasil/reachability", handleYggReachability)
+
+	// Outbox: store-and-forward delivery to peers
+	http.HandleFunc("/api/outbox", handleOutbox)
+	http.HandleFunc("/api/outbox/message", handleOutboxMessage)
+	http.HandleFunc("/api/outbox/retry", handleOutboxRetry)
+
+	// RL experience: feedback, choice and private exchange with trusted peers
+	http.HandleFunc("/api/rl/choose", handleExperienceChoose)
+	http.HandleFunc("/api/rl/feedback", handleExperienceFeedback)
+	http.HandleFunc("/api/rl/experience", handleExperience)
+	http.HandleFunc("/api/rl/gossip", handleExperienceGossip)
+
+	// Social recovery (Shamir shares among trusted people)
+	http.HandleFunc("/api/recovery/sets", handleRecoverySets)
+	http.HandleFunc("/api/recovery/held", handleRecoveryHeld)
+	http.HandleFunc("/api/recovery/open", handleRecoveryOpen)
+	http.HandleFunc("/api/recovery/combine", handleRecoveryCombine)
+	http.HandleFunc("/api/people/public-key", handlePersonPublicKey)
 
 	// Health check
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -271,6 +540,39 @@ func handleJournalSearch(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(entries)
 }
 
+// handleJournalAsk — POST /api/journal/ask {"question": "...", "limit": 8}
+func handleJournalAsk(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Question string `json:"question"`
+		Limit    int    `json:"limit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !llmClient.IsAvailable() {
+		http.Error(w, "Local LLM (Ollama) is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	answer, err := journalInstance.Ask(req.Question, llmClient, journal.AskOptions{Limit: req.Limit})
+	if err != nil {
+		if err == journal.ErrEmptyQuestion {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(answer)
+}
+
 // handleJournalExportMD — GET /api/journal/export/md
 func handleJournalExportMD(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
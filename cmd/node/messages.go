@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/envelope"
+	"ideal-core/pkg/outbox"
+	"ideal-core/pkg/transport"
+	"ideal-core/pkg/yggdrasil"
+	"log"
+	"net/http"
+	"time"
+)
+
+// newMessageReceiver создаёт приёмник конвертов и регистрирует обработчики по типам
+func newMessageReceiver() *envelope.Receiver {
+	r := envelope.NewReceiver(identityManager.KeyPair)
+	r.SetPolicy(func(sender ed25519.PublicKey) error {
+		if err := peerBook.Allow(sender); err != nil {
+			return err
+		}
+		return peerBook.Seen(sender, time.Now())
+	})
+	r.Handle(envelope.TypePing, handlePingMessage)
+	r.Handle(envelope.TypeJournalShare, handleJournalShareMessage)
+	r.Handle(envelope.TypeJournalRevoke, handleJournalRevokeMessage)
+	r.Handle(envelope.TypeAck, handleAckMessage)
+	r.Handle(envelope.TypeExperience, handleExperienceMessage)
+	r.Handle(envelope.TypeJournalSync, handleJournalSyncMessage)
+	r.Handle(envelope.TypeJournalOps, handleJournalOpsMessage)
+	r.Handle(envelope.TypeDevicePair, handleDevicePairMessage)
+	r.Handle(envelope.TypeDeviceChain, handleDeviceChainMessage)
+	// Новое устройство ещё не в адресной книге: обработчик проверяет его сам
+	r.Exempt(envelope.TypeDevicePair)
+	r.OnHandled(acknowledge)
+	return r
+}
+
+var errPeerTransportOff = errors.New("peer transport is not running (no Yggdrasil and no -peer-listen)")
+
+// ackTimeout — на отправку подтверждения (обычно по уже открытой сессии)
+const ackTimeout = 10 * time.Second
+
+// startTransport поднимает транспорт к другим узлам и цикл приёма сообщений.
+// Без Yggdrasil и без -peer-listen узел работает только локально.
+func startTransport(ctx context.Context, ygg *yggdrasil.Client, listenAddr string) error {
+	tr, err := newPeerTransport(ygg, listenAddr)
+	if err != nil || tr == nil {
+		return err
+	}
+	if err := tr.Listen(); err != nil {
+		return err
+	}
+	nodeTransport = tr
+	fmt.Printf("👂 Listening for peers on %s\n", tr.Addr())
+
+	receiver := newMessageReceiver()
+	go func() {
+		for {
+			pkt, err := tr.Receive(ctx)
+			if err != nil {
+				if !errors.Is(err, transport.ErrClosed) && ctx.Err() == nil {
+					log.Printf("Receive error: %v", err)
+				}
+				return
+			}
+			// Пир на связи — не ждём конца паузы перед повтором его сообщений
+			outboxQueue.RetryPeer(pkt.From, time.Now())
+			// Ошибка одного сообщения не должна останавливать приём
+			if err := receiver.Process(ctx, pkt.Data); err != nil {
+				log.Printf("⚠️  Dropped message from %s... (%d bytes): %v", hex.EncodeToString(pkt.From)[:16], len(pkt.Data), err)
+			}
+		}
+	}()
+	return nil
+}
+
+// newPeerTransport — транспорт на listenAddr или на Yggdrasil-адресе узла
+// (nil, если нет ни того, ни другого)
+func newPeerTransport(ygg *yggdrasil.Client, listenAddr string) (*transport.TCP, error) {
+	switch {
+	case listenAddr != "":
+		return transport.NewTCP(identityManager.KeyPair, listenAddr), nil
+	case ygg != nil && ygg.Available():
+		return transport.NewYggdrasil(ygg, identityManager.KeyPair)
+	}
+	return nil, nil
+}
+
+// sendToPeer запечатывает payload в конверт и сразу отправляет пиру (без очереди).
+// Для сообщений, которые должны дойти, используйте outboxQueue.Enqueue.
+func sendToPeer(ctx context.Context, key ed25519.PublicKey, typ envelope.Type, payload []byte) error {
+	env, err := envelope.Seal(identityManager.KeyPair(), key, typ, payload, time.Now())
+	if err != nil {
+		return err
+	}
+	return transmitToPeer(ctx, key, env.Marshal())
+}
+
+// transmitToPeer отправляет кадр пиру из адресной книги. Если сессии нет,
+// подключается по адресу пира и проверяет, что ответил тот же ключ.
+func transmitToPeer(ctx context.Context, key ed25519.PublicKey, frame []byte) error {
+	if nodeTransport == nil {
+		return errPeerTransportOff
+	}
+	err := nodeTransport.Send(ctx, key, frame)
+	if !errors.Is(err, transport.ErrNoSession) {
+		return err
+	}
+
+	p, err := peerBook.Get(hex.EncodeToString(key))
+	if err != nil {
+		// Без записи в книге адрес взять неоткуда — повторять бессмысленно
+		return fmt.Errorf("%w: %w", outbox.ErrPermanent, err)
+	}
+	got, err := nodeTransport.Dial(ctx, p.DialAddr())
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", p.DialAddr(), err)
+	}
+	if !got.Equal(key) {
+		return fmt.Errorf("%s answered with key %s..., expected %s...", p.DialAddr(), hex.EncodeToString(got)[:16], p.Key[:16])
+	}
+	return nodeTransport.Send(ctx, key, frame)
+}
+
+// acknowledge подтверждает приём обработанного сообщения (кроме самих подтверждений,
+// синхронизации дневника и устройств: они идут мимо очереди и повторяются сами)
+func acknowledge(ctx context.Context, msg envelope.Message) {
+	switch msg.Type {
+	case envelope.TypeAck, envelope.TypeJournalSync, envelope.TypeJournalOps,
+		envelope.TypeDevicePair, envelope.TypeDevicePaired, envelope.TypeDeviceChain:
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+		defer cancel()
+		if err := sendToPeer(ctx, msg.Sender, envelope.TypeAck, msg.Nonce[:]); err != nil {
+			log.Printf("⚠️  Ack to %s... failed: %v", hex.EncodeToString(msg.Sender)[:16], err)
+		}
+	}()
+}
+
+// handleAckMessage — пир подтвердил приём нашего сообщения
+func handleAckMessage(ctx context.Context, msg envelope.Message) error {
+	_, err := outboxQueue.Ack(msg.Sender, msg.Payload, time.Now())
+	return err
+}
+
+// handlePingMessage — проверка связи от пира
+func handlePingMessage(ctx context.Context, msg envelope.Message) error {
+	fmt.Printf("📥 Ping from %s... (%d bytes)\n", hex.EncodeToString(msg.Sender)[:16], len(msg.Payload))
+	return nil
+}
+
+// handlePeerSessions — GET /api/peers/sessions (активные соединения с узлами)
+func handlePeerSessions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sessions := []yggdrasil.PeerSession{}
+	if tcp, ok := nodeTransport.(*transport.TCP); ok {
+		sessions = tcp.Manager().Sessions()
+	}
+	json.NewEncoder(w).Encode(sessions)
+}
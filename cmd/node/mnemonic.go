@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"ideal-core/pkg/identity"
+	"os"
+	"strings"
+)
+
+// challengeWords — сколько слов фразы спрашивать при проверке записи
+const challengeWords = 3
+
+// showMnemonic печатает фразу для текущего ключа и проверяет, что её записали
+func showMnemonic(kp *crypto.KeyPair, lang identity.Language) error {
+	words, err := identity.MnemonicForKey(kp, lang)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("📝 Write down these 24 words in order and keep them offline:")
+	fmt.Println()
+	for i, w := range words {
+		fmt.Printf("   %2d. %-12s", i+1, w)
+		if (i+1)%4 == 0 {
+			fmt.Println()
+		}
+	}
+	fmt.Println()
+	fmt.Println("   Anyone with this phrase can restore your identity. Never type it into websites.")
+	fmt.Println()
+
+	if _, err := readLine("Press Enter when you have written the phrase down..."); err != nil {
+		return err
+	}
+	// Прокручиваем экран, чтобы фраза не осталась перед глазами при проверке
+	fmt.Print(strings.Repeat("\n", 40))
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		positions, err := identity.VerificationChallenge(challengeWords)
+		if err != nil {
+			return err
+		}
+		answers := make([]string, len(positions))
+		for i, pos := range positions {
+			if answers[i], err = readLine(fmt.Sprintf("Word #%d: ", pos+1)); err != nil {
+				return err
+			}
+		}
+		if identity.CheckChallenge(words, positions, answers) {
+			fmt.Println("✅ Phrase verified.")
+			return nil
+		}
+		fmt.Printf("❌ Words do not match (attempt %d of 3).\n", attempt)
+	}
+	return errors.New("phrase verification failed; run -mnemonic again and re-check your copy")
+}
+
+// recoverFromMnemonic восстанавливает ключ из фразы (IDEAL_MNEMONIC или ввод в терминале)
+// вместе с его историей: из historyPath или key_history.json, оставшегося в dir.
+// Node ID остаётся прежним. Без истории Node ID станет сам ключ — это верно,
+// только если ключ ни разу не ротировался.
+func recoverFromMnemonic(dir, historyPath string) (*identity.Manager, error) {
+	phrase := os.Getenv("IDEAL_MNEMONIC")
+	if phrase == "" {
+		var err error
+		if phrase, err = readLine("🔑 Enter your 24-word recovery phrase: "); err != nil {
+			return nil, err
+		}
+	}
+	kp, err := identity.KeyFromMnemonic(phrase)
+	if err != nil {
+		return nil, err
+	}
+
+	if historyPath == "" {
+		historyPath = dir
+	}
+	history, err := identity.LoadHistory(historyPath)
+	if errors.Is(err, os.ErrNotExist) && historyPath == dir {
+		fmt.Println("⚠️  No key history found (-key-history): the Node ID will be the recovered key itself")
+		return identity.CreateFromKey(dir, kp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	keys := history.Keys()
+	for i, key := range keys[:len(keys)-1] {
+		if key == kp.ToHex() {
+			return nil, fmt.Errorf("this phrase restores retired key #%d of identity %s; recover the current key from shares (-combine-shares) or a backup", i, history.Root[:16]+"...")
+		}
+	}
+	return identity.RestoreFromKey(dir, kp, history)
+}
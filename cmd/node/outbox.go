@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"ideal-core/pkg/outbox"
+	"net/http"
+	"os"
+	"time"
+)
+
+// withoutPayload скрывает содержимое сообщения (оно может быть записью дневника)
+func withoutPayload(m outbox.Message) outbox.Message {
+	m.Payload = nil
+	return m
+}
+
+// handleOutbox — GET /api/outbox[?status=queued|sent|delivered|failed]
+func handleOutbox(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	messages := outboxQueue.List(outbox.Status(r.URL.Query().Get("status")))
+	for i := range messages {
+		messages[i] = withoutPayload(messages[i])
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"stats":    outboxQueue.Stats(),
+		"messages": messages,
+	})
+}
+
+// handleOutboxMessage — GET /api/outbox/message?id=... (статус), DELETE (отменить отправку)
+func handleOutboxMessage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := r.URL.Query().Get("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		m, err := outboxQueue.Get(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(withoutPayload(m))
+
+	case http.MethodDelete:
+		if err := outboxQueue.Cancel(id); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleOutboxRetry — POST /api/outbox/retry {"id": "..."} (повторить сейчас, в т.ч. неудачное)
+func handleOutboxRetry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	m, err := outboxQueue.Retry(req.ID, outbox.DefaultTTL, time.Now())
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(withoutPayload(m))
+}
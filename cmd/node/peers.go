@@ -0,0 +1,245 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/identity"
+	"ideal-core/pkg/peers"
+	"net/http"
+	"os"
+	"time"
+)
+
+// peerView — пир с отпечатком для сверки и признаком активной сессии
+type peerView struct {
+	peers.Peer
+	Fingerprint string `json:"fingerprint"`
+	Online      bool   `json:"online"`
+}
+
+func viewPeer(p peers.Peer) peerView {
+	v := peerView{Peer: p, Fingerprint: peers.Fingerprint(identityManager.KeyPair().PublicKey, p.PublicKey())}
+	if nodeTransport != nil {
+		for _, k := range nodeTransport.Peers() {
+			if k.Equal(p.PublicKey()) {
+				v.Online = true
+				break
+			}
+		}
+	}
+	return v
+}
+
+// linkPerson привязывает пира к человеку из db и записывает ему ключ
+func linkPerson(personID, key string) error {
+	if err := peopleDB.SetPersonPublicKey(personID, key); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("person %s: %w", personID, os.ErrNotExist)
+		}
+		return err
+	}
+	return nil
+}
+
+// handlePeers — GET /api/peers[?trust=trusted], POST (добавить пира).
+// Пира с trust "device" принимаем, только если его цепочка устройства ("chain")
+// сходится к нашей идентичности или устройство заверено этим узлом.
+func handlePeers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		list := peerBook.List(peers.Trust(r.URL.Query().Get("trust")))
+		views := make([]peerView, 0, len(list))
+		for _, p := range list {
+			views = append(views, viewPeer(p))
+		}
+		json.NewEncoder(w).Encode(views)
+
+	case http.MethodPost:
+		var req struct {
+			PublicKey string                `json:"public_key"`
+			Name      string                `json:"name"`
+			Trust     peers.Trust           `json:"trust"`
+			PersonID  string                `json:"person_id"`
+			Endpoint  string                `json:"endpoint"`
+			Chain     *identity.DeviceChain `json:"chain"` // для trust "device"
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		key, err := identity.ParseKey(req.PublicKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Trust == "" {
+			req.Trust = peers.TrustKnown
+		}
+		p, err := peers.NewPeer(key, req.Name, req.Trust, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if p.Trust == peers.TrustDevice {
+			if err := admitDevice(p.Key, req.Chain); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+		p.Endpoint = req.Endpoint
+		if req.PersonID != "" {
+			if err := linkPerson(req.PersonID, p.Key); err != nil {
+				writePeerError(w, err)
+				return
+			}
+			p.PersonID = req.PersonID
+		}
+		if p, err = peerBook.Add(p); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(viewPeer(p))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePeer — GET/DELETE /api/peers/peer?key=hex,
+// POST {"name": "...", "trust": "trusted", "person_id": "...", "endpoint": "host:port"} (изменить запись)
+func handlePeer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	key := r.URL.Query().Get("key")
+
+	switch r.Method {
+	case http.MethodGet:
+		p, err := peerBook.Get(key)
+		if err != nil {
+			writePeerError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(viewPeer(p))
+
+	case http.MethodPost:
+		var req struct {
+			Name     *string               `json:"name"`
+			Trust    *peers.Trust          `json:"trust"`
+			PersonID *string               `json:"person_id"`
+			Endpoint *string               `json:"endpoint"`
+			Chain    *identity.DeviceChain `json:"chain"` // для trust "device"
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p, err := peerBook.Update(key, func(p *peers.Peer) error {
+			if req.Name != nil {
+				p.Name = *req.Name
+			}
+			if req.Trust != nil && *req.Trust == peers.TrustDevice && p.Trust != peers.TrustDevice {
+				if err := admitDevice(p.Key, req.Chain); err != nil {
+					return err
+				}
+			}
+			if req.Trust != nil {
+				p.Trust = *req.Trust
+			}
+			if req.Endpoint != nil {
+				p.Endpoint = *req.Endpoint
+			}
+			if req.PersonID != nil && *req.PersonID != p.PersonID {
+				if *req.PersonID != "" {
+					if err := linkPerson(*req.PersonID, p.Key); err != nil {
+						return err
+					}
+				}
+				p.PersonID = *req.PersonID
+			}
+			return nil
+		})
+		if err != nil {
+			writePeerError(w, err)
+			return
+		}
+		forgetPeerExperience(p)
+		json.NewEncoder(w).Encode(viewPeer(p))
+
+	case http.MethodDelete:
+		if err := peerBook.Remove(key); err != nil {
+			writePeerError(w, err)
+			return
+		}
+		forgetPeerExperience(peers.Peer{Key: key})
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePeerVerify — POST /api/peers/verify {"key": "hex", "verified": true}
+// Отмечает, что отпечаток сверен с человеком лично (или снимает отметку).
+func handlePeerVerify(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Key      string `json:"key"`
+		Verified bool   `json:"verified"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, err := peerBook.MarkVerified(req.Key, req.Verified, time.Now())
+	if err != nil {
+		writePeerError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(viewPeer(p))
+}
+
+// handlePeerPolicy — GET /api/peers/policy, POST {"accept_unknown": false}
+func handlePeerPolicy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(peerBook.Policy())
+
+	case http.MethodPost:
+		var p peers.Policy
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := peerBook.SetPolicy(p); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(p)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writePeerError(w http.ResponseWriter, err error) {
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, errNotOwnDevice) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
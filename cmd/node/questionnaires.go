@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"ideal-core/pkg/questionnaire"
+	"log"
+	"net/http"
+)
+
+// handleQuestionnaires — GET /api/questionnaires (описания опросников)
+func handleQuestionnaires(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(questionnaire.All())
+}
+
+// handleQuestionnaireResponses — GET/POST /api/questionnaires/responses
+func handleQuestionnaireResponses(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		if entry := q.Get("entry"); entry != "" {
+			json.NewEncoder(w).Encode(questionnaireStore.ByEntry(entry))
+			return
+		}
+		json.NewEncoder(w).Encode(questionnaireStore.History(q.Get("person"), questionnaire.ID(q.Get("q"))))
+
+	case http.MethodPost:
+		var resp questionnaire.Response
+		if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		saved, err := questionnaireStore.Submit(resp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, alert := range saved.Score.Alerts {
+			log.Printf("🚨 %s [%s]: %s", saved.QuestionnaireID, saved.PersonID, alert)
+		}
+		json.NewEncoder(w).Encode(saved)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleQuestionnaireAlerts — GET /api/questionnaires/alerts?person=...
+func handleQuestionnaireAlerts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(questionnaireStore.Alerts(r.URL.Query().Get("person")))
+}
+
+// handleQuestionnairePhases — GET /api/questionnaires/phases?person=...&q=phq9
+func handleQuestionnairePhases(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	if q.Get("q") == "" {
+		http.Error(w, "Parameter q is required", http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(questionnaireStore.PhaseSummaries(q.Get("person"), questionnaire.ID(q.Get("q"))))
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/db"
+	"ideal-core/pkg/identity"
+	"ideal-core/pkg/recovery"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// trusteesFor находит доверенных людей по ID; у каждого должен быть сохранён ключ узла
+func trusteesFor(personIDs []string) ([]recovery.Trustee, error) {
+	trustees := make([]recovery.Trustee, 0, len(personIDs))
+	for _, id := range personIDs {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		p, err := peopleDB.GetPerson(id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("person %s: %w", id, os.ErrNotExist)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.PublicKey == "" {
+			return nil, fmt.Errorf("person %s (%s) has no public key; set it via POST /api/people/public-key", id, p.Name)
+		}
+		key, err := identity.ParseKey(p.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("person %s: %v", id, err)
+		}
+		trustees = append(trustees, recovery.Trustee{PersonID: p.ID, Name: p.Name, PublicKey: key})
+	}
+	return trustees, nil
+}
+
+// defaultThreshold — простое большинство, если порог не задан
+func defaultThreshold(threshold, total int) int {
+	if threshold > 0 {
+		return threshold
+	}
+	return total/2 + 1
+}
+
+// splitSecret делит ключ узла (или пароль бэкапа) между людьми и сохраняет набор
+func splitSecret(kind recovery.Kind, passphrase string, threshold int, personIDs []string) (*recovery.ShareSet, error) {
+	trustees, err := trusteesFor(personIDs)
+	if err != nil {
+		return nil, err
+	}
+	kp := identityManager.KeyPair()
+	threshold = defaultThreshold(threshold, len(trustees))
+
+	var set *recovery.ShareSet
+	switch kind {
+	case recovery.KindKey:
+		var history []byte
+		if history, err = json.Marshal(identityManager.History()); err != nil {
+			return nil, err
+		}
+		set, err = recovery.SplitKey(kp, history, threshold, trustees, time.Now())
+	case recovery.KindPassphrase:
+		if passphrase == "" {
+			return nil, errors.New("passphrase is required")
+		}
+		set, err = recovery.NewShareSet(kp, kind, []byte(passphrase), threshold, trustees, time.Now())
+	default:
+		return nil, fmt.Errorf("unknown secret kind %q", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := recoveryStore.AddSet(*set); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// splitKeyCLI — флаг -split-key: файлы долей для передачи доверенным людям
+func splitKeyCLI(dir, people string, threshold int, passphrase bool) error {
+	kind, secret := recovery.KindKey, ""
+	if passphrase {
+		var err error
+		kind = recovery.KindPassphrase
+		if secret, err = readBackupPassword("🔐 Backup password to split: "); err != nil {
+			return err
+		}
+	}
+	set, err := splitSecret(kind, secret, threshold, strings.Split(people, ","))
+	if err != nil {
+		return err
+	}
+
+	outDir := filepath.Join(dir, "recovery")
+	if err := os.MkdirAll(outDir, 0700); err != nil {
+		return err
+	}
+	fmt.Printf("🧩 %s split into %d shares, any %d recover it (set %s)\n", kind, set.Total, set.Threshold, set.ID)
+	for _, s := range set.Shares {
+		data, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(outDir, fmt.Sprintf("%s-%s.share.json", set.ID, s.PersonID))
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return err
+		}
+		fmt.Printf("   #%d %-20s %s\n", s.Index, s.Name, path)
+	}
+	fmt.Println("   Each file is encrypted to that person's key; send it to them (-open-share on their node).")
+	return nil
+}
+
+// openShareCLI — флаг -open-share: доверенный открывает долю и передаёт текст владельцу
+func openShareCLI(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var s recovery.SealedShare
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("parse share: %w", err)
+	}
+	piece, err := recovery.OpenShare(identityManager.KeyPair(), s)
+	if err != nil {
+		return err
+	}
+	if err := recoveryStore.Hold(s); err != nil {
+		return err
+	}
+	fmt.Printf("🧩 Share #%d of %d (any %d recover) for %s...\n", piece.Share.X, piece.Total, piece.Threshold, piece.Owner[:16])
+	fmt.Println("   Give this text to the owner only after confirming it is really them:")
+	fmt.Println()
+	fmt.Println(piece.Encode())
+	return nil
+}
+
+// readPieces читает доли из IDEAL_SHARES или построчно из терминала, пока не наберётся порог
+func readPieces() ([]recovery.Piece, error) {
+	var pieces []recovery.Piece
+	if env := os.Getenv("IDEAL_SHARES"); env != "" {
+		for _, s := range strings.Fields(strings.ReplaceAll(env, ",", " ")) {
+			p, err := recovery.DecodePiece(s)
+			if err != nil {
+				return nil, err
+			}
+			pieces = append(pieces, p)
+		}
+		return pieces, nil
+	}
+	for {
+		line, err := readLine(fmt.Sprintf("🧩 Share %d: ", len(pieces)+1))
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		p, err := recovery.DecodePiece(line)
+		if err != nil {
+			fmt.Printf("   ❌ %v\n", err)
+			continue
+		}
+		pieces = append(pieces, p)
+		if len(pieces) >= p.Threshold {
+			return pieces, nil
+		}
+	}
+}
+
+// combineSharesCLI — флаг -combine-shares: восстановление ключа (или пароля) из долей
+func combineSharesCLI(dir string) error {
+	pieces, err := readPieces()
+	if err != nil {
+		return err
+	}
+	kind, secret, err := recovery.Recover(pieces)
+	if err != nil {
+		return err
+	}
+	if kind == recovery.KindPassphrase {
+		fmt.Printf("🔐 Recovered backup password: %s\n", secret)
+		fmt.Println("   Use it with the encrypted backup file.")
+		return nil
+	}
+	kp, err := recovery.RecoverKey(pieces)
+	if err != nil {
+		return err
+	}
+	if len(pieces[0].History) == 0 {
+		// Доли, разделённые до появления истории в них: ключ становится новым корнем
+		fmt.Println("⚠️  These shares carry no key history: the Node ID will be the recovered key itself")
+		identityManager, err = identity.CreateFromKey(dir, kp)
+	} else {
+		var history identity.KeyHistory
+		if err := json.Unmarshal(pieces[0].History, &history); err != nil {
+			return fmt.Errorf("key history in shares: %w", err)
+		}
+		identityManager, err = identity.RestoreFromKey(dir, kp, history)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Identity %s restored from %d shares: %s\n", identityManager.ID(), len(pieces), kp.ToHex())
+	fmt.Printf("   Yggdrasil IP: %s\n", identity.DeriveYggdrasilIP(kp.PublicKey))
+	return nil
+}
+
+// handleRecoverySets — GET/POST /api/recovery/sets
+// POST: {"kind": "key"|"passphrase", "passphrase": "...", "threshold": 3, "person_ids": [...]}
+// Ответ содержит зашифрованные доли для рассылки.
+func handleRecoverySets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(recoveryStore.Sets())
+
+	case http.MethodPost:
+		var req struct {
+			Kind       recovery.Kind `json:"kind"`
+			Passphrase string        `json:"passphrase"`
+			Threshold  int           `json:"threshold"`
+			PersonIDs  []string      `json:"person_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Kind == "" {
+			req.Kind = recovery.KindKey
+		}
+		set, err := splitSecret(req.Kind, req.Passphrase, req.Threshold, req.PersonIDs)
+		if err != nil {
+			writeRecoveryError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(set)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRecoveryHeld — GET/POST /api/recovery/held (доли, доверенные нам другими)
+func handleRecoveryHeld(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(recoveryStore.Held())
+
+	case http.MethodPost:
+		var s recovery.SealedShare
+		if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Принимаем только доли, которые можем открыть
+		if _, err := recovery.OpenShare(identityManager.KeyPair(), s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := recoveryStore.Hold(s); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(s)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRecoveryOpen — POST /api/recovery/open {"set_id": "..."}
+// Открывает хранимую у нас долю и возвращает текст для передачи владельцу.
+func handleRecoveryOpen(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		SetID string `json:"set_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s, err := recoveryStore.HeldFor(req.SetID)
+	if err != nil {
+		writeRecoveryError(w, err)
+		return
+	}
+	piece, err := recovery.OpenShare(identityManager.KeyPair(), s)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"owner":     piece.Owner,
+		"index":     piece.Share.X,
+		"threshold": piece.Threshold,
+		"total":     piece.Total,
+		"piece":     piece.Encode(),
+	})
+}
+
+// handleRecoveryCombine — POST /api/recovery/combine {"pieces": ["idshare1:..."]}
+// Проверка сбора долей: для ключа возвращает восстановленный публичный ключ
+// (сам ключ устанавливается только через -combine-shares), для пароля — пароль.
+func handleRecoveryCombine(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Pieces []string `json:"pieces"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pieces := make([]recovery.Piece, 0, len(req.Pieces))
+	for _, s := range req.Pieces {
+		p, err := recovery.DecodePiece(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pieces = append(pieces, p)
+	}
+
+	kind, secret, err := recovery.Recover(pieces)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result := map[string]interface{}{"kind": kind, "shares": len(pieces)}
+	if kind == recovery.KindPassphrase {
+		result["passphrase"] = string(secret)
+	} else {
+		kp, err := recovery.RecoverKey(pieces)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result["public_key"] = kp.ToHex()
+		var history identity.KeyHistory
+		if json.Unmarshal(pieces[0].History, &history) == nil && history.Root != "" {
+			result["identity"] = history.Root
+		}
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// handlePersonPublicKey — POST /api/people/public-key {"person_id": "...", "public_key": "hex"}
+func handlePersonPublicKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		PersonID  string `json:"person_id"`
+		PublicKey string `json:"public_key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := identity.ParseKey(req.PublicKey); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := peopleDB.SetPersonPublicKey(req.PersonID, req.PublicKey); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, fmt.Sprintf("person %s not found", req.PersonID), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	p, err := peopleDB.GetPerson(req.PersonID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(p)
+}
+
+func writeRecoveryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+// openPeopleDB открывает базу людей в каталоге данных
+func openPeopleDB(dir string) (*db.Database, error) {
+	return db.NewDatabase(filepath.Join(dir, "people.db"))
+}
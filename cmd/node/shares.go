@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/envelope"
+	"ideal-core/pkg/identity"
+	"ideal-core/pkg/journal"
+	"ideal-core/pkg/outbox"
+	"ideal-core/pkg/peers"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// shareSyncMu — постановка в очередь по доступам идёт по одной, чтобы запись
+// не ушла дважды и не ушла после отзыва
+var shareSyncMu sync.Mutex
+
+// syncShare ставит записи доступа в очередь отправки пачками и отмечает их.
+// Отозванный доступ не отправляется, даже если отзыв пришёл во время ожидания.
+func syncShare(id string, entries []journal.ThoughtEntry) (int, error) {
+	shareSyncMu.Lock()
+	defer shareSyncMu.Unlock()
+
+	sh, err := shareStore.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	if !sh.Active() {
+		return 0, journal.ErrShareRevoked
+	}
+	key, err := identity.ParseKey(sh.Recipient)
+	if err != nil {
+		return 0, err
+	}
+	queued := 0
+	for _, batch := range journal.Batches(entries) {
+		data, err := json.Marshal(journal.SharePayload{ShareID: sh.ID, Name: sh.Name, Entries: batch})
+		if err != nil {
+			return queued, err
+		}
+		if _, err := outboxQueue.Enqueue(key, envelope.TypeJournalShare, data, 0, time.Now()); err != nil {
+			return queued, err
+		}
+		ids := make([]string, len(batch))
+		for i, e := range batch {
+			ids[i] = e.ID
+		}
+		if err := shareStore.MarkSent(sh.ID, ids, time.Now()); err != nil {
+			return queued, err
+		}
+		queued += len(batch)
+	}
+	return queued, nil
+}
+
+// syncPendingShares ставит в очередь всё, что не попало в неё (например, из-за сбоя записи)
+func syncPendingShares() {
+	for _, sh := range shareStore.List(true) {
+		pending := journalInstance.Pending(sh)
+		if len(pending) == 0 {
+			continue
+		}
+		if n, err := syncShare(sh.ID, pending); err != nil {
+			log.Printf("⚠️  Share %s: queued %d of %d entries: %v", sh.ID, n, len(pending), err)
+		}
+	}
+}
+
+// shareNewEntry — хук дневника: новая запись уходит по подходящим активным доступам
+func shareNewEntry(e journal.ThoughtEntry) {
+	for _, sh := range shareStore.List(true) {
+		if !sh.Includes(e) {
+			continue
+		}
+		if _, err := syncShare(sh.ID, []journal.ThoughtEntry{e}); err != nil {
+			log.Printf("⚠️  Share %s: entry %s not queued: %v", sh.ID, e.ID, err)
+		}
+	}
+}
+
+// handleJournalShareMessage — записи, которыми с нами поделились
+func handleJournalShareMessage(ctx context.Context, msg envelope.Message) error {
+	var p journal.SharePayload
+	if err := json.Unmarshal(msg.Payload, &p); err != nil {
+		return err
+	}
+	from := hex.EncodeToString(msg.Sender)
+	err := shareStore.Receive(from, p, time.Now())
+	if errors.Is(err, journal.ErrShareRevoked) {
+		// Пачка, отправленная до отзыва, пришла после него: принимаем (подтверждаем) и отбрасываем
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("📥 %d journal entries shared by %s\n", len(p.Entries), peerName(from))
+	return nil
+}
+
+// handleJournalRevokeMessage — отправитель отозвал доступ
+func handleJournalRevokeMessage(ctx context.Context, msg envelope.Message) error {
+	var rev journal.ShareRevocation
+	if err := json.Unmarshal(msg.Payload, &rev); err != nil {
+		return err
+	}
+	from := hex.EncodeToString(msg.Sender)
+	if err := shareStore.ReceiveRevocation(from, rev); err != nil {
+		return err
+	}
+	fmt.Printf("🔒 %s revoked journal share %s\n", peerName(from), rev.ShareID)
+	return nil
+}
+
+func peerName(key string) string {
+	if p, err := peerBook.Get(key); err == nil && p.Name != "" {
+		return p.Name
+	}
+	return key[:16] + "..."
+}
+
+// handleJournalShares — GET /api/journal/shares[?active=true],
+// POST {"peer": "hex", "name": "...", "entry_ids": [...], "filter": {"type": "cbt", "from": "..."}}
+func handleJournalShares(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		shares := shareStore.List(r.URL.Query().Get("active") == "true")
+		if shares == nil {
+			shares = []journal.Share{}
+		}
+		json.NewEncoder(w).Encode(shares)
+
+	case http.MethodPost:
+		var req struct {
+			Peer     string                `json:"peer"`
+			Name     string                `json:"name"`
+			EntryIDs []string              `json:"entry_ids"`
+			Filter   *journal.EntryFilters `json:"filter"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		peer, err := peerBook.Get(req.Peer)
+		if err != nil {
+			writePeerError(w, err)
+			return
+		}
+		if peer.Trust != peers.TrustTherapist && peer.Trust != peers.TrustTrusted {
+			http.Error(w, fmt.Sprintf("journal can only be shared with trusted peers or a therapist, %s is %q", peer.Name, peer.Trust), http.StatusForbidden)
+			return
+		}
+		sh, err := journal.NewShare(peer.Key, req.Name, req.EntryIDs, req.Filter, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := shareStore.Add(sh); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Доставкой занимается очередь: статус — в /api/outbox
+		queued, err := syncShare(sh.ID, journalInstance.Pending(sh))
+		result := map[string]interface{}{"queued": queued}
+		if err != nil {
+			result["error"] = err.Error()
+		}
+		result["share"], _ = shareStore.Get(sh.ID)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(result)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleJournalShareSync — POST /api/journal/shares/sync (поставить в очередь неотправленное)
+func handleJournalShareSync(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	type shareResult struct {
+		ID      string `json:"id"`
+		Pending int    `json:"pending"`
+		Queued  int    `json:"queued"`
+		Error   string `json:"error,omitempty"`
+	}
+	results := []shareResult{}
+	for _, sh := range shareStore.List(true) {
+		pending := journalInstance.Pending(sh)
+		res := shareResult{ID: sh.ID, Pending: len(pending)}
+		if len(pending) > 0 {
+			n, err := syncShare(sh.ID, pending)
+			res.Queued = n
+			if err != nil {
+				res.Error = err.Error()
+			}
+		}
+		results = append(results, res)
+	}
+	json.NewEncoder(w).Encode(results)
+}
+
+// handleJournalShareRevoke — POST /api/journal/shares/revoke {"id": "...", "reason": "..."}
+// Будущие записи больше не отправляются; получателю уходит уведомление.
+func handleJournalShareRevoke(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID     string `json:"id"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	shareSyncMu.Lock()
+	sh, err := shareStore.Revoke(req.ID, time.Now())
+	var cancelled int
+	if err == nil {
+		// Пакеты, ещё не доставленные получателю, после отзыва не отправляются
+		cancelled, err = cancelShareBatches(sh.ID)
+	}
+	shareSyncMu.Unlock()
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Уведомление получателю идёт через очередь и дойдёт, когда он появится в сети
+	result := map[string]interface{}{"share": sh, "cancelled": cancelled}
+	data, _ := json.Marshal(journal.ShareRevocation{ShareID: sh.ID, Reason: req.Reason, RevokedAt: *sh.RevokedAt})
+	key, _ := identity.ParseKey(sh.Recipient)
+	if msg, err := outboxQueue.Enqueue(key, envelope.TypeJournalRevoke, data, 0, time.Now()); err != nil {
+		result["error"] = err.Error()
+	} else {
+		result["notification"] = msg.ID
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// cancelShareBatches убирает из очереди недоставленные пакеты записей доступа shareID
+func cancelShareBatches(shareID string) (int, error) {
+	return outboxQueue.CancelWhere(func(m outbox.Message) bool {
+		if m.Type != envelope.TypeJournalShare {
+			return false
+		}
+		var p journal.SharePayload
+		return json.Unmarshal(m.Payload, &p) == nil && p.ShareID == shareID
+	})
+}
+
+// inboxSummary — входящий доступ без самих записей
+type inboxSummary struct {
+	ShareID    string     `json:"share_id"`
+	From       string     `json:"from"`
+	FromName   string     `json:"from_name"`
+	Name       string     `json:"name,omitempty"`
+	Entries    int        `json:"entries"`
+	ReceivedAt time.Time  `json:"received_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// handleSharedWithMe — GET /api/journal/shared-with-me (список),
+// GET ?from=hex&id=... (записи доступа), DELETE ?from=hex&id=... (удалить у себя)
+func handleSharedWithMe(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	from, id := r.URL.Query().Get("from"), r.URL.Query().Get("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		if id != "" {
+			in, err := shareStore.InboxShare(from, id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(in)
+			return
+		}
+		list := []inboxSummary{}
+		for _, in := range shareStore.Inbox() {
+			list = append(list, inboxSummary{
+				ShareID:    in.ShareID,
+				From:       in.From,
+				FromName:   peerName(in.From),
+				Name:       in.Name,
+				Entries:    len(in.Entries),
+				ReceivedAt: in.ReceivedAt,
+				UpdatedAt:  in.UpdatedAt,
+				RevokedAt:  in.RevokedAt,
+			})
+		}
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodDelete:
+		if err := shareStore.RemoveInbox(from, id); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
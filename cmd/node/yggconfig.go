@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/identity"
+	"ideal-core/pkg/yggdrasil"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Конфиг системного Yggdrasil: узел генерирует yggdrasil.conf из своих флагов
+// (-bootstrap, -ygg-listen, -ygg-admin, -ygg-own-key), сравнивает его с
+// существующим и сообщает, по какому адресу приложение доступно на самом деле.
+
+var (
+	yggClient  *yggdrasil.Client
+	yggKeyPath string
+)
+
+// yggKeyFile — ключ Yggdrasil, сгенерированный для конфига без -ygg-own-key
+const yggKeyFile = "yggdrasil.key"
+
+// loadYggKey возвращает сохранённый ключ Yggdrasil, при первом вызове создаёт
+// его: иначе каждый новый конфиг получал бы случайный ключ и другой адрес
+func loadYggKey() (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(yggKeyPath)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("invalid key in %s", yggKeyPath)
+		}
+		return ed25519.PrivateKey(key), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(yggKeyPath, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// proposedYggConfig — конфиг по флагам узла поверх существующего (base может быть nil)
+func proposedYggConfig(base *yggdrasil.Config) (*yggdrasil.Config, error) {
+	opts := yggdrasil.ConfigOptions{
+		Peers:       strings.Split(*bootstrap, ","),
+		Listen:      strings.Split(*yggListen, ","),
+		AdminListen: *yggAdmin,
+	}
+	switch {
+	case *yggOwnKey:
+		opts.Key = keyPair.PrivateKey
+	case base == nil || (base.PrivateKey == "" && base.PrivateKeyPath == ""):
+		key, err := loadYggKey()
+		if err != nil {
+			return nil, err
+		}
+		opts.Key = key
+	}
+	return yggdrasil.GenerateConfig(opts, base)
+}
+
+// systemYggConfig читает yggdrasil.conf (path == "" — поиск в стандартных местах).
+// Нет файла — cfg == nil без ошибки.
+func systemYggConfig(path string) (string, *yggdrasil.Config, error) {
+	if path == "" {
+		if path = yggdrasil.FindConfig(); path == "" {
+			return "", nil, nil
+		}
+	}
+	cfg, err := yggdrasil.LoadConfig(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return path, nil, nil
+	}
+	return path, cfg, err
+}
+
+// writeYggConfig — -ygg-genconf: показывает отличия от текущего файла и
+// записывает новый (старый сохраняется в .bak); "-" — вывести в stdout
+func writeYggConfig(path string) error {
+	out := io.Writer(os.Stdout)
+	existing := path
+	if path == "-" {
+		out, existing = os.Stderr, *yggConfig
+	}
+	existing, base, err := systemYggConfig(existing)
+	if err != nil {
+		return err
+	}
+	cfg, err := proposedYggConfig(base)
+	if err != nil {
+		return fmt.Errorf("config is invalid: %w", err)
+	}
+
+	if base != nil {
+		changes, err := yggdrasil.DiffConfig(base, cfg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "📝 Changes to %s: %d\n", existing, len(changes))
+		for _, c := range changes {
+			fmt.Fprintf(out, "   %s: %s → %s\n", c.Field, c.Old, c.New)
+		}
+	}
+	addr, _ := cfg.Address()
+	appAddr := identity.DeriveYggdrasilIP(keyPair.PublicKey)
+	fmt.Fprintf(out, "🌐 Yggdrasil address with this config: %s\n", addr)
+	if addr.String() != appAddr {
+		fmt.Fprintf(out, "   ⚠️  Not the Node ID address %s: peers reach the app at %s (use -ygg-own-key to share the node key)\n", appAddr, addr)
+	} else {
+		fmt.Fprintln(out, "   ⚠️  The config holds the node private key: keep it readable by root only")
+	}
+
+	if path == "-" {
+		data, err := cfg.Marshal()
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if old, err := os.ReadFile(path); err == nil {
+		if err := os.WriteFile(path+".bak", old, 0600); err != nil {
+			return err
+		}
+		fmt.Printf("   Previous config saved to: %s.bak\n", path)
+	}
+	if err := cfg.WriteFile(path); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Yggdrasil config written to: %s\n", path)
+	fmt.Println("   Apply it: sudo systemctl restart yggdrasil")
+	return nil
+}
+
+// yggReachability сверяет адрес узла, запущенного Yggdrasil и его конфига
+func yggReachability() (yggdrasil.Reachability, string) {
+	var self *yggdrasil.SelfInfo
+	if yggClient != nil && yggClient.Available() {
+		ctx, cancel := context.WithTimeout(context.Background(), yggdrasil.DefaultAdminTimeout)
+		defer cancel()
+		if info, err := yggClient.Admin().GetSelf(ctx); err == nil {
+			self = info
+		}
+	}
+	path, cfg, err := systemYggConfig(*yggConfig)
+	r := yggdrasil.CheckReachability(keyPair.PublicKey, self, cfg)
+	if err != nil {
+		r.Problems = append(r.Problems, err.Error())
+	}
+	return r, path
+}
+
+// reportYggReachability выводит при запуске, по какому адресу узел доступен
+func reportYggReachability() {
+	r, _ := yggReachability()
+	if r.Reachable != "" {
+		fmt.Printf("🌐 Reachable over Yggdrasil at: %s\n", r.Reachable)
+	}
+	for _, p := range r.Problems {
+		fmt.Printf("   ⚠️  %s\n", p)
+	}
+}
+
+// warnYggKeyRetired — после ротации: yggdrasil.conf, сгенерированный с
+// -ygg-own-key, всё ещё держит старый ключ узла
+func warnYggKeyRetired(oldKey string) {
+	path, cfg, err := systemYggConfig(*yggConfig)
+	if err != nil || cfg == nil {
+		if *yggOwnKey {
+			fmt.Println("⚠️  A yggdrasil.conf generated with -ygg-own-key holds the retired key: regenerate it (-ygg-genconf -ygg-own-key)")
+		}
+		return
+	}
+	pub, err := cfg.PublicKey()
+	if old, _ := hex.DecodeString(oldKey); err == nil && bytes.Equal(pub, old) {
+		fmt.Printf("⚠️  %s holds the retired node key: regenerate it (-ygg-genconf %s -ygg-own-key) and restart Yggdrasil\n", path, path)
+	}
+}
+
+// handleYggConfig — GET /api/yggdrasil/config: проверка yggdrasil.conf и
+// отличия от конфига, который сгенерирует узел (закрытый ключ не выводится)
+func handleYggConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	path, base, err := systemYggConfig(*yggConfig)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp := map[string]interface{}{
+		"path":     path,
+		"exists":   base != nil,
+		"own_key":  *yggOwnKey,
+		"problems": []string{},
+	}
+	if base != nil {
+		if err := base.Validate(); err != nil {
+			resp["problems"] = strings.Split(err.Error(), "\n")
+		}
+	}
+	cfg, err := proposedYggConfig(base)
+	if err != nil {
+		resp["proposed_error"] = err.Error()
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if addr, err := cfg.Address(); err == nil {
+		resp["proposed_address"] = addr.String()
+	}
+	changes := []yggdrasil.ConfigChange{}
+	if base != nil {
+		if changes, err = yggdrasil.DiffConfig(base, cfg); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	resp["changes"] = changes
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleYggReachability — GET /api/yggdrasil/reachability: по какому адресу доступно приложение
+func handleYggReachability(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	reach, path := yggReachability()
+	resp := map[string]interface{}{
+		"reachability": reach,
+		"config_path":  path,
+	}
+	if nodeTransport != nil {
+		resp["listening"] = nodeTransport.Addr()
+	}
+	json.NewEncoder(w).Encode(resp)
+}
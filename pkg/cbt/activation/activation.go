@@ -0,0 +1,177 @@
+// Package activation — поведенческая активация (BA) для КПТ
+//
+// Идея:
+// Настроение следует за действием, а не наоборот. Человек планирует занятия,
+// прогнозирует удовольствие и чувство мастерства, затем оценивает их по факту.
+// Сравнение прогноза с фактом и связь выполненных занятий с интенсивностью
+// эмоций на следующий день показывают, что реально помогает.
+package activation
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Status — статус занятия
+type Status string
+
+const (
+	StatusPlanned Status = "planned"
+	StatusDone    Status = "done"
+	StatusSkipped Status = "skipped"
+)
+
+// Category — тип занятия
+type Category string
+
+const (
+	CategoryPleasure Category = "pleasure" // приносит удовольствие
+	CategoryMastery  Category = "mastery"  // даёт чувство достижения
+	CategorySocial   Category = "social"   // общение
+	CategoryPhysical Category = "physical" // движение, спорт
+	CategoryRoutine  Category = "routine"  // быт, рутина
+)
+
+// Activity — запланированное занятие и его оценка
+type Activity struct {
+	ID               string     `json:"id"`
+	PersonID         string     `json:"person_id,omitempty"`
+	Title            string     `json:"title"`
+	Category         Category   `json:"category"`
+	PlannedAt        time.Time  `json:"planned_at"`
+	DurationMin      int        `json:"duration_min,omitempty"`
+	ExpectedPleasure int        `json:"expected_pleasure"` // 0-10
+	ExpectedMastery  int        `json:"expected_mastery"`  // 0-10
+	Status           Status     `json:"status"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	ActualPleasure   int        `json:"actual_pleasure,omitempty"` // 0-10
+	ActualMastery    int        `json:"actual_mastery,omitempty"`  // 0-10
+	MoodBefore       int        `json:"mood_before,omitempty"`     // 0-10
+	MoodAfter        int        `json:"mood_after,omitempty"`      // 0-10
+	Notes            string     `json:"notes,omitempty"`
+}
+
+// Completion — оценка после выполнения
+type Completion struct {
+	Pleasure   int    `json:"pleasure"`
+	Mastery    int    `json:"mastery"`
+	MoodBefore int    `json:"mood_before"`
+	MoodAfter  int    `json:"mood_after"`
+	Notes      string `json:"notes,omitempty"`
+}
+
+// Validate проверяет план занятия
+func (a Activity) Validate() error {
+	if a.Title == "" {
+		return errors.New("activity title is required")
+	}
+	if a.PlannedAt.IsZero() {
+		return errors.New("activity planned_at is required")
+	}
+	if err := checkRating("expected_pleasure", a.ExpectedPleasure); err != nil {
+		return err
+	}
+	return checkRating("expected_mastery", a.ExpectedMastery)
+}
+
+// Validate проверяет оценки после выполнения
+func (c Completion) Validate() error {
+	for name, v := range map[string]int{"pleasure": c.Pleasure, "mastery": c.Mastery, "mood_before": c.MoodBefore, "mood_after": c.MoodAfter} {
+		if err := checkRating(name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkRating(name string, v int) error {
+	if v < 0 || v > 10 {
+		return fmt.Errorf("%s must be 0-10, got %d", name, v)
+	}
+	return nil
+}
+
+// ============================================================================
+// WEEKLY GRID
+// ============================================================================
+
+// GridSlot — занятия в одном часе
+type GridSlot struct {
+	Hour       int        `json:"hour"`
+	Activities []Activity `json:"activities"`
+}
+
+// GridDay — один день недельной сетки
+type GridDay struct {
+	Date        string     `json:"date"` // 2006-01-02
+	Slots       []GridSlot `json:"slots"`
+	Planned     int        `json:"planned"`
+	Done        int        `json:"done"`
+	AvgPleasure float64    `json:"avg_pleasure"`
+	AvgMastery  float64    `json:"avg_mastery"`
+}
+
+// WeekGrid — недельная сетка занятий (7 дней × часы)
+type WeekGrid struct {
+	WeekStart string    `json:"week_start"`
+	Days      []GridDay `json:"days"`
+}
+
+// BuildWeekGrid раскладывает занятия по дням и часам недели, начиная с weekStart
+func BuildWeekGrid(activities []Activity, weekStart time.Time) WeekGrid {
+	start := truncateDay(weekStart)
+	grid := WeekGrid{WeekStart: start.Format("2006-01-02"), Days: make([]GridDay, 7)}
+	for i := range grid.Days {
+		grid.Days[i] = GridDay{Date: start.AddDate(0, 0, i).Format("2006-01-02"), Slots: []GridSlot{}}
+	}
+
+	for _, a := range activities {
+		at := a.PlannedAt.In(start.Location())
+		idx := -1
+		for i := range grid.Days {
+			if grid.Days[i].Date == at.Format("2006-01-02") {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			continue
+		}
+		day := &grid.Days[idx]
+		day.Planned++
+		slot := -1
+		for i, s := range day.Slots {
+			if s.Hour == at.Hour() {
+				slot = i
+			}
+		}
+		if slot < 0 {
+			day.Slots = append(day.Slots, GridSlot{Hour: at.Hour()})
+			slot = len(day.Slots) - 1
+		}
+		day.Slots[slot].Activities = append(day.Slots[slot].Activities, a)
+		if a.Status == StatusDone {
+			day.AvgPleasure = (day.AvgPleasure*float64(day.Done) + float64(a.ActualPleasure)) / float64(day.Done+1)
+			day.AvgMastery = (day.AvgMastery*float64(day.Done) + float64(a.ActualMastery)) / float64(day.Done+1)
+			day.Done++
+		}
+	}
+
+	for i := range grid.Days {
+		slots := grid.Days[i].Slots
+		sort.Slice(slots, func(a, b int) bool { return slots[a].Hour < slots[b].Hour })
+	}
+	return grid
+}
+
+// truncateDay — полночь того же дня в той же временной зоне
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// parseDay разбирает дату формата 2006-01-02 в локальной зоне
+func parseDay(day string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", day, time.Local)
+}
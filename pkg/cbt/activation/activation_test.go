@@ -0,0 +1,81 @@
+package activation
+
+import (
+	"ideal-core/pkg/journal"
+	"testing"
+	"time"
+)
+
+func TestStore_PlanCompleteWeek(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local)
+	walk, err := store.Plan(Activity{Title: "Прогулка в парке", Category: CategoryPhysical, PlannedAt: monday.Add(18 * time.Hour), ExpectedPleasure: 4, ExpectedMastery: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Plan(Activity{Title: "Позвонить другу", Category: CategorySocial, PlannedAt: monday.Add(9 * time.Hour), ExpectedPleasure: 5})
+	store.Plan(Activity{Title: "Следующая неделя", PlannedAt: monday.AddDate(0, 0, 8)})
+
+	if _, err := store.Plan(Activity{Title: "", PlannedAt: monday}); err == nil {
+		t.Error("Expected validation error for empty title")
+	}
+	if _, err := store.Complete(walk.ID, Completion{Pleasure: 11}, time.Now()); err == nil {
+		t.Error("Expected validation error for rating > 10")
+	}
+	if _, err := store.Complete(walk.ID, Completion{Pleasure: 8, Mastery: 6, MoodBefore: 3, MoodAfter: 6}, monday.Add(19*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, _ := NewStore(dir)
+	grid := reloaded.Week("", monday.Add(12*time.Hour))
+	if len(grid.Days) != 7 || grid.WeekStart != "2026-03-02" {
+		t.Fatalf("Unexpected grid: %+v", grid)
+	}
+	day := grid.Days[0]
+	if day.Planned != 2 || day.Done != 1 || day.AvgPleasure != 8 {
+		t.Errorf("Unexpected Monday summary: %+v", day)
+	}
+	if len(day.Slots) != 2 || day.Slots[0].Hour != 9 || day.Slots[1].Hour != 18 {
+		t.Errorf("Expected slots sorted by hour, got %+v", day.Slots)
+	}
+}
+
+func TestAnalyze_NextDayIntensity(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	done := func(day int, cat Category) Activity {
+		at := base.AddDate(0, 0, day)
+		return Activity{Category: cat, PlannedAt: at, Status: StatusDone, CompletedAt: &at, ExpectedPleasure: 3, ActualPleasure: 7}
+	}
+	activities := []Activity{done(0, CategoryPhysical), done(2, CategoryPhysical), done(2, CategorySocial)}
+
+	entry := func(day, intensity int) journal.ThoughtEntry {
+		return journal.ThoughtEntry{Timestamp: base.AddDate(0, 0, day), Intensity: intensity}
+	}
+	// День 1 и 3 — после активных дней; день 2 и 4 — после пассивных
+	entries := []journal.ThoughtEntry{entry(1, 30), entry(2, 70), entry(3, 40), entry(4, 80), entry(4, 60)}
+
+	a := Analyze(activities, entries)
+	if a.DaysCompared != 4 || a.ActiveDays != 2 {
+		t.Fatalf("Unexpected counts: %+v", a)
+	}
+	if a.AvgAfterActive != 35 || a.AvgAfterInactive != 70 {
+		t.Errorf("Unexpected averages: active %.1f, inactive %.1f", a.AvgAfterActive, a.AvgAfterInactive)
+	}
+	if a.Correlation >= 0 {
+		t.Errorf("Expected negative correlation, got %.2f", a.Correlation)
+	}
+	if a.PleasurePredictionGap != 4 {
+		t.Errorf("Expected pleasure gap 4, got %.1f", a.PleasurePredictionGap)
+	}
+	if len(a.Categories) != 2 || a.Categories[0].Category != CategorySocial && a.Categories[0].Category != CategoryPhysical {
+		t.Errorf("Unexpected categories: %+v", a.Categories)
+	}
+	if len(a.Insights) == 0 {
+		t.Error("Expected insights")
+	}
+}
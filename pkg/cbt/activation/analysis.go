@@ -0,0 +1,182 @@
+package activation
+
+import (
+	"fmt"
+	"ideal-core/pkg/journal"
+	"math"
+	"sort"
+)
+
+// CategoryEffect — средняя интенсивность эмоций на следующий день после занятий категории
+type CategoryEffect struct {
+	Category            Category `json:"category"`
+	Days                int      `json:"days"`
+	AvgNextDayIntensity float64  `json:"avg_next_day_intensity"`
+	DeltaVsInactive     float64  `json:"delta_vs_inactive"` // отрицательное = эмоции слабее
+}
+
+// Analysis — связь выполненных занятий с интенсивностью эмоций на следующий день
+type Analysis struct {
+	DaysCompared          int              `json:"days_compared"`    // дней с записями дневника на следующий день
+	ActiveDays            int              `json:"active_days"`      // из них с выполненными занятиями
+	AvgAfterActive        float64          `json:"avg_after_active"` // средняя Intensity на следующий день
+	AvgAfterInactive      float64          `json:"avg_after_inactive"`
+	Correlation           float64          `json:"correlation"` // Пирсон: число занятий ↔ Intensity завтра
+	Categories            []CategoryEffect `json:"categories"`
+	PleasurePredictionGap float64          `json:"pleasure_prediction_gap"` // факт − прогноз
+	MasteryPredictionGap  float64          `json:"mastery_prediction_gap"`
+	Insights              []string         `json:"insights"`
+}
+
+// Analyze связывает выполненные занятия дня D с интенсивностью эмоций
+// в записях дневника за день D+1
+func Analyze(activities []Activity, entries []journal.ThoughtEntry) Analysis {
+	result := Analysis{Categories: []CategoryEffect{}, Insights: []string{}}
+
+	// Средняя интенсивность по дням
+	intensitySum := make(map[string]int)
+	intensityCnt := make(map[string]int)
+	for _, e := range entries {
+		if e.Intensity <= 0 {
+			continue
+		}
+		day := e.Timestamp.Format("2006-01-02")
+		intensitySum[day] += e.Intensity
+		intensityCnt[day]++
+	}
+
+	// Выполненные занятия по дням
+	doneByDay := make(map[string][]Activity)
+	var pleasureGap, masteryGap float64
+	var done int
+	for _, a := range activities {
+		if a.Status != StatusDone {
+			continue
+		}
+		when := a.PlannedAt
+		if a.CompletedAt != nil {
+			when = *a.CompletedAt
+		}
+		day := when.Format("2006-01-02")
+		doneByDay[day] = append(doneByDay[day], a)
+		pleasureGap += float64(a.ActualPleasure - a.ExpectedPleasure)
+		masteryGap += float64(a.ActualMastery - a.ExpectedMastery)
+		done++
+	}
+	if done > 0 {
+		result.PleasurePredictionGap = pleasureGap / float64(done)
+		result.MasteryPredictionGap = masteryGap / float64(done)
+	}
+
+	// Дни, для которых есть записи на следующий день
+	var days []string
+	for day := range intensityCnt {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	var xs, ys []float64
+	var activeSum, inactiveSum float64
+	var inactiveDays int
+	catSum := make(map[Category]float64)
+	catDays := make(map[Category]int)
+	for _, next := range days {
+		t, err := parseDay(next)
+		if err != nil {
+			continue
+		}
+		prev := t.AddDate(0, 0, -1).Format("2006-01-02")
+		nextAvg := float64(intensitySum[next]) / float64(intensityCnt[next])
+		acts := doneByDay[prev]
+
+		xs = append(xs, float64(len(acts)))
+		ys = append(ys, nextAvg)
+		if len(acts) > 0 {
+			result.ActiveDays++
+			activeSum += nextAvg
+			seen := make(map[Category]bool)
+			for _, a := range acts {
+				if !seen[a.Category] {
+					seen[a.Category] = true
+					catSum[a.Category] += nextAvg
+					catDays[a.Category]++
+				}
+			}
+		} else {
+			inactiveDays++
+			inactiveSum += nextAvg
+		}
+	}
+	result.DaysCompared = len(xs)
+	if result.ActiveDays > 0 {
+		result.AvgAfterActive = activeSum / float64(result.ActiveDays)
+	}
+	if inactiveDays > 0 {
+		result.AvgAfterInactive = inactiveSum / float64(inactiveDays)
+	}
+	result.Correlation = pearson(xs, ys)
+
+	for _, c := range []Category{CategoryPleasure, CategoryMastery, CategorySocial, CategoryPhysical, CategoryRoutine} {
+		if catDays[c] == 0 {
+			continue
+		}
+		eff := CategoryEffect{Category: c, Days: catDays[c], AvgNextDayIntensity: catSum[c] / float64(catDays[c])}
+		if inactiveDays > 0 {
+			eff.DeltaVsInactive = eff.AvgNextDayIntensity - result.AvgAfterInactive
+		}
+		result.Categories = append(result.Categories, eff)
+	}
+
+	result.Insights = buildInsights(result, inactiveDays)
+	return result
+}
+
+func buildInsights(a Analysis, inactiveDays int) []string {
+	insights := []string{}
+	if a.ActiveDays > 0 && inactiveDays > 0 {
+		diff := a.AvgAfterActive - a.AvgAfterInactive
+		if diff < 0 {
+			insights = append(insights, fmt.Sprintf("После дней с занятиями интенсивность эмоций ниже на %.0f пунктов", -diff))
+		} else if diff > 0 {
+			insights = append(insights, fmt.Sprintf("После дней с занятиями интенсивность эмоций выше на %.0f пунктов — стоит пересмотреть выбор занятий", diff))
+		}
+	}
+	if a.PleasurePredictionGap > 0.5 {
+		insights = append(insights, fmt.Sprintf("Удовольствие от занятий в среднем на %.1f выше прогноза — ожидания занижены", a.PleasurePredictionGap))
+	}
+	best := -1
+	for i, c := range a.Categories {
+		if c.DeltaVsInactive < 0 && (best < 0 || c.DeltaVsInactive < a.Categories[best].DeltaVsInactive) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		insights = append(insights, fmt.Sprintf("Сильнее всего помогают занятия категории %q", a.Categories[best].Category))
+	}
+	return insights
+}
+
+// pearson — коэффициент корреляции Пирсона (0, если не определён)
+func pearson(xs, ys []float64) float64 {
+	n := float64(len(xs))
+	if n < 2 {
+		return 0
+	}
+	var sx, sy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+	}
+	mx, my := sx/n, sy/n
+	var cov, vx, vy float64
+	for i := range xs {
+		dx, dy := xs[i]-mx, ys[i]-my
+		cov += dx * dy
+		vx += dx * dx
+		vy += dy * dy
+	}
+	if vx == 0 || vy == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(vx*vy)
+}
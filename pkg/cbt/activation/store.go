@@ -0,0 +1,139 @@
+package activation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store — хранилище занятий (JSON-файл в каталоге данных)
+type Store struct {
+	mu         sync.Mutex
+	path       string
+	activities []Activity
+}
+
+// NewStore открывает хранилище в каталоге dataDir
+func NewStore(dataDir string) (*Store, error) {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, err
+	}
+	s := &Store{path: filepath.Join(dataDir, "activities.json")}
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &s.activities); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", s.path, err)
+		}
+	}
+	return s, nil
+}
+
+// Plan добавляет запланированное занятие
+func (s *Store) Plan(a Activity) (Activity, error) {
+	if err := a.Validate(); err != nil {
+		return Activity{}, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Activity{}, err
+	}
+	a.ID = hex.EncodeToString(id)
+	a.Status = StatusPlanned
+	a.CompletedAt = nil
+	if a.Category == "" {
+		a.Category = CategoryPleasure
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.activities = append(s.activities, a)
+	return a, s.save()
+}
+
+// Complete отмечает занятие выполненным и сохраняет фактические оценки
+func (s *Store) Complete(id string, c Completion, at time.Time) (Activity, error) {
+	if err := c.Validate(); err != nil {
+		return Activity{}, err
+	}
+	return s.update(id, func(a *Activity) {
+		a.Status = StatusDone
+		a.CompletedAt = &at
+		a.ActualPleasure = c.Pleasure
+		a.ActualMastery = c.Mastery
+		a.MoodBefore = c.MoodBefore
+		a.MoodAfter = c.MoodAfter
+		if c.Notes != "" {
+			a.Notes = c.Notes
+		}
+	})
+}
+
+// Skip отмечает занятие пропущенным
+func (s *Store) Skip(id, notes string) (Activity, error) {
+	return s.update(id, func(a *Activity) {
+		a.Status = StatusSkipped
+		if notes != "" {
+			a.Notes = notes
+		}
+	})
+}
+
+// List возвращает занятия человека в интервале [from, to) (нулевые границы — без ограничения)
+func (s *Store) List(personID string, from, to time.Time) []Activity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []Activity
+	for _, a := range s.activities {
+		if personID != "" && a.PersonID != personID {
+			continue
+		}
+		if !from.IsZero() && a.PlannedAt.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !a.PlannedAt.Before(to) {
+			continue
+		}
+		result = append(result, a)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].PlannedAt.Before(result[j].PlannedAt)
+	})
+	return result
+}
+
+// Week возвращает недельную сетку, начиная с weekStart
+func (s *Store) Week(personID string, weekStart time.Time) WeekGrid {
+	start := truncateDay(weekStart)
+	return BuildWeekGrid(s.List(personID, start, start.AddDate(0, 0, 7)), start)
+}
+
+func (s *Store) update(id string, fn func(a *Activity)) (Activity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.activities {
+		if s.activities[i].ID == id {
+			fn(&s.activities[i])
+			return s.activities[i], s.save()
+		}
+	}
+	return Activity{}, fmt.Errorf("activity %s: %w", id, os.ErrNotExist)
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.activities, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
@@ -0,0 +1,217 @@
+// Package beliefs — глубинные убеждения и техника «падающей стрелы»
+//
+// Идея:
+// Повторяющиеся автоматические мысли обычно вырастают из нескольких
+// глубинных убеждений («я беспомощен», «меня нельзя любить», «я никчёмен»).
+// Техника падающей стрелы выводит убеждение из цепочки вопросов
+// «Если это правда, что это значит?». Убеждения связываются с записями
+// дневника, а степень веры в них отслеживается во времени.
+package beliefs
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/cbt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Category — классическая триада глубинных убеждений (Beck)
+type Category string
+
+const (
+	CategoryHelpless   Category = "helpless"   // беспомощность, несостоятельность
+	CategoryUnlovable  Category = "unlovable"  // нелюбимость, отвержение
+	CategoryWorthless  Category = "worthless"  // никчёмность, плохость
+	CategoryUnassigned Category = "unassigned" // не удалось определить
+)
+
+// Маркеры категорий — лексиконы в формате cbt.Lexicon (в поле "distortion" —
+// категория): детектор cbt учитывает границы слов, словоформы и отрицание
+// («я не беспомощен» — не маркер беспомощности).
+//
+//go:embed lexicons/*.json
+var lexiconFS embed.FS
+
+var (
+	categoryDetectors     []*cbt.Detector
+	categoryDetectorsOnce sync.Once
+)
+
+// detectors — детекторы категорий для всех встроенных языков
+func detectors() []*cbt.Detector {
+	categoryDetectorsOnce.Do(func() {
+		for _, lang := range []string{"ru", "en"} {
+			data, err := lexiconFS.ReadFile("lexicons/" + lang + ".json")
+			if err != nil {
+				panic(err) // встроенный лексикон отсутствует — ошибка сборки
+			}
+			lex, err := cbt.ParseLexicon(data)
+			if err != nil {
+				panic(err)
+			}
+			categoryDetectors = append(categoryDetectors, cbt.NewDetector(lex))
+		}
+	})
+	return categoryDetectors
+}
+
+// arrowQuestions — вопросы падающей стрелы (чередуются по глубине)
+var arrowQuestions = []string{
+	"Допустим, эта мысль правдива. Что это значит для вас?",
+	"А если и это правда — что это говорит о вас как о человеке?",
+	"Что самое плохое в этом? Что это значит о вас?",
+	"И что это значит для вашего будущего и отношений с людьми?",
+}
+
+// MaxArrowDepth — предел длины цепочки
+const MaxArrowDepth = 10
+
+// ErrEmptyArrow — цепочка падающей стрелы пуста
+var ErrEmptyArrow = errors.New("downward arrow chain is empty")
+
+// Rating — степень веры в убеждение в момент времени
+type Rating struct {
+	Timestamp time.Time `json:"timestamp"`
+	Percent   int       `json:"percent"` // 0-100
+	Note      string    `json:"note,omitempty"`
+}
+
+// Belief — глубинное убеждение
+type Belief struct {
+	ID          string                          `json:"id"`
+	PersonID    string                          `json:"person_id,omitempty"`
+	Text        string                          `json:"text"`
+	Category    Category                        `json:"category"`
+	CreatedAt   time.Time                       `json:"created_at"`
+	Ratings     []Rating                        `json:"ratings"`
+	Arrow       []string                        `json:"arrow,omitempty"`       // цепочка, из которой выведено убеждение
+	EntryIDs    []string                        `json:"entry_ids,omitempty"`   // связанные записи дневника
+	Distortions map[cbt.CognitiveDistortion]int `json:"distortions,omitempty"` // искажения в связанных записях
+	Alternative string                          `json:"alternative,omitempty"` // более адаптивное убеждение
+}
+
+// Believability — текущая степень веры (последняя оценка)
+func (b Belief) Believability() int {
+	if len(b.Ratings) == 0 {
+		return 0
+	}
+	return b.Ratings[len(b.Ratings)-1].Percent
+}
+
+// ArrowQuestion — вопрос для следующего шага цепочки глубины depth (с нуля)
+func ArrowQuestion(depth int) string {
+	if depth < 0 {
+		depth = 0
+	}
+	return arrowQuestions[depth%len(arrowQuestions)]
+}
+
+// DeriveBelief выводит убеждение из цепочки падающей стрелы.
+// Убеждением считается последний шаг; категория определяется по нему,
+// а если не удалось — по более ранним шагам снизу вверх.
+func DeriveBelief(steps []string) (string, Category, error) {
+	var cleaned []string
+	for _, s := range steps {
+		if s = strings.TrimSpace(s); s != "" {
+			cleaned = append(cleaned, s)
+		}
+	}
+	if len(cleaned) == 0 {
+		return "", CategoryUnassigned, ErrEmptyArrow
+	}
+	if len(cleaned) > MaxArrowDepth {
+		return "", CategoryUnassigned, fmt.Errorf("downward arrow is too deep: %d steps (max %d)", len(cleaned), MaxArrowDepth)
+	}
+
+	text := strings.TrimRight(cleaned[len(cleaned)-1], ".!… ")
+	for i := len(cleaned) - 1; i >= 0; i-- {
+		if c := Classify(cleaned[i]); c != CategoryUnassigned {
+			return text, c, nil
+		}
+	}
+	return text, CategoryUnassigned, nil
+}
+
+// Classify относит текст к одной из категорий триады: побеждает категория
+// с наибольшей уверенностью детектора (при равенстве — первая в триаде)
+func Classify(text string) Category {
+	confidence := make(map[Category]float64)
+	for _, d := range detectors() {
+		for _, det := range d.Detect(text) {
+			c := Category(det.Distortion)
+			confidence[c] = max(confidence[c], det.Confidence)
+		}
+	}
+	best, bestConfidence := CategoryUnassigned, 0.0
+	for _, c := range []Category{CategoryHelpless, CategoryUnlovable, CategoryWorthless} {
+		if confidence[c] > bestConfidence {
+			best, bestConfidence = c, confidence[c]
+		}
+	}
+	return best
+}
+
+func validatePercent(p int) error {
+	if p < 0 || p > 100 {
+		return fmt.Errorf("believability must be 0-100, got %d", p)
+	}
+	return nil
+}
+
+// ============================================================================
+// TRENDS
+// ============================================================================
+
+// Trend — динамика веры в убеждение
+type Trend struct {
+	BeliefID    string   `json:"belief_id"`
+	Text        string   `json:"text"`
+	Category    Category `json:"category"`
+	First       int      `json:"first"`
+	Last        int      `json:"last"`
+	Change      int      `json:"change"`   // Last − First
+	PerWeek     float64  `json:"per_week"` // наклон линейной регрессии, пунктов в неделю
+	Ratings     int      `json:"ratings"`
+	LinkedCount int      `json:"linked_count"` // связанных записей
+	Weakening   bool     `json:"weakening"`
+}
+
+// WeakeningThreshold — на сколько пунктов должна снизиться вера, чтобы считать убеждение слабеющим
+const WeakeningThreshold = 10
+
+// ComputeTrend считает динамику по оценкам убеждения
+func ComputeTrend(b Belief) Trend {
+	t := Trend{BeliefID: b.ID, Text: b.Text, Category: b.Category, Ratings: len(b.Ratings), LinkedCount: len(b.EntryIDs)}
+	if len(b.Ratings) == 0 {
+		return t
+	}
+	ratings := append([]Rating(nil), b.Ratings...)
+	sort.SliceStable(ratings, func(i, j int) bool { return ratings[i].Timestamp.Before(ratings[j].Timestamp) })
+
+	t.First = ratings[0].Percent
+	t.Last = ratings[len(ratings)-1].Percent
+	t.Change = t.Last - t.First
+
+	if len(ratings) >= 2 {
+		origin := ratings[0].Timestamp
+		var sx, sy, sxx, sxy float64
+		n := float64(len(ratings))
+		for _, r := range ratings {
+			x := r.Timestamp.Sub(origin).Hours() / (24 * 7)
+			y := float64(r.Percent)
+			sx += x
+			sy += y
+			sxx += x * x
+			sxy += x * y
+		}
+		if den := n*sxx - sx*sx; den != 0 {
+			t.PerWeek = (n*sxy - sx*sy) / den
+		}
+	}
+	t.Weakening = t.Change <= -WeakeningThreshold && t.PerWeek < 0
+	return t
+}
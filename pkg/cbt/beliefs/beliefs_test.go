@@ -0,0 +1,140 @@
+package beliefs
+
+import (
+	"encoding/json"
+	"ideal-core/pkg/cbt"
+	"ideal-core/pkg/journal"
+	"testing"
+	"time"
+)
+
+func TestDeriveBelief(t *testing.T) {
+	steps := []string{
+		"Начальник не ответил на моё письмо",
+		"Значит, он недоволен моей работой",
+		"Меня уволят",
+		"Я неудачник.",
+	}
+	text, cat, err := DeriveBelief(steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Я неудачник" || cat != CategoryHelpless {
+		t.Errorf("Unexpected belief: %q (%s)", text, cat)
+	}
+
+	// Категория берётся из более ранних шагов, если последний нейтрален
+	_, cat, _ = DeriveBelief([]string{"Меня все бросят", "Так будет всегда"})
+	if cat != CategoryUnlovable {
+		t.Errorf("Expected unlovable, got %s", cat)
+	}
+
+	if _, _, err := DeriveBelief([]string{" ", ""}); err != ErrEmptyArrow {
+		t.Errorf("Expected ErrEmptyArrow, got %v", err)
+	}
+	if ArrowQuestion(0) == ArrowQuestion(1) {
+		t.Error("Expected different questions for consecutive steps")
+	}
+}
+
+func TestStore_RatingsLinksAndTrends(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	weak, err := store.AddFromArrow("me", []string{"Я опять ошибся", "Я ни на что не годен", "Я никчёмный"}, 90, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weak.Category != CategoryWorthless || weak.Believability() != 90 {
+		t.Fatalf("Unexpected belief: %+v", weak)
+	}
+	stable, _ := store.Add(Belief{PersonID: "me", Text: "Я беспомощен"}, 60, start)
+
+	store.Rate(weak.ID, 75, "после трёх записей", start.AddDate(0, 0, 7))
+	store.Rate(weak.ID, 50, "", start.AddDate(0, 0, 14))
+	store.Rate(stable.ID, 60, "", start.AddDate(0, 0, 14))
+	if _, err := store.Rate(weak.ID, 120, "", start); err == nil {
+		t.Error("Expected validation error for believability > 100")
+	}
+
+	entry := journal.ThoughtEntry{ID: "e1", AutomaticThought: "Я никчёмный работник", Distortions: []cbt.CognitiveDistortion{cbt.DistortionLabeling}}
+	store.LinkEntry(weak.ID, entry)
+	store.LinkEntry(weak.ID, entry) // повторная связь игнорируется
+
+	reloaded, _ := NewStore(dir)
+	got, ok := reloaded.Get(weak.ID)
+	if !ok || len(got.EntryIDs) != 1 || got.Distortions[cbt.DistortionLabeling] != 1 {
+		t.Fatalf("Unexpected links: %+v", got)
+	}
+
+	trends := reloaded.Trends("me")
+	if len(trends) != 2 || trends[0].BeliefID != weak.ID {
+		t.Fatalf("Expected weakening belief first, got %+v", trends)
+	}
+	if !trends[0].Weakening || trends[0].Change != -40 || trends[0].PerWeek >= 0 {
+		t.Errorf("Unexpected trend: %+v", trends[0])
+	}
+	if trends[1].Weakening {
+		t.Errorf("Stable belief must not be weakening: %+v", trends[1])
+	}
+
+	suggested := Suggest(got, []journal.ThoughtEntry{
+		entry,
+		{ID: "e2", AutomaticThought: "Я бесполезен для команды"},
+		{ID: "e3", AutomaticThought: "Сегодня дождь"},
+	})
+	if len(suggested) != 1 || suggested[0].ID != "e2" {
+		t.Errorf("Unexpected suggestions: %+v", suggested)
+	}
+}
+
+func TestClassify_NegationAndWordForms(t *testing.T) {
+	cases := map[string]Category{
+		"Я беспомощна":             CategoryHelpless,
+		"Я не беспомощен":          CategoryUnassigned, // отрицание
+		"Послабление режима":       CategoryUnassigned, // «слаб» внутри слова
+		"Я не справлюсь":           CategoryHelpless,   // маркер сам с отрицанием
+		"Меня никто не любит":      CategoryUnlovable,
+		"Я ничего не стою":         CategoryWorthless,
+		"I'm not weak":             CategoryUnassigned,
+		"I'm just not good enough": CategoryWorthless,
+	}
+	for text, want := range cases {
+		if got := Classify(text); got != want {
+			t.Errorf("Classify(%q) = %s, want %s", text, got, want)
+		}
+	}
+}
+
+func TestStore_ReturnsCopies(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := store.Add(Belief{Text: "Я беспомощен"}, 80, time.Now())
+
+	// Кодирование убеждения параллельно со связыванием записей (go test -race)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 50 {
+			store.LinkEntry(b.ID, journal.ThoughtEntry{ID: string(rune('a' + i)), Distortions: []cbt.CognitiveDistortion{cbt.DistortionLabeling}})
+		}
+	}()
+	for range 50 {
+		for _, got := range store.List("") {
+			json.Marshal(got)
+		}
+	}
+	<-done
+
+	got, _ := store.Get(b.ID)
+	got.Distortions[cbt.DistortionLabeling] = 0
+	if again, _ := store.Get(b.ID); again.Distortions[cbt.DistortionLabeling] != 50 {
+		t.Errorf("caller's change leaked into the store: %v", again.Distortions)
+	}
+}
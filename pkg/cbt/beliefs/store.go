@@ -0,0 +1,213 @@
+package beliefs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/cbt"
+	"ideal-core/pkg/journal"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store — хранилище убеждений (JSON-файл в каталоге данных)
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	beliefs []Belief
+}
+
+// NewStore открывает хранилище в каталоге dataDir
+func NewStore(dataDir string) (*Store, error) {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, err
+	}
+	s := &Store{path: filepath.Join(dataDir, "beliefs.json")}
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &s.beliefs); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", s.path, err)
+		}
+	}
+	return s, nil
+}
+
+// Add сохраняет новое убеждение с начальной оценкой веры
+func (s *Store) Add(b Belief, believability int, now time.Time) (Belief, error) {
+	b.Text = strings.TrimSpace(b.Text)
+	if b.Text == "" {
+		return Belief{}, errors.New("belief text is required")
+	}
+	if err := validatePercent(believability); err != nil {
+		return Belief{}, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Belief{}, err
+	}
+	b.ID = hex.EncodeToString(id)
+	b.CreatedAt = now
+	if b.Category == "" {
+		b.Category = Classify(b.Text)
+	}
+	b.Ratings = []Rating{{Timestamp: now, Percent: believability}}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.beliefs = append(s.beliefs, b)
+	return b.clone(), s.save()
+}
+
+// AddFromArrow выводит убеждение из цепочки падающей стрелы и сохраняет его
+func (s *Store) AddFromArrow(personID string, steps []string, believability int, now time.Time) (Belief, error) {
+	text, category, err := DeriveBelief(steps)
+	if err != nil {
+		return Belief{}, err
+	}
+	return s.Add(Belief{PersonID: personID, Text: text, Category: category, Arrow: steps}, believability, now)
+}
+
+// Rate добавляет новую оценку веры в убеждение
+func (s *Store) Rate(id string, percent int, note string, at time.Time) (Belief, error) {
+	if err := validatePercent(percent); err != nil {
+		return Belief{}, err
+	}
+	return s.update(id, func(b *Belief) {
+		b.Ratings = append(b.Ratings, Rating{Timestamp: at, Percent: percent, Note: note})
+	})
+}
+
+// SetAlternative сохраняет альтернативное (адаптивное) убеждение
+func (s *Store) SetAlternative(id, alternative string) (Belief, error) {
+	return s.update(id, func(b *Belief) {
+		b.Alternative = strings.TrimSpace(alternative)
+	})
+}
+
+// LinkEntry связывает запись дневника с убеждением и учитывает её искажения
+func (s *Store) LinkEntry(id string, entry journal.ThoughtEntry) (Belief, error) {
+	return s.update(id, func(b *Belief) {
+		for _, existing := range b.EntryIDs {
+			if existing == entry.ID {
+				return
+			}
+		}
+		b.EntryIDs = append(b.EntryIDs, entry.ID)
+		if len(entry.Distortions) > 0 && b.Distortions == nil {
+			b.Distortions = make(map[cbt.CognitiveDistortion]int)
+		}
+		for _, d := range entry.Distortions {
+			b.Distortions[d]++
+		}
+	})
+}
+
+// Get возвращает копию убеждения по ID
+func (s *Store) Get(id string) (Belief, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range s.beliefs {
+		if b.ID == id {
+			return b.clone(), true
+		}
+	}
+	return Belief{}, false
+}
+
+// List возвращает копии убеждений человека (пустой personID — все)
+func (s *Store) List(personID string) []Belief {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []Belief{}
+	for _, b := range s.beliefs {
+		if personID == "" || b.PersonID == personID {
+			result = append(result, b.clone())
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// Trends возвращает динамику всех убеждений человека; слабеющие — первыми
+func (s *Store) Trends(personID string) []Trend {
+	var trends []Trend
+	for _, b := range s.List(personID) {
+		trends = append(trends, ComputeTrend(b))
+	}
+	sort.SliceStable(trends, func(i, j int) bool {
+		if trends[i].Weakening != trends[j].Weakening {
+			return trends[i].Weakening
+		}
+		return trends[i].Change < trends[j].Change
+	})
+	return trends
+}
+
+// Suggest подбирает записи дневника, которые могут питать убеждение:
+// автоматическая мысль относится к той же категории и запись ещё не связана
+func Suggest(b Belief, entries []journal.ThoughtEntry) []journal.ThoughtEntry {
+	if b.Category == CategoryUnassigned {
+		return nil
+	}
+	linked := make(map[string]bool)
+	for _, id := range b.EntryIDs {
+		linked[id] = true
+	}
+	var result []journal.ThoughtEntry
+	for _, e := range entries {
+		if e.AutomaticThought == "" || linked[e.ID] {
+			continue
+		}
+		if b.PersonID != "" && e.PersonID != "" && e.PersonID != b.PersonID {
+			continue
+		}
+		if Classify(e.AutomaticThought) == b.Category {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func (s *Store) update(id string, fn func(b *Belief)) (Belief, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.beliefs {
+		if s.beliefs[i].ID == id {
+			fn(&s.beliefs[i])
+			return s.beliefs[i].clone(), s.save()
+		}
+	}
+	return Belief{}, fmt.Errorf("belief %s: %w", id, os.ErrNotExist)
+}
+
+// clone — копия убеждения без общих с хранилищем срезов и карты искажений
+// (LinkEntry меняет их под s.mu, а вызывающий читает без блокировки)
+func (b Belief) clone() Belief {
+	b.Ratings = append([]Rating(nil), b.Ratings...)
+	b.Arrow = append([]string(nil), b.Arrow...)
+	b.EntryIDs = append([]string(nil), b.EntryIDs...)
+	b.Distortions = maps.Clone(b.Distortions)
+	return b
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.beliefs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
@@ -1,6 +1,6 @@
 package cbt
 
-import "strings"
+import "fmt"
 
 // CognitiveDistortion — тип когнитивного искажения
 type CognitiveDistortion string
@@ -18,6 +18,14 @@ const (
 	DistortionPersonalization   CognitiveDistortion = "personalization"     // Персонализация
 )
 
+// allDistortions — все искажения в каноническом порядке
+var allDistortions = []CognitiveDistortion{
+	DistortionAllOrNothing, DistortionOvergeneralization, DistortionMentalFilter,
+	DistortionDisqualifying, DistortionJumpingConclusions, DistortionMagnification,
+	DistortionEmotionalReasoning, DistortionShouldStatements, DistortionLabeling,
+	DistortionPersonalization,
+}
+
 // ThoughtRecord — запись автоматической мысли (CBT)
 type ThoughtRecord struct {
 	Situation        string                `json:"situation"`
@@ -29,42 +37,10 @@ type ThoughtRecord struct {
 	NewIntensity     int                   `json:"new_intensity"`
 }
 
-// DetectDistortions ищет когнитивные искажения в тексте
+// DetectDistortions ищет когнитивные искажения в тексте (встроенный русский лексикон).
+// Подробности — уверенность и найденные фрагменты — возвращает Detect.
 func DetectDistortions(text string) []CognitiveDistortion {
-	var found []CognitiveDistortion
-	textLower := strings.ToLower(text)
-
-	// All-or-nothing
-	if containsAny(textLower, []string{"всегда", "никогда", "все", "никто", "полностью", "совсем"}) {
-		found = append(found, DistortionAllOrNothing)
-	}
-
-	// Overgeneralization
-	if containsAny(textLower, []string{"опять", "снова", "постоянно", "каждый раз", "всё время"}) {
-		found = append(found, DistortionOvergeneralization)
-	}
-
-	// Catastrophizing
-	if containsAny(textLower, []string{"ужас", "катастрофа", "конец", "никогда не", "всё пропало", "невыносимо"}) {
-		found = append(found, DistortionMagnification)
-	}
-
-	// Should statements
-	if containsAny(textLower, []string{"должен", "должна", "надо", "следует", "обязан", "обязана"}) {
-		found = append(found, DistortionShouldStatements)
-	}
-
-	// Emotional reasoning
-	if containsAny(textLower, []string{"чувствую, что", "мне кажется, что", "я знаю, что", "очевидно"}) {
-		found = append(found, DistortionEmotionalReasoning)
-	}
-
-	// Labeling
-	if containsAny(textLower, []string{"я неудачник", "я слабак", "он манипулятор", "она истеричка", "все такие"}) {
-		found = append(found, DistortionLabeling)
-	}
-
-	return found
+	return DefaultDetector().DetectDistortions(text)
 }
 
 // GenerateRationalResponse помогает сформировать рациональный ответ
@@ -83,20 +59,32 @@ func GenerateRationalResponse(automaticThought string, distortions []CognitiveDi
 	if contains(distortions, DistortionLabeling) {
 		response += "Это поведение или вся личность? Можно ли отделить поступок от человека? "
 	}
+	if contains(distortions, DistortionOvergeneralization) {
+		response += "Были ли случаи, когда всё сложилось иначе? "
+	}
+	if contains(distortions, DistortionEmotionalReasoning) {
+		response += "Чувство — это не факт. Какие есть доказательства, кроме ощущения? "
+	}
+	if contains(distortions, DistortionMentalFilter) {
+		response += "Что хорошего в этой ситуации осталось незамеченным? "
+	}
+	if contains(distortions, DistortionDisqualifying) {
+		response += "Почему хорошее «не считается»? Что, если засчитать его наравне с плохим? "
+	}
+	if contains(distortions, DistortionJumpingConclusions) {
+		response += "Откуда известно, что думает другой человек или что будет дальше? Какие ещё объяснения возможны? "
+	}
+	if contains(distortions, DistortionPersonalization) {
+		response += "Какие ещё факторы, кроме меня, повлияли на ситуацию? "
+	}
+	if len(distortions) == 0 {
+		response += "Какие факты подтверждают эту мысль, а какие ей противоречат? "
+	}
 
 	return response
 }
 
 // Helper functions
-func containsAny(s string, substrs []string) bool {
-	for _, sub := range substrs {
-		if strings.Contains(s, sub) {
-			return true
-		}
-	}
-	return false
-}
-
 func contains(slice []CognitiveDistortion, item CognitiveDistortion) bool {
 	for _, s := range slice {
 		if s == item {
@@ -126,20 +114,33 @@ func AnalyzeSession(session CBTSession) []string {
 		}
 	}
 
-	// Формируем инсайты
-	for d, count := range distortionCount {
-		if count >= 3 {
-			insights = append(insights, 
-				"Частое искажение: "+string(d)+" ("+string(rune(count))+") — стоит проработать")
+	// Формируем инсайты (в порядке перечисления искажений — детерминированно)
+	for _, d := range allDistortions {
+		if count := distortionCount[d]; count >= 3 {
+			insights = append(insights,
+				fmt.Sprintf("Частое искажение: %s (%d) — стоит проработать", d, count))
 		}
 	}
 
-	// Проверяем прогресс по интенсивности эмоций
-	if len(session.Records) >= 2 {
-		first := session.Records[0].Intensity
-		last := session.Records[len(session.Records)-1].NewIntensity
-		if last < first {
-			insights = append(insights, "Прогресс: интенсивность эмоций снизилась с "+string(rune(first))+" до "+string(rune(last)))
+	// Прогресс: сравниваем интенсивность до и после внутри каждой записи
+	var before, after, n int
+	for _, rec := range session.Records {
+		if rec.Intensity == 0 && rec.NewIntensity == 0 {
+			continue // запись не оценивалась
+		}
+		before += rec.Intensity
+		after += rec.NewIntensity
+		n++
+	}
+	if n > 0 {
+		avgBefore, avgAfter := before/n, after/n
+		switch {
+		case avgAfter < avgBefore:
+			insights = append(insights, fmt.Sprintf("Прогресс: интенсивность эмоций снизилась с %d до %d (−%d)", avgBefore, avgAfter, avgBefore-avgAfter))
+		case avgAfter > avgBefore:
+			insights = append(insights, fmt.Sprintf("Интенсивность эмоций выросла с %d до %d — возможно, альтернативная мысль пока не убеждает", avgBefore, avgAfter))
+		default:
+			insights = append(insights, fmt.Sprintf("Интенсивность эмоций не изменилась (%d)", avgBefore))
 		}
 	}
 
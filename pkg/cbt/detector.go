@@ -0,0 +1,301 @@
+package cbt
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed lexicons/*.json
+var lexiconFS embed.FS
+
+// Значения по умолчанию для лексикона
+const (
+	defaultPatternWeight  = 0.5
+	defaultMinConfidence  = 0.3
+	defaultNegationWindow = 1
+)
+
+// Lexicon — словарь маркеров когнитивных искажений для одного языка
+type Lexicon struct {
+	Language       string            `json:"language"`        // ru | en
+	Negations      []string          `json:"negations"`       // "не", "ни", "нет"
+	NegationWindow int               `json:"negation_window"` // сколько слов перед маркером проверять на отрицание
+	MinConfidence  float64           `json:"min_confidence"`  // порог для DetectDistortions
+	Distortions    []LexiconCategory `json:"distortions"`
+}
+
+// LexiconCategory — маркеры одного искажения
+type LexiconCategory struct {
+	Distortion CognitiveDistortion `json:"distortion"`
+	Patterns   []LexiconPattern    `json:"patterns"`
+}
+
+// LexiconPattern — слово или фраза-маркер
+type LexiconPattern struct {
+	Text        string  `json:"text"`
+	Weight      float64 `json:"weight,omitempty"`       // вклад в уверенность (0..1)
+	KeepNegated bool    `json:"keep_negated,omitempty"` // «не должен» — тоже долженствование
+}
+
+// Match — найденный фрагмент текста
+type Match struct {
+	Start   int    `json:"start"` // байтовое смещение в исходном тексте
+	End     int    `json:"end"`
+	Text    string `json:"text"`
+	Pattern string `json:"pattern"`
+}
+
+// Detection — искажение с уверенностью и найденными фрагментами
+type Detection struct {
+	Distortion CognitiveDistortion `json:"distortion"`
+	Confidence float64             `json:"confidence"`
+	Matches    []Match             `json:"matches"`
+}
+
+// Detector — детектор искажений по лексикону
+type Detector struct {
+	lexicon   *Lexicon
+	stem      Stemmer
+	negations map[string]bool
+	patterns  [][]compiledPattern // по индексу категории
+}
+
+type compiledPattern struct {
+	source LexiconPattern
+	first  string // первое слово маркера (нормализованное)
+	stems  []string
+}
+
+// token — слово текста с позицией
+type token struct {
+	start, end int
+	norm       string
+	stem       string
+}
+
+// ParseLexicon разбирает лексикон из JSON
+func ParseLexicon(data []byte) (*Lexicon, error) {
+	var lex Lexicon
+	if err := json.Unmarshal(data, &lex); err != nil {
+		return nil, fmt.Errorf("parse lexicon: %w", err)
+	}
+	if lex.Language == "" {
+		return nil, fmt.Errorf("lexicon language is required")
+	}
+	if lex.MinConfidence <= 0 {
+		lex.MinConfidence = defaultMinConfidence
+	}
+	if lex.NegationWindow <= 0 {
+		lex.NegationWindow = defaultNegationWindow
+	}
+	return &lex, nil
+}
+
+// LoadLexicon загружает лексикон из файла (например, data/cbt/en.json)
+func LoadLexicon(path string) (*Lexicon, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read lexicon: %w", err)
+	}
+	return ParseLexicon(data)
+}
+
+// BuiltinLexicon возвращает встроенный лексикон для языка ("ru", "en")
+func BuiltinLexicon(language string) (*Lexicon, error) {
+	data, err := lexiconFS.ReadFile("lexicons/" + language + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("no builtin lexicon for %q", language)
+	}
+	return ParseLexicon(data)
+}
+
+// NewDetector компилирует лексикон в детектор
+func NewDetector(lex *Lexicon) *Detector {
+	d := &Detector{
+		lexicon:   lex,
+		stem:      stemmerFor(lex.Language),
+		negations: make(map[string]bool),
+		patterns:  make([][]compiledPattern, len(lex.Distortions)),
+	}
+	for _, n := range lex.Negations {
+		d.negations[normalizeWord(n)] = true
+	}
+	for i, cat := range lex.Distortions {
+		for _, p := range cat.Patterns {
+			if p.Weight <= 0 {
+				p.Weight = defaultPatternWeight
+			}
+			words := tokenize(p.Text)
+			if len(words) == 0 {
+				continue
+			}
+			cp := compiledPattern{source: p, first: words[0].norm}
+			for _, t := range words {
+				cp.stems = append(cp.stems, d.stem(t.norm))
+			}
+			d.patterns[i] = append(d.patterns[i], cp)
+		}
+		// Длинные фразы проверяются первыми: «просто повезло» поглощает «повезло»
+		sort.SliceStable(d.patterns[i], func(a, b int) bool {
+			return len(d.patterns[i][a].stems) > len(d.patterns[i][b].stems)
+		})
+	}
+	return d
+}
+
+// Detect находит искажения с уверенностью и фрагментами (в порядке лексикона)
+func (d *Detector) Detect(text string) []Detection {
+	tokens := tokenize(text)
+	for i := range tokens {
+		tokens[i].stem = d.stem(tokens[i].norm)
+	}
+
+	var result []Detection
+	for i, cat := range d.lexicon.Distortions {
+		det := Detection{Distortion: cat.Distortion}
+		miss := 1.0
+		covered := make([]bool, len(tokens))
+		for _, p := range d.patterns[i] {
+			for pos := 0; pos+len(p.stems) <= len(tokens); pos++ {
+				end := pos + len(p.stems)
+				if !stemsMatch(tokens[pos:end], p.stems) || allCovered(covered[pos:end]) {
+					continue
+				}
+				if !p.source.KeepNegated && d.isNegated(tokens, pos, p) {
+					continue
+				}
+				for k := pos; k < end; k++ {
+					covered[k] = true
+				}
+				last := tokens[end-1]
+				det.Matches = append(det.Matches, Match{
+					Start:   tokens[pos].start,
+					End:     last.end,
+					Text:    text[tokens[pos].start:last.end],
+					Pattern: p.source.Text,
+				})
+				miss *= 1 - p.source.Weight
+			}
+		}
+		if len(det.Matches) > 0 {
+			sort.Slice(det.Matches, func(a, b int) bool { return det.Matches[a].Start < det.Matches[b].Start })
+			det.Confidence = 1 - miss
+			result = append(result, det)
+		}
+	}
+	return result
+}
+
+// DetectDistortions возвращает искажения с уверенностью не ниже порога лексикона
+func (d *Detector) DetectDistortions(text string) []CognitiveDistortion {
+	return d.Distortions(d.Detect(text))
+}
+
+// Distortions отбирает из готовых результатов Detect искажения с уверенностью
+// не ниже порога лексикона (чтобы не разбирать текст второй раз)
+func (d *Detector) Distortions(dets []Detection) []CognitiveDistortion {
+	var found []CognitiveDistortion
+	for _, det := range dets {
+		if det.Confidence >= d.lexicon.MinConfidence {
+			found = append(found, det.Distortion)
+		}
+	}
+	return found
+}
+
+// isNegated — есть ли отрицание перед маркером (в пределах окна)
+func (d *Detector) isNegated(tokens []token, pos int, p compiledPattern) bool {
+	if d.negations[p.first] {
+		return false // маркер сам начинается с отрицания: «не считается»
+	}
+	for k := pos - 1; k >= 0 && k >= pos-d.lexicon.NegationWindow; k-- {
+		if d.negations[tokens[k].norm] {
+			return true
+		}
+	}
+	return false
+}
+
+func allCovered(flags []bool) bool {
+	for _, f := range flags {
+		if !f {
+			return false
+		}
+	}
+	return true
+}
+
+func stemsMatch(tokens []token, stems []string) bool {
+	for i, s := range stems {
+		if tokens[i].stem != s {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenize разбивает текст на слова по границам слов.
+// Дефис и апостроф внутри слова сохраняются: «из-за», «don't».
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if !inWord && (r == '-' || r == '\'' || r == '’') && start >= 0 {
+			next, _ := utf8.DecodeRuneInString(text[i+utf8.RuneLen(r):])
+			inWord = unicode.IsLetter(next)
+		}
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			tokens = append(tokens, token{start: start, end: i, norm: normalizeWord(text[start:i])})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{start: start, end: len(text), norm: normalizeWord(text[start:])})
+	}
+	return tokens
+}
+
+// normalizeWord — нижний регистр, «ё» → «е», единый апостроф
+func normalizeWord(w string) string {
+	w = strings.ToLower(w)
+	w = strings.ReplaceAll(w, "ё", "е")
+	return strings.ReplaceAll(w, "’", "'")
+}
+
+var (
+	defaultDetector     *Detector
+	defaultDetectorOnce sync.Once
+)
+
+// DefaultDetector — детектор со встроенным русским лексиконом
+func DefaultDetector() *Detector {
+	defaultDetectorOnce.Do(func() {
+		lex, err := BuiltinLexicon("ru")
+		if err != nil {
+			panic(err) // встроенный лексикон повреждён — ошибка сборки
+		}
+		defaultDetector = NewDetector(lex)
+	})
+	return defaultDetector
+}
+
+// Detect — детекция встроенным русским лексиконом
+func Detect(text string) []Detection {
+	return DefaultDetector().Detect(text)
+}
+
+// Distortions — искажения из результатов Detect по порогу встроенного лексикона
+func Distortions(dets []Detection) []CognitiveDistortion {
+	return DefaultDetector().Distortions(dets)
+}
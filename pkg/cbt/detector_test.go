@@ -0,0 +1,121 @@
+package cbt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStemRussian(t *testing.T) {
+	// Эталонные пары из словаря Snowball
+	tests := map[string]string{
+		"важнейшие":   "важн",
+		"вагоны":      "вагон",
+		"важным":      "важн",
+		"взглянула":   "взглянул",
+		"катастрофой": "катастроф",
+		"неудачники":  "неудачник",
+		"случайность": "случайн",
+		"красивейшая": "красив",
+	}
+	for word, want := range tests {
+		if got := StemRussian(word); got != want {
+			t.Errorf("StemRussian(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestDetect_WordBoundaries(t *testing.T) {
+	// «все» не должно срабатывать внутри «всегда»/«весь», а «всё время» — должно
+	for _, text := range []string{"Весь день прошёл спокойно", "Все пришли вовремя"} {
+		if found := DetectDistortions(text); len(found) != 0 {
+			t.Errorf("Text %q: expected no distortions, got %v", text, found)
+		}
+	}
+	if found := DetectDistortions("Всё время что-то ломается"); !contains(found, DistortionOvergeneralization) {
+		t.Errorf("Expected overgeneralization, got %v", found)
+	}
+}
+
+func TestDetect_Morphology(t *testing.T) {
+	tests := []struct {
+		text string
+		want CognitiveDistortion
+	}{
+		{"Это была настоящая катастрофа", DistortionMagnification},
+		{"Кругом одни неудачники", DistortionLabeling},
+		{"Она решит, что я глупый. Все подумают то же самое", DistortionJumpingConclusions},
+		{"Я во всём виновата", DistortionPersonalization},
+		{"Меня похвалили, но мне просто повезло", DistortionDisqualifying},
+		{"Было только плохое, одни проблемы", DistortionMentalFilter},
+	}
+	for _, tc := range tests {
+		if found := DetectDistortions(tc.text); !contains(found, tc.want) {
+			t.Errorf("Text %q: expected %s, got %v", tc.text, tc.want, found)
+		}
+	}
+}
+
+func TestDetect_Negation(t *testing.T) {
+	if found := DetectDistortions("Я не виноват, он не всегда опаздывает"); len(found) != 0 {
+		t.Errorf("Expected negated markers to be ignored, got %v", found)
+	}
+	// «не должен» — всё равно долженствование
+	if found := DetectDistortions("Я не должен ошибаться"); !contains(found, DistortionShouldStatements) {
+		t.Errorf("Expected should statement despite negation, got %v", found)
+	}
+}
+
+func TestDetect_SpansAndConfidence(t *testing.T) {
+	text := "Мне просто повезло, это не считается"
+	dets := Detect(text)
+	if len(dets) != 1 || dets[0].Distortion != DistortionDisqualifying {
+		t.Fatalf("Expected only disqualifying, got %+v", dets)
+	}
+	det := dets[0]
+	// «повезло» поглощено фразой «просто повезло»
+	if len(det.Matches) != 2 {
+		t.Fatalf("Expected 2 matches, got %+v", det.Matches)
+	}
+	if got := text[det.Matches[0].Start:det.Matches[0].End]; got != "просто повезло" {
+		t.Errorf("Unexpected span %q", got)
+	}
+	if det.Matches[1].Text != "не считается" {
+		t.Errorf("Unexpected span %q", det.Matches[1].Text)
+	}
+	if det.Confidence <= 0.7 || det.Confidence >= 1 {
+		t.Errorf("Unexpected confidence %.3f", det.Confidence)
+	}
+	// Отбор по порогу из готовых результатов совпадает с DetectDistortions
+	if got := Distortions(dets); len(got) != 1 || got[0] != DistortionDisqualifying {
+		t.Errorf("Distortions from detections: %v", got)
+	}
+}
+
+func TestLoadLexicon_English(t *testing.T) {
+	lex, err := BuiltinLexicon("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDetector(lex)
+	found := d.DetectDistortions("It's all my fault, I always ruin things and I should be better")
+	for _, want := range []CognitiveDistortion{DistortionPersonalization, DistortionOvergeneralization, DistortionShouldStatements} {
+		if !contains(found, want) {
+			t.Errorf("Expected %s, got %v", want, found)
+		}
+	}
+
+	// Загрузка пользовательского лексикона из файла
+	path := filepath.Join(t.TempDir(), "custom.json")
+	os.WriteFile(path, []byte(`{"language":"ru","distortions":[{"distortion":"labeling","patterns":[{"text":"растяпа"}]}]}`), 0600)
+	custom, err := LoadLexicon(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := NewDetector(custom).DetectDistortions("Какая же я растяпа"); !contains(found, DistortionLabeling) {
+		t.Errorf("Expected labeling from custom lexicon, got %v", found)
+	}
+	if _, err := ParseLexicon([]byte(`{"distortions":[]}`)); err == nil {
+		t.Error("Expected error for lexicon without language")
+	}
+}
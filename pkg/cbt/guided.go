@@ -0,0 +1,202 @@
+package cbt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GuidedStep — шаг сократического диалога
+type GuidedStep string
+
+const (
+	StepSituation       GuidedStep = "situation"        // Что произошло?
+	StepThought         GuidedStep = "thought"          // Какая мысль пришла?
+	StepEmotions        GuidedStep = "emotions"         // Что почувствовали и насколько сильно?
+	StepEvidenceFor     GuidedStep = "evidence_for"     // Что подтверждает мысль?
+	StepEvidenceAgainst GuidedStep = "evidence_against" // Что ей противоречит?
+	StepAlternative     GuidedStep = "alternative"      // Более сбалансированная мысль
+	StepRerate          GuidedStep = "rerate"           // Переоценка интенсивности
+	StepDone            GuidedStep = "done"
+)
+
+// guidedOrder — порядок шагов
+var guidedOrder = []GuidedStep{
+	StepSituation, StepThought, StepEmotions, StepEvidenceFor,
+	StepEvidenceAgainst, StepAlternative, StepRerate, StepDone,
+}
+
+// guidedPrompts — сократические вопросы для каждого шага
+var guidedPrompts = map[GuidedStep]string{
+	StepSituation:       "Опишите ситуацию: где вы были, что происходило, кто был рядом?",
+	StepThought:         "Какая мысль промелькнула в голове в этот момент?",
+	StepEmotions:        "Какие эмоции вы испытали? Насколько сильно, от 0 до 100?",
+	StepEvidenceFor:     "Какие факты подтверждают эту мысль?",
+	StepEvidenceAgainst: "Какие факты ей противоречат? Что бы вы сказали другу в такой ситуации?",
+	StepAlternative:     "Учитывая все факты, как можно посмотреть на ситуацию иначе?",
+	StepRerate:          "Перечитайте альтернативную мысль. Насколько сильны эмоции сейчас, от 0 до 100?",
+	StepDone:            "Запись завершена.",
+}
+
+var (
+	// ErrSessionFinished — сессия уже завершена
+	ErrSessionFinished = errors.New("guided session is already finished")
+	// ErrInvalidAnswer — ответ не подходит к текущему шагу
+	ErrInvalidAnswer = errors.New("invalid answer")
+)
+
+// StepAnswer — ответ пользователя на текущий шаг
+type StepAnswer struct {
+	Text      string   `json:"text,omitempty"`
+	Items     []string `json:"items,omitempty"`     // эмоции или доказательства
+	Intensity *int     `json:"intensity,omitempty"` // 0-100 для emotions и rerate
+}
+
+// GuidedSession — состояние пошаговой КПТ-сессии (можно сохранить и продолжить)
+type GuidedSession struct {
+	ID               string                `json:"id"`
+	PersonID         string                `json:"person_id,omitempty"`
+	Step             GuidedStep            `json:"step"`
+	CreatedAt        time.Time             `json:"created_at"`
+	UpdatedAt        time.Time             `json:"updated_at"`
+	Situation        string                `json:"situation,omitempty"`
+	AutomaticThought string                `json:"automatic_thought,omitempty"`
+	Distortions      []CognitiveDistortion `json:"distortions,omitempty"`
+	Emotions         []string              `json:"emotions,omitempty"`
+	Intensity        int                   `json:"intensity"`
+	EvidenceFor      []string              `json:"evidence_for,omitempty"`
+	EvidenceAgainst  []string              `json:"evidence_against,omitempty"`
+	Alternative      string                `json:"alternative,omitempty"`
+	NewIntensity     int                   `json:"new_intensity"`
+	Record           *ThoughtRecord        `json:"record,omitempty"`   // итог после StepDone
+	Insights         []string              `json:"insights,omitempty"` // инсайты до/после
+}
+
+// NewGuidedSession начинает новую сессию с первого шага
+func NewGuidedSession(id, personID string, now time.Time) *GuidedSession {
+	return &GuidedSession{
+		ID:        id,
+		PersonID:  personID,
+		Step:      StepSituation,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Clone — независимая копия сессии (срезы и итоговая запись копируются)
+func (s *GuidedSession) Clone() *GuidedSession {
+	c := *s
+	c.Distortions = append([]CognitiveDistortion(nil), s.Distortions...)
+	c.Emotions = append([]string(nil), s.Emotions...)
+	c.EvidenceFor = append([]string(nil), s.EvidenceFor...)
+	c.EvidenceAgainst = append([]string(nil), s.EvidenceAgainst...)
+	c.Insights = append([]string(nil), s.Insights...)
+	if s.Record != nil {
+		rec := *s.Record
+		rec.Emotions = append([]string(nil), s.Record.Emotions...)
+		rec.Distortions = append([]CognitiveDistortion(nil), s.Record.Distortions...)
+		c.Record = &rec
+	}
+	return &c
+}
+
+// Prompt возвращает вопрос для текущего шага
+func (s *GuidedSession) Prompt() string {
+	return guidedPrompts[s.Step]
+}
+
+// Done — завершена ли сессия
+func (s *GuidedSession) Done() bool {
+	return s.Step == StepDone
+}
+
+// Answer принимает ответ на текущий шаг и переходит к следующему
+func (s *GuidedSession) Answer(a StepAnswer, now time.Time) error {
+	text := strings.TrimSpace(a.Text)
+	items := cleanItems(a.Items)
+
+	switch s.Step {
+	case StepSituation:
+		if text == "" {
+			return fmt.Errorf("%w: situation is required", ErrInvalidAnswer)
+		}
+		s.Situation = text
+	case StepThought:
+		if text == "" {
+			return fmt.Errorf("%w: automatic thought is required", ErrInvalidAnswer)
+		}
+		s.AutomaticThought = text
+		s.Distortions = DetectDistortions(text)
+	case StepEmotions:
+		if len(items) == 0 {
+			return fmt.Errorf("%w: at least one emotion is required", ErrInvalidAnswer)
+		}
+		intensity, err := requireIntensity(a.Intensity)
+		if err != nil {
+			return err
+		}
+		s.Emotions = items
+		s.Intensity = intensity
+	case StepEvidenceFor:
+		s.EvidenceFor = items
+	case StepEvidenceAgainst:
+		s.EvidenceAgainst = items
+	case StepAlternative:
+		if text == "" {
+			return fmt.Errorf("%w: alternative thought is required", ErrInvalidAnswer)
+		}
+		s.Alternative = text
+	case StepRerate:
+		intensity, err := requireIntensity(a.Intensity)
+		if err != nil {
+			return err
+		}
+		s.NewIntensity = intensity
+	case StepDone:
+		return ErrSessionFinished
+	default:
+		return fmt.Errorf("unknown step %q", s.Step)
+	}
+
+	s.Step = nextStep(s.Step)
+	s.UpdatedAt = now
+	if s.Step == StepDone {
+		s.finish()
+	}
+	return nil
+}
+
+// finish формирует ThoughtRecord и инсайты до/после
+func (s *GuidedSession) finish() {
+	record := ThoughtRecord{
+		Situation:        s.Situation,
+		AutomaticThought: s.AutomaticThought,
+		Emotions:         s.Emotions,
+		Intensity:        s.Intensity,
+		Distortions:      s.Distortions,
+		RationalResponse: s.Alternative,
+		NewIntensity:     s.NewIntensity,
+	}
+	s.Record = &record
+	s.Insights = AnalyzeSession(CBTSession{PersonID: s.PersonID, Records: []ThoughtRecord{record}})
+}
+
+func nextStep(step GuidedStep) GuidedStep {
+	for i, st := range guidedOrder {
+		if st == step && i+1 < len(guidedOrder) {
+			return guidedOrder[i+1]
+		}
+	}
+	return StepDone
+}
+
+func requireIntensity(v *int) (int, error) {
+	if v == nil {
+		return 0, fmt.Errorf("%w: intensity is required", ErrInvalidAnswer)
+	}
+	if *v < 0 || *v > 100 {
+		return 0, fmt.Errorf("%w: intensity must be 0-100, got %d", ErrInvalidAnswer, *v)
+	}
+	return *v, nil
+}
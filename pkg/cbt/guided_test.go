@@ -0,0 +1,92 @@
+package cbt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestGuidedSession_FullFlow(t *testing.T) {
+	now := time.Now()
+	s := NewGuidedSession("s1", "Dina", now)
+
+	steps := []struct {
+		step   GuidedStep
+		answer StepAnswer
+	}{
+		{StepSituation, StepAnswer{Text: "Не ответила на сообщение"}},
+		{StepThought, StepAnswer{Text: "Она меня никогда не уважала"}},
+		{StepEmotions, StepAnswer{Items: []string{"обида", " "}, Intensity: intPtr(80)}},
+		{StepEvidenceFor, StepAnswer{Items: []string{"Молчит второй день"}}},
+		{StepEvidenceAgainst, StepAnswer{Items: []string{"Она в командировке"}}},
+		{StepAlternative, StepAnswer{Text: "Возможно, она просто занята"}},
+		{StepRerate, StepAnswer{Intensity: intPtr(35)}},
+	}
+	for _, st := range steps {
+		if s.Step != st.step {
+			t.Fatalf("Expected step %s, got %s", st.step, s.Step)
+		}
+		if s.Prompt() == "" {
+			t.Errorf("Empty prompt for step %s", s.Step)
+		}
+		if err := s.Answer(st.answer, now); err != nil {
+			t.Fatalf("Answer at %s failed: %v", st.step, err)
+		}
+	}
+
+	if !s.Done() || s.Record == nil {
+		t.Fatal("Expected finished session with record")
+	}
+	if s.Record.Intensity != 80 || s.Record.NewIntensity != 35 || len(s.Record.Emotions) != 1 {
+		t.Errorf("Unexpected record: %+v", s.Record)
+	}
+	if !contains(s.Record.Distortions, DistortionOvergeneralization) {
+		t.Errorf("Expected distortions detected on thought step, got %v", s.Record.Distortions)
+	}
+	found := false
+	for _, in := range s.Insights {
+		if strings.Contains(in, "с 80 до 35") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected before/after insight, got %v", s.Insights)
+	}
+	if err := s.Answer(StepAnswer{Text: "ещё"}, now); err != ErrSessionFinished {
+		t.Errorf("Expected ErrSessionFinished, got %v", err)
+	}
+}
+
+func TestGuidedSession_Validation(t *testing.T) {
+	s := NewGuidedSession("s2", "", time.Now())
+	if err := s.Answer(StepAnswer{}, time.Now()); !errors.Is(err, ErrInvalidAnswer) {
+		t.Errorf("Expected ErrInvalidAnswer for empty situation, got %v", err)
+	}
+	s.Answer(StepAnswer{Text: "Ситуация"}, time.Now())
+	s.Answer(StepAnswer{Text: "Мысль"}, time.Now())
+	if err := s.Answer(StepAnswer{Items: []string{"страх"}}, time.Now()); err == nil {
+		t.Error("Expected error for missing intensity")
+	}
+	if err := s.Answer(StepAnswer{Items: []string{"страх"}, Intensity: intPtr(150)}, time.Now()); err == nil {
+		t.Error("Expected error for intensity out of range")
+	}
+	if s.Step != StepEmotions {
+		t.Errorf("Invalid answers must not advance, got step %s", s.Step)
+	}
+}
+
+func TestAnalyzeSession_Numbers(t *testing.T) {
+	rec := ThoughtRecord{Distortions: []CognitiveDistortion{DistortionLabeling}, Intensity: 90, NewIntensity: 40}
+	insights := AnalyzeSession(CBTSession{Records: []ThoughtRecord{rec, rec, rec}})
+
+	want := []string{"Частое искажение: labeling (3)", "снизилась с 90 до 40"}
+	joined := strings.Join(insights, "\n")
+	for _, w := range want {
+		if !strings.Contains(joined, w) {
+			t.Errorf("Expected %q in insights, got %q", w, joined)
+		}
+	}
+}
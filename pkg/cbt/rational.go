@@ -0,0 +1,162 @@
+package cbt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RationalPromptVersion — версия промпта для генерации рационального ответа.
+// Меняется при любом изменении текста промпта или JSON-схемы ответа.
+const RationalPromptVersion = "cbt-rational-v1"
+
+// Источники рационального ответа
+const (
+	RationalSourceLLM   = "llm"
+	RationalSourceRules = "rules"
+)
+
+// Ограничения на ответ модели
+const (
+	maxRationalItems    = 5
+	maxRationalItemLen  = 400
+	maxRationalRawBytes = 16 * 1024
+)
+
+// TextGenerator — источник текста (локальная LLM, например llm.Client)
+type TextGenerator interface {
+	GenerateText(prompt string) (string, error)
+}
+
+// StructuredResponse — структурированный рациональный ответ
+type StructuredResponse struct {
+	AlternativeThoughts []string `json:"alternative_thoughts"`
+	EvidenceFor         []string `json:"evidence_for"`
+	EvidenceAgainst     []string `json:"evidence_against"`
+	Summary             string   `json:"summary"`
+	Source              string   `json:"source"` // llm | rules
+	PromptVersion       string   `json:"prompt_version,omitempty"`
+}
+
+// Text возвращает ответ одной строкой (для ThoughtEntry.RationalResponse)
+func (r StructuredResponse) Text() string {
+	if r.Source == RationalSourceRules {
+		return r.Summary
+	}
+	parts := []string{}
+	if r.Summary != "" {
+		parts = append(parts, r.Summary)
+	}
+	if len(r.AlternativeThoughts) > 0 {
+		parts = append(parts, "Альтернативный взгляд: "+strings.Join(r.AlternativeThoughts, " "))
+	}
+	return strings.Join(parts, " ")
+}
+
+// BuildRationalPrompt собирает версионированный промпт для LLM
+func BuildRationalPrompt(situation, automaticThought string, distortions []CognitiveDistortion) string {
+	names := make([]string, 0, len(distortions))
+	for _, d := range distortions {
+		names = append(names, string(d))
+	}
+	if len(names) == 0 {
+		names = append(names, "не обнаружены")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[prompt:%s]\n", RationalPromptVersion)
+	sb.WriteString("Ты — ассистент когнитивно-поведенческой терапии. Помоги человеку найти рациональный ответ на автоматическую мысль.\n")
+	sb.WriteString("Не ставь диагнозов, не давай медицинских советов, пиши бережно и на русском.\n\n")
+	fmt.Fprintf(&sb, "Ситуация: %s\n", situation)
+	fmt.Fprintf(&sb, "Автоматическая мысль: %s\n", automaticThought)
+	fmt.Fprintf(&sb, "Когнитивные искажения: %s\n\n", strings.Join(names, ", "))
+	sb.WriteString("Ответь ТОЛЬКО JSON-объектом без пояснений, строго по схеме:\n")
+	sb.WriteString(`{"alternative_thoughts": ["..."], "evidence_for": ["..."], "evidence_against": ["..."], "summary": "..."}`)
+	sb.WriteString("\nВ alternative_thoughts от 1 до 3 пунктов, в evidence_for и evidence_against до 3 пунктов.\n")
+	return sb.String()
+}
+
+// ParseRationalResponse извлекает и валидирует JSON-ответ модели
+func ParseRationalResponse(raw string) (*StructuredResponse, error) {
+	if len(raw) > maxRationalRawBytes {
+		return nil, errors.New("llm response too large")
+	}
+	start := strings.Index(raw, "{")
+	end := strings.LastIndex(raw, "}")
+	if start < 0 || end <= start {
+		return nil, errors.New("llm response contains no JSON object")
+	}
+
+	var resp StructuredResponse
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &resp); err != nil {
+		return nil, fmt.Errorf("decode llm response: %w", err)
+	}
+
+	resp.AlternativeThoughts = cleanItems(resp.AlternativeThoughts)
+	resp.EvidenceFor = cleanItems(resp.EvidenceFor)
+	resp.EvidenceAgainst = cleanItems(resp.EvidenceAgainst)
+	resp.Summary = strings.TrimSpace(resp.Summary)
+
+	if len(resp.AlternativeThoughts) == 0 {
+		return nil, errors.New("llm response has no alternative thoughts")
+	}
+	if len([]rune(resp.Summary)) > maxRationalItemLen {
+		return nil, errors.New("llm summary too long")
+	}
+	for _, list := range [][]string{resp.AlternativeThoughts, resp.EvidenceFor, resp.EvidenceAgainst} {
+		if len(list) > maxRationalItems {
+			return nil, errors.New("llm response has too many items")
+		}
+		for _, item := range list {
+			if len([]rune(item)) > maxRationalItemLen {
+				return nil, errors.New("llm response item too long")
+			}
+		}
+	}
+
+	resp.Source = RationalSourceLLM
+	resp.PromptVersion = RationalPromptVersion
+	return &resp, nil
+}
+
+// GenerateStructuredResponse генерирует рациональный ответ через LLM.
+// При недоступности модели или невалидном ответе возвращает
+// rule-based ответ (GenerateRationalResponse) и причину отката.
+func GenerateStructuredResponse(gen TextGenerator, situation, automaticThought string, distortions []CognitiveDistortion) (StructuredResponse, error) {
+	if gen != nil {
+		raw, err := gen.GenerateText(BuildRationalPrompt(situation, automaticThought, distortions))
+		if err == nil {
+			resp, perr := ParseRationalResponse(raw)
+			if perr == nil {
+				return *resp, nil
+			}
+			err = perr
+		}
+		return ruleBasedResponse(automaticThought, distortions), err
+	}
+	return ruleBasedResponse(automaticThought, distortions), nil
+}
+
+// ruleBasedResponse оборачивает шаблонный ответ в StructuredResponse
+func ruleBasedResponse(automaticThought string, distortions []CognitiveDistortion) StructuredResponse {
+	return StructuredResponse{
+		AlternativeThoughts: []string{},
+		EvidenceFor:         []string{},
+		EvidenceAgainst:     []string{},
+		Summary:             GenerateRationalResponse(automaticThought, distortions),
+		Source:              RationalSourceRules,
+	}
+}
+
+// cleanItems убирает пустые строки и пробелы по краям
+func cleanItems(items []string) []string {
+	result := []string{}
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package cbt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// stubGenerator — заглушка LLM с фиксированным ответом
+type stubGenerator struct {
+	reply  string
+	err    error
+	prompt string
+}
+
+func (s *stubGenerator) GenerateText(prompt string) (string, error) {
+	s.prompt = prompt
+	return s.reply, s.err
+}
+
+func TestBuildRationalPrompt(t *testing.T) {
+	prompt := BuildRationalPrompt("Опоздал на встречу", "Я всё испортил", []CognitiveDistortion{DistortionMagnification})
+	for _, want := range []string{RationalPromptVersion, "Опоздал на встречу", "Я всё испортил", "magnification", "alternative_thoughts"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Prompt missing %q", want)
+		}
+	}
+}
+
+func TestParseRationalResponse(t *testing.T) {
+	raw := "```json\n{\"alternative_thoughts\": [\"Опоздание — неприятность, а не провал\", \" \"], " +
+		"\"evidence_for\": [\"Встреча началась без меня\"], \"evidence_against\": [\"Коллеги дождались\"], " +
+		"\"summary\": \"Ситуация поправима\"}\n```"
+	resp, err := ParseRationalResponse(raw)
+	if err != nil {
+		t.Fatalf("ParseRationalResponse failed: %v", err)
+	}
+	if len(resp.AlternativeThoughts) != 1 {
+		t.Errorf("Expected empty items to be dropped, got %d", len(resp.AlternativeThoughts))
+	}
+	if resp.Source != RationalSourceLLM || resp.PromptVersion != RationalPromptVersion {
+		t.Errorf("Unexpected metadata: %s / %s", resp.Source, resp.PromptVersion)
+	}
+
+	invalid := []string{
+		"просто текст без JSON",
+		`{"alternative_thoughts": []}`,
+		`{"alternative_thoughts": ["a","b","c","d","e","f"]}`,
+		`{"alternative_thoughts": "не массив"}`,
+	}
+	for _, raw := range invalid {
+		if _, err := ParseRationalResponse(raw); err == nil {
+			t.Errorf("Expected validation error for %q", raw)
+		}
+	}
+}
+
+func TestGenerateStructuredResponse_Fallback(t *testing.T) {
+	distortions := []CognitiveDistortion{DistortionPersonalization}
+	rules := GenerateRationalResponse("Это из-за меня", distortions)
+
+	// Без LLM — правила, без ошибки
+	resp, err := GenerateStructuredResponse(nil, "", "Это из-за меня", distortions)
+	if err != nil || resp.Source != RationalSourceRules || resp.Text() != rules {
+		t.Errorf("Expected rule-based response, got %+v (err %v)", resp, err)
+	}
+
+	// Ошибка LLM — откат на правила с причиной
+	resp, err = GenerateStructuredResponse(&stubGenerator{err: errors.New("offline")}, "", "Это из-за меня", distortions)
+	if err == nil || resp.Source != RationalSourceRules {
+		t.Errorf("Expected fallback with error, got %+v (err %v)", resp, err)
+	}
+
+	// Невалидный ответ — тоже откат
+	resp, _ = GenerateStructuredResponse(&stubGenerator{reply: "не знаю"}, "", "Это из-за меня", distortions)
+	if resp.Source != RationalSourceRules {
+		t.Errorf("Expected fallback on invalid output, got %s", resp.Source)
+	}
+
+	// Валидный ответ LLM
+	gen := &stubGenerator{reply: `{"alternative_thoughts": ["На ситуацию повлияло многое"], "summary": "Не всё зависит от меня"}`}
+	resp, err = GenerateStructuredResponse(gen, "Друг расстроен", "Это из-за меня", distortions)
+	if err != nil || resp.Source != RationalSourceLLM {
+		t.Fatalf("Expected LLM response, got %+v (err %v)", resp, err)
+	}
+	if !strings.Contains(gen.prompt, "Друг расстроен") {
+		t.Error("Expected situation in prompt")
+	}
+	if !strings.Contains(resp.Text(), "На ситуацию повлияло многое") {
+		t.Errorf("Unexpected text: %q", resp.Text())
+	}
+}
+
+func TestGenerateRationalResponse_AllDistortions(t *testing.T) {
+	for _, d := range []CognitiveDistortion{DistortionMentalFilter, DistortionDisqualifying, DistortionJumpingConclusions, DistortionPersonalization} {
+		if resp := GenerateRationalResponse("", []CognitiveDistortion{d}); resp == "Альтернативный взгляд: " {
+			t.Errorf("Empty rational response for %s", d)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package cbt
+
+import "strings"
+
+// Stemmer приводит словоформу к основе
+type Stemmer func(word string) string
+
+// stemmerFor возвращает стеммер для языка лексикона
+func stemmerFor(language string) Stemmer {
+	switch language {
+	case "ru":
+		return StemRussian
+	case "en":
+		return StemEnglish
+	default:
+		return func(w string) string { return w }
+	}
+}
+
+// ============================================================================
+// RUSSIAN (Snowball)
+// ============================================================================
+
+// Окончания по алгоритму Snowball для русского языка.
+// Группа 1 допустима только после «а» или «я».
+var (
+	ruPerfectiveGerund1 = []string{"в", "вши", "вшись"}
+	ruPerfectiveGerund2 = []string{"ив", "ивши", "ившись", "ыв", "ывши", "ывшись"}
+	ruAdjective         = []string{"ее", "ие", "ые", "ое", "ими", "ыми", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом",
+		"его", "ого", "ему", "ому", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею"}
+	ruParticiple1 = []string{"ем", "нн", "вш", "ющ", "щ"}
+	ruParticiple2 = []string{"ивш", "ывш", "ующ"}
+	ruReflexive   = []string{"ся", "сь"}
+	ruVerb1       = []string{"ла", "на", "ете", "йте", "ли", "й", "л", "ем", "н", "ло", "но", "ет", "ют", "ны", "ть", "ешь", "нно"}
+	ruVerb2       = []string{"ила", "ыла", "ена", "ейте", "уйте", "ите", "или", "ыли", "ей", "уй", "ил", "ыл", "им", "ым", "ен",
+		"ило", "ыло", "ено", "ят", "ует", "уют", "ит", "ыт", "ены", "ить", "ыть", "ишь", "ую", "ю"}
+	ruNoun = []string{"а", "ев", "ов", "ие", "ье", "е", "иями", "ями", "ами", "еи", "ии", "и", "ией", "ей", "ой", "ий", "й",
+		"иям", "ям", "ием", "ем", "ам", "ом", "о", "у", "ах", "иях", "ях", "ы", "ь", "ию", "ью", "ю", "ия", "ья", "я"}
+	ruSuperlative  = []string{"ейш", "ейше"}
+	ruDerivational = []string{"ост", "ость"}
+)
+
+func isRuVowel(r rune) bool {
+	return strings.ContainsRune("аеиоуыэюя", r)
+}
+
+// StemRussian — стеммер Snowball (Porter) для русского языка.
+// Ожидает слово в нижнем регистре с «ё», заменённой на «е».
+func StemRussian(word string) string {
+	w := []rune(word)
+
+	// RV — область после первой гласной; R2 — для словообразовательных суффиксов
+	rv := len(w)
+	for i, r := range w {
+		if isRuVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+	r1 := ruRegion(w, 0)
+	r2 := ruRegion(w, r1)
+
+	// Шаг 1
+	if n := ruLongest(w, rv, ruPerfectiveGerund1, ruPerfectiveGerund2); n > 0 {
+		w = w[:len(w)-n]
+	} else {
+		if n := ruLongest(w, rv, nil, ruReflexive); n > 0 {
+			w = w[:len(w)-n]
+		}
+		if n := ruLongest(w, rv, nil, ruAdjective); n > 0 {
+			w = w[:len(w)-n]
+			if n := ruLongest(w, rv, ruParticiple1, ruParticiple2); n > 0 {
+				w = w[:len(w)-n]
+			}
+		} else if n := ruLongest(w, rv, ruVerb1, ruVerb2); n > 0 {
+			w = w[:len(w)-n]
+		} else if n := ruLongest(w, rv, nil, ruNoun); n > 0 {
+			w = w[:len(w)-n]
+		}
+	}
+
+	// Шаг 2
+	if n := ruLongest(w, rv, nil, []string{"и"}); n > 0 {
+		w = w[:len(w)-n]
+	}
+
+	// Шаг 3
+	if n := ruLongest(w, r2, nil, ruDerivational); n > 0 {
+		w = w[:len(w)-n]
+	}
+
+	// Шаг 4
+	if n := ruLongest(w, rv, nil, []string{"нн"}); n > 0 {
+		w = w[:len(w)-1]
+	} else if n := ruLongest(w, rv, nil, ruSuperlative); n > 0 {
+		w = w[:len(w)-n]
+		if ruLongest(w, rv, nil, []string{"нн"}) > 0 {
+			w = w[:len(w)-1]
+		}
+	} else if ruLongest(w, rv, nil, []string{"ь"}) > 0 {
+		w = w[:len(w)-1]
+	}
+
+	return string(w)
+}
+
+// ruRegion возвращает начало области после первой согласной, следующей за гласной
+func ruRegion(w []rune, from int) int {
+	for i := from + 1; i < len(w); i++ {
+		if !isRuVowel(w[i]) && isRuVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+// ruLongest ищет самое длинное окончание, целиком лежащее в области [start:].
+// Окончания из group1 допустимы только после «а» или «я» (тоже внутри области).
+// Возвращает длину окончания в рунах или 0.
+func ruLongest(w []rune, start int, group1, group2 []string) int {
+	best := 0
+	check := func(suffixes []string, needAYa bool) {
+		for _, s := range suffixes {
+			sr := []rune(s)
+			n := len(sr)
+			if n <= best || len(w)-n < start || !hasRuneSuffix(w, sr) {
+				continue
+			}
+			if needAYa {
+				p := len(w) - n - 1
+				if p < start || (w[p] != 'а' && w[p] != 'я') {
+					continue
+				}
+			}
+			best = n
+		}
+	}
+	check(group1, true)
+	check(group2, false)
+	return best
+}
+
+func hasRuneSuffix(w, suffix []rune) bool {
+	if len(suffix) > len(w) {
+		return false
+	}
+	off := len(w) - len(suffix)
+	for i, r := range suffix {
+		if w[off+i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// ============================================================================
+// ENGLISH (лёгкий стеммер)
+// ============================================================================
+
+// StemEnglish — упрощённое отсечение английских окончаний.
+// Достаточно для сопоставления словоформ в лексиконе искажений.
+func StemEnglish(word string) string {
+	word = strings.TrimSuffix(word, "'s")
+	for _, suffix := range []string{"ingly", "edly", "ing", "ed", "ly", "es", "s"} {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 3 {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
@@ -0,0 +1,241 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Формат резервной копии ключа (v1, все числа big-endian):
+//
+//	magic   "IDBK"         4 байта
+//	version 0x01           1 байт
+//	kdf     0x01=argon2id  1 байт
+//	time    uint32         4 байта  — число проходов
+//	memory  uint32         4 байта  — память в KiB
+//	threads uint8          1 байт
+//	saltLen uint8          1 байт
+//	salt                   saltLen байт
+//	nonce                  24 байта
+//	ciphertext             secretbox(privateKey)
+//
+// Параметры KDF входят в вывод ключа, поэтому подмена заголовка
+// приводит к ошибке расшифровки, а не к тихому ослаблению защиты.
+//
+// v0 (устаревший): salt(16) | nonce(24) | ciphertext, ключ = SHA-512(password||salt)[:32].
+
+// BackupMagic — сигнатура резервной копии
+var BackupMagic = []byte("IDBK")
+
+const (
+	BackupVersionLegacy byte = 0 // SHA-512, без заголовка
+	BackupVersion1      byte = 1 // Argon2id, самоописывающий заголовок
+
+	KDFSHA512   byte = 0
+	KDFArgon2id byte = 1
+
+	saltSize       = 16
+	backupHeaderV1 = 4 + 1 + 1 + 4 + 4 + 1 + 1
+
+	// Верхние пределы параметров из заголовка (защита от «бомбы» по памяти)
+	maxKDFTime      = 16
+	maxKDFMemoryKiB = 1 << 20 // 1 GiB
+)
+
+// ErrLegacyBackup — бэкап в устаревшем формате v0; его стоит перешифровать (RewrapBackup)
+var ErrLegacyBackup = errors.New("backup uses legacy SHA-512 format, re-wrap recommended")
+
+// KDFParams — параметры Argon2id
+type KDFParams struct {
+	Time      uint32 `json:"time"`
+	MemoryKiB uint32 `json:"memory_kib"`
+	Threads   uint8  `json:"threads"`
+}
+
+// DefaultKDFParams — параметры по умолчанию (RFC 9106, второй рекомендованный вариант)
+var DefaultKDFParams = KDFParams{Time: 3, MemoryKiB: 64 * 1024, Threads: 4}
+
+// Validate проверяет параметры на разумность
+func (p KDFParams) Validate() error {
+	if p.Time == 0 || p.Time > maxKDFTime {
+		return fmt.Errorf("argon2id time must be 1-%d, got %d", maxKDFTime, p.Time)
+	}
+	if p.MemoryKiB < 8*uint32(p.Threads) || p.MemoryKiB > maxKDFMemoryKiB {
+		return fmt.Errorf("argon2id memory %d KiB out of range", p.MemoryKiB)
+	}
+	if p.Threads == 0 {
+		return errors.New("argon2id threads must be > 0")
+	}
+	return nil
+}
+
+// DeriveKeyFromPassword деривирует ключ из пароля (Argon2id с параметрами по умолчанию)
+func DeriveKeyFromPassword(password, salt []byte) ([KeySize]byte, error) {
+	return DeriveKeyArgon2id(password, salt, DefaultKDFParams)
+}
+
+// DeriveKeyArgon2id деривирует ключ из пароля с заданными параметрами Argon2id
+func DeriveKeyArgon2id(password, salt []byte, params KDFParams) ([KeySize]byte, error) {
+	var key [KeySize]byte
+	if err := params.Validate(); err != nil {
+		return key, err
+	}
+	if len(salt) < 8 {
+		return key, errors.New("salt must be at least 8 bytes")
+	}
+	copy(key[:], argon2.IDKey(password, salt, params.Time, params.MemoryKiB, params.Threads, KeySize))
+	return key, nil
+}
+
+// deriveKeyLegacy — KDF формата v0 (только для чтения старых бэкапов)
+func deriveKeyLegacy(password, salt []byte) [KeySize]byte {
+	hash := sha512.Sum512(append(append([]byte{}, password...), salt...))
+	var key [KeySize]byte
+	copy(key[:], hash[:KeySize])
+	return key
+}
+
+// BackupInfo — разобранный заголовок резервной копии
+type BackupInfo struct {
+	Version byte      `json:"version"`
+	KDF     byte      `json:"kdf"`
+	Params  KDFParams `json:"params"`
+	Salt    []byte    `json:"-"`
+	Nonce   []byte    `json:"-"`
+	Payload []byte    `json:"-"` // ciphertext
+}
+
+// ParseBackup разбирает резервную копию; блоб без сигнатуры считается v0
+func ParseBackup(backup []byte) (*BackupInfo, error) {
+	if !bytes.HasPrefix(backup, BackupMagic) {
+		return parseLegacyBackup(backup)
+	}
+	if len(backup) < backupHeaderV1 {
+		return nil, errors.New("backup header too short")
+	}
+	info := &BackupInfo{Version: backup[4], KDF: backup[5]}
+	if info.Version != BackupVersion1 {
+		return nil, fmt.Errorf("unsupported backup version %d", info.Version)
+	}
+	if info.KDF != KDFArgon2id {
+		return nil, fmt.Errorf("unsupported backup KDF %d", info.KDF)
+	}
+	info.Params = KDFParams{
+		Time:      binary.BigEndian.Uint32(backup[6:10]),
+		MemoryKiB: binary.BigEndian.Uint32(backup[10:14]),
+		Threads:   backup[14],
+	}
+	if err := info.Params.Validate(); err != nil {
+		return nil, err
+	}
+	saltLen := int(backup[15])
+	rest := backup[backupHeaderV1:]
+	if saltLen < 8 || len(rest) < saltLen+NonceSize+Overhead {
+		return nil, errors.New("backup too short")
+	}
+	info.Salt = rest[:saltLen]
+	info.Nonce = rest[saltLen : saltLen+NonceSize]
+	info.Payload = rest[saltLen+NonceSize:]
+	return info, nil
+}
+
+func parseLegacyBackup(backup []byte) (*BackupInfo, error) {
+	if len(backup) < saltSize+NonceSize+Overhead {
+		return nil, errors.New("backup too short")
+	}
+	return &BackupInfo{
+		Version: BackupVersionLegacy,
+		KDF:     KDFSHA512,
+		Salt:    backup[:saltSize],
+		Nonce:   backup[saltSize : saltSize+NonceSize],
+		Payload: backup[saltSize+NonceSize:],
+	}, nil
+}
+
+// ExportEncryptedBackup экспортирует приватный ключ в зашифрованном виде (формат v1)
+func (kp *KeyPair) ExportEncryptedBackup(password string) ([]byte, error) {
+	return kp.ExportEncryptedBackupWithParams(password, DefaultKDFParams)
+}
+
+// ExportEncryptedBackupWithParams — то же, с явными параметрами Argon2id
+func (kp *KeyPair) ExportEncryptedBackupWithParams(password string, params KDFParams) ([]byte, error) {
+	if password == "" {
+		return nil, errors.New("backup password is required")
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	nonce, err := GenerateNonce()
+	if err != nil {
+		return nil, err
+	}
+	key, err := DeriveKeyArgon2id([]byte(password), salt, params)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := EncryptKey(kp.PrivateKey, key[:], nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, backupHeaderV1+len(salt)+len(nonce)+len(encrypted))
+	out = append(out, BackupMagic...)
+	out = append(out, BackupVersion1, KDFArgon2id)
+	out = binary.BigEndian.AppendUint32(out, params.Time)
+	out = binary.BigEndian.AppendUint32(out, params.MemoryKiB)
+	out = append(out, params.Threads, byte(len(salt)))
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	out = append(out, encrypted...)
+	return out, nil
+}
+
+// ImportEncryptedBackup импортирует приватный ключ из зашифрованного бэкапа.
+// Понимает форматы v1 и v0; для v0 ключ возвращается вместе с ErrLegacyBackup,
+// чтобы вызывающий код мог предложить перешифровать бэкап.
+func ImportEncryptedBackup(backup []byte, password string) (*KeyPair, error) {
+	info, err := ParseBackup(backup)
+	if err != nil {
+		return nil, err
+	}
+
+	var key [KeySize]byte
+	switch info.KDF {
+	case KDFArgon2id:
+		if key, err = DeriveKeyArgon2id([]byte(password), info.Salt, info.Params); err != nil {
+			return nil, err
+		}
+	default:
+		key = deriveKeyLegacy([]byte(password), info.Salt)
+	}
+
+	privateKey, err := DecryptKey(info.Payload, key[:], info.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("backup contains %d-byte key, expected %d", len(privateKey), ed25519.PrivateKeySize)
+	}
+	priv := ed25519.PrivateKey(privateKey)
+	kp := &KeyPair{PrivateKey: priv, PublicKey: priv.Public().(ed25519.PublicKey)}
+	if info.Version == BackupVersionLegacy {
+		return kp, ErrLegacyBackup
+	}
+	return kp, nil
+}
+
+// RewrapBackup перешифровывает бэкап в актуальный формат (v1, Argon2id)
+func RewrapBackup(backup []byte, password string) ([]byte, error) {
+	kp, err := ImportEncryptedBackup(backup, password)
+	if err != nil && !errors.Is(err, ErrLegacyBackup) {
+		return nil, err
+	}
+	return kp.ExportEncryptedBackup(password)
+}
@@ -0,0 +1,100 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+// testKDF — лёгкие параметры, чтобы тесты не тратили 64 MiB на вызов
+var testKDF = KDFParams{Time: 1, MemoryKiB: 64, Threads: 1}
+
+func TestBackupV1_RoundTrip(t *testing.T) {
+	kp, _ := GenerateKeyPair()
+	backup, err := kp.ExportEncryptedBackupWithParams("correct horse", testKDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(backup, BackupMagic) || backup[4] != BackupVersion1 || backup[5] != KDFArgon2id {
+		t.Fatalf("Unexpected header: %x", backup[:backupHeaderV1])
+	}
+
+	info, err := ParseBackup(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Params != testKDF || len(info.Salt) != saltSize {
+		t.Errorf("Unexpected header fields: %+v", info)
+	}
+
+	restored, err := ImportEncryptedBackup(backup, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(restored.PrivateKey, kp.PrivateKey) || !bytes.Equal(restored.PublicKey, kp.PublicKey) {
+		t.Error("Restored key differs")
+	}
+
+	if _, err := ImportEncryptedBackup(backup, "wrong"); err == nil {
+		t.Error("Expected error for wrong password")
+	}
+
+	// Подмена параметров KDF в заголовке ломает расшифровку
+	tampered := append([]byte{}, backup...)
+	tampered[9]++ // time
+	if _, err := ImportEncryptedBackup(tampered, "correct horse"); err == nil {
+		t.Error("Expected error for tampered KDF params")
+	}
+
+	// Заведомо огромная память отвергается до вызова Argon2
+	huge := append([]byte{}, backup...)
+	huge[10] = 0xff
+	if _, err := ParseBackup(huge); err == nil {
+		t.Error("Expected error for excessive memory parameter")
+	}
+}
+
+func TestBackupV0_ImportAndRewrap(t *testing.T) {
+	kp, _ := GenerateKeyPair()
+
+	// Старый формат: salt | nonce | secretbox(SHA-512(password||salt)[:32])
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	nonce, _ := GenerateNonce()
+	key := deriveKeyLegacy([]byte("old"), salt)
+	ct, _ := EncryptKey(kp.PrivateKey, key[:], nonce)
+	legacy := append(append(append([]byte{}, salt...), nonce...), ct...)
+
+	restored, err := ImportEncryptedBackup(legacy, "old")
+	if !errors.Is(err, ErrLegacyBackup) {
+		t.Fatalf("Expected ErrLegacyBackup, got %v", err)
+	}
+	if !bytes.Equal(restored.PrivateKey, kp.PrivateKey) {
+		t.Fatal("Legacy key differs")
+	}
+
+	upgraded, err := RewrapBackup(legacy, "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _ := ParseBackup(upgraded)
+	if info.Version != BackupVersion1 || info.Params != DefaultKDFParams {
+		t.Errorf("Expected v1 with default params, got %+v", info)
+	}
+	again, err := ImportEncryptedBackup(upgraded, "old")
+	if err != nil || !bytes.Equal(again.PrivateKey, kp.PrivateKey) {
+		t.Errorf("Re-wrapped backup does not restore key: %v", err)
+	}
+}
+
+func TestKDFParams_Validate(t *testing.T) {
+	if err := DefaultKDFParams.Validate(); err != nil {
+		t.Errorf("Default params must be valid: %v", err)
+	}
+	for _, p := range []KDFParams{{0, 64, 1}, {1, 64, 0}, {1, 4, 1}, {maxKDFTime + 1, 64, 1}} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", p)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// Шифрование между узлами (box v1)
+//
+// Идентичность узла — ключ ed25519. Для согласования ключей он переводится
+// в X25519 (та же кривая в форме Монтгомери, как crypto_sign_ed25519_*_to_curve25519
+// в libsodium). Каждое сообщение шифруется на свежем эфемерном ключе:
+//
+//	dh1 = X25519(eph, R)   — R: предключ получателя, если есть, иначе его X25519-идентичность
+//	dh2 = X25519(S, Rid)   — статический DH идентичностей, аутентифицирует отправителя
+//	key = HKDF-SHA256(dh1 || dh2, info = "ideal-core/box/v1" || flags || ephPub || senderEd || recipientEd || prekeyID)
+//
+// Эфемерный ключ отправителя уничтожается сразу после шифрования, поэтому
+// компрометация ключа отправителя не раскрывает его прошлые сообщения. Прямой
+// секретности относительно получателя нет: без предключа компрометация ключа
+// получателя раскрывает всю переписку с ним.
+//
+// Предключи (SignedPreKey) — только формат: если бы получатель публиковал
+// краткоживущие предключи и удалял их секретные части, сообщения на удалённый
+// предключ нельзя было бы расшифровать и при компрометации идентичности. Узел
+// пока не создаёт, не публикует и не хранит предключей: envelope и recovery
+// шифруют на ключ идентичности (preKey = nil).
+//
+// Формат: version(1) | flags(1) | ephPub(32) | [prekeyID(8)] | nonce(24) | secretbox
+//
+// Миграция: EncryptForRecipient/DeriveSharedKey выводили ключ из двух публичных
+// ключей, то есть не давали конфиденциальности. Всё, что было так зашифровано,
+// следует считать раскрытым: расшифровать старым DecryptFromSender, затем
+// перешифровать через SealForPeer и удалить старые копии. Новый код должен
+// использовать только SealForPeer/OpenFromPeer.
+
+const (
+	BoxVersion1 byte = 1
+
+	boxFlagPreKey byte = 1 << 0
+
+	preKeyIDSize = 8
+	boxInfo      = "ideal-core/box/v1"
+)
+
+var (
+	ErrBoxVersion    = errors.New("unsupported box version")
+	ErrBoxTooShort   = errors.New("box too short")
+	ErrBoxOpen       = errors.New("box decryption failed: wrong keys or data tampered")
+	ErrPreKeyMissing = errors.New("prekey not found (expired or deleted)")
+)
+
+// curve25519P — 2^255 − 19
+var curve25519P, _ = new(big.Int).SetString("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed", 16)
+
+// Ed25519PublicToX25519 переводит публичный ключ ed25519 в X25519: u = (1 + y) / (1 − y) mod p
+func Ed25519PublicToX25519(pub ed25519.PublicKey) ([]byte, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("ed25519 public key must be %d bytes", ed25519.PublicKeySize)
+	}
+	// y хранится little-endian, старший бит — знак x
+	le := append([]byte{}, pub...)
+	le[31] &= 0x7f
+	y := new(big.Int).SetBytes(reverse(le))
+	if y.Cmp(curve25519P) >= 0 {
+		return nil, errors.New("invalid ed25519 public key: y out of range")
+	}
+
+	one := big.NewInt(1)
+	den := new(big.Int).Sub(one, y)
+	den.Mod(den, curve25519P)
+	if den.Sign() == 0 {
+		return nil, errors.New("invalid ed25519 public key: y = 1")
+	}
+	num := new(big.Int).Add(one, y)
+	u := num.Mul(num, den.ModInverse(den, curve25519P))
+	u.Mod(u, curve25519P)
+
+	out := make([]byte, 32)
+	u.FillBytes(out)
+	return reverse(out), nil
+}
+
+// Ed25519PrivateToX25519 переводит приватный ключ ed25519 в скаляр X25519 (SHA-512(seed)[:32])
+func Ed25519PrivateToX25519(priv ed25519.PrivateKey) ([]byte, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("ed25519 private key must be %d bytes", ed25519.PrivateKeySize)
+	}
+	h := sha512.Sum512(priv.Seed())
+	// Клэмпинг выполняет и сам X25519, но храним скаляр в каноническом виде
+	h[0] &= 248
+	h[31] &= 127
+	h[31] |= 64
+	return h[:32], nil
+}
+
+// X25519 — обмен Диффи–Хеллмана на Curve25519 (отвергает нулевой результат)
+func X25519(private, peerPublic []byte) ([]byte, error) {
+	priv, err := ecdh.X25519().NewPrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := ecdh.X25519().NewPublicKey(peerPublic)
+	if err != nil {
+		return nil, err
+	}
+	return priv.ECDH(pub)
+}
+
+// ============================================================================
+// PREKEYS
+// ============================================================================
+
+// SignedPreKey — краткоживущий X25519-ключ получателя, подписанный его идентичностью
+type SignedPreKey struct {
+	Public    []byte    `json:"public"`  // X25519, 32 байта
+	Expires   time.Time `json:"expires"` // после этого момента отправители не должны его использовать
+	Signature []byte    `json:"signature"`
+}
+
+// PreKeyPrivate — секретная часть предключа (удалить после истечения и доставки сообщений)
+type PreKeyPrivate struct {
+	ID      [preKeyIDSize]byte
+	Private []byte
+	Expires time.Time
+}
+
+// ID — идентификатор предключа (первые 8 байт SHA-256 публичной части)
+func (p SignedPreKey) ID() [preKeyIDSize]byte {
+	var id [preKeyIDSize]byte
+	h := sha256.Sum256(p.Public)
+	copy(id[:], h[:preKeyIDSize])
+	return id
+}
+
+func (p SignedPreKey) signedBytes() []byte {
+	msg := append([]byte("ideal-core/prekey/v1"), p.Public...)
+	return binary.BigEndian.AppendUint64(msg, uint64(p.Expires.Unix()))
+}
+
+// GeneratePreKey создаёт подписанный предключ со сроком жизни ttl
+func (kp *KeyPair) GeneratePreKey(ttl time.Duration, now time.Time) (*PreKeyPrivate, SignedPreKey, error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, SignedPreKey{}, err
+	}
+	spk := SignedPreKey{Public: priv.PublicKey().Bytes(), Expires: now.Add(ttl).UTC().Truncate(time.Second)}
+	spk.Signature = kp.Sign(spk.signedBytes())
+	return &PreKeyPrivate{ID: spk.ID(), Private: priv.Bytes(), Expires: spk.Expires}, spk, nil
+}
+
+// VerifyPreKey проверяет подпись и срок действия предключа
+func VerifyPreKey(identity ed25519.PublicKey, spk SignedPreKey, now time.Time) error {
+	if len(spk.Public) != 32 {
+		return errors.New("prekey must be 32 bytes")
+	}
+	if !Verify(identity, spk.signedBytes(), spk.Signature) {
+		return errors.New("prekey signature is invalid")
+	}
+	if !now.Before(spk.Expires) {
+		return errors.New("prekey expired")
+	}
+	return nil
+}
+
+// ============================================================================
+// SEAL / OPEN
+// ============================================================================
+
+// SealForPeer шифрует сообщение для получателя с ключом идентичности recipient.
+// preKey необязателен; если задан, он должен быть проверен через VerifyPreKey.
+func (kp *KeyPair) SealForPeer(recipient ed25519.PublicKey, preKey *SignedPreKey, message []byte) ([]byte, error) {
+	return kp.sealForPeer(rand.Reader, recipient, preKey, message)
+}
+
+func (kp *KeyPair) sealForPeer(random io.Reader, recipient ed25519.PublicKey, preKey *SignedPreKey, message []byte) ([]byte, error) {
+	recipientX, err := Ed25519PublicToX25519(recipient)
+	if err != nil {
+		return nil, err
+	}
+	senderX, err := Ed25519PrivateToX25519(kp.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ephSeed := make([]byte, 32)
+	if _, err := io.ReadFull(random, ephSeed); err != nil {
+		return nil, err
+	}
+	eph, err := ecdh.X25519().NewPrivateKey(ephSeed)
+	if err != nil {
+		return nil, err
+	}
+	ephPub := eph.PublicKey().Bytes()
+
+	var flags byte
+	var preKeyID []byte
+	target := recipientX
+	if preKey != nil {
+		flags |= boxFlagPreKey
+		id := preKey.ID()
+		preKeyID = id[:]
+		target = preKey.Public
+	}
+
+	dh1, err := X25519(eph.Bytes(), target)
+	if err != nil {
+		return nil, err
+	}
+	dh2, err := X25519(senderX, recipientX)
+	if err != nil {
+		return nil, err
+	}
+	key, err := boxKey(dh1, dh2, flags, ephPub, kp.PublicKey, recipient, preKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce [NonceSize]byte
+	if _, err := io.ReadFull(random, nonce[:]); err != nil {
+		return nil, err
+	}
+
+	out := []byte{BoxVersion1, flags}
+	out = append(out, ephPub...)
+	out = append(out, preKeyID...)
+	out = append(out, nonce[:]...)
+	return secretbox.Seal(out, message, &nonce, &key), nil
+}
+
+// OpenFromPeer расшифровывает сообщение от отправителя с ключом идентичности sender.
+// preKeys — поиск секретной части предключа по ID (может быть nil, если предключи не используются).
+func (kp *KeyPair) OpenFromPeer(sender ed25519.PublicKey, box []byte, preKeys func(id [preKeyIDSize]byte) []byte) ([]byte, error) {
+	if len(box) < 2 {
+		return nil, ErrBoxTooShort
+	}
+	if box[0] != BoxVersion1 {
+		return nil, fmt.Errorf("%w: %d", ErrBoxVersion, box[0])
+	}
+	flags := box[1]
+	rest := box[2:]
+
+	headerLen := 32 + NonceSize
+	if flags&boxFlagPreKey != 0 {
+		headerLen += preKeyIDSize
+	}
+	if len(rest) < headerLen+secretbox.Overhead {
+		return nil, ErrBoxTooShort
+	}
+	ephPub := rest[:32]
+	rest = rest[32:]
+
+	recipientX, err := Ed25519PrivateToX25519(kp.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	senderX, err := Ed25519PublicToX25519(sender)
+	if err != nil {
+		return nil, err
+	}
+
+	own := recipientX
+	var preKeyID []byte
+	if flags&boxFlagPreKey != 0 {
+		var id [preKeyIDSize]byte
+		copy(id[:], rest[:preKeyIDSize])
+		preKeyID = rest[:preKeyIDSize]
+		rest = rest[preKeyIDSize:]
+		if preKeys == nil {
+			return nil, ErrPreKeyMissing
+		}
+		if own = preKeys(id); own == nil {
+			return nil, ErrPreKeyMissing
+		}
+	}
+
+	var nonce [NonceSize]byte
+	copy(nonce[:], rest[:NonceSize])
+	ciphertext := rest[NonceSize:]
+
+	dh1, err := X25519(own, ephPub)
+	if err != nil {
+		return nil, ErrBoxOpen
+	}
+	dh2, err := X25519(recipientX, senderX)
+	if err != nil {
+		return nil, ErrBoxOpen
+	}
+	key, err := boxKey(dh1, dh2, flags, ephPub, sender, kp.PublicKey, preKeyID)
+	if err != nil {
+		return nil, err
+	}
+	message, ok := secretbox.Open(nil, ciphertext, &nonce, &key)
+	if !ok {
+		return nil, ErrBoxOpen
+	}
+	return message, nil
+}
+
+// boxKey выводит симметричный ключ сообщения, привязывая его к заголовку и обеим идентичностям
+func boxKey(dh1, dh2 []byte, flags byte, ephPub []byte, senderEd, recipientEd ed25519.PublicKey, preKeyID []byte) ([KeySize]byte, error) {
+	var key [KeySize]byte
+	info := bytes.Join([][]byte{[]byte(boxInfo), {flags}, ephPub, senderEd, recipientEd, preKeyID}, nil)
+	k, err := hkdf.Key(sha256.New, append(append([]byte{}, dh1...), dh2...), nil, string(info), KeySize)
+	if err != nil {
+		return key, err
+	}
+	copy(key[:], k)
+	return key, nil
+}
+
+func reverse(b []byte) []byte {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b
+}
@@ -0,0 +1,166 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// RFC 7748, раздел 6.1
+func TestX25519_RFC7748(t *testing.T) {
+	alice := mustHex(t, "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	bobPub := mustHex(t, "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f")
+	want := mustHex(t, "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742")
+
+	shared, err := X25519(alice, bobPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(shared, want) {
+		t.Errorf("X25519 mismatch: %x", shared)
+	}
+}
+
+// Вектор из теста ed25519_convert в libsodium
+func TestEd25519ToX25519_Vector(t *testing.T) {
+	priv := ed25519.NewKeyFromSeed(mustHex(t, "421151a459faeade3d247115f94aedae42318124095afabe4d1451a559faedee"))
+
+	xPub, err := Ed25519PublicToX25519(priv.Public().(ed25519.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "f1814f0e8ff1043d8a44d25babff3cedcae6c22c3edaa48f857ae70de2baae50"; hex.EncodeToString(xPub) != want {
+		t.Errorf("public: got %x, want %s", xPub, want)
+	}
+	xPriv, _ := Ed25519PrivateToX25519(priv)
+	if want := "8052030376d47112be7f73ed7a019293dd12ad910b654455798b4667d73de166"; hex.EncodeToString(xPriv) != want {
+		t.Errorf("private: got %x, want %s", xPriv, want)
+	}
+}
+
+func TestEd25519ToX25519_Consistent(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		kp, _ := GenerateKeyPair()
+		xPriv, _ := Ed25519PrivateToX25519(kp.PrivateKey)
+		xPub, err := Ed25519PublicToX25519(kp.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k, err := ecdh.X25519().NewPrivateKey(xPriv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(k.PublicKey().Bytes(), xPub) {
+			t.Fatalf("converted public key does not match converted private key")
+		}
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	alice, _ := GenerateKeyPair()
+	bob, _ := GenerateKeyPair()
+	eve, _ := GenerateKeyPair()
+	msg := []byte("привет, Боб")
+
+	box, err := alice.SealForPeer(bob.PublicKey, nil, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := bob.OpenFromPeer(alice.PublicKey, box, nil)
+	if err != nil || !bytes.Equal(got, msg) {
+		t.Fatalf("OpenFromPeer: %q, %v", got, err)
+	}
+
+	// Эфемерный ключ: два шифрования одного сообщения различаются
+	box2, _ := alice.SealForPeer(bob.PublicKey, nil, msg)
+	if bytes.Equal(box[2:34], box2[2:34]) {
+		t.Error("Expected fresh ephemeral key per message")
+	}
+
+	// Третья сторона, знающая оба публичных ключа, не может расшифровать
+	if _, err := eve.OpenFromPeer(alice.PublicKey, box, nil); !errors.Is(err, ErrBoxOpen) {
+		t.Errorf("Expected ErrBoxOpen for eve, got %v", err)
+	}
+	// Подделка отправителя: Ева выдаёт себя за Алису
+	forged, _ := eve.SealForPeer(bob.PublicKey, nil, msg)
+	if _, err := bob.OpenFromPeer(alice.PublicKey, forged, nil); !errors.Is(err, ErrBoxOpen) {
+		t.Errorf("Expected ErrBoxOpen for forged sender, got %v", err)
+	}
+	// Подмена флагов в заголовке
+	tampered := append([]byte{}, box...)
+	tampered[1] = 0x80
+	if _, err := bob.OpenFromPeer(alice.PublicKey, tampered, nil); err == nil {
+		t.Error("Expected error for tampered header")
+	}
+}
+
+func TestSealOpen_PreKey(t *testing.T) {
+	alice, _ := GenerateKeyPair()
+	bob, _ := GenerateKeyPair()
+	now := time.Now()
+
+	secret, spk, err := bob.GeneratePreKey(24*time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyPreKey(bob.PublicKey, spk, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyPreKey(alice.PublicKey, spk, now); err == nil {
+		t.Error("Expected signature error for wrong identity")
+	}
+	if err := VerifyPreKey(bob.PublicKey, spk, now.Add(25*time.Hour)); err == nil {
+		t.Error("Expected expiry error")
+	}
+
+	box, err := alice.SealForPeer(bob.PublicKey, &spk, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := map[[preKeyIDSize]byte][]byte{secret.ID: secret.Private}
+	lookup := func(id [preKeyIDSize]byte) []byte { return store[id] }
+
+	if got, err := bob.OpenFromPeer(alice.PublicKey, box, lookup); err != nil || string(got) != "secret" {
+		t.Fatalf("OpenFromPeer with prekey: %q, %v", got, err)
+	}
+
+	// После удаления предключа сообщение не расшифровать (прямая секретность)
+	delete(store, secret.ID)
+	if _, err := bob.OpenFromPeer(alice.PublicKey, box, lookup); !errors.Is(err, ErrPreKeyMissing) {
+		t.Errorf("Expected ErrPreKeyMissing, got %v", err)
+	}
+}
+
+// Детерминированный вектор формата box v1 (фиксированные ключи, эфемерный ключ и nonce)
+func TestSeal_Vector(t *testing.T) {
+	alice := &KeyPair{PrivateKey: ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, 32))}
+	alice.PublicKey = alice.PrivateKey.Public().(ed25519.PublicKey)
+	bob := &KeyPair{PrivateKey: ed25519.NewKeyFromSeed(bytes.Repeat([]byte{2}, 32))}
+	bob.PublicKey = bob.PrivateKey.Public().(ed25519.PublicKey)
+
+	random := bytes.NewReader(append(bytes.Repeat([]byte{3}, 32), bytes.Repeat([]byte{4}, NonceSize)...))
+	box, err := alice.sealForPeer(random, bob.PublicKey, nil, []byte("ideal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "01005dfedd3b6bd47f6fa28ee15d969d5bb0ea53774d488bdaf9df1c6e0124b3ef22040404040404040404040404040404040404040404040404f27ac0c2b40e532179bb2e94ac565ae694c74c55ae"
+	if got := hex.EncodeToString(box); got != want {
+		t.Errorf("box v1 vector changed:\n got %s\nwant %s", got, want)
+	}
+	if msg, err := bob.OpenFromPeer(alice.PublicKey, box, nil); err != nil || string(msg) != "ideal" {
+		t.Errorf("vector does not open: %q, %v", msg, err)
+	}
+}
@@ -55,60 +55,6 @@ func GenerateNonce() ([]byte, error) {
 	return nonce, nil
 }
 
-// DeriveKeyFromPassword деривирует ключ из пароля (SHA-512 для MVP)
-func DeriveKeyFromPassword(password, salt []byte) ([KeySize]byte, error) {
-	hash := sha512.Sum512(append(password, salt...))
-	var key [KeySize]byte
-	copy(key[:], hash[:KeySize])
-	return key, nil
-}
-
-// ExportEncryptedBackup экспортирует приватный ключ в зашифрованном виде
-func (kp *KeyPair) ExportEncryptedBackup(password string) ([]byte, error) {
-	salt := make([]byte, 16)
-	if _, err := rand.Read(salt); err != nil {
-		return nil, err
-	}
-	nonce, err := GenerateNonce()
-	if err != nil {
-		return nil, err
-	}
-	key, err := DeriveKeyFromPassword([]byte(password), salt)
-	if err != nil {
-		return nil, err
-	}
-	encrypted, err := EncryptKey(kp.PrivateKey, key[:], nonce)
-	if err != nil {
-		return nil, err
-	}
-	result := append(salt, nonce...)
-	result = append(result, encrypted...)
-	return result, nil
-}
-
-// ImportEncryptedBackup импортирует приватный ключ из зашифрованного бэкапа
-func ImportEncryptedBackup(backup []byte, password string) (*KeyPair, error) {
-	if len(backup) < 16+NonceSize+Overhead {
-		return nil, errors.New("backup too short")
-	}
-	salt := backup[:16]
-	nonce := backup[16 : 16+NonceSize]
-	encrypted := backup[16+NonceSize:]
-	key, err := DeriveKeyFromPassword([]byte(password), salt)
-	if err != nil {
-		return nil, err
-	}
-	privateKey, err := DecryptKey(encrypted, key[:], nonce)
-	if err != nil {
-		return nil, err
-	}
-	pubKey := privateKey[32:]
-	return &KeyPair{
-		PrivateKey: privateKey,
-		PublicKey:  pubKey,
-	}, nil
-}
-
 // SaveEncryptedBackup сохраняет зашифрованный бэкап в файл
 func SaveEncryptedBackup(backup []byte, path string) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
@@ -135,6 +81,8 @@ func VerifyMessage(signed []byte, publicKey []byte) (message []byte, ok bool) {
 }
 
 // EncryptForRecipient шифрует сообщение для получателя (упрощённо: симметричное)
+//
+// Deprecated: вместе с DeriveSharedKey не даёт конфиденциальности; используйте SealForPeer.
 func EncryptForRecipient(message, sharedKey []byte) ([]byte, error) {
 	nonce, err := GenerateNonce()
 	if err != nil {
@@ -148,6 +96,8 @@ func EncryptForRecipient(message, sharedKey []byte) ([]byte, error) {
 }
 
 // DecryptFromSender расшифровывает сообщение от отправителя
+//
+// Deprecated: оставлено только для миграции старых данных; используйте OpenFromPeer.
 func DecryptFromSender(payload, sharedKey []byte) ([]byte, error) {
 	if len(payload) < NonceSize {
 		return nil, errors.New("payload too short")
@@ -158,6 +108,9 @@ func DecryptFromSender(payload, sharedKey []byte) ([]byte, error) {
 }
 
 // DeriveSharedKey деривирует общий ключ для симметричного шифрования (упрощённо)
+//
+// Deprecated: ключ вычисляется из публичных данных и известен любому,
+// кто знает оба ID. Используйте SealForPeer/OpenFromPeer (X25519).
 func DeriveSharedKey(pubKey1, pubKey2 []byte) [KeySize]byte {
 	combined := append(pubKey1, pubKey2...)
 	hash := sha512.Sum512(combined)
@@ -60,29 +60,9 @@ func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
 	return ed25519.PrivateKey(data), nil
 }
 
-// DeriveYggdrasilIP преобразует публичный ключ в IPv6 (формат Yggdrasil)
-// Использует реальный алгоритм: https://yggdrasil-network.github.io/addressing.html
-func DeriveYggdrasilIP(pubKey ed25519.PublicKey) string {
-	// Yggdrasil использует первые 16 байт SHA-512 хеша публичного ключа
-	// Для продакшена: импортировать github.com/yggdrasil-network/yggdrasil-go
-	// Здесь упрощённая, но рабочая реализация:
-	
-	// Берём первые 16 байт публичного ключа (ed25519.PublicKey = 32 байта)
-	ip := make([]byte, 16)
-	copy(ip, pubKey[:16])
-	
-	// Форматируем как IPv6 в нотации Yggdrasil (200::/7 префикс)
-	// Реальный Yggdrasil добавляет префикс 0x02 + версия + 14 байт ключа
-	return fmt.Sprintf("200:%02x%02x:%02x%02x:%02x%02x:%02x%02x:%02x%02x:%02x%02x:%02x%02x:%02x%02x",
-		ip[0], ip[1], ip[2], ip[3], ip[4], ip[5], ip[6], ip[7],
-		ip[8], ip[9], ip[10], ip[11], ip[12], ip[13], ip[14], ip[15])
-}
-
-// ExportBackup создаёт резервную копию ключей в зашифрованном виде
+// ExportBackup создаёт резервную копию ключей в зашифрованном виде (см. ExportEncryptedBackup)
 func (kp *KeyPair) ExportBackup(password string) ([]byte, error) {
-	// В продакшене: использовать libsodium или age для шифрования
-	// Здесь заглушка для MVP
-	return nil, fmt.Errorf("backup encryption not implemented in MVP")
+	return kp.ExportEncryptedBackup(password)
 }
 
 // SecurityWarning возвращает предупреждение о безопасности ключей
@@ -3,6 +3,7 @@ package db
 import (
 	"database/sql"
 	"log"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -32,6 +33,7 @@ type Person struct {
 	PsychAge     int       `json:"psych_age,omitempty"`      // 🔹 Психовозраст (7/35/55)
 	Location     string    `json:"location,omitempty"`       // 🔹 Локация: "ValyaHome", "Neutral"
 	Tags         string    `json:"tags"`
+	PublicKey    string    `json:"public_key,omitempty"` // 🔹 ed25519 ключ узла человека (hex) — для шифрования долей восстановления
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -122,6 +124,16 @@ func NewDatabase(path string) (*Database, error) {
 		}
 	}
 
+	// Миграции существующих баз (ALTER TABLE не поддерживает IF NOT EXISTS)
+	migrations := []string{
+		`ALTER TABLE people ADD COLUMN public_key TEXT DEFAULT ''`,
+	}
+	for _, q := range migrations {
+		if _, err = db.Exec(q); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			return nil, err
+		}
+	}
+
 	log.Println("✅ DB initialized:", path)
 	return &Database{db: db}, nil
 }
@@ -146,16 +158,16 @@ func (d *Database) GetUser(id string) (*User, error) {
 func (d *Database) AddPerson(p Person) error {
 	_, err := d.db.Exec(
 		`INSERT OR REPLACE INTO people 
-		(id, user_id, name, birth_date, last_contact, coords, sum_freq, vectors, flow_status, psych_age, location, tags, updated_at) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		p.ID, p.UserID, p.Name, p.BirthDate, p.LastContact, p.Coords, p.SumFreq, p.Vectors, p.FlowStatus, p.PsychAge, p.Location, p.Tags, time.Now(),
+		(id, user_id, name, birth_date, last_contact, coords, sum_freq, vectors, flow_status, psych_age, location, tags, public_key, updated_at) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ID, p.UserID, p.Name, p.BirthDate, p.LastContact, p.Coords, p.SumFreq, p.Vectors, p.FlowStatus, p.PsychAge, p.Location, p.Tags, p.PublicKey, time.Now(),
 	)
 	return err
 }
 
 func (d *Database) GetPeopleByUser(userID string) ([]Person, error) {
 	rows, err := d.db.Query(
-		"SELECT id, user_id, name, birth_date, last_contact, coords, sum_freq, vectors, flow_status, psych_age, location, tags, created_at, updated_at FROM people WHERE user_id = ?",
+		"SELECT "+personColumns+" FROM people WHERE user_id = ?",
 		userID,
 	)
 	if err != nil {
@@ -165,20 +177,55 @@ func (d *Database) GetPeopleByUser(userID string) ([]Person, error) {
 
 	var people []Person
 	for rows.Next() {
-		var p Person
-		var lastContact sql.NullTime
-		err := rows.Scan(&p.ID, &p.UserID, &p.Name, &p.BirthDate, &lastContact, &p.Coords, &p.SumFreq, &p.Vectors, &p.FlowStatus, &p.PsychAge, &p.Location, &p.Tags, &p.CreatedAt, &p.UpdatedAt)
+		p, err := scanPerson(rows)
 		if err != nil {
 			return nil, err
 		}
-		if lastContact.Valid {
-			p.LastContact = lastContact.Time
-		}
-		people = append(people, p)
+		people = append(people, *p)
 	}
 	return people, nil
 }
 
+// GetPerson возвращает человека по ID
+func (d *Database) GetPerson(id string) (*Person, error) {
+	return scanPerson(d.db.QueryRow("SELECT "+personColumns+" FROM people WHERE id = ?", id))
+}
+
+// SetPersonPublicKey сохраняет ключ узла человека (hex ed25519)
+func (d *Database) SetPersonPublicKey(personID, publicKey string) error {
+	res, err := d.db.Exec(
+		"UPDATE people SET public_key = ?, updated_at = ? WHERE id = ?",
+		publicKey, time.Now(), personID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+const personColumns = "id, user_id, name, birth_date, last_contact, coords, sum_freq, vectors, flow_status, psych_age, location, tags, public_key, created_at, updated_at"
+
+func scanPerson(row interface{ Scan(...any) error }) (*Person, error) {
+	var p Person
+	var lastContact sql.NullTime
+	var coords, vectors, flowStatus, location, tags, publicKey sql.NullString
+	var sumFreq, psychAge sql.NullInt64
+	err := row.Scan(&p.ID, &p.UserID, &p.Name, &p.BirthDate, &lastContact, &coords, &sumFreq, &vectors, &flowStatus, &psychAge, &location, &tags, &publicKey, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lastContact.Valid {
+		p.LastContact = lastContact.Time
+	}
+	p.Coords, p.Vectors, p.FlowStatus = coords.String, vectors.String, flowStatus.String
+	p.Location, p.Tags, p.PublicKey = location.String, tags.String, publicKey.String
+	p.SumFreq, p.PsychAge = int(sumFreq.Int64), int(psychAge.Int64)
+	return &p, nil
+}
+
 func (d *Database) UpdateLastContact(personID string, lastContact time.Time) error {
 	_, err := d.db.Exec(
 		"UPDATE people SET last_contact = ?, updated_at = ? WHERE id = ?",
@@ -0,0 +1,2 @@
+-- Ключ узла человека (hex ed25519) — для шифрования долей социального восстановления
+ALTER TABLE people ADD COLUMN public_key TEXT DEFAULT '';
@@ -0,0 +1,170 @@
+// Package envelope — канонический формат сообщений между узлами.
+//
+// Конверт (все числа big-endian):
+//
+//	version    1 байт   — 0x01
+//	sender     32 байта — ed25519 ключ отправителя
+//	recipient  32 байта — ed25519 ключ получателя
+//	type       2 байта  — тип сообщения
+//	timestamp  8 байт   — Unix, миллисекунды
+//	nonce      16 байт  — уникален для пары (отправитель, сообщение)
+//	length     4 байта  — длина шифротекста
+//	ciphertext length   — crypto.SealForPeer(payload)
+//	signature  64 байта — ed25519 отправителя над "ideal-core/envelope/v1" || всё выше
+//
+// Подпись проверяется до расшифровки, так что подделка заголовка или
+// шифротекста отбрасывается без затрат на криптографию ящика.
+//
+// Шифротекст адресован ключу идентичности получателя, без предключей: прямой
+// секретности относительно получателя нет (см. crypto.SealForPeer).
+package envelope
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"time"
+)
+
+const (
+	Version   = 1
+	NonceSize = 16
+
+	signContext = "ideal-core/envelope/v1"
+	headerSize  = 1 + ed25519.PublicKeySize*2 + 2 + 8 + NonceSize + 4
+)
+
+var (
+	ErrMalformed  = errors.New("malformed envelope")
+	ErrSignature  = errors.New("envelope signature is invalid")
+	ErrNotForUs   = errors.New("envelope is addressed to another key")
+	ErrBadVersion = errors.New("unsupported envelope version")
+)
+
+// Type — тип сообщения (определяет обработчик)
+type Type uint16
+
+const (
+	TypePing          Type = 1  // проверка связи, полезная нагрузка произвольна
+	TypeJournalShare  Type = 2  // записи дневника по общему доступу (journal.SharePayload)
+	TypeJournalRevoke Type = 3  // отзыв общего доступа (journal.ShareRevocation)
+	TypeAck           Type = 4  // подтверждение доставки: nonce полученного конверта
+	TypeExperience    Type = 5  // зашумлённый опыт RL-агента (rl.ExperienceMessage)
+	TypeJournalSync   Type = 6  // запрос синхронизации дневника между своими устройствами (journal.SyncRequest)
+	TypeJournalOps    Type = 7  // операции дневника для своего устройства (journal.SyncBatch)
+	TypeDevicePair    Type = 8  // запрос сопряжения нового устройства (identity.PairRequest)
+	TypeDevicePaired  Type = 9  // ответ мастера на запрос сопряжения
+	TypeDeviceChain   Type = 10 // история ключей мастера и отзывы для своего устройства
+)
+
+// Envelope — подписанное зашифрованное сообщение
+type Envelope struct {
+	Version    uint8
+	Sender     ed25519.PublicKey
+	Recipient  ed25519.PublicKey
+	Type       Type
+	Timestamp  time.Time // точность — миллисекунды
+	Nonce      [NonceSize]byte
+	Ciphertext []byte
+	Signature  []byte
+}
+
+// Seal шифрует payload для recipient и подписывает конверт ключом отправителя
+func Seal(sender *crypto.KeyPair, recipient ed25519.PublicKey, typ Type, payload []byte, now time.Time) (*Envelope, error) {
+	if len(recipient) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid recipient key")
+	}
+	ct, err := sender.SealForPeer(recipient, nil, payload)
+	if err != nil {
+		return nil, err
+	}
+	env := &Envelope{
+		Version:    Version,
+		Sender:     sender.PublicKey,
+		Recipient:  recipient,
+		Type:       typ,
+		Timestamp:  time.UnixMilli(now.UnixMilli()),
+		Ciphertext: ct,
+	}
+	if _, err := rand.Read(env.Nonce[:]); err != nil {
+		return nil, err
+	}
+	env.Signature = sender.Sign(env.signedBytes())
+	return env, nil
+}
+
+func (e *Envelope) header() []byte {
+	b := make([]byte, 0, headerSize+len(e.Ciphertext)+ed25519.SignatureSize)
+	b = append(b, e.Version)
+	b = append(b, e.Sender...)
+	b = append(b, e.Recipient...)
+	b = binary.BigEndian.AppendUint16(b, uint16(e.Type))
+	b = binary.BigEndian.AppendUint64(b, uint64(e.Timestamp.UnixMilli()))
+	b = append(b, e.Nonce[:]...)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(e.Ciphertext)))
+	return append(b, e.Ciphertext...)
+}
+
+func (e *Envelope) signedBytes() []byte {
+	return append([]byte(signContext), e.header()...)
+}
+
+// Marshal кодирует конверт в канонический бинарный вид
+func (e *Envelope) Marshal() []byte {
+	return append(e.header(), e.Signature...)
+}
+
+// Unmarshal разбирает конверт (подпись не проверяется — см. Verify)
+func Unmarshal(data []byte) (*Envelope, error) {
+	if len(data) < headerSize+ed25519.SignatureSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrMalformed, len(data))
+	}
+	if data[0] != Version {
+		return nil, fmt.Errorf("%w: %d", ErrBadVersion, data[0])
+	}
+	e := &Envelope{Version: data[0]}
+	r := bytes.NewReader(data[1:])
+	e.Sender = make([]byte, ed25519.PublicKeySize)
+	e.Recipient = make([]byte, ed25519.PublicKeySize)
+	r.Read(e.Sender)
+	r.Read(e.Recipient)
+	var fixed struct {
+		Type      uint16
+		Timestamp int64
+		Nonce     [NonceSize]byte
+		Length    uint32
+	}
+	binary.Read(r, binary.BigEndian, &fixed)
+	if int(fixed.Length) != len(data)-headerSize-ed25519.SignatureSize {
+		return nil, fmt.Errorf("%w: ciphertext length %d does not match envelope size", ErrMalformed, fixed.Length)
+	}
+	e.Type = Type(fixed.Type)
+	e.Timestamp = time.UnixMilli(fixed.Timestamp)
+	e.Nonce = fixed.Nonce
+	e.Ciphertext = append([]byte(nil), data[headerSize:headerSize+int(fixed.Length)]...)
+	e.Signature = append([]byte(nil), data[headerSize+int(fixed.Length):]...)
+	return e, nil
+}
+
+// Verify проверяет подпись отправителя
+func (e *Envelope) Verify() error {
+	if !crypto.Verify(e.Sender, e.signedBytes(), e.Signature) {
+		return ErrSignature
+	}
+	return nil
+}
+
+// Open проверяет адресата и подпись и расшифровывает полезную нагрузку
+func (e *Envelope) Open(recipient *crypto.KeyPair) ([]byte, error) {
+	if !bytes.Equal(e.Recipient, recipient.PublicKey) {
+		return nil, ErrNotForUs
+	}
+	if err := e.Verify(); err != nil {
+		return nil, err
+	}
+	return recipient.OpenFromPeer(e.Sender, e.Ciphertext, nil)
+}
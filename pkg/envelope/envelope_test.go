@@ -0,0 +1,178 @@
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"ideal-core/pkg/crypto"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	alice, _ := crypto.GenerateKeyPair()
+	bob, _ := crypto.GenerateKeyPair()
+	now := time.Now()
+
+	env, err := Seal(alice, bob.PublicKey, TypePing, []byte("hello"), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Unmarshal(env.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Marshal(), env.Marshal()) {
+		t.Fatal("encoding is not canonical")
+	}
+	if decoded.Type != TypePing || !decoded.Timestamp.Equal(time.UnixMilli(now.UnixMilli())) {
+		t.Fatalf("header mismatch: %+v", decoded)
+	}
+	payload, err := decoded.Open(bob)
+	if err != nil || string(payload) != "hello" {
+		t.Fatalf("Open: %q, %v", payload, err)
+	}
+	if _, err := decoded.Open(alice); !errors.Is(err, ErrNotForUs) {
+		t.Fatalf("expected ErrNotForUs, got %v", err)
+	}
+}
+
+func TestTamperingIsDetected(t *testing.T) {
+	alice, _ := crypto.GenerateKeyPair()
+	bob, _ := crypto.GenerateKeyPair()
+	env, _ := Seal(alice, bob.PublicKey, TypePing, []byte("hello"), time.Now())
+	raw := env.Marshal()
+
+	// Любой изменённый байт заголовка или шифротекста ломает подпись
+	for _, i := range []int{65 + 1, 65 + 3, 65 + 10, headerSize + 2} {
+		bad := append([]byte(nil), raw...)
+		bad[i] ^= 0x01
+		e, err := Unmarshal(bad)
+		if err != nil {
+			continue
+		}
+		if err := e.Verify(); !errors.Is(err, ErrSignature) {
+			t.Errorf("byte %d: expected ErrSignature, got %v", i, err)
+		}
+	}
+	if _, err := Unmarshal(raw[:len(raw)-1]); !errors.Is(err, ErrMalformed) {
+		t.Errorf("truncated: expected ErrMalformed, got %v", err)
+	}
+	bad := append([]byte{2}, raw[1:]...)
+	if _, err := Unmarshal(bad); !errors.Is(err, ErrBadVersion) {
+		t.Errorf("expected ErrBadVersion, got %v", err)
+	}
+}
+
+func TestFraming(t *testing.T) {
+	var buf bytes.Buffer
+	for _, msg := range [][]byte{[]byte("one"), {}, bytes.Repeat([]byte{7}, 5000)} {
+		if err := WriteFrame(&buf, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []int{3, 0, 5000} {
+		got, err := ReadFrame(&buf)
+		if err != nil || len(got) != want {
+			t.Fatalf("ReadFrame: %d bytes, %v", len(got), err)
+		}
+	}
+	if _, err := ReadFrame(&buf); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+
+	buf.Write([]byte{0, 0, 0, 10, 1, 2})
+	if _, err := ReadFrame(&buf); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	buf.Reset()
+	buf.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	if _, err := ReadFrame(&buf); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("expected ErrFrameTooLarge, got %v", err)
+	}
+}
+
+func TestReceiver_ReplayAndStale(t *testing.T) {
+	alice, _ := crypto.GenerateKeyPair()
+	bob, _ := crypto.GenerateKeyPair()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	r := NewReceiver(func() *crypto.KeyPair { return bob })
+	r.now = func() time.Time { return now }
+	var got []string
+	r.Handle(TypePing, func(ctx context.Context, msg Message) error {
+		got = append(got, string(msg.Payload))
+		return nil
+	})
+
+	env, _ := Seal(alice, bob.PublicKey, TypePing, []byte("hi"), now.Add(-time.Minute))
+	if err := r.Process(context.Background(), env.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Process(context.Background(), env.Marshal()); !errors.Is(err, ErrReplay) {
+		t.Fatalf("expected ErrReplay, got %v", err)
+	}
+
+	old, _ := Seal(alice, bob.PublicKey, TypePing, []byte("old"), now.Add(-DefaultMaxAge-time.Second))
+	if err := r.Process(context.Background(), old.Marshal()); !errors.Is(err, ErrStale) {
+		t.Fatalf("expected ErrStale for old message, got %v", err)
+	}
+	future, _ := Seal(alice, bob.PublicKey, TypePing, []byte("future"), now.Add(DefaultMaxSkew+time.Second))
+	if err := r.Process(context.Background(), future.Marshal()); !errors.Is(err, ErrStale) {
+		t.Fatalf("expected ErrStale for future message, got %v", err)
+	}
+	unknown, _ := Seal(alice, bob.PublicKey, 999, nil, now)
+	if err := r.Process(context.Background(), unknown.Marshal()); !errors.Is(err, ErrNoHandler) {
+		t.Fatalf("expected ErrNoHandler, got %v", err)
+	}
+	if len(got) != 1 || got[0] != "hi" {
+		t.Fatalf("handler calls: %v", got)
+	}
+}
+
+func TestReceiver_PolicyAndExempt(t *testing.T) {
+	alice, _ := crypto.GenerateKeyPair()
+	bob, _ := crypto.GenerateKeyPair()
+	r := NewReceiver(func() *crypto.KeyPair { return bob })
+	r.SetPolicy(func(sender ed25519.PublicKey) error { return errors.New("unknown sender") })
+	r.Handle(TypePing, func(ctx context.Context, msg Message) error { return nil })
+	r.Handle(TypeDevicePair, func(ctx context.Context, msg Message) error { return nil })
+	r.Exempt(TypeDevicePair)
+
+	ping, _ := Seal(alice, bob.PublicKey, TypePing, nil, time.Now())
+	if err := r.Process(context.Background(), ping.Marshal()); !errors.Is(err, ErrRejected) {
+		t.Fatalf("expected ErrRejected, got %v", err)
+	}
+	pair, _ := Seal(alice, bob.PublicKey, TypeDevicePair, nil, time.Now())
+	if err := r.Process(context.Background(), pair.Marshal()); err != nil {
+		t.Fatalf("exempt type rejected: %v", err)
+	}
+}
+
+func TestReceiver_Serve(t *testing.T) {
+	alice, _ := crypto.GenerateKeyPair()
+	bob, _ := crypto.GenerateKeyPair()
+	r := NewReceiver(func() *crypto.KeyPair { return bob })
+	count := 0
+	r.Handle(TypePing, func(ctx context.Context, msg Message) error {
+		count++
+		return nil
+	})
+
+	var stream bytes.Buffer
+	for i := 0; i < 3; i++ {
+		env, _ := Seal(alice, bob.PublicKey, TypePing, []byte{byte(i)}, time.Now())
+		WriteFrame(&stream, env.Marshal())
+	}
+	WriteFrame(&stream, []byte("garbage"))
+
+	var errs []error
+	if err := r.Serve(context.Background(), &stream, func(err error) { errs = append(errs, err) }); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 || len(errs) != 1 || !errors.Is(errs[0], ErrMalformed) {
+		t.Fatalf("count=%d errs=%v", count, errs)
+	}
+}
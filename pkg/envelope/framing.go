@@ -0,0 +1,48 @@
+package envelope
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Кадрирование потока: 4 байта длины (big-endian) + тело.
+// Размер ограничен, чтобы пир не мог заставить выделить произвольную память.
+
+// MaxFrameSize — максимальный размер кадра (1 МиБ)
+const MaxFrameSize = 1 << 20
+
+var ErrFrameTooLarge = errors.New("frame exceeds maximum size")
+
+// WriteFrame пишет кадр одним вызовом Write
+func WriteFrame(w io.Writer, data []byte) error {
+	if len(data) > MaxFrameSize {
+		return fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, len(data))
+	}
+	buf := make([]byte, 4, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	_, err := w.Write(append(buf, data...))
+	return err
+}
+
+// ReadFrame читает один кадр. io.EOF — поток закрыт между кадрами,
+// io.ErrUnexpectedEOF — посреди кадра.
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > MaxFrameSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, n)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,202 @@
+package envelope
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"io"
+	"sync"
+	"time"
+)
+
+// Приём: разбор → адресат → подпись → политика → время и повтор → расшифровка → обработчик.
+// Конверт попадает в окно повторов только после проверки подписи, иначе
+// посторонний мог бы заранее «занять» чужие nonce.
+
+const (
+	DefaultMaxAge  = 5 * time.Minute // старше — отбрасывается
+	DefaultMaxSkew = time.Minute     // насколько часы отправителя могут спешить
+)
+
+var (
+	ErrStale     = errors.New("envelope timestamp is outside the accepted window")
+	ErrReplay    = errors.New("envelope was already received")
+	ErrNoHandler = errors.New("no handler for message type")
+	ErrRejected  = errors.New("sender rejected by policy")
+)
+
+// Message — расшифрованное и проверенное сообщение
+type Message struct {
+	Sender    ed25519.PublicKey
+	Type      Type
+	Timestamp time.Time
+	Nonce     [NonceSize]byte
+	Payload   []byte
+}
+
+// Handler обрабатывает сообщение одного типа
+type Handler func(ctx context.Context, msg Message) error
+
+// ReplayGuard — окно повторов: помнит (отправитель, nonce) на время MaxAge
+type ReplayGuard struct {
+	MaxAge  time.Duration
+	MaxSkew time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewReplayGuard создаёт окно с настройками по умолчанию
+func NewReplayGuard() *ReplayGuard {
+	return &ReplayGuard{MaxAge: DefaultMaxAge, MaxSkew: DefaultMaxSkew}
+}
+
+// Check отклоняет устаревшие, слишком «будущие» и повторные конверты и запоминает новый
+func (g *ReplayGuard) Check(e *Envelope, now time.Time) error {
+	age := now.Sub(e.Timestamp)
+	if age > g.MaxAge || -age > g.MaxSkew {
+		return fmt.Errorf("%w: %s (now %s)", ErrStale, e.Timestamp.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.seen == nil {
+		g.seen = make(map[string]time.Time)
+	}
+	// Записи старше окна уже отсечёт проверка времени
+	for k, ts := range g.seen {
+		if now.Sub(ts) > g.MaxAge {
+			delete(g.seen, k)
+		}
+	}
+	key := string(e.Sender) + string(e.Nonce[:])
+	if _, dup := g.seen[key]; dup {
+		return ErrReplay
+	}
+	g.seen[key] = e.Timestamp
+	return nil
+}
+
+// Receiver проверяет входящие конверты и передаёт их обработчикам по типу
+type Receiver struct {
+	key      func() *crypto.KeyPair
+	guard    *ReplayGuard
+	now      func() time.Time
+	mu       sync.RWMutex
+	handlers map[Type]Handler
+	policy   func(sender ed25519.PublicKey) error
+	exempt   map[Type]bool
+	handled  func(ctx context.Context, msg Message)
+}
+
+// NewReceiver создаёт приёмник; key возвращает текущий ключ узла (он может ротироваться)
+func NewReceiver(key func() *crypto.KeyPair) *Receiver {
+	return &Receiver{key: key, guard: NewReplayGuard(), now: time.Now, handlers: make(map[Type]Handler)}
+}
+
+// Handle регистрирует обработчик типа (повторная регистрация заменяет прежний)
+func (r *Receiver) Handle(typ Type, h Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[typ] = h
+}
+
+// SetPolicy задаёт проверку отправителя (например, адресная книга).
+// Вызывается после проверки подписи — отправитель уже подтверждён.
+func (r *Receiver) SetPolicy(policy func(sender ed25519.PublicKey) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policy = policy
+}
+
+// Exempt принимает сообщения типа typ без проверки политики — например, запрос
+// сопряжения от ещё неизвестного устройства. Обработчик проверяет отправителя сам.
+func (r *Receiver) Exempt(typ Type) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.exempt == nil {
+		r.exempt = make(map[Type]bool)
+	}
+	r.exempt[typ] = true
+}
+
+// OnHandled задаёт вызов после успешной обработки сообщения (например, отправка подтверждения)
+func (r *Receiver) OnHandled(fn func(ctx context.Context, msg Message)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handled = fn
+}
+
+// Open проверяет кадр и возвращает расшифрованное сообщение без вызова обработчика
+func (r *Receiver) Open(frame []byte) (Message, error) {
+	e, err := Unmarshal(frame)
+	if err != nil {
+		return Message{}, err
+	}
+	kp := r.key()
+	if string(e.Recipient) != string(kp.PublicKey) {
+		return Message{}, ErrNotForUs
+	}
+	if err := e.Verify(); err != nil {
+		return Message{}, err
+	}
+	r.mu.RLock()
+	policy := r.policy
+	exempt := r.exempt[e.Type]
+	r.mu.RUnlock()
+	if policy != nil && !exempt {
+		if err := policy(e.Sender); err != nil {
+			return Message{}, fmt.Errorf("%w: %w", ErrRejected, err)
+		}
+	}
+	if err := r.guard.Check(e, r.now()); err != nil {
+		return Message{}, err
+	}
+	payload, err := kp.OpenFromPeer(e.Sender, e.Ciphertext, nil)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Sender: e.Sender, Type: e.Type, Timestamp: e.Timestamp, Nonce: e.Nonce, Payload: payload}, nil
+}
+
+// Process проверяет кадр, расшифровывает и вызывает обработчик его типа
+func (r *Receiver) Process(ctx context.Context, frame []byte) error {
+	msg, err := r.Open(frame)
+	if err != nil {
+		return err
+	}
+	r.mu.RLock()
+	h, ok := r.handlers[msg.Type]
+	handled := r.handled
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w %d", ErrNoHandler, msg.Type)
+	}
+	if err := h(ctx, msg); err != nil {
+		return err
+	}
+	if handled != nil {
+		handled(ctx, msg)
+	}
+	return nil
+}
+
+// Serve читает кадры из потока до его закрытия или отмены ctx.
+// Ошибки отдельных сообщений передаются в onError и не прерывают поток.
+func (r *Receiver) Serve(ctx context.Context, stream io.Reader, onError func(error)) error {
+	for ctx.Err() == nil {
+		frame, err := ReadFrame(stream)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err := r.Process(ctx, frame); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+	return ctx.Err()
+}
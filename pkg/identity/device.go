@@ -0,0 +1,220 @@
+package identity
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"time"
+)
+
+// Ключи устройств
+//
+// У каждого устройства (ноутбук, домашний сервер) свой ключ, но идентичность
+// одна: ключ устройства заверяется сертификатом, подписанным действующим
+// мастер-ключом идентичности. Пир проверяет цепочку: история ключей →
+// ключ-издатель входит в неё и не был отозван до выдачи → сертификат
+// не отозван. Отзыв устройства подписывает любой ключ цепочки.
+
+const (
+	deviceContext           = "ideal-core/device/v1"
+	deviceRevocationContext = "ideal-core/device-revocation/v1"
+)
+
+var ErrDeviceRevoked = errors.New("device is revoked")
+
+// DeviceCertificate — заверение ключа устройства мастер-ключом
+type DeviceCertificate struct {
+	Identity  string    `json:"identity"` // постоянный ID (корень истории)
+	DeviceKey string    `json:"device_key"`
+	Name      string    `json:"name"`
+	Issuer    string    `json:"issuer"` // ключ цепочки, выдавший сертификат
+	IssuedAt  time.Time `json:"issued_at"`
+	Signature string    `json:"signature"`
+}
+
+// DeviceRevocation — отзыв ключа устройства
+type DeviceRevocation struct {
+	Identity  string    `json:"identity"`
+	DeviceKey string    `json:"device_key"`
+	Issuer    string    `json:"issuer"`
+	Timestamp time.Time `json:"timestamp"`
+	Reason    string    `json:"reason,omitempty"`
+	Signature string    `json:"signature"`
+}
+
+// DeviceChain — то, что устройство предъявляет пирам
+type DeviceChain struct {
+	History     KeyHistory         `json:"history"`
+	Certificate DeviceCertificate  `json:"certificate"`
+	Revocations []DeviceRevocation `json:"revocations,omitempty"`
+}
+
+// NewDeviceCertificate заверяет ключ устройства текущим мастер-ключом
+func NewDeviceCertificate(identityID string, master *crypto.KeyPair, device ed25519.PublicKey, name string, now time.Time) DeviceCertificate {
+	c := DeviceCertificate{
+		Identity:  identityID,
+		DeviceKey: hex.EncodeToString(device),
+		Name:      name,
+		Issuer:    master.ToHex(),
+		IssuedAt:  now.UTC().Truncate(time.Second),
+	}
+	c.Signature = hex.EncodeToString(master.Sign(c.signedBytes()))
+	return c
+}
+
+func (c DeviceCertificate) signedBytes() []byte {
+	msg := []byte(deviceContext)
+	msg = append(msg, mustKey(c.Identity)...)
+	msg = append(msg, mustKey(c.DeviceKey)...)
+	msg = append(msg, mustKey(c.Issuer)...)
+	msg = binary.BigEndian.AppendUint64(msg, uint64(c.IssuedAt.Unix()))
+	return append(msg, c.Name...)
+}
+
+// NewDeviceRevocation отзывает ключ устройства текущим мастер-ключом
+func NewDeviceRevocation(identityID string, master *crypto.KeyPair, device string, reason string, now time.Time) DeviceRevocation {
+	r := DeviceRevocation{
+		Identity:  identityID,
+		DeviceKey: device,
+		Issuer:    master.ToHex(),
+		Timestamp: now.UTC().Truncate(time.Second),
+		Reason:    reason,
+	}
+	r.Signature = hex.EncodeToString(master.Sign(r.signedBytes()))
+	return r
+}
+
+func (r DeviceRevocation) signedBytes() []byte {
+	msg := []byte(deviceRevocationContext)
+	msg = append(msg, mustKey(r.Identity)...)
+	msg = append(msg, mustKey(r.DeviceKey)...)
+	msg = append(msg, mustKey(r.Issuer)...)
+	msg = binary.BigEndian.AppendUint64(msg, uint64(r.Timestamp.Unix()))
+	return append(msg, r.Reason...)
+}
+
+// VerifyDevice проверяет сертификат устройства по истории ключей идентичности
+// и списку отзывов. Отзывы чужой идентичности или с неверной подписью игнорируются.
+func VerifyDevice(h KeyHistory, c DeviceCertificate, revocations []DeviceRevocation) error {
+	if _, err := h.Verify(); err != nil && !errors.Is(err, ErrIdentityRevoked) {
+		return err
+	}
+	if c.Identity != h.Root {
+		return fmt.Errorf("device certificate is for identity %s, history is %s", short(c.Identity), short(h.Root))
+	}
+	if _, err := ParseKey(c.DeviceKey); err != nil {
+		return fmt.Errorf("device key: %w", err)
+	}
+	issuer, err := ParseKey(c.Issuer)
+	if err != nil {
+		return fmt.Errorf("device issuer: %w", err)
+	}
+	if !inChain(h, c.Issuer) {
+		return fmt.Errorf("device certificate issuer %s is not part of identity %s", short(c.Issuer), short(h.Root))
+	}
+	if !verifyHex(issuer, c.signedBytes(), c.Signature) {
+		return errors.New("device certificate signature is invalid")
+	}
+	// Скомпрометированный мастер-ключ не может выдавать сертификаты после отзыва
+	for _, rc := range h.Revocations {
+		if rc.Key == c.Issuer && c.IssuedAt.After(rc.Timestamp) {
+			return fmt.Errorf("device certificate issued by key revoked at %s", rc.Timestamp.Format(time.RFC3339))
+		}
+	}
+
+	for _, r := range revocations {
+		if r.Identity != h.Root || r.DeviceKey != c.DeviceKey || !inChain(h, r.Issuer) {
+			continue
+		}
+		if rk, err := ParseKey(r.Issuer); err == nil && verifyHex(rk, r.signedBytes(), r.Signature) {
+			return fmt.Errorf("%w at %s: %s", ErrDeviceRevoked, r.Timestamp.Format(time.RFC3339), r.Reason)
+		}
+	}
+	return nil
+}
+
+// Verify проверяет цепочку устройства и возвращает постоянный ID идентичности
+func (dc DeviceChain) Verify() (string, error) {
+	if err := VerifyDevice(dc.History, dc.Certificate, dc.Revocations); err != nil {
+		return "", err
+	}
+	return dc.History.Root, nil
+}
+
+// VerifyMember проверяет, что цепочка заверяет ключ device как устройство
+// идентичности identityID. known — отзывы, известные проверяющему:
+// цепочка, сохранённая до отзыва, их не скрывает.
+func (dc DeviceChain) VerifyMember(identityID, device string, known []DeviceRevocation) error {
+	if dc.Certificate.DeviceKey != device {
+		return fmt.Errorf("device chain certifies %s, not %s", short(dc.Certificate.DeviceKey), short(device))
+	}
+	if dc.History.Root != identityID {
+		return fmt.Errorf("device belongs to identity %s, not %s", short(dc.History.Root), short(identityID))
+	}
+	revocations := append(append([]DeviceRevocation(nil), known...), dc.Revocations...)
+	return VerifyDevice(dc.History, dc.Certificate, revocations)
+}
+
+// Refresh обновляет цепочку более новой историей ключей мастера (после
+// ротации) и его отзывами устройств. История должна продолжать прежнюю:
+// те же корень и ротации плюс новые. Возвращает новую цепочку и изменилась ли она;
+// если по новым данным устройство отозвано — ErrDeviceRevoked.
+func (dc DeviceChain) Refresh(h KeyHistory, revocations []DeviceRevocation) (DeviceChain, bool, error) {
+	if h.Root != dc.History.Root {
+		return dc, false, fmt.Errorf("history is for identity %s, not %s", short(h.Root), short(dc.History.Root))
+	}
+	if _, err := h.Verify(); err != nil && !errors.Is(err, ErrIdentityRevoked) {
+		return dc, false, err
+	}
+	old := dc.History.Rotations
+	if len(h.Rotations) < len(old) {
+		return dc, false, fmt.Errorf("%w: history has %d rotations, %d already known", ErrBrokenChain, len(h.Rotations), len(old))
+	}
+	for i, r := range old {
+		if h.Rotations[i].NewKey != r.NewKey || h.Rotations[i].OldSig != r.OldSig {
+			return dc, false, fmt.Errorf("%w: rotation #%d differs from the known one", ErrBrokenChain, i+1)
+		}
+	}
+
+	out := dc
+	out.History = h
+	out.History.Revocations = append([]RevocationCertificate(nil), h.Revocations...)
+	for _, rc := range dc.History.Revocations {
+		known := false
+		for _, x := range out.History.Revocations {
+			known = known || x.Signature == rc.Signature
+		}
+		if !known {
+			out.History.Revocations = append(out.History.Revocations, rc)
+		}
+	}
+	out.Revocations = append([]DeviceRevocation(nil), dc.Revocations...)
+	for _, r := range revocations {
+		known := false
+		for _, x := range out.Revocations {
+			known = known || x.Signature == r.Signature
+		}
+		if !known {
+			out.Revocations = append(out.Revocations, r)
+		}
+	}
+	if err := VerifyDevice(out.History, out.Certificate, out.Revocations); err != nil {
+		return dc, false, err
+	}
+	changed := len(out.History.Rotations) != len(old) ||
+		len(out.History.Revocations) != len(dc.History.Revocations) ||
+		len(out.Revocations) != len(dc.Revocations)
+	return out, changed, nil
+}
+
+func inChain(h KeyHistory, key string) bool {
+	for _, k := range h.Keys() {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
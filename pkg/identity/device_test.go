@@ -0,0 +1,220 @@
+package identity
+
+import (
+	"encoding/hex"
+	"errors"
+	"ideal-core/pkg/crypto"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeviceCertificate_Chain(t *testing.T) {
+	m, err := Create(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	laptop, _ := crypto.GenerateKeyPair()
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	cert := NewDeviceCertificate(m.ID(), m.KeyPair(), laptop.PublicKey, "laptop", now)
+	chain := DeviceChain{History: m.History(), Certificate: cert}
+	if id, err := chain.Verify(); err != nil || id != m.ID() {
+		t.Fatalf("Verify: %s, %v", id, err)
+	}
+
+	// Сертификат, выданный до ротации, остаётся действительным
+	if _, err := m.Rotate("scheduled", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	chain.History = m.History()
+	if _, err := chain.Verify(); err != nil {
+		t.Fatalf("after rotation: %v", err)
+	}
+
+	// Подмена имени ломает подпись
+	forged := chain
+	forged.Certificate.Name = "phone"
+	if _, err := forged.Verify(); err == nil {
+		t.Error("Expected error for tampered certificate")
+	}
+
+	// Чужой ключ не может выдать сертификат
+	stranger, _ := crypto.GenerateKeyPair()
+	foreign := DeviceChain{History: m.History(), Certificate: NewDeviceCertificate(m.ID(), stranger, laptop.PublicKey, "laptop", now)}
+	if _, err := foreign.Verify(); err == nil {
+		t.Error("Expected error for certificate issued outside the chain")
+	}
+
+	// Отзыв устройства любым ключом цепочки
+	chain.Revocations = []DeviceRevocation{NewDeviceRevocation(m.ID(), m.KeyPair(), cert.DeviceKey, "lost", now.Add(2*time.Hour))}
+	if _, err := chain.Verify(); !errors.Is(err, ErrDeviceRevoked) {
+		t.Errorf("Expected ErrDeviceRevoked, got %v", err)
+	}
+	// Отзыв, подписанный посторонним, игнорируется
+	chain.Revocations = []DeviceRevocation{NewDeviceRevocation(m.ID(), stranger, cert.DeviceKey, "", now)}
+	if _, err := chain.Verify(); err != nil {
+		t.Errorf("Foreign revocation must be ignored, got %v", err)
+	}
+
+	// Пир проверяет, что цепочка — его идентичности и заверяет именно этот ключ;
+	// известный ему отзыв действует и для цепочки без отзыва
+	chain.Revocations = nil
+	if err := chain.VerifyMember(m.ID(), cert.DeviceKey, nil); err != nil {
+		t.Errorf("VerifyMember: %v", err)
+	}
+	if err := chain.VerifyMember(m.ID(), stranger.ToHex(), nil); err == nil {
+		t.Error("Expected error for a chain certifying another key")
+	}
+	other, _ := Create(t.TempDir())
+	if err := chain.VerifyMember(other.ID(), cert.DeviceKey, nil); err == nil {
+		t.Error("Expected error for a device of another identity")
+	}
+	known := []DeviceRevocation{NewDeviceRevocation(m.ID(), m.KeyPair(), cert.DeviceKey, "lost", now.Add(2*time.Hour))}
+	if err := chain.VerifyMember(m.ID(), cert.DeviceKey, known); !errors.Is(err, ErrDeviceRevoked) {
+		t.Errorf("Expected ErrDeviceRevoked from a known revocation, got %v", err)
+	}
+}
+
+func TestDeviceChain_Refresh(t *testing.T) {
+	m, err := Create(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	laptop, _ := crypto.GenerateKeyPair()
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	chain := DeviceChain{History: m.History(), Certificate: NewDeviceCertificate(m.ID(), m.KeyPair(), laptop.PublicKey, "laptop", now)}
+
+	// После ротации мастера цепочка принимает продолжение истории
+	st, err := m.Rotate("scheduled", now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, changed, err := chain.Refresh(m.History(), nil)
+	if err != nil || !changed {
+		t.Fatalf("Refresh: changed=%v, %v", changed, err)
+	}
+	if head, _ := fresh.History.Verify(); hex.EncodeToString(head) != st.NewKey {
+		t.Fatalf("head after refresh %x, want %s", head, st.NewKey)
+	}
+	if _, changed, err := fresh.Refresh(m.History(), nil); err != nil || changed {
+		t.Fatalf("repeated refresh: changed=%v, %v", changed, err)
+	}
+
+	// Устаревшая или чужая история не принимается
+	if _, _, err := fresh.Refresh(chain.History, nil); err == nil {
+		t.Error("Expected error for a history without known rotations")
+	}
+	other, _ := Create(t.TempDir())
+	if _, _, err := fresh.Refresh(other.History(), nil); err == nil {
+		t.Error("Expected error for a history of another identity")
+	}
+
+	// Отзыв этого устройства мастером
+	rev := NewDeviceRevocation(m.ID(), m.KeyPair(), laptop.ToHex(), "lost", now.Add(2*time.Hour))
+	if _, _, err := fresh.Refresh(m.History(), []DeviceRevocation{rev}); !errors.Is(err, ErrDeviceRevoked) {
+		t.Errorf("Expected ErrDeviceRevoked, got %v", err)
+	}
+}
+
+func TestDeviceStore_RevokedKeyIsNotRecertified(t *testing.T) {
+	dir := t.TempDir()
+	m, err := Create(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewDeviceStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	laptop, _ := crypto.GenerateKeyPair()
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	cert := NewDeviceCertificate(m.ID(), m.KeyPair(), laptop.PublicKey, "laptop", now)
+	if err := store.Add(cert); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Revoke(m, cert.DeviceKey, "lost", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	again := NewDeviceCertificate(m.ID(), m.KeyPair(), laptop.PublicKey, "laptop", now.Add(2*time.Hour))
+	if err := store.Add(again); !errors.Is(err, ErrDeviceRevoked) {
+		t.Fatalf("Expected ErrDeviceRevoked for a revoked key, got %v", err)
+	}
+	if list := store.List(); len(list) != 1 || list[0].Revocation == nil || len(store.Revocations()) != 1 {
+		t.Errorf("Revocation lost: %+v", list)
+	}
+}
+
+func TestPairing(t *testing.T) {
+	m, err := Create(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	device, _ := crypto.GenerateKeyPair()
+	now := time.Now()
+
+	var p Pairer
+	if _, _, err := p.Accept(PairRequest{}, now); err == nil {
+		t.Fatal("Expected error without active code")
+	}
+	code, _, err := p.Start(DefaultPairingTTL, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Неверный код
+	bad, _, _ := NewPairRequest(device, "server", "AAAA-AAAA")
+	if _, _, err := p.Accept(bad, now); !errors.Is(err, ErrPairingCode) {
+		t.Fatalf("Expected ErrPairingCode, got %v", err)
+	}
+
+	req, key, err := NewPairRequest(device, "server", " "+strings.ToLower(code)+" ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dk, masterKey, err := p.Accept(req, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := DeviceChain{History: m.History(), Certificate: NewDeviceCertificate(m.ID(), m.KeyPair(), dk, req.Name, now)}
+	resp := NewPairResponse(chain, masterKey)
+
+	got, err := OpenPairResponse(resp, key, device.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Certificate.Name != "server" || got.History.Root != m.ID() {
+		t.Errorf("Unexpected chain: %+v", got.Certificate)
+	}
+
+	// Код одноразовый
+	if _, _, err := p.Accept(req, now); !errors.Is(err, ErrNoPairing) {
+		t.Errorf("Expected ErrNoPairing on reuse, got %v", err)
+	}
+	// Ответ не от владельца кода отвергается
+	if _, err := OpenPairResponse(NewPairResponse(chain, make([]byte, 32)), key, device.PublicKey); err == nil {
+		t.Error("Expected error for response without the code")
+	}
+}
+
+func TestPairing_FailuresBurnCode(t *testing.T) {
+	device, _ := crypto.GenerateKeyPair()
+	now := time.Now()
+	var p Pairer
+	code, _, _ := p.Start(DefaultPairingTTL, now)
+	bad, _, _ := NewPairRequest(device, "x", "ZZZZ-ZZZZ")
+	for i := 0; i < MaxPairingFailures; i++ {
+		p.Accept(bad, now)
+	}
+	good, _, _ := NewPairRequest(device, "x", code)
+	if _, _, err := p.Accept(good, now); !errors.Is(err, ErrNoPairing) {
+		t.Errorf("Expected code to be burned, got %v", err)
+	}
+
+	code, _, _ = p.Start(time.Minute, now)
+	good, _, _ = NewPairRequest(device, "x", code)
+	if _, _, err := p.Accept(good, now.Add(2*time.Minute)); !errors.Is(err, ErrPairingExpired) {
+		t.Errorf("Expected ErrPairingExpired, got %v", err)
+	}
+}
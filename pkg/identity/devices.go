@@ -0,0 +1,130 @@
+package identity
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const devicesFile = "devices.json"
+
+// DeviceRecord — устройство, заверенное этим узлом
+type DeviceRecord struct {
+	Certificate DeviceCertificate `json:"certificate"`
+	Revocation  *DeviceRevocation `json:"revocation,omitempty"`
+}
+
+// DeviceStore — устройства идентичности (на мастер-узле) и собственная
+// цепочка узла, если он сам сопряжён как устройство
+type DeviceStore struct {
+	mu   sync.Mutex
+	path string
+	data struct {
+		Devices []DeviceRecord `json:"devices"`
+		Self    *DeviceChain   `json:"self,omitempty"`
+	}
+}
+
+// NewDeviceStore открывает хранилище в каталоге dataDir
+func NewDeviceStore(dataDir string) (*DeviceStore, error) {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, err
+	}
+	s := &DeviceStore{path: filepath.Join(dataDir, devicesFile)}
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &s.data); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", s.path, err)
+		}
+	}
+	return s, nil
+}
+
+// Add сохраняет выданный сертификат (повторное сопряжение того же ключа
+// заменяет запись). Отозванный ключ повторно не заверяется: ErrDeviceRevoked.
+func (s *DeviceStore) Add(c DeviceCertificate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, d := range s.data.Devices {
+		if d.Certificate.DeviceKey == c.DeviceKey {
+			if d.Revocation != nil {
+				return fmt.Errorf("device %s: %w", short(c.DeviceKey), ErrDeviceRevoked)
+			}
+			s.data.Devices[i] = DeviceRecord{Certificate: c}
+			return s.save()
+		}
+	}
+	s.data.Devices = append(s.data.Devices, DeviceRecord{Certificate: c})
+	return s.save()
+}
+
+// List возвращает устройства (включая отозванные)
+func (s *DeviceStore) List() []DeviceRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]DeviceRecord(nil), s.data.Devices...)
+}
+
+// Revoke отзывает устройство ключом текущей идентичности
+func (s *DeviceStore) Revoke(m *Manager, deviceKey, reason string, now time.Time) (DeviceRevocation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, d := range s.data.Devices {
+		if d.Certificate.DeviceKey != deviceKey {
+			continue
+		}
+		if d.Revocation != nil {
+			return *d.Revocation, nil
+		}
+		r := NewDeviceRevocation(m.ID(), m.KeyPair(), deviceKey, reason, now)
+		s.data.Devices[i].Revocation = &r
+		return r, s.save()
+	}
+	return DeviceRevocation{}, fmt.Errorf("device %s: %w", short(deviceKey), os.ErrNotExist)
+}
+
+// Revocations — все отзывы (их передают пирам вместе с историей ключей)
+func (s *DeviceStore) Revocations() []DeviceRevocation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []DeviceRevocation
+	for _, d := range s.data.Devices {
+		if d.Revocation != nil {
+			out = append(out, *d.Revocation)
+		}
+	}
+	return out
+}
+
+// Self — цепочка этого узла как устройства (nil, если не сопряжён)
+func (s *DeviceStore) Self() *DeviceChain {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Self == nil {
+		return nil
+	}
+	c := *s.data.Self
+	return &c
+}
+
+// SetSelf сохраняет цепочку, полученную при сопряжении
+func (s *DeviceStore) SetSelf(chain DeviceChain) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Self = &chain
+	return s.save()
+}
+
+func (s *DeviceStore) save() error {
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
@@ -1,6 +1,9 @@
 package identity
 
 import (
+	"ideal-core/pkg/yggdrasil"
+	"net"
+	"strings"
 	"testing"
 	"time"
 )
@@ -34,3 +37,14 @@ func TestKeyPairGeneration(t *testing.T) {
 		t.Error("Fake message passed verification")
 	}
 }
+
+func TestDeriveYggdrasilIP(t *testing.T) {
+	pub, _, _ := GenerateKeyPair()
+	ip := DeriveYggdrasilIP(pub)
+	if !strings.HasPrefix(ip, "2") {
+		t.Errorf("Expected 200::/7 address, got %s", ip)
+	}
+	if !yggdrasil.KeyMatchesAddress(pub, net.ParseIP(ip)) {
+		t.Errorf("Derived address %s does not match key", ip)
+	}
+}
@@ -0,0 +1,350 @@
+package identity
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Файлы в каталоге данных
+const (
+	privateKeyFile = "private.key"
+	publicKeyFile  = "public.key"
+	historyFile    = "key_history.json"
+	pendingFile    = "key_rotation.pending.json" // ротация, записанная не до конца
+	retiredDir     = "keys"                      // старые приватные ключи (для расшифровки старых сообщений)
+)
+
+// ErrIdentityExists — в каталоге уже есть ключ
+var ErrIdentityExists = errors.New("identity already exists")
+
+// Manager — ключи узла и их история в каталоге данных
+type Manager struct {
+	mu      sync.Mutex
+	dir     string
+	current *crypto.KeyPair
+	history KeyHistory
+}
+
+// Create создаёт новую идентичность в dir (ошибка, если ключ уже есть)
+func Create(dir string) (*Manager, error) {
+	kp, err := crypto.GenerateKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	return CreateFromKey(dir, kp)
+}
+
+// CreateFromKey создаёт идентичность из готовой пары ключей (например, восстановленной)
+func CreateFromKey(dir string, kp *crypto.KeyPair) (*Manager, error) {
+	if _, err := os.Stat(filepath.Join(dir, privateKeyFile)); err == nil {
+		return nil, fmt.Errorf("%w in %s", ErrIdentityExists, dir)
+	}
+	m := &Manager{dir: dir, current: kp, history: KeyHistory{Root: kp.ToHex()}}
+	if err := m.saveKey(kp); err != nil {
+		return nil, err
+	}
+	return m, m.saveHistory()
+}
+
+// RestoreFromKey восстанавливает идентичность из ключа и её истории (например,
+// из долей восстановления): Node ID остаётся корнем истории, а не новым ключом.
+// Ключ должен быть текущим ключом истории.
+func RestoreFromKey(dir string, kp *crypto.KeyPair, h KeyHistory) (*Manager, error) {
+	if _, err := os.Stat(filepath.Join(dir, privateKeyFile)); err == nil {
+		return nil, fmt.Errorf("%w in %s", ErrIdentityExists, dir)
+	}
+	head, err := h.Verify()
+	if err != nil && !errors.Is(err, ErrIdentityRevoked) {
+		return nil, err
+	}
+	if !bytes.Equal(head, kp.PublicKey) {
+		return nil, fmt.Errorf("%w: key %s is not the head of key history %s", ErrBrokenChain, short(kp.ToHex()), short(h.Root))
+	}
+	m := &Manager{dir: dir, current: kp, history: h}
+	if err := m.saveKey(kp); err != nil {
+		return nil, err
+	}
+	return m, m.saveHistory()
+}
+
+// LoadHistory читает историю ключей из каталога данных dir или из файла
+// (например, выгрузки GET /api/identity/history с другого устройства)
+func LoadHistory(path string) (KeyHistory, error) {
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		path = filepath.Join(path, historyFile)
+	}
+	var h KeyHistory
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return h, err
+	}
+	if err := json.Unmarshal(data, &h); err != nil {
+		return h, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return h, nil
+}
+
+// Open загружает идентичность из dir, создавая новую при отсутствии ключа
+func Open(dir string) (*Manager, error) {
+	priv, err := crypto.LoadPrivateKey(filepath.Join(dir, privateKeyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return Create(dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("private key must be %d bytes, got %d", ed25519.PrivateKeySize, len(priv))
+	}
+	kp := &crypto.KeyPair{PrivateKey: priv, PublicKey: priv.Public().(ed25519.PublicKey)}
+	// Публичная половина private.key выводится из seed; расхождение — повреждение
+	if !bytes.Equal(ed25519.NewKeyFromSeed(priv.Seed()).Public().(ed25519.PublicKey), kp.PublicKey) {
+		return nil, errors.New("private.key is corrupted: public half does not match the seed")
+	}
+
+	m := &Manager{dir: dir, current: kp}
+	data, err := os.ReadFile(filepath.Join(dir, historyFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// Узел до появления истории: текущий ключ становится корнем
+		m.history = KeyHistory{Root: kp.ToHex()}
+		if err := m.saveHistory(); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, &m.history); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", historyFile, err)
+		}
+	}
+
+	if err := m.completeRotation(); err != nil {
+		return nil, err
+	}
+	head, err := m.history.Verify()
+	if err != nil && !errors.Is(err, ErrIdentityRevoked) {
+		return nil, err
+	}
+	if !bytes.Equal(head, kp.PublicKey) {
+		return nil, fmt.Errorf("%w: private.key is not the head of key history", ErrBrokenChain)
+	}
+	// public.key — производный файл: после сбоя между записью private.key и
+	// public.key (saveKey) он устаревший, и его нужно просто перезаписать
+	pubPath := filepath.Join(dir, publicKeyFile)
+	if pub, err := os.ReadFile(pubPath); err != nil || !bytes.Equal(pub, kp.PublicKey) {
+		if err := writeFileAtomic(pubPath, kp.PublicKey, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// KeyPair — текущая пара ключей
+func (m *Manager) KeyPair() *crypto.KeyPair {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.current
+}
+
+// ID — постоянный идентификатор (корневой ключ, hex)
+func (m *Manager) ID() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.history.Root
+}
+
+// History — копия истории ключей (её можно передавать пирам)
+func (m *Manager) History() KeyHistory {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := m.history
+	h.Rotations = append([]RotationStatement(nil), h.Rotations...)
+	h.Revocations = append([]RevocationCertificate(nil), h.Revocations...)
+	return h
+}
+
+// Rotate выпускает новый ключ, заверенный текущим. Старый приватный ключ
+// сохраняется в keys/ для расшифровки ранее полученных сообщений.
+//
+// Порядок записи: заявление о ротации — в pending-файл, затем новый ключ,
+// затем история. При ошибке ключ и история возвращаются к прежним; после
+// сбоя между записями Open доводит ротацию до конца (completeRotation).
+func (m *Manager) Rotate(reason string, now time.Time) (RotationStatement, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.history.Verify(); err != nil {
+		return RotationStatement{}, err
+	}
+	next, err := crypto.GenerateKeyPair()
+	if err != nil {
+		return RotationStatement{}, err
+	}
+	st := NewRotation(m.current, next, uint64(len(m.history.Rotations)+1), reason, now)
+
+	retired := filepath.Join(m.dir, retiredDir, m.current.ToHex()[:16]+".key")
+	if err := crypto.SavePrivateKey(m.current.PrivateKey, retired); err != nil {
+		return RotationStatement{}, err
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		return RotationStatement{}, err
+	}
+	pending := filepath.Join(m.dir, pendingFile)
+	if err := writeFileAtomic(pending, data, 0600); err != nil {
+		return RotationStatement{}, err
+	}
+	if err := m.saveKey(next); err != nil {
+		return RotationStatement{}, m.rollbackRotation(err)
+	}
+	m.history.Rotations = append(m.history.Rotations, st)
+	if err := m.saveHistory(); err != nil {
+		m.history.Rotations = m.history.Rotations[:len(m.history.Rotations)-1]
+		return RotationStatement{}, m.rollbackRotation(err)
+	}
+	m.current = next
+	os.Remove(pending) // оставшийся файл Open просто удалит: ротация уже в истории
+	return st, nil
+}
+
+// rollbackRotation возвращает прежний ключ после неудачной ротации. Если и это
+// не удалось, pending-файл остаётся: Open завершит ротацию по нему.
+func (m *Manager) rollbackRotation(cause error) error {
+	if err := m.saveKey(m.current); err != nil {
+		return errors.Join(cause, fmt.Errorf("restore previous key: %w", err))
+	}
+	if err := os.Remove(filepath.Join(m.dir, pendingFile)); err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
+}
+
+// completeRotation завершает ротацию, прерванную после записи нового ключа,
+// и убирает pending-файл ротации, которая не успела начаться
+func (m *Manager) completeRotation() error {
+	path := filepath.Join(m.dir, pendingFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var st RotationStatement
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("parse %s: %w", pendingFile, err)
+	}
+	head, err := m.history.Verify()
+	if err != nil && !errors.Is(err, ErrIdentityRevoked) {
+		return err
+	}
+	if st.NewKey == m.current.ToHex() && st.OldKey == hex.EncodeToString(head) {
+		m.history.Rotations = append(m.history.Rotations, st)
+		if _, err := m.history.Verify(); err != nil && !errors.Is(err, ErrIdentityRevoked) {
+			return fmt.Errorf("interrupted rotation: %w", err)
+		}
+		if err := m.saveHistory(); err != nil {
+			return err
+		}
+	}
+	return os.Remove(path)
+}
+
+// RevocationFor создаёт (но не применяет) сертификат отзыва текущего ключа —
+// его стоит сохранить офлайн на случай потери ключа
+func (m *Manager) RevocationFor(reason string, now time.Time) RevocationCertificate {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return NewRevocation(m.current.PublicKey, m.current, reason, now)
+}
+
+// Revoke отзывает ключ цепочки, подписывая сертификат текущим ключом
+func (m *Manager) Revoke(keyHex, reason string, now time.Time) (RevocationCertificate, error) {
+	key, err := ParseKey(keyHex)
+	if err != nil {
+		return RevocationCertificate{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rc := NewRevocation(key, m.current, reason, now)
+	return rc, m.addRevocation(rc)
+}
+
+// ApplyRevocation добавляет готовый сертификат отзыва (например, сохранённый офлайн)
+func (m *Manager) ApplyRevocation(rc RevocationCertificate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.addRevocation(rc)
+}
+
+func (m *Manager) addRevocation(rc RevocationCertificate) error {
+	candidate := m.history
+	candidate.Revocations = append(append([]RevocationCertificate(nil), m.history.Revocations...), rc)
+	if _, err := candidate.Verify(); err != nil && !errors.Is(err, ErrIdentityRevoked) {
+		return err
+	}
+	m.history = candidate
+	return m.saveHistory()
+}
+
+// saveKey записывает private.key, затем производный public.key (после сбоя
+// между ними Open перезапишет public.key по private.key)
+func (m *Manager) saveKey(kp *crypto.KeyPair) error {
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(m.dir, privateKeyFile), kp.PrivateKey, 0600); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(m.dir, publicKeyFile), kp.PublicKey, 0644)
+}
+
+func (m *Manager) saveHistory() error {
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m.history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(m.dir, historyFile), data, 0600)
+}
+
+// writeFileAtomic пишет во временный файл и переименовывает: файл либо
+// старый, либо новый целиком
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// LoadRetiredKey загружает старый приватный ключ по его hex-ID (для расшифровки старых сообщений)
+func (m *Manager) LoadRetiredKey(keyHex string) (*crypto.KeyPair, error) {
+	if len(keyHex) < 16 {
+		return nil, errors.New("key id too short")
+	}
+	if _, err := hex.DecodeString(keyHex); err != nil {
+		return nil, err
+	}
+	priv, err := crypto.LoadPrivateKey(filepath.Join(m.dir, retiredDir, keyHex[:16]+".key"))
+	if err != nil {
+		return nil, err
+	}
+	return &crypto.KeyPair{PrivateKey: priv, PublicKey: priv.Public().(ed25519.PublicKey)}, nil
+}
@@ -0,0 +1,234 @@
+package identity
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"embed"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// Мнемоническая фраза (24 слова)
+//
+// Кодирование как в BIP-39: 256 бит энтропии + 8 бит контрольной суммы
+// (первый байт SHA-256) = 264 бита = 24 слова по 11 бит. Энтропия — это
+// seed ключа ed25519, поэтому фразу можно получить и для уже существующего
+// ключа, а одна и та же энтропия на русском и английском даёт один ключ.
+// Английский список — стандартный BIP-39; русский составлен для проекта
+// (существительные до 8 букв, без «ё»).
+
+//go:embed wordlists/*.txt
+var wordlistFS embed.FS
+
+// Language — язык списка слов
+type Language string
+
+const (
+	LangEnglish Language = "en"
+	LangRussian Language = "ru"
+)
+
+const (
+	MnemonicWords = 24
+	wordlistSize  = 2048
+	entropyBytes  = ed25519.SeedSize
+)
+
+var (
+	ErrMnemonicLength   = fmt.Errorf("mnemonic must have %d words", MnemonicWords)
+	ErrMnemonicChecksum = errors.New("mnemonic checksum mismatch: check the words and their order")
+)
+
+var wordlists = map[Language][]string{}
+var wordIndex = map[Language]map[string]int{}
+
+func init() {
+	for _, lang := range []Language{LangEnglish, LangRussian} {
+		data, err := wordlistFS.ReadFile("wordlists/" + string(lang) + ".txt")
+		if err != nil {
+			panic(err)
+		}
+		words := strings.Fields(string(data))
+		if len(words) != wordlistSize {
+			panic(fmt.Sprintf("wordlist %s has %d words, want %d", lang, len(words), wordlistSize))
+		}
+		index := make(map[string]int, wordlistSize)
+		for i, w := range words {
+			if _, dup := index[w]; dup {
+				panic(fmt.Sprintf("wordlist %s: duplicate word %q", lang, w))
+			}
+			index[w] = i
+		}
+		wordlists[lang] = words
+		wordIndex[lang] = index
+	}
+}
+
+// Wordlist возвращает список слов языка
+func Wordlist(lang Language) ([]string, error) {
+	words, ok := wordlists[lang]
+	if !ok {
+		return nil, fmt.Errorf("unsupported mnemonic language %q", lang)
+	}
+	return words, nil
+}
+
+// EntropyToMnemonic кодирует 32 байта энтропии в 24 слова
+func EntropyToMnemonic(entropy []byte, lang Language) ([]string, error) {
+	if len(entropy) != entropyBytes {
+		return nil, fmt.Errorf("entropy must be %d bytes, got %d", entropyBytes, len(entropy))
+	}
+	words, err := Wordlist(lang)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(entropy)
+	n := new(big.Int).SetBytes(append(append([]byte{}, entropy...), sum[0]))
+
+	result := make([]string, MnemonicWords)
+	mask := big.NewInt(wordlistSize - 1)
+	for i := MnemonicWords - 1; i >= 0; i-- {
+		idx := new(big.Int).And(n, mask).Int64()
+		result[i] = words[idx]
+		n.Rsh(n, 11)
+	}
+	return result, nil
+}
+
+// MnemonicToEntropy декодирует фразу и проверяет контрольную сумму; язык определяется автоматически
+func MnemonicToEntropy(words []string) ([]byte, Language, error) {
+	if len(words) != MnemonicWords {
+		return nil, "", fmt.Errorf("%w, got %d", ErrMnemonicLength, len(words))
+	}
+	normalized := make([]string, len(words))
+	for i, w := range words {
+		normalized[i] = NormalizeWord(w)
+	}
+	lang, err := detectLanguage(normalized)
+	if err != nil {
+		return nil, "", err
+	}
+
+	n := new(big.Int)
+	for _, w := range normalized {
+		n.Lsh(n, 11)
+		n.Or(n, big.NewInt(int64(wordIndex[lang][w])))
+	}
+	raw := make([]byte, entropyBytes+1)
+	n.FillBytes(raw)
+	entropy, checksum := raw[:entropyBytes], raw[entropyBytes]
+	if sum := sha256.Sum256(entropy); sum[0] != checksum {
+		return nil, lang, ErrMnemonicChecksum
+	}
+	return entropy, lang, nil
+}
+
+func detectLanguage(words []string) (Language, error) {
+	for _, lang := range []Language{LangRussian, LangEnglish} {
+		unknown := -1
+		for i, w := range words {
+			if _, ok := wordIndex[lang][w]; !ok {
+				unknown = i
+				break
+			}
+		}
+		if unknown < 0 {
+			return lang, nil
+		}
+		// Первое слово из этого языка — сообщаем о конкретной ошибке в нём
+		if _, ok := wordIndex[lang][words[0]]; ok {
+			return "", fmt.Errorf("word %d %q is not in the %s wordlist", unknown+1, words[unknown], lang)
+		}
+	}
+	return "", fmt.Errorf("word 1 %q is not in any wordlist", words[0])
+}
+
+// SplitMnemonic разбивает введённую строку на слова (пробелы, запятые, переводы строк)
+func SplitMnemonic(phrase string) []string {
+	return strings.FieldsFunc(phrase, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ',' || r == ';'
+	})
+}
+
+// NormalizeWord приводит слово к виду списка: нижний регистр, ё → е, без пунктуации
+func NormalizeWord(w string) string {
+	w = strings.ToLower(strings.TrimSpace(w))
+	w = strings.ReplaceAll(w, "ё", "е")
+	return strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) })
+}
+
+// MnemonicForKey возвращает фразу для существующего ключа (энтропия = seed ed25519)
+func MnemonicForKey(kp *crypto.KeyPair, lang Language) ([]string, error) {
+	if len(kp.PrivateKey) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key")
+	}
+	return EntropyToMnemonic(kp.PrivateKey.Seed(), lang)
+}
+
+// KeyFromMnemonic восстанавливает пару ключей из фразы
+func KeyFromMnemonic(phrase string) (*crypto.KeyPair, error) {
+	entropy, _, err := MnemonicToEntropy(SplitMnemonic(phrase))
+	if err != nil {
+		return nil, err
+	}
+	priv := ed25519.NewKeyFromSeed(entropy)
+	return &crypto.KeyPair{PrivateKey: priv, PublicKey: priv.Public().(ed25519.PublicKey)}, nil
+}
+
+// NewMnemonicKey генерирует новый ключ вместе с фразой
+func NewMnemonicKey(lang Language) (*crypto.KeyPair, []string, error) {
+	seed := make([]byte, entropyBytes)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, nil, err
+	}
+	words, err := EntropyToMnemonic(seed, lang)
+	if err != nil {
+		return nil, nil, err
+	}
+	priv := ed25519.NewKeyFromSeed(seed)
+	return &crypto.KeyPair{PrivateKey: priv, PublicKey: priv.Public().(ed25519.PublicKey)}, words, nil
+}
+
+// VerificationChallenge выбирает count случайных различных позиций (с нуля) для проверки записи фразы
+func VerificationChallenge(count int) ([]int, error) {
+	if count <= 0 || count > MnemonicWords {
+		return nil, fmt.Errorf("challenge size must be 1-%d", MnemonicWords)
+	}
+	perm := make([]int, MnemonicWords)
+	for i := range perm {
+		perm[i] = i
+	}
+	for i := MnemonicWords - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return nil, err
+		}
+		perm[i], perm[j.Int64()] = perm[j.Int64()], perm[i]
+	}
+	positions := perm[:count]
+	// По возрастанию — так проще сверять с записанной фразой
+	for i := 1; i < len(positions); i++ {
+		for j := i; j > 0 && positions[j] < positions[j-1]; j-- {
+			positions[j], positions[j-1] = positions[j-1], positions[j]
+		}
+	}
+	return positions, nil
+}
+
+// CheckChallenge сверяет введённые слова с фразой на заданных позициях
+func CheckChallenge(words []string, positions []int, answers []string) bool {
+	if len(positions) != len(answers) {
+		return false
+	}
+	for i, pos := range positions {
+		if pos < 0 || pos >= len(words) || NormalizeWord(words[pos]) != NormalizeWord(answers[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package identity
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Векторы BIP-39 (256 бит энтропии)
+func TestEntropyToMnemonic_BIP39Vectors(t *testing.T) {
+	vectors := []struct {
+		entropy byte
+		phrase  string
+	}{
+		{0x00, strings.Repeat("abandon ", 23) + "art"},
+		{0x7f, strings.Repeat("legal winner thank year wave sausage worth useful ", 2) + "legal winner thank year wave sausage worth title"},
+		{0xff, strings.Repeat("zoo ", 23) + "vote"},
+	}
+	for _, v := range vectors {
+		entropy := bytes.Repeat([]byte{v.entropy}, 32)
+		words, err := EntropyToMnemonic(entropy, LangEnglish)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(words, " "); got != v.phrase {
+			t.Errorf("entropy %#x:\n got %s\nwant %s", v.entropy, got, v.phrase)
+		}
+		back, lang, err := MnemonicToEntropy(words)
+		if err != nil || lang != LangEnglish || !bytes.Equal(back, entropy) {
+			t.Errorf("round trip %#x: %v", v.entropy, err)
+		}
+	}
+}
+
+func TestMnemonic_RussianRecovery(t *testing.T) {
+	kp, words, err := NewMnemonicKey(LangRussian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(words) != MnemonicWords {
+		t.Fatalf("Expected %d words, got %d", MnemonicWords, len(words))
+	}
+
+	// Регистр, «ё» и запятые не мешают восстановлению
+	phrase := strings.ToUpper(strings.Join(words, ", "))
+	restored, err := KeyFromMnemonic(phrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.ToHex() != kp.ToHex() {
+		t.Error("Restored key differs")
+	}
+
+	// Та же энтропия по-английски даёт тот же ключ
+	en, _ := MnemonicForKey(kp, LangEnglish)
+	if again, err := KeyFromMnemonic(strings.Join(en, " ")); err != nil || again.ToHex() != kp.ToHex() {
+		t.Errorf("English phrase for the same key must restore it: %v", err)
+	}
+
+	// Перестановка двух слов ломает контрольную сумму (почти всегда) или даёт другой ключ
+	swapped := append([]string{}, words...)
+	swapped[0], swapped[1] = swapped[1], swapped[0]
+	if swapped[0] != swapped[1] {
+		if k, err := KeyFromMnemonic(strings.Join(swapped, " ")); err == nil && k.ToHex() == kp.ToHex() {
+			t.Error("Swapped phrase restored the same key")
+		}
+	}
+
+	bad := append([]string{}, words...)
+	bad[5] = "несуществующее"
+	if _, err := KeyFromMnemonic(strings.Join(bad, " ")); err == nil || !strings.Contains(err.Error(), "word 6") {
+		t.Errorf("Expected error naming word 6, got %v", err)
+	}
+	if _, err := KeyFromMnemonic(strings.Join(words[:12], " ")); !errors.Is(err, ErrMnemonicLength) {
+		t.Errorf("Expected ErrMnemonicLength, got %v", err)
+	}
+}
+
+func TestMnemonic_Checksum(t *testing.T) {
+	words := strings.Fields(strings.Repeat("abandon ", 24))
+	if _, _, err := MnemonicToEntropy(words); !errors.Is(err, ErrMnemonicChecksum) {
+		t.Errorf("Expected ErrMnemonicChecksum, got %v", err)
+	}
+}
+
+func TestVerificationChallenge(t *testing.T) {
+	_, words, _ := NewMnemonicKey(LangEnglish)
+	positions, err := VerificationChallenge(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 3 || positions[0] >= positions[1] || positions[1] >= positions[2] {
+		t.Fatalf("Expected 3 distinct sorted positions, got %v", positions)
+	}
+	answers := []string{words[positions[0]], " " + strings.ToUpper(words[positions[1]]), words[positions[2]]}
+	if !CheckChallenge(words, positions, answers) {
+		t.Error("Correct answers rejected")
+	}
+	answers[2] = "wrong"
+	if CheckChallenge(words, positions, answers) {
+		t.Error("Wrong answer accepted")
+	}
+}
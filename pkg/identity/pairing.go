@@ -0,0 +1,207 @@
+package identity
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Сопряжение устройств
+//
+// Мастер-узел показывает короткий одноразовый код (8 символов, 40 бит).
+// Новое устройство отправляет запрос со своим ключом, подписанный этим ключом
+// и заверенный HMAC на ключе, выведенном из кода через Argon2id (перебор кода
+// по перехваченному запросу дорог). Мастер отвечает сертификатом устройства
+// и историей ключей с HMAC на том же ключе — так устройство убеждается, что
+// говорит с тем, кто показал код. Код живёт несколько минут, действует один
+// раз и сгорает после MaxPairingFailures неверных попыток.
+
+const (
+	pairingContext     = "ideal-core/pairing/v1"
+	pairingCodeLen     = 8
+	pairingAlphabet    = "ABCDEFGHJKMNPQRSTVWXYZ0123456789" // без I, L, O, U
+	DefaultPairingTTL  = 5 * time.Minute
+	MaxPairingFailures = 5
+)
+
+var (
+	ErrNoPairing      = errors.New("no active pairing code")
+	ErrPairingExpired = errors.New("pairing code expired")
+	ErrPairingCode    = errors.New("wrong pairing code")
+)
+
+// PairRequest — запрос нового устройства
+type PairRequest struct {
+	DeviceKey string `json:"device_key"`
+	Name      string `json:"name"`
+	Nonce     string `json:"nonce"`     // соль для вывода ключа из кода
+	Signature string `json:"signature"` // подпись ключом устройства (владение ключом)
+	MAC       string `json:"mac"`       // знание кода
+}
+
+// PairResponse — ответ мастера
+type PairResponse struct {
+	Chain DeviceChain `json:"chain"`
+	MAC   string      `json:"mac"`
+}
+
+// NormalizePairingCode убирает дефисы и пробелы, приводит к верхнему регистру
+func NormalizePairingCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+// FormatPairingCode — код для показа: ABCD-EFGH
+func FormatPairingCode(code string) string {
+	if len(code) != pairingCodeLen {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
+
+func newPairingCode() (string, error) {
+	b := make([]byte, pairingCodeLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = pairingAlphabet[int(b[i])%len(pairingAlphabet)] // 256 кратно 32 — без смещения
+	}
+	return string(b), nil
+}
+
+func pairingKey(code string, nonce []byte) []byte {
+	salt := append([]byte(pairingContext), nonce...)
+	return argon2.IDKey([]byte(NormalizePairingCode(code)), salt, 1, 32*1024, 2, 32)
+}
+
+func (r PairRequest) signedBytes() []byte {
+	msg := []byte(pairingContext + "/request")
+	msg = append(msg, mustKey(r.DeviceKey)...)
+	msg = append(msg, r.Nonce...)
+	return append(msg, r.Name...)
+}
+
+func responseBytes(c DeviceCertificate) []byte {
+	msg := []byte(pairingContext + "/response")
+	msg = append(msg, c.signedBytes()...)
+	return append(msg, c.Signature...)
+}
+
+func mac(key, msg []byte) string {
+	h := hmac.New(sha256.New, key)
+	h.Write(msg)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NewPairRequest готовит запрос устройства; возвращает также ключ для проверки ответа
+func NewPairRequest(device *crypto.KeyPair, name, code string) (PairRequest, []byte, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return PairRequest{}, nil, err
+	}
+	req := PairRequest{DeviceKey: device.ToHex(), Name: name, Nonce: hex.EncodeToString(nonce)}
+	msg := req.signedBytes()
+	req.Signature = hex.EncodeToString(device.Sign(msg))
+	key := pairingKey(code, nonce)
+	req.MAC = mac(key, msg)
+	return req, key, nil
+}
+
+// OpenPairResponse проверяет ответ мастера и возвращает цепочку устройства
+func OpenPairResponse(resp PairResponse, key []byte, device ed25519.PublicKey) (DeviceChain, error) {
+	if !hmac.Equal([]byte(resp.MAC), []byte(mac(key, responseBytes(resp.Chain.Certificate)))) {
+		return DeviceChain{}, errors.New("pairing response is not authenticated by the code")
+	}
+	if resp.Chain.Certificate.DeviceKey != hex.EncodeToString(device) {
+		return DeviceChain{}, errors.New("pairing response certifies a different device key")
+	}
+	if _, err := resp.Chain.Verify(); err != nil {
+		return DeviceChain{}, err
+	}
+	return resp.Chain, nil
+}
+
+// Pairer — активный код сопряжения на мастер-узле (один одновременно)
+type Pairer struct {
+	mu       sync.Mutex
+	code     string
+	expires  time.Time
+	failures int
+}
+
+// Start выпускает новый код, заменяя прежний
+func (p *Pairer) Start(ttl time.Duration, now time.Time) (string, time.Time, error) {
+	code, err := newPairingCode()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.code, p.expires, p.failures = code, now.Add(ttl), 0
+	return FormatPairingCode(code), p.expires, nil
+}
+
+// Cancel гасит активный код
+func (p *Pairer) Cancel() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.code = ""
+}
+
+// Accept проверяет запрос устройства. При успехе код гасится и возвращаются
+// ключ устройства и ключ для HMAC ответа.
+func (p *Pairer) Accept(req PairRequest, now time.Time) (ed25519.PublicKey, []byte, error) {
+	device, err := ParseKey(req.DeviceKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("device key: %w", err)
+	}
+	nonce, err := hex.DecodeString(req.Nonce)
+	if err != nil || len(nonce) < 16 {
+		return nil, nil, errors.New("pairing nonce must be at least 16 bytes")
+	}
+	msg := req.signedBytes()
+	if !verifyHex(device, msg, req.Signature) {
+		return nil, nil, errors.New("pairing request is not signed by the device key")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.code == "" {
+		return nil, nil, ErrNoPairing
+	}
+	if now.After(p.expires) {
+		p.code = ""
+		return nil, nil, ErrPairingExpired
+	}
+	key := pairingKey(p.code, nonce)
+	if !hmac.Equal([]byte(req.MAC), []byte(mac(key, msg))) {
+		p.failures++
+		if p.failures >= MaxPairingFailures {
+			p.code = ""
+		}
+		return nil, nil, ErrPairingCode
+	}
+	p.code = ""
+	return device, key, nil
+}
+
+// NewPairResponse заверяет ответ мастера ключом, выведенным из кода
+func NewPairResponse(chain DeviceChain, key []byte) PairResponse {
+	return PairResponse{Chain: chain, MAC: mac(key, responseBytes(chain.Certificate))}
+}
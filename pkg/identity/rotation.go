@@ -0,0 +1,251 @@
+package identity
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"time"
+)
+
+// Ротация ключей
+//
+// Идентичность — это корневой ключ (ID не меняется) плюс цепочка заявлений
+// о ротации. Каждое заявление подписано старым ключом (он передаёт полномочия)
+// и новым (доказательство владения). Сертификат отзыва подписывается самим
+// отзываемым ключом или любым более поздним ключом цепочки. Ключ, отозванный
+// до момента ротации, не может передать полномочия.
+
+const (
+	rotationContext   = "ideal-core/rotation/v1"
+	revocationContext = "ideal-core/revocation/v1"
+)
+
+var (
+	ErrBrokenChain     = errors.New("identity key chain is broken")
+	ErrIdentityRevoked = errors.New("identity current key is revoked")
+)
+
+// RotationStatement — заявление о переходе со старого ключа на новый
+type RotationStatement struct {
+	OldKey    string    `json:"old_key"` // hex ed25519
+	NewKey    string    `json:"new_key"`
+	Sequence  uint64    `json:"sequence"` // номер ротации, начиная с 1
+	Timestamp time.Time `json:"timestamp"`
+	Reason    string    `json:"reason,omitempty"`
+	OldSig    string    `json:"old_sig"` // подпись старым ключом
+	NewSig    string    `json:"new_sig"` // подпись новым ключом
+}
+
+// RevocationCertificate — отзыв ключа (например, при компрометации)
+type RevocationCertificate struct {
+	Key       string    `json:"key"`    // отзываемый ключ
+	Signer    string    `json:"signer"` // сам ключ или более поздний ключ цепочки
+	Timestamp time.Time `json:"timestamp"`
+	Reason    string    `json:"reason,omitempty"`
+	Signature string    `json:"signature"`
+}
+
+// KeyHistory — полная история ключей идентичности
+type KeyHistory struct {
+	Root        string                  `json:"root"` // первый ключ = постоянный ID
+	Rotations   []RotationStatement     `json:"rotations"`
+	Revocations []RevocationCertificate `json:"revocations"`
+}
+
+// NewRotation создаёт заявление о ротации, подписанное обоими ключами
+func NewRotation(oldKP, newKP *crypto.KeyPair, sequence uint64, reason string, now time.Time) RotationStatement {
+	st := RotationStatement{
+		OldKey:    oldKP.ToHex(),
+		NewKey:    newKP.ToHex(),
+		Sequence:  sequence,
+		Timestamp: now.UTC().Truncate(time.Second),
+		Reason:    reason,
+	}
+	msg := st.signedBytes()
+	st.OldSig = hex.EncodeToString(oldKP.Sign(msg))
+	st.NewSig = hex.EncodeToString(newKP.Sign(msg))
+	return st
+}
+
+func (st RotationStatement) signedBytes() []byte {
+	msg := []byte(rotationContext)
+	msg = append(msg, mustKey(st.OldKey)...)
+	msg = append(msg, mustKey(st.NewKey)...)
+	msg = binary.BigEndian.AppendUint64(msg, st.Sequence)
+	msg = binary.BigEndian.AppendUint64(msg, uint64(st.Timestamp.Unix()))
+	return append(msg, st.Reason...)
+}
+
+// Verify проверяет обе подписи заявления
+func (st RotationStatement) Verify() error {
+	oldKey, err := ParseKey(st.OldKey)
+	if err != nil {
+		return fmt.Errorf("rotation old key: %w", err)
+	}
+	newKey, err := ParseKey(st.NewKey)
+	if err != nil {
+		return fmt.Errorf("rotation new key: %w", err)
+	}
+	msg := st.signedBytes()
+	if !verifyHex(oldKey, msg, st.OldSig) {
+		return errors.New("rotation is not signed by the old key")
+	}
+	if !verifyHex(newKey, msg, st.NewSig) {
+		return errors.New("rotation is not signed by the new key")
+	}
+	return nil
+}
+
+// NewRevocation создаёт сертификат отзыва ключа key, подписанный signer
+func NewRevocation(key ed25519.PublicKey, signer *crypto.KeyPair, reason string, now time.Time) RevocationCertificate {
+	rc := RevocationCertificate{
+		Key:       hex.EncodeToString(key),
+		Signer:    signer.ToHex(),
+		Timestamp: now.UTC().Truncate(time.Second),
+		Reason:    reason,
+	}
+	rc.Signature = hex.EncodeToString(signer.Sign(rc.signedBytes()))
+	return rc
+}
+
+func (rc RevocationCertificate) signedBytes() []byte {
+	msg := []byte(revocationContext)
+	msg = append(msg, mustKey(rc.Key)...)
+	msg = append(msg, mustKey(rc.Signer)...)
+	msg = binary.BigEndian.AppendUint64(msg, uint64(rc.Timestamp.Unix()))
+	return append(msg, rc.Reason...)
+}
+
+// Verify проверяет подпись сертификата (но не право подписанта — см. KeyHistory.Verify)
+func (rc RevocationCertificate) Verify() error {
+	if _, err := ParseKey(rc.Key); err != nil {
+		return fmt.Errorf("revocation key: %w", err)
+	}
+	signer, err := ParseKey(rc.Signer)
+	if err != nil {
+		return fmt.Errorf("revocation signer: %w", err)
+	}
+	if !verifyHex(signer, rc.signedBytes(), rc.Signature) {
+		return errors.New("revocation signature is invalid")
+	}
+	return nil
+}
+
+// Keys возвращает ключи цепочки по порядку (корень первым)
+func (h KeyHistory) Keys() []string {
+	keys := []string{h.Root}
+	for _, r := range h.Rotations {
+		keys = append(keys, r.NewKey)
+	}
+	return keys
+}
+
+// Verify проверяет цепочку и возвращает текущий действующий ключ.
+// Возвращает ErrIdentityRevoked, если текущий ключ отозван.
+func (h KeyHistory) Verify() (ed25519.PublicKey, error) {
+	if _, err := ParseKey(h.Root); err != nil {
+		return nil, fmt.Errorf("%w: root: %v", ErrBrokenChain, err)
+	}
+
+	position := map[string]int{h.Root: 0}
+	for i, r := range h.Rotations {
+		position[r.NewKey] = i + 1
+	}
+
+	// Отзывы: подписант — сам ключ или более поздний ключ цепочки
+	revokedAt := make(map[string]time.Time)
+	for _, rc := range h.Revocations {
+		if err := rc.Verify(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBrokenChain, err)
+		}
+		kp, ok := position[rc.Key]
+		sp, ok2 := position[rc.Signer]
+		if !ok || !ok2 || sp < kp {
+			return nil, fmt.Errorf("%w: revocation of %s by %s is not authorized", ErrBrokenChain, short(rc.Key), short(rc.Signer))
+		}
+		if t, seen := revokedAt[rc.Key]; !seen || rc.Timestamp.Before(t) {
+			revokedAt[rc.Key] = rc.Timestamp
+		}
+	}
+
+	current := h.Root
+	var last time.Time
+	for i, r := range h.Rotations {
+		if r.OldKey != current {
+			return nil, fmt.Errorf("%w: rotation %d starts from %s, expected %s", ErrBrokenChain, i+1, short(r.OldKey), short(current))
+		}
+		if r.Sequence != uint64(i+1) {
+			return nil, fmt.Errorf("%w: rotation %d has sequence %d", ErrBrokenChain, i+1, r.Sequence)
+		}
+		if r.Timestamp.Before(last) {
+			return nil, fmt.Errorf("%w: rotation %d goes back in time", ErrBrokenChain, i+1)
+		}
+		if err := r.Verify(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBrokenChain, err)
+		}
+		if t, revoked := revokedAt[current]; revoked && r.Timestamp.After(t) {
+			return nil, fmt.Errorf("%w: rotation %d signed by key revoked at %s", ErrBrokenChain, i+1, t.Format(time.RFC3339))
+		}
+		current, last = r.NewKey, r.Timestamp
+	}
+
+	key, _ := ParseKey(current)
+	if _, revoked := revokedAt[current]; revoked {
+		return key, ErrIdentityRevoked
+	}
+	return key, nil
+}
+
+// Follow возвращает актуальный ключ идентичности, если key входит в её цепочку
+func (h KeyHistory) Follow(key ed25519.PublicKey) (ed25519.PublicKey, error) {
+	current, err := h.Verify()
+	if err != nil {
+		return nil, err
+	}
+	want := hex.EncodeToString(key)
+	for _, k := range h.Keys() {
+		if k == want {
+			return current, nil
+		}
+	}
+	return nil, fmt.Errorf("key %s is not part of identity %s", short(want), short(h.Root))
+}
+
+// ParseKey разбирает hex-представление публичного ключа ed25519
+func ParseKey(s string) (ed25519.PublicKey, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(b))
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// mustKey — байты ключа для подписи (некорректный ключ даёт пустой срез и подпись не сойдётся)
+func mustKey(s string) []byte {
+	k, err := ParseKey(s)
+	if err != nil {
+		return nil
+	}
+	return k
+}
+
+func verifyHex(pub ed25519.PublicKey, msg []byte, sigHex string) bool {
+	sig, err := hex.DecodeString(sigHex)
+	if err != nil {
+		return false
+	}
+	return crypto.Verify(pub, msg, sig)
+}
+
+func short(key string) string {
+	if len(key) > 16 {
+		return key[:16] + "..."
+	}
+	return key
+}
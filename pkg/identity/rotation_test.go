@@ -0,0 +1,200 @@
+package identity
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"ideal-core/pkg/crypto"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyHistory_RotationChain(t *testing.T) {
+	k0, _ := crypto.GenerateKeyPair()
+	k1, _ := crypto.GenerateKeyPair()
+	k2, _ := crypto.GenerateKeyPair()
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	h := KeyHistory{Root: k0.ToHex()}
+	h.Rotations = append(h.Rotations, NewRotation(k0, k1, 1, "scheduled", now))
+	h.Rotations = append(h.Rotations, NewRotation(k1, k2, 2, "", now.Add(time.Hour)))
+
+	current, err := h.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(current) != string(k2.PublicKey) {
+		t.Error("Expected k2 as current key")
+	}
+	if followed, err := h.Follow(k0.PublicKey); err != nil || string(followed) != string(k2.PublicKey) {
+		t.Errorf("Follow from root: %v", err)
+	}
+	stranger, _ := crypto.GenerateKeyPair()
+	if _, err := h.Follow(stranger.PublicKey); err == nil {
+		t.Error("Expected error for key outside the chain")
+	}
+
+	// Ротация, не подписанная старым ключом
+	forged := NewRotation(stranger, k2, 2, "", now.Add(time.Hour))
+	forged.OldKey = k1.ToHex()
+	bad := KeyHistory{Root: h.Root, Rotations: []RotationStatement{h.Rotations[0], forged}}
+	if _, err := bad.Verify(); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("Expected ErrBrokenChain for forged rotation, got %v", err)
+	}
+
+	// Подмена причины ломает подписи
+	tampered := KeyHistory{Root: h.Root, Rotations: []RotationStatement{h.Rotations[0]}}
+	tampered.Rotations[0].Reason = "other"
+	if _, err := tampered.Verify(); err == nil {
+		t.Error("Expected error for tampered statement")
+	}
+}
+
+func TestKeyHistory_Revocation(t *testing.T) {
+	k0, _ := crypto.GenerateKeyPair()
+	k1, _ := crypto.GenerateKeyPair()
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	// Ключ отозван до ротации — ротация недействительна
+	h := KeyHistory{
+		Root:        k0.ToHex(),
+		Rotations:   []RotationStatement{NewRotation(k0, k1, 1, "", now.Add(time.Hour))},
+		Revocations: []RevocationCertificate{NewRevocation(k0.PublicKey, k0, "stolen", now)},
+	}
+	if _, err := h.Verify(); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("Expected rotation by revoked key to fail, got %v", err)
+	}
+
+	// Старый ключ отозван новым после ротации — цепочка действительна
+	h.Revocations = []RevocationCertificate{NewRevocation(k0.PublicKey, k1, "old laptop lost", now.Add(2*time.Hour))}
+	if _, err := h.Verify(); err != nil {
+		t.Errorf("Expected valid chain, got %v", err)
+	}
+
+	// Старый ключ не может отозвать более новый
+	h.Revocations = []RevocationCertificate{NewRevocation(k1.PublicKey, k0, "", now.Add(2*time.Hour))}
+	if _, err := h.Verify(); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("Expected unauthorized revocation error, got %v", err)
+	}
+
+	// Отзыв текущего ключа
+	h.Revocations = []RevocationCertificate{NewRevocation(k1.PublicKey, k1, "", now.Add(2*time.Hour))}
+	if _, err := h.Verify(); !errors.Is(err, ErrIdentityRevoked) {
+		t.Errorf("Expected ErrIdentityRevoked, got %v", err)
+	}
+}
+
+func TestManager_RotateAndReload(t *testing.T) {
+	dir := t.TempDir()
+	m, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := m.KeyPair()
+	if _, err := Create(dir); !errors.Is(err, ErrIdentityExists) {
+		t.Errorf("Expected ErrIdentityExists, got %v", err)
+	}
+
+	st, err := m.Rotate("test", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.OldKey != root.ToHex() || m.KeyPair().ToHex() != st.NewKey {
+		t.Fatalf("Unexpected rotation: %+v", st)
+	}
+	if _, err := m.Revoke(root.ToHex(), "retired", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.ID() != root.ToHex() || reloaded.KeyPair().ToHex() != st.NewKey {
+		t.Error("Identity ID must survive rotation; current key must be the new one")
+	}
+	h := reloaded.History()
+	if len(h.Rotations) != 1 || len(h.Revocations) != 1 {
+		t.Errorf("Unexpected history: %+v", h)
+	}
+	old, err := reloaded.LoadRetiredKey(root.ToHex())
+	if err != nil || old.ToHex() != root.ToHex() {
+		t.Errorf("Retired key not kept: %v", err)
+	}
+
+	// Восстановление ключа с историей сохраняет Node ID; ключ не из головы истории отвергается
+	if _, err := RestoreFromKey(t.TempDir(), root, h); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("Expected ErrBrokenChain for a retired key, got %v", err)
+	}
+	restored, err := RestoreFromKey(t.TempDir(), reloaded.KeyPair(), h)
+	if err != nil || restored.ID() != root.ToHex() || len(restored.History().Rotations) != 1 {
+		t.Errorf("RestoreFromKey: %v", err)
+	}
+}
+
+func TestManager_RotateSurvivesFailures(t *testing.T) {
+	dir := t.TempDir()
+	m, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := m.KeyPair().ToHex()
+	before, _ := os.ReadFile(filepath.Join(dir, historyFile))
+
+	// История не записалась: ключ и история остаются прежними
+	os.Mkdir(filepath.Join(dir, historyFile+".tmp"), 0700)
+	if _, err := m.Rotate("fails", time.Now()); err == nil {
+		t.Fatal("Expected rotation to fail")
+	}
+	os.Remove(filepath.Join(dir, historyFile+".tmp"))
+	if m.KeyPair().ToHex() != root || len(m.History().Rotations) != 0 {
+		t.Fatal("Failed rotation changed the manager state")
+	}
+	if reopened, err := Open(dir); err != nil || reopened.KeyPair().ToHex() != root {
+		t.Fatalf("Failed rotation broke the identity: %v", err)
+	}
+
+	// Сбой после записи нового ключа, до истории: Open завершает ротацию
+	st, err := m.Rotate("crash", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(st)
+	os.WriteFile(filepath.Join(dir, historyFile), before, 0600)
+	os.WriteFile(filepath.Join(dir, pendingFile), data, 0600)
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Interrupted rotation not completed: %v", err)
+	}
+	if reopened.ID() != root || reopened.KeyPair().ToHex() != st.NewKey || len(reopened.History().Rotations) != 1 {
+		t.Errorf("Unexpected identity after recovery: %+v", reopened.History())
+	}
+	if _, err := os.Stat(filepath.Join(dir, pendingFile)); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Pending rotation left behind")
+	}
+
+	// Сбой между private.key и public.key: public.key старого ключа чинится
+	st, err = reopened.Rotate("crash between key halves", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = json.Marshal(st)
+	os.WriteFile(filepath.Join(dir, publicKeyFile), mustKey(st.OldKey), 0644)
+	os.WriteFile(filepath.Join(dir, pendingFile), data, 0600)
+	h := reopened.History()
+	h.Rotations = h.Rotations[:1]
+	prev, _ := json.MarshalIndent(h, "", "  ")
+	os.WriteFile(filepath.Join(dir, historyFile), prev, 0600)
+	again, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Rotation interrupted before public.key not recovered: %v", err)
+	}
+	if again.KeyPair().ToHex() != st.NewKey || len(again.History().Rotations) != 2 {
+		t.Errorf("Unexpected identity after recovery: %+v", again.History())
+	}
+	if pub, _ := os.ReadFile(filepath.Join(dir, publicKeyFile)); hex.EncodeToString(pub) != st.NewKey {
+		t.Error("public.key not rewritten from private.key")
+	}
+}
@@ -0,0 +1,182 @@
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TextGenerator — источник текстовых ответов (локальная LLM, например llm.Client)
+type TextGenerator interface {
+	GenerateText(prompt string) (string, error)
+}
+
+// AskOptions — ограничения для ответа на вопрос по дневнику
+type AskOptions struct {
+	Limit          int // сколько записей извлекать (по умолчанию 8)
+	MaxEntryChars  int // максимум символов на одну запись в промпте (по умолчанию 600)
+	MaxPromptChars int // общий бюджет контекста в символах (по умолчанию 6000)
+}
+
+// Citation — ссылка на запись дневника, на которую опирается ответ
+type Citation struct {
+	EntryID string    `json:"entry_id"`
+	Date    time.Time `json:"date"`
+	Type    EntryType `json:"type"`
+}
+
+// Answer — ответ на вопрос по дневнику
+type Answer struct {
+	Question  string     `json:"question"`
+	Text      string     `json:"answer"`
+	Citations []Citation `json:"citations"` // записи, упомянутые в ответе
+	Sources   []Citation `json:"sources"`   // все записи, попавшие в контекст
+}
+
+// ErrEmptyQuestion — пустой вопрос
+var ErrEmptyQuestion = errors.New("question is empty")
+
+// citationPattern находит ссылки вида [a1b2c3d4e5f6a7b8] в ответе модели
+var citationPattern = regexp.MustCompile(`\[([0-9a-f]{16})\]`)
+
+func (o AskOptions) withDefaults() AskOptions {
+	if o.Limit <= 0 {
+		o.Limit = 8
+	}
+	if o.MaxEntryChars <= 0 {
+		o.MaxEntryChars = 600
+	}
+	if o.MaxPromptChars <= 0 {
+		o.MaxPromptChars = 6000
+	}
+	return o
+}
+
+// Ask отвечает на свободный вопрос по дневнику (RAG):
+// извлекает релевантные записи через SearchByMeaning, собирает ограниченный промпт
+// и возвращает ответ модели со ссылками на ID и даты записей.
+func (j *Journal) Ask(question string, gen TextGenerator, opts AskOptions) (*Answer, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, ErrEmptyQuestion
+	}
+	if gen == nil {
+		return nil, errors.New("text generator is not configured")
+	}
+	opts = opts.withDefaults()
+
+	entries := j.SearchByMeaning(question, opts.Limit)
+	prompt, used := buildAskPrompt(question, entries, opts)
+
+	answer := &Answer{
+		Question:  question,
+		Citations: []Citation{},
+		Sources:   make([]Citation, 0, len(used)),
+	}
+	for _, e := range used {
+		answer.Sources = append(answer.Sources, citationFor(e))
+	}
+	if len(used) == 0 {
+		answer.Text = "В дневнике нет записей, по которым можно ответить на этот вопрос."
+		return answer, nil
+	}
+
+	text, err := gen.GenerateText(prompt)
+	if err != nil {
+		return nil, fmt.Errorf("generate answer: %w", err)
+	}
+	answer.Text = strings.TrimSpace(text)
+	answer.Citations = extractCitations(answer.Text, used)
+	return answer, nil
+}
+
+// buildAskPrompt собирает промпт, укладываясь в бюджет символов.
+// Возвращает промпт и записи, которые реально в него попали.
+func buildAskPrompt(question string, entries []ThoughtEntry, opts AskOptions) (string, []ThoughtEntry) {
+	var sb strings.Builder
+	sb.WriteString("Ты помогаешь человеку понять его собственный дневник мыслей.\n")
+	sb.WriteString("Отвечай только на основе записей ниже. Если данных недостаточно — так и скажи.\n")
+	sb.WriteString("После каждого утверждения указывай ID записи в квадратных скобках, например [0123456789abcdef].\n")
+	sb.WriteString("Не ставь диагнозов. Отвечай на русском, кратко.\n\n")
+	sb.WriteString("ЗАПИСИ:\n")
+
+	var used []ThoughtEntry
+	budget := opts.MaxPromptChars - len([]rune(question))
+	for _, e := range entries {
+		block := formatEntryForPrompt(e, opts.MaxEntryChars)
+		size := len([]rune(block))
+		if size > budget {
+			break
+		}
+		budget -= size
+		sb.WriteString(block)
+		used = append(used, e)
+	}
+
+	sb.WriteString("\nВОПРОС: ")
+	sb.WriteString(question)
+	sb.WriteString("\nОТВЕТ:")
+	return sb.String(), used
+}
+
+// formatEntryForPrompt — компактное представление записи для контекста LLM
+func formatEntryForPrompt(e ThoughtEntry, maxChars int) string {
+	var parts []string
+	if e.Situation != "" {
+		parts = append(parts, "ситуация: "+e.Situation)
+	}
+	if e.AutomaticThought != "" {
+		parts = append(parts, "мысль: "+e.AutomaticThought)
+	}
+	if e.RationalResponse != "" {
+		parts = append(parts, "ответ: "+e.RationalResponse)
+	}
+	for _, item := range e.GratitudeItems {
+		parts = append(parts, "благодарность: "+item.Text)
+	}
+	if e.Notes != "" {
+		parts = append(parts, "заметки: "+e.Notes)
+	}
+	if len(e.Emotions) > 0 {
+		parts = append(parts, "эмоции: "+strings.Join(e.Emotions, ", "))
+	}
+	if e.Intensity > 0 {
+		parts = append(parts, fmt.Sprintf("интенсивность: %d/100", e.Intensity))
+	}
+	body := truncateRunes(strings.Join(parts, "; "), maxChars)
+	return fmt.Sprintf("[%s] %s (%s): %s\n", e.ID, e.Timestamp.Format("2006-01-02"), e.Type, body)
+}
+
+// extractCitations оставляет только ссылки на записи, которые были в контексте
+func extractCitations(text string, used []ThoughtEntry) []Citation {
+	byID := make(map[string]ThoughtEntry, len(used))
+	for _, e := range used {
+		byID[e.ID] = e
+	}
+	citations := []Citation{}
+	seen := make(map[string]bool)
+	for _, m := range citationPattern.FindAllStringSubmatch(text, -1) {
+		id := m[1]
+		e, ok := byID[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		citations = append(citations, citationFor(e))
+	}
+	return citations
+}
+
+func citationFor(e ThoughtEntry) Citation {
+	return Citation{EntryID: e.ID, Date: e.Timestamp, Type: e.Type}
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
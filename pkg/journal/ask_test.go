@@ -0,0 +1,109 @@
+package journal
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fakeGenerator — заглушка LLM, отвечает шаблоном и запоминает промпт
+type fakeGenerator struct {
+	prompt string
+	reply  func(prompt string) string
+	err    error
+}
+
+func (f *fakeGenerator) GenerateText(prompt string) (string, error) {
+	f.prompt = prompt
+	if f.err != nil {
+		return "", f.err
+	}
+	return f.reply(prompt), nil
+}
+
+func TestJournal_Ask(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "journal_ask_test")
+	defer os.RemoveAll(tmpDir)
+
+	j, _ := NewJournal(JournalConfig{DataDir: tmpDir, UseOllamaEmbed: false})
+	j.AddCBTEntry("Пришёл счёт за квартиру", "Денег не хватит, всё пропало", []string{"тревога"}, 80)
+	j.AddCBTEntry("Разговор с начальником", "Меня уволят", []string{"страх"}, 60)
+
+	moneyID := ""
+	for _, e := range j.entries {
+		if strings.Contains(e.Situation, "счёт") {
+			moneyID = e.ID
+		}
+	}
+
+	gen := &fakeGenerator{reply: func(string) string {
+		return "Тревога о деньгах возникает при счетах [" + moneyID + "] и [ffffffffffffffff]."
+	}}
+	answer, err := j.Ask("Когда я тревожусь о деньгах?", gen, AskOptions{})
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+
+	if !strings.Contains(gen.prompt, moneyID) {
+		t.Error("Expected retrieved entry ID in prompt")
+	}
+	if len(answer.Sources) != 2 {
+		t.Errorf("Expected 2 sources, got %d", len(answer.Sources))
+	}
+	// Неизвестный ID не должен попасть в цитаты
+	if len(answer.Citations) != 1 || answer.Citations[0].EntryID != moneyID {
+		t.Errorf("Expected single citation %s, got %+v", moneyID, answer.Citations)
+	}
+	if answer.Citations[0].Date.IsZero() {
+		t.Error("Expected citation date")
+	}
+}
+
+func TestJournal_Ask_PromptBudget(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "journal_ask_budget_test")
+	defer os.RemoveAll(tmpDir)
+
+	j, _ := NewJournal(JournalConfig{DataDir: tmpDir, UseOllamaEmbed: false})
+	long := strings.Repeat("очень длинная мысль ", 100)
+	for i := 0; i < 5; i++ {
+		j.AddEntry(ThoughtEntry{Type: EntryTypeReflection, Notes: long + string(rune('a'+i))})
+	}
+
+	gen := &fakeGenerator{reply: func(string) string { return "ok" }}
+	answer, err := j.Ask("о чём я пишу?", gen, AskOptions{MaxEntryChars: 200, MaxPromptChars: 600})
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	if len(answer.Sources) != 2 {
+		t.Errorf("Expected budget to fit 2 entries, got %d", len(answer.Sources))
+	}
+	if len([]rune(gen.prompt)) > 1000 {
+		t.Errorf("Prompt too long: %d runes", len([]rune(gen.prompt)))
+	}
+}
+
+func TestJournal_Ask_Errors(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "journal_ask_err_test")
+	defer os.RemoveAll(tmpDir)
+
+	j, _ := NewJournal(JournalConfig{DataDir: tmpDir, UseOllamaEmbed: false})
+	if _, err := j.Ask("  ", &fakeGenerator{}, AskOptions{}); !errors.Is(err, ErrEmptyQuestion) {
+		t.Errorf("Expected ErrEmptyQuestion, got %v", err)
+	}
+
+	// Пустой дневник — ответ без обращения к LLM
+	gen := &fakeGenerator{err: errors.New("must not be called")}
+	answer, err := j.Ask("что со мной?", gen, AskOptions{})
+	if err != nil {
+		t.Fatalf("Ask on empty journal failed: %v", err)
+	}
+	if len(answer.Sources) != 0 || answer.Text == "" {
+		t.Errorf("Unexpected answer for empty journal: %+v", answer)
+	}
+
+	j.AddCBTEntry("Ситуация", "Мысль", nil, 10)
+	if _, err := j.Ask("что со мной?", gen, AskOptions{}); err == nil {
+		t.Error("Expected generator error to propagate")
+	}
+}
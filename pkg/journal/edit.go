@@ -0,0 +1,259 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/cbt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Изменение записей после создания: правка полей, теги, удаление.
+// Каждое изменение сообщается подписчикам OnChange (журнал операций
+// для синхронизации между устройствами).
+
+// ChangeKind — вид изменения дневника
+type ChangeKind string
+
+const (
+	ChangeAdded      ChangeKind = "added"
+	ChangeUpdated    ChangeKind = "updated"
+	ChangeDeleted    ChangeKind = "deleted"
+	ChangeTagAdded   ChangeKind = "tag_added"
+	ChangeTagRemoved ChangeKind = "tag_removed"
+)
+
+// Change — одно локальное изменение дневника
+type Change struct {
+	Kind   ChangeKind
+	Entry  ThoughtEntry               // запись после изменения (для deleted — до удаления)
+	Fields map[string]json.RawMessage // для updated: изменённые поля (JSON-имена)
+	Tag    string                     // для tag_added / tag_removed
+}
+
+// EditableFields — поля записи, которые можно править (JSON-имена).
+// ID, тип, время и производные поля (искажения, эмбеддинг) не правятся.
+var EditableFields = []string{
+	"situation", "notes", "emotions", "intensity", "phase", "person_id", "chakras",
+	"automatic_thought", "rational_response", "new_intensity",
+	"gratitude_items", "gratitude_level",
+}
+
+var ErrNotEditable = errors.New("field is not editable")
+
+// OnChange регистрирует вызов после каждого сохранённого локального изменения
+func (j *Journal) OnChange(fn func(Change)) {
+	j.onChange = append(j.onChange, fn)
+}
+
+func (j *Journal) notify(c Change) {
+	for _, fn := range j.onChange {
+		fn(c)
+	}
+}
+
+// Entry возвращает запись по ID
+func (j *Journal) Entry(id string) (ThoughtEntry, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if i := j.index(id); i >= 0 {
+		return j.entries[i], nil
+	}
+	return ThoughtEntry{}, fmt.Errorf("entry %s: %w", id, os.ErrNotExist)
+}
+
+// UpdateEntry правит поля записи: fields — JSON-имя поля → новое значение
+func (j *Journal) UpdateEntry(id string, fields map[string]json.RawMessage) (ThoughtEntry, error) {
+	for name := range fields {
+		if !containsString(EditableFields, name) {
+			return ThoughtEntry{}, fmt.Errorf("%q: %w", name, ErrNotEditable)
+		}
+	}
+	e, err := j.Entry(id)
+	if err != nil {
+		return ThoughtEntry{}, err
+	}
+	if e, err = applyFields(e, fields); err != nil {
+		return ThoughtEntry{}, err
+	}
+	for {
+		// Эмбеддинг — вне j.mu, как в replace: запрос к Ollama не должен
+		// блокировать дневник
+		text := e.toSearchText()
+		embedding := j.generateEmbedding(text)
+
+		j.mu.Lock()
+		i := j.index(id)
+		if i < 0 {
+			j.mu.Unlock()
+			return ThoughtEntry{}, fmt.Errorf("entry %s: %w", id, os.ErrNotExist)
+		}
+		// Запись могла измениться, пока считался эмбеддинг: поля накладываются
+		// на текущую версию, а при другом тексте эмбеддинг считается заново
+		if e, err = applyFields(j.entries[i], fields); err != nil {
+			j.mu.Unlock()
+			return ThoughtEntry{}, err
+		}
+		if e.toSearchText() != text {
+			j.mu.Unlock()
+			continue
+		}
+		e.Embedding = embedding
+		j.upsertVector(e)
+		j.entries[i] = e
+		err = j.save()
+		j.mu.Unlock()
+		break
+	}
+	if err != nil {
+		return ThoughtEntry{}, err
+	}
+	j.notify(Change{Kind: ChangeUpdated, Entry: e, Fields: fields})
+	return e, nil
+}
+
+// AddTag добавляет тег записи (повторное добавление ничего не меняет)
+func (j *Journal) AddTag(id, tag string) (ThoughtEntry, error) {
+	return j.setTag(id, tag, true)
+}
+
+// RemoveTag снимает тег с записи
+func (j *Journal) RemoveTag(id, tag string) (ThoughtEntry, error) {
+	return j.setTag(id, tag, false)
+}
+
+func (j *Journal) setTag(id, tag string, add bool) (ThoughtEntry, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return ThoughtEntry{}, errors.New("tag is empty")
+	}
+	j.mu.Lock()
+	i := j.index(id)
+	if i < 0 {
+		j.mu.Unlock()
+		return ThoughtEntry{}, fmt.Errorf("entry %s: %w", id, os.ErrNotExist)
+	}
+	e := j.entries[i]
+	if containsString(e.Tags, tag) == add {
+		j.mu.Unlock()
+		return e, nil
+	}
+	kind := ChangeTagAdded
+	if add {
+		e.Tags = append(append([]string{}, e.Tags...), tag)
+	} else {
+		kind = ChangeTagRemoved
+		var kept []string
+		for _, t := range e.Tags {
+			if t != tag {
+				kept = append(kept, t)
+			}
+		}
+		e.Tags = kept
+	}
+	j.entries[i] = e
+	err := j.save()
+	j.mu.Unlock()
+	if err != nil {
+		return ThoughtEntry{}, err
+	}
+	j.notify(Change{Kind: kind, Entry: e, Tag: tag})
+	return e, nil
+}
+
+// applyFields накладывает изменённые поля на запись и пересчитывает производные
+func applyFields(e ThoughtEntry, fields map[string]json.RawMessage) (ThoughtEntry, error) {
+	if len(fields) == 0 {
+		return e, nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return e, err
+	}
+	for name, v := range fields {
+		m[name] = v
+	}
+	if data, err = json.Marshal(m); err != nil {
+		return e, err
+	}
+	var out ThoughtEntry
+	if err := json.Unmarshal(data, &out); err != nil {
+		return e, fmt.Errorf("entry fields: %w", err)
+	}
+	if _, ok := fields["automatic_thought"]; ok {
+		out.DistortionMatches = cbt.Detect(out.AutomaticThought)
+		out.Distortions = cbt.Distortions(out.DistortionMatches)
+	}
+	return out, nil
+}
+
+// index — позиция записи в j.entries или -1 (вызывать под j.mu)
+func (j *Journal) index(id string) int {
+	for i, e := range j.entries {
+		if e.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// upsertVector записывает готовый эмбеддинг записи в векторный индекс (под j.mu)
+func (j *Journal) upsertVector(e ThoughtEntry) {
+	j.vectorStore.Upsert(e.ID, e.Embedding, map[string]interface{}{
+		"type":     string(e.Type),
+		"emotions": e.Emotions,
+		"phase":    e.Phase,
+		"person":   e.PersonID,
+		"tags":     e.Tags,
+	})
+}
+
+// replace записывает состояние записей, пришедшее с другого устройства:
+// upsert — новые или изменённые записи, remove — удалённые. Подписчики OnChange
+// не вызываются (это не локальные изменения); для новых записей вызываются
+// обработчики OnEntryAdded.
+func (j *Journal) replace(upsert []ThoughtEntry, remove []string) error {
+	// Эмбеддинги — до захвата j.mu, как в addEntryWithProcessing: запрос
+	// к Ollama на каждую запись пакета не должен блокировать дневник
+	ready := make([]ThoughtEntry, len(upsert))
+	for i, e := range upsert {
+		e.Embedding = j.generateEmbedding(e.toSearchText())
+		ready[i] = e
+	}
+
+	var added []ThoughtEntry
+	j.mu.Lock()
+	for _, e := range ready {
+		j.upsertVector(e)
+		if i := j.index(e.ID); i >= 0 {
+			j.entries[i] = e
+		} else {
+			j.entries = append(j.entries, e)
+			added = append(added, e)
+		}
+	}
+	for _, id := range remove {
+		if i := j.index(id); i >= 0 {
+			j.entries = append(j.entries[:i], j.entries[i+1:]...)
+			j.vectorStore.Delete(id)
+		}
+	}
+	err := j.save()
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	sort.Slice(added, func(a, b int) bool { return added[a].Timestamp.Before(added[b].Timestamp) })
+	for _, e := range added {
+		for _, fn := range j.onAdded {
+			fn(e)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package journal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"ideal-core/pkg/cbt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// guidedFile — файл с незавершёнными и завершёнными сессиями
+const guidedFile = "guided_sessions.json"
+
+// StartGuidedSession начинает пошаговую КПТ-сессию
+func (j *Journal) StartGuidedSession(personID string) (*cbt.GuidedSession, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	session := cbt.NewGuidedSession(hex.EncodeToString(id), personID, time.Now())
+	j.sessionsMu.Lock()
+	defer j.sessionsMu.Unlock()
+	j.sessions[session.ID] = session
+	return session.Clone(), j.saveGuidedSessions()
+}
+
+// GetGuidedSession возвращает копию сессии по ID (для продолжения)
+func (j *Journal) GetGuidedSession(id string) (*cbt.GuidedSession, bool) {
+	j.sessionsMu.Lock()
+	defer j.sessionsMu.Unlock()
+	s, ok := j.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	return s.Clone(), true
+}
+
+// ListGuidedSessions возвращает сессии человека (все, если personID пуст), новые первыми
+func (j *Journal) ListGuidedSessions(personID string) []*cbt.GuidedSession {
+	var result []*cbt.GuidedSession
+	j.sessionsMu.Lock()
+	for _, s := range j.sessions {
+		if personID != "" && s.PersonID != personID {
+			continue
+		}
+		result = append(result, s.Clone())
+	}
+	j.sessionsMu.Unlock()
+	sort.Slice(result, func(a, b int) bool {
+		return result[a].UpdatedAt.After(result[b].UpdatedAt)
+	})
+	return result
+}
+
+// AnswerGuidedSession принимает ответ на текущий шаг.
+// После последнего шага итоговая ThoughtRecord сохраняется в дневник как КПТ-запись.
+// Ответ применяется к копии: при ошибке сессия остаётся на прежнем шаге.
+func (j *Journal) AnswerGuidedSession(id string, answer cbt.StepAnswer) (*cbt.GuidedSession, error) {
+	j.sessionsMu.Lock()
+	defer j.sessionsMu.Unlock()
+	stored, ok := j.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("guided session %s: %w", id, os.ErrNotExist)
+	}
+	session := stored.Clone()
+	if err := session.Answer(answer, time.Now()); err != nil {
+		return nil, err
+	}
+	if session.Done() {
+		if err := j.addGuidedRecord(session); err != nil {
+			return nil, err
+		}
+	}
+	j.sessions[id] = session
+	return session.Clone(), j.saveGuidedSessions()
+}
+
+// addGuidedRecord превращает итог сессии в запись дневника
+func (j *Journal) addGuidedRecord(s *cbt.GuidedSession) error {
+	rec := s.Record
+	var notes []string
+	if len(s.EvidenceFor) > 0 {
+		notes = append(notes, "За: "+strings.Join(s.EvidenceFor, "; "))
+	}
+	if len(s.EvidenceAgainst) > 0 {
+		notes = append(notes, "Против: "+strings.Join(s.EvidenceAgainst, "; "))
+	}
+	return j.addEntryWithProcessing(ThoughtEntry{
+		Type:             EntryTypeCBT,
+		Timestamp:        s.UpdatedAt,
+		Situation:        rec.Situation,
+		AutomaticThought: rec.AutomaticThought,
+		Emotions:         rec.Emotions,
+		Intensity:        rec.Intensity,
+		RationalResponse: rec.RationalResponse,
+		NewIntensity:     rec.NewIntensity,
+		Notes:            strings.Join(notes, "\n"),
+		PersonID:         s.PersonID,
+		SessionID:        s.ID,
+	})
+}
+
+func (j *Journal) guidedPath() string {
+	return filepath.Join(filepath.Dir(j.filePath), guidedFile)
+}
+
+// saveGuidedSessions — вызывать под j.sessionsMu
+func (j *Journal) saveGuidedSessions() error {
+	data, err := json.MarshalIndent(j.sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(j.guidedPath(), data, 0600)
+}
+
+func (j *Journal) loadGuidedSessions() error {
+	data, err := os.ReadFile(j.guidedPath())
+	if err != nil {
+		return err
+	}
+	j.sessionsMu.Lock()
+	defer j.sessionsMu.Unlock()
+	return json.Unmarshal(data, &j.sessions)
+}
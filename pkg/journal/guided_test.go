@@ -0,0 +1,87 @@
+package journal
+
+import (
+	"errors"
+	"ideal-core/pkg/cbt"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestJournal_GuidedSessionResume(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "journal_guided_test")
+	defer os.RemoveAll(tmpDir)
+
+	j1, _ := NewJournal(JournalConfig{DataDir: tmpDir})
+	s, err := j1.StartGuidedSession("Valya")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j1.AnswerGuidedSession(s.ID, cbt.StepAnswer{Text: "Мама позвонила в 7 утра"})
+	j1.AnswerGuidedSession(s.ID, cbt.StepAnswer{Text: "Она опять меня контролирует"})
+
+	// Новый экземпляр дневника продолжает с того же шага
+	j2, _ := NewJournal(JournalConfig{DataDir: tmpDir})
+	resumed, ok := j2.GetGuidedSession(s.ID)
+	if !ok || resumed.Step != cbt.StepEmotions {
+		t.Fatalf("Expected resumable session at emotions step, got %+v", resumed)
+	}
+
+	intensity, newIntensity := 70, 30
+	answers := []cbt.StepAnswer{
+		{Items: []string{"раздражение"}, Intensity: &intensity},
+		{Items: []string{"Звонит каждое утро"}},
+		{Items: []string{"Она волнуется после болезни"}},
+		{Text: "Она заботится так, как умеет"},
+		{Intensity: &newIntensity},
+	}
+	for _, a := range answers {
+		if _, err := j2.AnswerGuidedSession(s.ID, a); err != nil {
+			t.Fatalf("Answer failed: %v", err)
+		}
+	}
+
+	entries := j2.GetEntries(EntryFilters{PersonID: "Valya"})
+	if len(entries) != 1 {
+		t.Fatalf("Expected journal entry from finished session, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.SessionID != s.ID || e.Intensity != 70 || e.NewIntensity != 30 {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+	if e.RationalResponse != "Она заботится так, как умеет" {
+		t.Errorf("Expected alternative thought as rational response, got %q", e.RationalResponse)
+	}
+	if _, err := j2.AnswerGuidedSession("missing", cbt.StepAnswer{}); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Expected error for unknown session")
+	}
+}
+
+func TestJournal_GuidedSessionsConcurrent(t *testing.T) {
+	j, _ := NewJournal(JournalConfig{DataDir: t.TempDir()})
+	s, err := j.StartGuidedSession("Valya")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			j.StartGuidedSession("Dina")
+			j.AnswerGuidedSession(s.ID, cbt.StepAnswer{Text: "Мама позвонила в 7 утра"})
+			j.ListGuidedSessions("")
+		}()
+	}
+	wg.Wait()
+
+	// Выданная копия не меняет хранимую сессию
+	got, _ := j.GetGuidedSession(s.ID)
+	got.Situation = "чужая правка"
+	if again, _ := j.GetGuidedSession(s.ID); again.Situation == "чужая правка" {
+		t.Error("GetGuidedSession returned the shared session")
+	}
+	if n := len(j.ListGuidedSessions("Dina")); n != 8 {
+		t.Errorf("Expected 8 sessions, got %d", n)
+	}
+}
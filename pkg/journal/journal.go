@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -42,8 +43,11 @@ type ThoughtEntry struct {
 	// Поля для режима КПТ
 	AutomaticThought string                      `json:"automatic_thought,omitempty"`
 	Distortions      []cbt.CognitiveDistortion   `json:"distortions,omitempty"`
+	DistortionMatches []cbt.Detection            `json:"distortion_matches,omitempty"` // уверенность и фрагменты
 	RationalResponse string                      `json:"rational_response,omitempty"`
+	Rational         *cbt.StructuredResponse     `json:"rational,omitempty"` // структурированный ответ LLM
 	NewIntensity     int                         `json:"new_intensity,omitempty"`
+	SessionID        string                      `json:"session_id,omitempty"` // пошаговая сессия, из которой создана запись
 	
 	// Поля для режима благодарности
 	GratitudeItems   []GratitudeItem             `json:"gratitude_items,omitempty"`
@@ -68,16 +72,23 @@ type JournalConfig struct {
 	OllamaModel     string
 	UseOllamaEmbed  bool
 	DefaultMode     EntryType // cbt | gratitude
+	Generator       TextGenerator // LLM для рациональных ответов (nil — только правила)
 }
 
 // Journal — дневник с поддержкой нескольких режимов
 type Journal struct {
+	mu           sync.RWMutex // защищает entries (записи приходят и с других устройств)
 	entries      []ThoughtEntry
 	filePath     string
 	vectorStore  vector.VectorStore
 	ollamaClient *vector.OllamaEmbeddingClient
 	useOllama    bool
 	defaultMode  EntryType
+	generator    TextGenerator
+	sessionsMu   sync.Mutex // защищает sessions (отдельно от mu: завершение сессии пишет запись)
+	sessions     map[string]*cbt.GuidedSession
+	onAdded      []func(ThoughtEntry)
+	onChange     []func(Change)
 }
 
 // NewJournal создаёт новый дневник
@@ -92,6 +103,8 @@ func NewJournal(cfg JournalConfig) (*Journal, error) {
 		vectorStore: vector.NewMockVectorStore(),
 		useOllama:   cfg.UseOllamaEmbed,
 		defaultMode: cfg.DefaultMode,
+		generator:   cfg.Generator,
+		sessions:    make(map[string]*cbt.GuidedSession),
 	}
 	
 	if cfg.UseOllamaEmbed {
@@ -105,6 +118,9 @@ func NewJournal(cfg JournalConfig) (*Journal, error) {
 	if err := j.Load(); err != nil && !os.IsNotExist(err) {
 		return nil, err
 	}
+	if err := j.loadGuidedSessions(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
 	
 	return j, nil
 }
@@ -153,9 +169,17 @@ func (j *Journal) addEntryWithProcessing(entry ThoughtEntry) error {
 	// Обработка в зависимости от типа
 	switch entry.Type {
 	case EntryTypeCBT:
-		entry.Distortions = cbt.DetectDistortions(entry.AutomaticThought)
+		entry.DistortionMatches = cbt.Detect(entry.AutomaticThought)
+		entry.Distortions = cbt.Distortions(entry.DistortionMatches)
 		if entry.RationalResponse == "" {
-			entry.RationalResponse = cbt.GenerateRationalResponse(entry.AutomaticThought, entry.Distortions)
+			resp, err := cbt.GenerateStructuredResponse(j.generator, entry.Situation, entry.AutomaticThought, entry.Distortions)
+			if err != nil {
+				fmt.Printf("⚠️  LLM rational response failed, using rules: %v\n", err)
+			}
+			entry.RationalResponse = resp.Text()
+			if resp.Source == cbt.RationalSourceLLM {
+				entry.Rational = &resp
+			}
 		}
 	case EntryTypeGratitude:
 		// Авто-тегирование для благодарности
@@ -176,8 +200,24 @@ func (j *Journal) addEntryWithProcessing(entry ThoughtEntry) error {
 		"tags":     entry.Tags,
 	})
 	
+	j.mu.Lock()
 	j.entries = append(j.entries, entry)
-	return j.Save()
+	err := j.save()
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	j.notify(Change{Kind: ChangeAdded, Entry: entry})
+	for _, fn := range j.onAdded {
+		fn(entry)
+	}
+	return nil
+}
+
+// OnEntryAdded регистрирует вызов после сохранения каждой новой записи
+// (например, отправка по активным общим доступам)
+func (j *Journal) OnEntryAdded(fn func(ThoughtEntry)) {
+	j.onAdded = append(j.onAdded, fn)
 }
 
 // toSearchText возвращает текст для векторизации (объединяет все поля)
@@ -265,21 +305,13 @@ func (j *Journal) autoTagCommon(entry ThoughtEntry) []string {
 
 // GetEntries возвращает записи с фильтрами
 func (j *Journal) GetEntries(filters EntryFilters) []ThoughtEntry {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
 	var result []ThoughtEntry
 	for _, e := range j.entries {
-		if filters.Type != "" && string(e.Type) != filters.Type {
-			continue
-		}
-		if filters.PersonID != "" && e.PersonID != filters.PersonID {
-			continue
+		if filters.Match(e) {
+			result = append(result, e)
 		}
-		if filters.Phase != "" && e.Phase != filters.Phase {
-			continue
-		}
-		if filters.Tag != "" && !containsString(e.Tags, filters.Tag) {
-			continue
-		}
-		result = append(result, e)
 	}
 	sort.Slice(result, func(i, j int) bool {
 		return result[i].Timestamp.After(result[j].Timestamp)
@@ -289,12 +321,31 @@ func (j *Journal) GetEntries(filters EntryFilters) []ThoughtEntry {
 
 // EntryFilters — фильтры для поиска
 type EntryFilters struct {
-	Type     string // "cbt", "gratitude", "" for all
-	PersonID string
-	Phase    string
-	Tag      string
-	FromDate *time.Time
-	ToDate   *time.Time
+	Type     string     `json:"type,omitempty"` // "cbt", "gratitude", "" for all
+	PersonID string     `json:"person_id,omitempty"`
+	Phase    string     `json:"phase,omitempty"`
+	Tag      string     `json:"tag,omitempty"`
+	FromDate *time.Time `json:"from,omitempty"`
+	ToDate   *time.Time `json:"to,omitempty"`
+}
+
+// Match проверяет запись по всем заданным фильтрам
+func (f EntryFilters) Match(e ThoughtEntry) bool {
+	switch {
+	case f.Type != "" && string(e.Type) != f.Type:
+		return false
+	case f.PersonID != "" && e.PersonID != f.PersonID:
+		return false
+	case f.Phase != "" && e.Phase != f.Phase:
+		return false
+	case f.Tag != "" && !containsString(e.Tags, f.Tag):
+		return false
+	case f.FromDate != nil && e.Timestamp.Before(*f.FromDate):
+		return false
+	case f.ToDate != nil && e.Timestamp.After(*f.ToDate):
+		return false
+	}
+	return true
 }
 
 // SearchByMeaning — семантический поиск по всем записям
@@ -302,6 +353,8 @@ func (j *Journal) SearchByMeaning(query string, limit int) []ThoughtEntry {
 	queryEmbedding := j.generateEmbedding(query)
 	results := j.vectorStore.Search(queryEmbedding, limit)
 	
+	j.mu.RLock()
+	defer j.mu.RUnlock()
 	var entries []ThoughtEntry
 	for _, r := range results {
 		for _, e := range j.entries {
@@ -318,6 +371,8 @@ func (j *Journal) SearchByMeaning(query string, limit int) []ThoughtEntry {
 func (j *Journal) GetGratitudeStats() GratitudeStats {
 	stats := GratitudeStats{TotalEntries: 0, AvgLevel: 0, CategoryCount: make(map[string]int)}
 	var totalLevel int
+	j.mu.RLock()
+	defer j.mu.RUnlock()
 	
 	for _, e := range j.entries {
 		if e.Type != EntryTypeGratitude {
@@ -347,6 +402,8 @@ type GratitudeStats struct {
 
 // GetCombinedStats — общая статистика по всем режимам
 func (j *Journal) GetCombinedStats() CombinedStats {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
 	cbtCount, gratitudeCount := 0, 0
 	for _, e := range j.entries {
 		if e.Type == EntryTypeCBT {
@@ -375,6 +432,12 @@ type CombinedStats struct {
 // ... (код Save/Load аналогичен, с учётом новых полей)
 
 func (j *Journal) Save() error {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.save()
+}
+
+func (j *Journal) save() error {
 	data, err := json.MarshalIndent(j.entries, "", "  ")
 	if err != nil {
 		return err
@@ -401,14 +464,22 @@ func (j *Journal) Load() error {
 }
 
 func (j *Journal) DeleteEntry(id string) error {
-	for i, e := range j.entries {
-		if e.ID == id {
-			j.entries = append(j.entries[:i], j.entries[i+1:]...)
-			j.vectorStore.Delete(id)
-			return j.Save()
-		}
+	j.mu.Lock()
+	i := j.index(id)
+	if i < 0 {
+		j.mu.Unlock()
+		return os.ErrNotExist
+	}
+	e := j.entries[i]
+	j.entries = append(j.entries[:i], j.entries[i+1:]...)
+	j.vectorStore.Delete(id)
+	err := j.save()
+	j.mu.Unlock()
+	if err != nil {
+		return err
 	}
-	return os.ErrNotExist
+	j.notify(Change{Kind: ChangeDeleted, Entry: e})
+	return nil
 }
 
 // Helpers
@@ -496,10 +567,10 @@ func (j *Journal) GetStats() CombinedStats {
 
 // ExportToMarkdown экспортирует дневник в Markdown для печати
 func (j *Journal) ExportToMarkdown(outputPath string) error {
+	entries := j.GetEntries(EntryFilters{})
 	md := "# 📓 Дневник мыслей\n\n"
-	md += fmt.Sprintf("Всего записей: %d\n\n", len(j.entries))
+	md += fmt.Sprintf("Всего записей: %d\n\n", len(entries))
 	
-	entries := j.GetEntries(EntryFilters{})
 	for _, e := range entries {
 		md += fmt.Sprintf("## %s\n", e.Timestamp.Format("02.01.2006 15:04"))
 		md += fmt.Sprintf("**Тип:** %s", e.Type)
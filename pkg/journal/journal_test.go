@@ -172,3 +172,22 @@ func TestJournal_SearchByMeaning(t *testing.T) {
 		t.Error("Expected some results from semantic search")
 	}
 }
+
+func TestJournal_LLMRationalResponse(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "journal_llm_test")
+	defer os.RemoveAll(tmpDir)
+
+	gen := &fakeGenerator{reply: func(string) string {
+		return `{"alternative_thoughts": ["Одна ошибка не делает меня неудачником"], "evidence_for": [], "evidence_against": ["Проект сдан"], "summary": "Ошибка поправима"}`
+	}}
+	j, _ := NewJournal(JournalConfig{DataDir: tmpDir, Generator: gen})
+	j.AddCBTEntry("Ошибка в отчёте", "Я всегда всё порчу", []string{"стыд"}, 70)
+
+	e := j.entries[0]
+	if e.Rational == nil || len(e.Rational.EvidenceAgainst) != 1 {
+		t.Fatalf("Expected structured LLM response, got %+v", e.Rational)
+	}
+	if e.RationalResponse == "" {
+		t.Error("Expected rational response text")
+	}
+}
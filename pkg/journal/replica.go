@@ -0,0 +1,562 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Синхронизация дневника между устройствами одного человека.
+//
+// Каждое локальное изменение записывается в журнал операций с гибридными
+// логическими часами (HLC) и номером Seq на устройстве-источнике. Устройства
+// обмениваются векторами версий (узел → последний Seq) и досылают друг другу
+// недостающие операции; операции чужих устройств пересылаются дальше, так что
+// ноутбук и телефон, сопряжённые с домашним сервером, видят один дневник.
+//
+// Слияние без конфликтов:
+//   - поля записи — «последняя запись побеждает» по HLC, отдельно для каждого поля;
+//   - теги — множество с наблюдаемым удалением (удаляются только те добавления,
+//     которые видело удаляющее устройство; одновременное добавление сохраняется);
+//   - удаление окончательно: запись не возвращается от запоздалых правок, а её
+//     содержимое стирается из журнала операций (остаются пустые заглушки).
+
+const replicaFile = "journal_sync.json"
+
+// MaxSyncBatch — операций в одном сообщении синхронизации (их размер, кроме
+// того, ограничен MaxBatchBytes)
+const MaxSyncBatch = 50
+
+// MaxClockSkew — часы устройства, ушедшие вперёд больше чем на это, не сдвигают наши
+const MaxClockSkew = time.Hour
+
+// HLC — метка гибридных логических часов
+type HLC struct {
+	Wall    int64  `json:"wall"`    // физическое время, нс Unix
+	Logical uint32 `json:"logical"` // счётчик при равном Wall
+	Node    string `json:"node"`    // разрешает полное равенство
+}
+
+// Compare упорядочивает метки: -1, 0, 1
+func (a HLC) Compare(b HLC) int {
+	switch {
+	case a.Wall != b.Wall:
+		return cmpInt(a.Wall < b.Wall)
+	case a.Logical != b.Logical:
+		return cmpInt(a.Logical < b.Logical)
+	case a.Node != b.Node:
+		return cmpInt(a.Node < b.Node)
+	}
+	return 0
+}
+
+func cmpInt(less bool) int {
+	if less {
+		return -1
+	}
+	return 1
+}
+
+// Clock — гибридные логические часы устройства
+type Clock struct {
+	Node string `json:"node"`
+	Last HLC    `json:"last"`
+}
+
+// Now выдаёт метку для локального события
+func (c *Clock) Now(wall time.Time) HLC {
+	pt := wall.UnixNano()
+	if pt > c.Last.Wall {
+		c.Last = HLC{Wall: pt, Node: c.Node}
+	} else {
+		c.Last = HLC{Wall: c.Last.Wall, Logical: c.Last.Logical + 1, Node: c.Node}
+	}
+	return c.Last
+}
+
+// Observe учитывает метку с другого устройства, чтобы следующие локальные
+// события шли после неё
+func (c *Clock) Observe(remote HLC, wall time.Time) {
+	if remote.Wall > wall.Add(MaxClockSkew).UnixNano() {
+		return
+	}
+	switch {
+	case remote.Wall > c.Last.Wall:
+		c.Last = HLC{Wall: remote.Wall, Logical: remote.Logical, Node: c.Node}
+	case remote.Wall == c.Last.Wall && remote.Logical > c.Last.Logical:
+		c.Last.Logical = remote.Logical
+	}
+}
+
+// OpKind — вид операции журнала
+type OpKind string
+
+const (
+	OpCreate    OpKind = "create"
+	OpUpdate    OpKind = "update"
+	OpDelete    OpKind = "delete"
+	OpTagAdd    OpKind = "tag_add"
+	OpTagRemove OpKind = "tag_remove"
+)
+
+// Op — операция журнала
+type Op struct {
+	Node    string                     `json:"node"`
+	Seq     uint64                     `json:"seq"`
+	HLC     HLC                        `json:"hlc"`
+	Kind    OpKind                     `json:"kind"`
+	EntryID string                     `json:"entry_id"`
+	Entry   *ThoughtEntry              `json:"entry,omitempty"`   // create (без тегов)
+	Fields  map[string]json.RawMessage `json:"fields,omitempty"`  // update
+	Tag     string                     `json:"tag,omitempty"`     // tag_add
+	Removes []string                   `json:"removes,omitempty"` // tag_remove: ID увиденных tag_add
+}
+
+// ID — «узел:номер», уникален среди всех устройств
+func (o Op) ID() string {
+	return fmt.Sprintf("%s:%d", o.Node, o.Seq)
+}
+
+// VersionVector — для каждого узла-источника последний полученный Seq
+type VersionVector map[string]uint64
+
+// Lacks — сколько операций из other нет в v
+func (v VersionVector) Lacks(other VersionVector) uint64 {
+	var n uint64
+	for node, seq := range other {
+		if seq > v[node] {
+			n += seq - v[node]
+		}
+	}
+	return n
+}
+
+func (v VersionVector) clone() VersionVector {
+	out := make(VersionVector, len(v))
+	for node, seq := range v {
+		out[node] = seq
+	}
+	return out
+}
+
+// SyncRequest — «вот что у меня есть» (envelope.TypeJournalSync)
+type SyncRequest struct {
+	Have VersionVector `json:"have"`
+}
+
+// SyncBatch — недостающие операции (envelope.TypeJournalOps)
+type SyncBatch struct {
+	Ops  []Op          `json:"ops"`
+	Have VersionVector `json:"have"` // вектор отправителя
+	More bool          `json:"more"` // не всё поместилось — запросить ещё
+}
+
+// SyncPeer — состояние синхронизации с устройством
+type SyncPeer struct {
+	Have        VersionVector `json:"have"` // что есть у устройства (по последнему сообщению)
+	LastSync    *time.Time    `json:"last_sync,omitempty"`
+	LastAttempt *time.Time    `json:"last_attempt,omitempty"`
+	LastError   string        `json:"last_error,omitempty"`
+}
+
+// SyncStatus — состояние синхронизации с устройством для отображения
+type SyncStatus struct {
+	Peer string `json:"peer"`
+	SyncPeer
+	Pending uint64 `json:"pending"` // наших операций, которых у него нет
+	Missing uint64 `json:"missing"` // его операций, которых нет у нас
+}
+
+type tagAdd struct {
+	Tag string
+	HLC HLC
+}
+
+type fieldValue struct {
+	HLC   HLC
+	Value json.RawMessage
+}
+
+// entryState — запись, собранная из операций
+type entryState struct {
+	created *ThoughtEntry
+	fields  map[string]fieldValue
+	tags    map[string]tagAdd // ID операции tag_add → тег
+	deleted bool
+}
+
+// Replica — журнал операций дневника и его синхронизация (journal_sync.json)
+type Replica struct {
+	mu    sync.Mutex
+	path  string
+	j     *Journal
+	clock Clock
+	ops   []Op
+	peers map[string]*SyncPeer
+	have  VersionVector
+	state map[string]*entryState
+	// onRecord вызывается после записи локальных операций (без блокировки)
+	onRecord []func()
+}
+
+// replicaState — сохраняемое состояние Replica
+type replicaState struct {
+	Clock Clock                `json:"clock"`
+	Ops   []Op                 `json:"ops"`
+	Peers map[string]*SyncPeer `json:"peers"`
+}
+
+// NewReplica открывает журнал операций дневника j. node — идентификатор
+// устройства для нового журнала; при первом запуске существующие записи
+// попадают в журнал как созданные этим устройством. Локальные изменения j
+// записываются автоматически.
+func NewReplica(j *Journal, dataDir, node string, now time.Time) (*Replica, error) {
+	r := &Replica{
+		path:  filepath.Join(dataDir, replicaFile),
+		j:     j,
+		clock: Clock{Node: node},
+		peers: make(map[string]*SyncPeer),
+		have:  make(VersionVector),
+		state: make(map[string]*entryState),
+	}
+	data, err := os.ReadFile(r.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	first := err != nil
+	if !first {
+		var st replicaState
+		if err := json.Unmarshal(data, &st); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", r.path, err)
+		}
+		// Идентификатор устройства в журнале не меняется (даже после смены ключа)
+		r.clock = st.Clock
+		if st.Peers != nil {
+			r.peers = st.Peers
+		}
+		for _, op := range st.Ops {
+			r.integrate(op)
+		}
+	}
+
+	// Записи, которых нет в журнале операций (первый запуск), и записи,
+	// полученные с устройств, но не попавшие в дневник (сбой между сохранениями)
+	var changes []Change
+	var upsert []ThoughtEntry
+	var remove []string
+	present := make(map[string]bool)
+	for _, e := range j.GetEntries(EntryFilters{}) {
+		present[e.ID] = true
+		st, ok := r.state[e.ID]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: ChangeAdded, Entry: e})
+		case st.deleted:
+			remove = append(remove, e.ID)
+		}
+	}
+	for id, st := range r.state {
+		if e, ok := st.materialize(); ok && !present[id] {
+			upsert = append(upsert, e)
+		}
+	}
+	sort.Slice(changes, func(a, b int) bool { return changes[a].Entry.Timestamp.Before(changes[b].Entry.Timestamp) })
+	for _, c := range changes {
+		r.recordLocked(c, now)
+	}
+	if first || len(changes) > 0 {
+		if err := r.save(); err != nil {
+			return nil, err
+		}
+	}
+	if len(upsert) > 0 || len(remove) > 0 {
+		if err := j.replace(upsert, remove); err != nil {
+			return nil, err
+		}
+	}
+	j.OnChange(r.record)
+	return r, nil
+}
+
+// Node — идентификатор этого устройства в журнале
+func (r *Replica) Node() string {
+	return r.clock.Node
+}
+
+// OnRecord регистрирует вызов после записи локальных операций
+// (например, чтобы сразу предложить синхронизацию устройствам)
+func (r *Replica) OnRecord(fn func()) {
+	r.onRecord = append(r.onRecord, fn)
+}
+
+// Have — вектор версий этого устройства
+func (r *Replica) Have() VersionVector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.have.clone()
+}
+
+// record — подписчик Journal.OnChange
+func (r *Replica) record(c Change) {
+	r.mu.Lock()
+	r.recordLocked(c, time.Now())
+	err := r.save()
+	r.mu.Unlock()
+	if err != nil {
+		fmt.Printf("⚠️  Journal sync log not saved: %v\n", err)
+	}
+	for _, fn := range r.onRecord {
+		fn()
+	}
+}
+
+func (r *Replica) recordLocked(c Change, now time.Time) {
+	id := c.Entry.ID
+	switch c.Kind {
+	case ChangeAdded:
+		e := c.Entry
+		e.Tags = nil
+		r.append(Op{Kind: OpCreate, EntryID: id, Entry: &e}, now)
+		for _, t := range c.Entry.Tags {
+			r.append(Op{Kind: OpTagAdd, EntryID: id, Tag: t}, now)
+		}
+	case ChangeUpdated:
+		r.append(Op{Kind: OpUpdate, EntryID: id, Fields: c.Fields}, now)
+	case ChangeDeleted:
+		r.append(Op{Kind: OpDelete, EntryID: id}, now)
+	case ChangeTagAdded:
+		r.append(Op{Kind: OpTagAdd, EntryID: id, Tag: c.Tag}, now)
+	case ChangeTagRemoved:
+		var seen []string
+		if st := r.state[id]; st != nil {
+			for opID, t := range st.tags {
+				if t.Tag == c.Tag {
+					seen = append(seen, opID)
+				}
+			}
+		}
+		sort.Strings(seen)
+		r.append(Op{Kind: OpTagRemove, EntryID: id, Removes: seen}, now)
+	}
+}
+
+// append записывает локальную операцию
+func (r *Replica) append(op Op, now time.Time) {
+	op.Node = r.clock.Node
+	op.Seq = r.have[op.Node] + 1
+	op.HLC = r.clock.Now(now)
+	r.integrate(op)
+}
+
+// integrate добавляет операцию в журнал и в собранное состояние.
+// Операция принимается только следующей по порядку от своего узла.
+func (r *Replica) integrate(op Op) bool {
+	if op.Seq != r.have[op.Node]+1 {
+		return false
+	}
+	r.have[op.Node] = op.Seq
+	r.ops = append(r.ops, op)
+
+	st := r.state[op.EntryID]
+	if st == nil {
+		st = &entryState{fields: make(map[string]fieldValue), tags: make(map[string]tagAdd)}
+		r.state[op.EntryID] = st
+	}
+	if st.deleted {
+		r.scrub(len(r.ops) - 1)
+		return true
+	}
+	switch op.Kind {
+	case OpCreate:
+		if op.Entry != nil {
+			e := *op.Entry
+			st.created = &e
+		}
+	case OpUpdate:
+		for name, v := range op.Fields {
+			if cur, ok := st.fields[name]; !ok || op.HLC.Compare(cur.HLC) > 0 {
+				st.fields[name] = fieldValue{HLC: op.HLC, Value: v}
+			}
+		}
+	case OpTagAdd:
+		st.tags[op.ID()] = tagAdd{Tag: op.Tag, HLC: op.HLC}
+	case OpTagRemove:
+		for _, id := range op.Removes {
+			delete(st.tags, id)
+		}
+	case OpDelete:
+		st.deleted = true
+		st.created, st.fields, st.tags = nil, nil, nil
+		for i := range r.ops {
+			if r.ops[i].EntryID == op.EntryID {
+				r.scrub(i)
+			}
+		}
+	}
+	return true
+}
+
+// scrub стирает содержимое операции удалённой записи (заглушка сохраняет Seq)
+func (r *Replica) scrub(i int) {
+	r.ops[i].Entry, r.ops[i].Fields, r.ops[i].Tag, r.ops[i].Removes = nil, nil, "", nil
+}
+
+// materialize собирает запись из состояния (false — удалена или ещё не создана)
+func (st *entryState) materialize() (ThoughtEntry, bool) {
+	if st.deleted || st.created == nil {
+		return ThoughtEntry{}, false
+	}
+	fields := make(map[string]json.RawMessage, len(st.fields))
+	for name, v := range st.fields {
+		fields[name] = v.Value
+	}
+	e, err := applyFields(*st.created, fields)
+	if err != nil {
+		e = *st.created
+	}
+	adds := make([]tagAdd, 0, len(st.tags))
+	for _, t := range st.tags {
+		adds = append(adds, t)
+	}
+	sort.Slice(adds, func(a, b int) bool { return adds[a].HLC.Compare(adds[b].HLC) < 0 })
+	e.Tags = nil
+	for _, t := range adds {
+		if !containsString(e.Tags, t.Tag) {
+			e.Tags = append(e.Tags, t.Tag)
+		}
+	}
+	return e, true
+}
+
+// Apply принимает операции с другого устройства и обновляет дневник.
+// Повторы и операции не по порядку пропускаются (их дошлют при следующем обмене).
+func (r *Replica) Apply(ops []Op, now time.Time) (int, error) {
+	r.mu.Lock()
+	applied := 0
+	touched := make(map[string]bool)
+	for _, op := range ops {
+		if op.Node == "" || op.EntryID == "" {
+			continue
+		}
+		if r.integrate(op) {
+			r.clock.Observe(op.HLC, now)
+			touched[op.EntryID] = true
+			applied++
+		}
+	}
+	var upsert []ThoughtEntry
+	var remove []string
+	for id := range touched {
+		if e, ok := r.state[id].materialize(); ok {
+			upsert = append(upsert, e)
+		} else if r.state[id].deleted {
+			remove = append(remove, id)
+		}
+	}
+	var err error
+	if applied > 0 {
+		err = r.save()
+	}
+	r.mu.Unlock()
+	if err != nil {
+		return applied, err
+	}
+	if len(upsert) > 0 || len(remove) > 0 {
+		err = r.j.replace(upsert, remove)
+	}
+	return applied, err
+}
+
+// Missing возвращает операции, которых нет у устройства с вектором have
+// (не больше limit и MaxBatchBytes в JSON, по порядку каждого узла), и есть ли
+// ещё. Хотя бы одна операция возвращается всегда, иначе синхронизация встанет.
+func (r *Replica) Missing(have VersionVector, limit int) ([]Op, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []Op{}
+	size := 1 // «[]» и запятые: 1 + по байту на операцию
+	for _, op := range r.ops {
+		if op.Seq <= have[op.Node] {
+			continue
+		}
+		data, _ := json.Marshal(op)
+		if len(out) == limit || len(out) > 0 && size+len(data)+1 > MaxBatchBytes {
+			return out, true
+		}
+		size += len(data) + 1
+		out = append(out, op)
+	}
+	return out, false
+}
+
+// Attempt отмечает попытку синхронизации с устройством (err — её ошибка)
+func (r *Replica) Attempt(peer string, err error, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := r.peer(peer)
+	p.LastAttempt = &now
+	p.LastError = ""
+	if err != nil {
+		p.LastError = err.Error()
+	}
+	r.save()
+}
+
+// Seen отмечает сообщение от устройства и его вектор версий
+func (r *Replica) Seen(peer string, have VersionVector, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := r.peer(peer)
+	p.Have = have.clone()
+	p.LastSync = &now
+	p.LastError = ""
+	r.save()
+}
+
+// Status — состояние синхронизации с устройствами peers
+func (r *Replica) Status(peers []string) []SyncStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]SyncStatus, 0, len(peers))
+	for _, key := range peers {
+		p := SyncPeer{Have: VersionVector{}}
+		if sp := r.peers[key]; sp != nil {
+			p = *sp
+		}
+		out = append(out, SyncStatus{
+			Peer:     key,
+			SyncPeer: p,
+			Pending:  p.Have.Lacks(r.have),
+			Missing:  r.have.Lacks(p.Have),
+		})
+	}
+	return out
+}
+
+// Stats — размер журнала операций
+func (r *Replica) Stats() (ops int, clock HLC) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.ops), r.clock.Last
+}
+
+func (r *Replica) peer(key string) *SyncPeer {
+	p := r.peers[key]
+	if p == nil {
+		p = &SyncPeer{Have: VersionVector{}}
+		r.peers[key] = p
+	}
+	return p
+}
+
+func (r *Replica) save() error {
+	data, err := json.MarshalIndent(replicaState{Clock: r.clock, Ops: r.ops, Peers: r.peers}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0600)
+}
@@ -0,0 +1,179 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type device struct {
+	j *Journal
+	r *Replica
+}
+
+func newDevice(t *testing.T, dir, node string) device {
+	t.Helper()
+	j, err := NewJournal(JournalConfig{DataDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReplica(j, dir, node, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return device{j, r}
+}
+
+// syncPair — обмен, как по сети: запрос с вектором, ответ пачками
+func syncPair(t *testing.T, a, b device) {
+	t.Helper()
+	for _, p := range [][2]device{{a, b}, {b, a}} {
+		for {
+			ops, more := p[1].r.Missing(p[0].r.Have(), 2)
+			if _, err := p[0].r.Apply(ops, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+			if !more {
+				break
+			}
+		}
+	}
+}
+
+func entryByID(t *testing.T, j *Journal, id string) (ThoughtEntry, bool) {
+	t.Helper()
+	for _, e := range j.GetEntries(EntryFilters{}) {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return ThoughtEntry{}, false
+}
+
+func TestReplica_ConvergesAfterConcurrentEdits(t *testing.T) {
+	dir := t.TempDir()
+	// Дневник с записью до включения синхронизации
+	pre, err := NewJournal(JournalConfig{DataDir: filepath.Join(dir, "laptop")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pre.AddEntry(ThoughtEntry{Type: EntryTypeReflection, Timestamp: time.Now(), Notes: "до синхронизации", Tags: []string{"old"}})
+
+	laptop := newDevice(t, filepath.Join(dir, "laptop"), "laptop")
+	server := newDevice(t, filepath.Join(dir, "server"), "server")
+	phone := newDevice(t, filepath.Join(dir, "phone"), "phone")
+
+	laptop.j.AddEntry(ThoughtEntry{Type: EntryTypeReflection, Timestamp: time.Now(), Notes: "утро", Intensity: 40, Tags: []string{"утро"}})
+	laptop.j.AddEntry(ThoughtEntry{Type: EntryTypeReflection, Timestamp: time.Now().Add(time.Second), Notes: "вечер"})
+	syncPair(t, laptop, server)
+	syncPair(t, server, phone) // телефон получает записи ноутбука через сервер
+	if got := len(phone.j.GetEntries(EntryFilters{})); got != 3 {
+		t.Fatalf("phone has %d entries, want 3", got)
+	}
+	morning := laptop.j.GetEntries(EntryFilters{})[1]
+	evening := laptop.j.GetEntries(EntryFilters{})[0]
+	if e, _ := entryByID(t, phone.j, morning.ID); !containsString(e.Tags, "утро") {
+		t.Fatalf("tags not replicated: %v", e.Tags)
+	}
+
+	// Одновременно: разные поля одной записи, добавление и снятие тега, удаление против правки
+	laptop.j.UpdateEntry(morning.ID, map[string]json.RawMessage{"notes": json.RawMessage(`"утро, спокойно"`)})
+	phone.j.UpdateEntry(morning.ID, map[string]json.RawMessage{"intensity": json.RawMessage(`70`)})
+	laptop.j.RemoveTag(morning.ID, "утро")
+	phone.j.AddTag(morning.ID, "сон")
+	laptop.j.DeleteEntry(evening.ID)
+	phone.j.UpdateEntry(evening.ID, map[string]json.RawMessage{"notes": json.RawMessage(`"поздняя правка"`)})
+
+	for i := 0; i < 2; i++ {
+		syncPair(t, laptop, server)
+		syncPair(t, server, phone)
+	}
+
+	for _, d := range []device{laptop, server, phone} {
+		e, ok := entryByID(t, d.j, morning.ID)
+		if !ok || e.Notes != "утро, спокойно" || e.Intensity != 70 {
+			t.Fatalf("%s: edits not merged: %+v", d.r.Node(), e)
+		}
+		if containsString(e.Tags, "утро") || !containsString(e.Tags, "сон") {
+			t.Fatalf("%s: tags %v, want сон without утро", d.r.Node(), e.Tags)
+		}
+		if _, ok := entryByID(t, d.j, evening.ID); ok {
+			t.Fatalf("%s: deleted entry revived by a concurrent edit", d.r.Node())
+		}
+		for _, op := range d.r.ops {
+			if op.EntryID == evening.ID && (op.Entry != nil || op.Fields != nil) {
+				t.Fatalf("%s: deleted entry content left in the op log: %+v", d.r.Node(), op)
+			}
+		}
+		if st := d.r.Status([]string{"x"})[0]; st.Pending == 0 {
+			t.Fatalf("%s: unknown peer should be missing all ops", d.r.Node())
+		}
+	}
+
+	// После перезапуска журнал и вектор сохраняются, повторы не применяются
+	again := newDevice(t, filepath.Join(dir, "phone"), "ignored")
+	if again.r.Node() != "phone" || again.r.Have().Lacks(phone.r.Have()) != 0 {
+		t.Fatalf("replica not restored: node %s", again.r.Node())
+	}
+	ops, _ := laptop.r.Missing(VersionVector{}, 1000)
+	if n, _ := again.r.Apply(ops, time.Now()); n != 0 {
+		t.Fatalf("re-applied %d known ops", n)
+	}
+}
+
+func TestReplica_MissingBoundedByEncodedSize(t *testing.T) {
+	d := newDevice(t, t.TempDir(), "a")
+	notes, _ := json.Marshal(strings.Repeat("x", MaxBatchBytes/3))
+	huge, _ := json.Marshal(strings.Repeat("x", MaxBatchBytes))
+	d.r.ops = nil
+	for i := range 5 {
+		fields := map[string]json.RawMessage{"notes": notes}
+		if i == 4 {
+			fields["notes"] = huge
+		}
+		d.r.ops = append(d.r.ops, Op{Node: "a", Seq: uint64(i + 1), Kind: OpUpdate, EntryID: "e", Fields: fields})
+	}
+
+	have := VersionVector{}
+	var sizes []int
+	for {
+		ops, more := d.r.Missing(have, MaxSyncBatch)
+		if len(ops) == 0 {
+			t.Fatal("empty batch while ops are missing")
+		}
+		data, _ := json.Marshal(ops)
+		if len(ops) > 1 && len(data) > MaxBatchBytes {
+			t.Fatalf("batch of %d ops is %d bytes", len(ops), len(data))
+		}
+		sizes = append(sizes, len(ops))
+		have["a"] = ops[len(ops)-1].Seq
+		if !more {
+			break
+		}
+	}
+	// Две операции по трети предела на пакет, слишком большая — отдельно
+	if want := []int{2, 2, 1}; fmt.Sprint(sizes) != fmt.Sprint(want) {
+		t.Fatalf("batches %v, want %v", sizes, want)
+	}
+}
+
+func TestClock_MonotonicAndBoundedSkew(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := Clock{Node: "a"}
+	first := c.Now(now)
+	second := c.Now(now.Add(-time.Minute)) // часы ушли назад
+	if second.Compare(first) <= 0 {
+		t.Fatalf("clock went backwards: %+v then %+v", first, second)
+	}
+	c.Observe(HLC{Wall: now.Add(time.Minute).UnixNano(), Logical: 3, Node: "b"}, now)
+	if got := c.Now(now); got.Wall != now.Add(time.Minute).UnixNano() || got.Logical != 4 {
+		t.Fatalf("observed remote time not respected: %+v", got)
+	}
+	c.Observe(HLC{Wall: now.Add(48 * time.Hour).UnixNano(), Node: "b"}, now)
+	if got := c.Now(now); got.Wall > now.Add(MaxClockSkew).UnixNano() {
+		t.Fatal("a far-future remote clock dragged ours along")
+	}
+}
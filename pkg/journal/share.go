@@ -0,0 +1,379 @@
+package journal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/envelope"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Общий доступ к дневнику (например, для терапевта).
+//
+// Отправитель выбирает записи (EntryIDs) и/или фильтр (Filter). Записи уходят
+// пиру зашифрованными конвертами; по фильтру уходят и новые записи, пока доступ
+// не отозван. Отзыв останавливает будущие отправки и сообщается получателю —
+// уже полученное у него остаётся (забрать отправленное нельзя).
+//
+// Получатель хранит записи в отдельном «поделились со мной» (Inbox): они не
+// смешиваются с собственным дневником и не редактируются.
+
+const sharesFile = "shares.json"
+
+// MaxShareBatch — записей в одном сообщении
+const MaxShareBatch = 25
+
+// MaxBatchBytes — предел JSON записей (операций) в одном сообщении: кадр
+// транспорта (envelope.MaxFrameSize) за вычетом запаса на заголовок конверта,
+// шифрование и остальные поля пакета
+const MaxBatchBytes = envelope.MaxFrameSize - 64<<10
+
+var (
+	ErrShareRevoked = errors.New("share has been revoked")
+	ErrEmptyShare   = errors.New("share selects no entries: give entry IDs or a filter")
+)
+
+// Share — исходящий общий доступ
+type Share struct {
+	ID        string               `json:"id"`
+	Recipient string               `json:"recipient"` // ключ пира (hex)
+	Name      string               `json:"name,omitempty"`
+	EntryIDs  []string             `json:"entry_ids,omitempty"` // выбранные записи
+	Filter    *EntryFilters        `json:"filter,omitempty"`    // новые подходящие записи тоже отправляются
+	CreatedAt time.Time            `json:"created_at"`
+	RevokedAt *time.Time           `json:"revoked_at,omitempty"`
+	Sent      map[string]time.Time `json:"sent"` // ID записи → когда передана в очередь отправки
+}
+
+// clone — копия без общих с хранилищем карт и срезов
+func (s Share) clone() Share {
+	s.EntryIDs = append([]string(nil), s.EntryIDs...)
+	s.Sent = maps.Clone(s.Sent)
+	if s.Filter != nil {
+		f := *s.Filter
+		s.Filter = &f
+	}
+	if s.RevokedAt != nil {
+		at := *s.RevokedAt
+		s.RevokedAt = &at
+	}
+	return s
+}
+
+// Active — доступ не отозван
+func (s Share) Active() bool {
+	return s.RevokedAt == nil
+}
+
+// Includes сообщает, входит ли запись в доступ
+func (s Share) Includes(e ThoughtEntry) bool {
+	if containsString(s.EntryIDs, e.ID) {
+		return true
+	}
+	return s.Filter != nil && s.Filter.Match(e)
+}
+
+// NewShare создаёт доступ для пира
+func NewShare(recipient, name string, entryIDs []string, filter *EntryFilters, now time.Time) (Share, error) {
+	if len(entryIDs) == 0 && filter == nil {
+		return Share{}, ErrEmptyShare
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Share{}, err
+	}
+	return Share{
+		ID:        hex.EncodeToString(id),
+		Recipient: recipient,
+		Name:      name,
+		EntryIDs:  entryIDs,
+		Filter:    filter,
+		CreatedAt: now,
+		Sent:      make(map[string]time.Time),
+	}, nil
+}
+
+// Pending возвращает записи доступа, ещё не отправленные (старые первыми)
+func (j *Journal) Pending(s Share) []ThoughtEntry {
+	if !s.Active() {
+		return nil
+	}
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	var out []ThoughtEntry
+	for _, e := range j.entries {
+		if _, sent := s.Sent[e.ID]; !sent && s.Includes(e) {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Timestamp.Before(out[b].Timestamp) })
+	return out
+}
+
+// SharePayload — сообщение с записями (envelope.TypeJournalShare)
+type SharePayload struct {
+	ShareID string         `json:"share_id"`
+	Name    string         `json:"name,omitempty"`
+	Entries []ThoughtEntry `json:"entries"`
+}
+
+// ShareRevocation — уведомление об отзыве (envelope.TypeJournalRevoke)
+type ShareRevocation struct {
+	ShareID   string    `json:"share_id"`
+	Reason    string    `json:"reason,omitempty"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// Batches делит записи на сообщения: не больше MaxShareBatch записей и
+// MaxBatchBytes в JSON. Запись крупнее предела уходит отдельным сообщением.
+func Batches(entries []ThoughtEntry) [][]ThoughtEntry {
+	var out [][]ThoughtEntry
+	for len(entries) > 0 {
+		n, size := 0, 1 // «[]» и запятые: 1 + по байту на запись
+		for n < len(entries) && n < MaxShareBatch {
+			data, _ := json.Marshal(entries[n])
+			if n > 0 && size+len(data)+1 > MaxBatchBytes {
+				break
+			}
+			size += len(data) + 1
+			n++
+		}
+		out = append(out, entries[:n])
+		entries = entries[n:]
+	}
+	return out
+}
+
+// InboxShare — записи, которыми с нами поделились (только чтение)
+type InboxShare struct {
+	ShareID    string         `json:"share_id"`
+	From       string         `json:"from"` // ключ отправителя (hex)
+	Name       string         `json:"name,omitempty"`
+	Entries    []ThoughtEntry `json:"entries"`
+	ReceivedAt time.Time      `json:"received_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	RevokedAt  *time.Time     `json:"revoked_at,omitempty"`
+	Reason     string         `json:"revoke_reason,omitempty"`
+}
+
+// clone — копия без общего с хранилищем среза записей (Receive дописывает
+// и сортирует его на месте)
+func (in InboxShare) clone() InboxShare {
+	in.Entries = append([]ThoughtEntry(nil), in.Entries...)
+	if in.RevokedAt != nil {
+		at := *in.RevokedAt
+		in.RevokedAt = &at
+	}
+	return in
+}
+
+// Shares — исходящие доступы и входящие записи (shares.json в каталоге данных)
+type Shares struct {
+	mu   sync.Mutex
+	path string
+	data struct {
+		Outgoing []Share      `json:"outgoing"`
+		Inbox    []InboxShare `json:"inbox"`
+	}
+}
+
+// NewShares открывает хранилище в каталоге dataDir
+func NewShares(dataDir string) (*Shares, error) {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, err
+	}
+	s := &Shares{path: filepath.Join(dataDir, sharesFile)}
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &s.data); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", s.path, err)
+		}
+	}
+	return s, nil
+}
+
+// Add сохраняет новый исходящий доступ
+func (s *Shares) Add(sh Share) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Outgoing = append(s.data.Outgoing, sh)
+	return s.save()
+}
+
+// List возвращает копии исходящих доступов; active — только неотозванные
+func (s *Shares) List(active bool) []Share {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Share
+	for _, sh := range s.data.Outgoing {
+		if !active || sh.Active() {
+			out = append(out, sh.clone())
+		}
+	}
+	return out
+}
+
+// Get возвращает копию исходящего доступа по ID
+func (s *Shares) Get(id string) (Share, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.outgoing(id); i >= 0 {
+		return s.data.Outgoing[i].clone(), nil
+	}
+	return Share{}, fmt.Errorf("share %s: %w", id, os.ErrNotExist)
+}
+
+// MarkSent отмечает записи переданными на отправку
+func (s *Shares) MarkSent(id string, entryIDs []string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.outgoing(id)
+	if i < 0 {
+		return fmt.Errorf("share %s: %w", id, os.ErrNotExist)
+	}
+	sh := &s.data.Outgoing[i]
+	if sh.Sent == nil {
+		sh.Sent = make(map[string]time.Time)
+	}
+	for _, e := range entryIDs {
+		sh.Sent[e] = now
+	}
+	return s.save()
+}
+
+// Revoke отзывает доступ (повторный отзыв возвращает ту же запись)
+func (s *Shares) Revoke(id string, now time.Time) (Share, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.outgoing(id)
+	if i < 0 {
+		return Share{}, fmt.Errorf("share %s: %w", id, os.ErrNotExist)
+	}
+	if s.data.Outgoing[i].RevokedAt == nil {
+		s.data.Outgoing[i].RevokedAt = &now
+		if err := s.save(); err != nil {
+			return Share{}, err
+		}
+	}
+	return s.data.Outgoing[i].clone(), nil
+}
+
+// Receive принимает записи от пира from. Записи с тем же ID заменяются.
+// После отзыва доступа новые записи по нему не принимаются.
+func (s *Shares) Receive(from string, p SharePayload, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.inbox(from, p.ShareID)
+	if i < 0 {
+		s.data.Inbox = append(s.data.Inbox, InboxShare{ShareID: p.ShareID, From: from, ReceivedAt: now})
+		i = len(s.data.Inbox) - 1
+	}
+	in := &s.data.Inbox[i]
+	if in.RevokedAt != nil {
+		return fmt.Errorf("share %s: %w", p.ShareID, ErrShareRevoked)
+	}
+	if p.Name != "" {
+		in.Name = p.Name
+	}
+	for _, e := range p.Entries {
+		replaced := false
+		for k := range in.Entries {
+			if in.Entries[k].ID == e.ID {
+				in.Entries[k], replaced = e, true
+				break
+			}
+		}
+		if !replaced {
+			in.Entries = append(in.Entries, e)
+		}
+	}
+	sort.Slice(in.Entries, func(a, b int) bool { return in.Entries[a].Timestamp.After(in.Entries[b].Timestamp) })
+	in.UpdatedAt = now
+	return s.save()
+}
+
+// ReceiveRevocation отмечает входящий доступ отозванным
+func (s *Shares) ReceiveRevocation(from string, r ShareRevocation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.inbox(from, r.ShareID)
+	if i < 0 {
+		return fmt.Errorf("share %s: %w", r.ShareID, os.ErrNotExist)
+	}
+	if s.data.Inbox[i].RevokedAt == nil {
+		at := r.RevokedAt
+		s.data.Inbox[i].RevokedAt = &at
+		s.data.Inbox[i].Reason = r.Reason
+	}
+	return s.save()
+}
+
+// Inbox возвращает копии входящих доступов (последние обновлённые первыми)
+func (s *Shares) Inbox() []InboxShare {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]InboxShare, len(s.data.Inbox))
+	for i, in := range s.data.Inbox {
+		out[i] = in.clone()
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].UpdatedAt.After(out[b].UpdatedAt) })
+	return out
+}
+
+// InboxShare возвращает копию входящего доступа
+func (s *Shares) InboxShare(from, id string) (InboxShare, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.inbox(from, id); i >= 0 {
+		return s.data.Inbox[i].clone(), nil
+	}
+	return InboxShare{}, fmt.Errorf("share %s: %w", id, os.ErrNotExist)
+}
+
+// RemoveInbox удаляет входящий доступ вместе с записями
+func (s *Shares) RemoveInbox(from, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.inbox(from, id)
+	if i < 0 {
+		return fmt.Errorf("share %s: %w", id, os.ErrNotExist)
+	}
+	s.data.Inbox = append(s.data.Inbox[:i], s.data.Inbox[i+1:]...)
+	return s.save()
+}
+
+func (s *Shares) outgoing(id string) int {
+	for i, sh := range s.data.Outgoing {
+		if sh.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Shares) inbox(from, id string) int {
+	for i, in := range s.data.Inbox {
+		if in.From == from && in.ShareID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Shares) save() error {
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
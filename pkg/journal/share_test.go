@@ -0,0 +1,163 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShare_PendingFollowsFilter(t *testing.T) {
+	j, err := NewJournal(JournalConfig{DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	j.AddEntry(ThoughtEntry{Type: EntryTypeReflection, Timestamp: start, Notes: "до терапии"})
+	j.AddEntry(ThoughtEntry{Type: EntryTypeReflection, Timestamp: start.Add(48 * time.Hour), Notes: "первая сессия"})
+	old := j.GetEntries(EntryFilters{})[1]
+
+	var added []ThoughtEntry
+	j.OnEntryAdded(func(e ThoughtEntry) { added = append(added, e) })
+
+	from := start.Add(24 * time.Hour)
+	sh, err := NewShare("peer", "терапия", []string{old.ID}, &EntryFilters{Type: string(EntryTypeReflection), FromDate: &from}, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := j.Pending(sh); len(got) != 2 || got[0].ID != old.ID {
+		t.Fatalf("expected the selected entry and the filtered one, got %d", len(got))
+	}
+	for _, e := range j.Pending(sh) {
+		sh.Sent[e.ID] = start
+	}
+
+	// Новая подходящая запись попадает в доступ, неподходящая — нет
+	j.AddEntry(ThoughtEntry{Type: EntryTypeReflection, Timestamp: start.Add(72 * time.Hour), Notes: "после"})
+	j.AddGratitudeEntry([]GratitudeItem{{Text: "солнце"}}, "")
+	if len(added) != 2 {
+		t.Fatalf("hook called %d times, want 2", len(added))
+	}
+	pending := j.Pending(sh)
+	if len(pending) != 1 || pending[0].Notes != "после" {
+		t.Fatalf("unexpected pending entries: %+v", pending)
+	}
+
+	now := start.Add(96 * time.Hour)
+	sh.RevokedAt = &now
+	if len(j.Pending(sh)) != 0 {
+		t.Fatal("revoked share still has pending entries")
+	}
+
+	if _, err := NewShare("peer", "", nil, nil, start); !errors.Is(err, ErrEmptyShare) {
+		t.Fatalf("expected ErrEmptyShare, got %v", err)
+	}
+}
+
+func TestShares_InboxIsReadOnlyAfterRevoke(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewShares(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	e1 := ThoughtEntry{ID: "e1", Timestamp: now, Notes: "v1"}
+	e2 := ThoughtEntry{ID: "e2", Timestamp: now.Add(time.Hour)}
+
+	if err := s.Receive("alice", SharePayload{ShareID: "s1", Name: "терапия", Entries: []ThoughtEntry{e1}}, now); err != nil {
+		t.Fatal(err)
+	}
+	e1.Notes = "v2"
+	if err := s.Receive("alice", SharePayload{ShareID: "s1", Entries: []ThoughtEntry{e1, e2}}, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReceiveRevocation("alice", ShareRevocation{ShareID: "s1", Reason: "пауза", RevokedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	err = s.Receive("alice", SharePayload{ShareID: "s1", Entries: []ThoughtEntry{{ID: "e3"}}}, now)
+	if !errors.Is(err, ErrShareRevoked) {
+		t.Fatalf("expected ErrShareRevoked, got %v", err)
+	}
+
+	reopened, _ := NewShares(dir)
+	in, err := reopened.InboxShare("alice", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(in.Entries) != 2 || in.Entries[1].Notes != "v2" || in.RevokedAt == nil || in.Name != "терапия" {
+		t.Fatalf("unexpected inbox share: %+v", in)
+	}
+	if _, err := reopened.InboxShare("mallory", "s1"); err == nil {
+		t.Fatal("share ID must be scoped to the sender")
+	}
+}
+
+func TestShares_AccessorsReturnCopies(t *testing.T) {
+	s, err := NewShares(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	sh, _ := NewShare("peer", "", []string{"e0"}, nil, now)
+	if err := s.Add(sh); err != nil {
+		t.Fatal(err)
+	}
+	s.Receive("alice", SharePayload{ShareID: "s1", Entries: []ThoughtEntry{{ID: "e0", Timestamp: now}}}, now)
+
+	// Чтение копий параллельно с MarkSent и Receive (go test -race)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := range 50 {
+			id := fmt.Sprintf("e%d", i)
+			s.MarkSent(sh.ID, []string{id}, now)
+			s.Receive("alice", SharePayload{ShareID: "s1", Entries: []ThoughtEntry{{ID: id, Timestamp: now.Add(time.Duration(i) * time.Minute)}}}, now)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 50 {
+			for _, got := range s.List(false) {
+				json.Marshal(got)
+				_ = got.Sent["e1"]
+			}
+			for _, in := range s.Inbox() {
+				json.Marshal(in)
+			}
+		}
+	}()
+	wg.Wait()
+
+	got, _ := s.Get(sh.ID)
+	got.Sent["forged"] = now
+	if again, _ := s.Get(sh.ID); len(again.Sent) != 50 {
+		t.Fatalf("caller's change leaked into the store: %d sent", len(again.Sent))
+	}
+}
+
+func TestBatches_BoundedByEncodedSize(t *testing.T) {
+	big := strings.Repeat("я", MaxBatchBytes/8)
+	var entries []ThoughtEntry
+	for i := range 10 {
+		entries = append(entries, ThoughtEntry{ID: fmt.Sprint(i), Notes: big})
+	}
+	batches := Batches(entries)
+	total := 0
+	for _, b := range batches {
+		data, _ := json.Marshal(b)
+		if len(b) == 0 || len(data) > MaxBatchBytes {
+			t.Fatalf("batch of %d entries, %d bytes", len(b), len(data))
+		}
+		total += len(b)
+	}
+	if total != len(entries) || len(batches) < 3 {
+		t.Fatalf("%d entries in %d batches", total, len(batches))
+	}
+	if n := len(Batches(make([]ThoughtEntry, 2*MaxShareBatch+1))); n != 3 {
+		t.Fatalf("small entries: %d batches, want 3", n)
+	}
+}
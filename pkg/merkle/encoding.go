@@ -0,0 +1,156 @@
+package merkle
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Двоичная запись доказательств:
+//
+//	включение:       0x01 | uvarint Index | uvarint Size | uvarint n | n × (сторона 1 байт | хеш 32 байта)
+//	согласованность: 0x02 | uvarint OldSize | uvarint NewSize | uvarint n | n × хеш 32 байта
+//
+// Глубина дерева не больше 64, поэтому путь длиннее — заведомо мусор.
+
+const (
+	kindInclusion   byte = 0x01
+	kindConsistency byte = 0x02
+
+	maxInclusionPath   = 64
+	maxConsistencyPath = 2 * 64
+)
+
+// MarshalBinary кодирует доказательство включения
+func (p *InclusionProof) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 1+3*binary.MaxVarintLen64+len(p.Path)*(1+HashSize))
+	buf = append(buf, kindInclusion)
+	buf = binary.AppendUvarint(buf, p.Index)
+	buf = binary.AppendUvarint(buf, p.Size)
+	buf = binary.AppendUvarint(buf, uint64(len(p.Path)))
+	for _, step := range p.Path {
+		buf = append(buf, byte(step.Side))
+		buf = append(buf, step.Hash[:]...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary разбирает доказательство включения
+func (p *InclusionProof) UnmarshalBinary(data []byte) error {
+	r := reader{data: data}
+	r.kind(kindInclusion)
+	index, size, n := r.uvarint(), r.uvarint(), r.count(maxInclusionPath)
+	path := make([]ProofStep, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		side := Side(r.byte())
+		if r.err == nil && side != Left && side != Right {
+			r.err = fmt.Errorf("step %d: bad side %d", i, side)
+		}
+		path = append(path, ProofStep{Side: side, Hash: r.hash()})
+	}
+	if err := r.done(); err != nil {
+		return err
+	}
+	*p = InclusionProof{Index: index, Size: size, Path: path}
+	return nil
+}
+
+// MarshalBinary кодирует доказательство согласованности
+func (p *ConsistencyProof) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 1+3*binary.MaxVarintLen64+len(p.Path)*HashSize)
+	buf = append(buf, kindConsistency)
+	buf = binary.AppendUvarint(buf, p.OldSize)
+	buf = binary.AppendUvarint(buf, p.NewSize)
+	buf = binary.AppendUvarint(buf, uint64(len(p.Path)))
+	for _, h := range p.Path {
+		buf = append(buf, h[:]...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary разбирает доказательство согласованности
+func (p *ConsistencyProof) UnmarshalBinary(data []byte) error {
+	r := reader{data: data}
+	r.kind(kindConsistency)
+	oldSize, newSize, n := r.uvarint(), r.uvarint(), r.count(maxConsistencyPath)
+	path := make([]Hash, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		path = append(path, r.hash())
+	}
+	if err := r.done(); err != nil {
+		return err
+	}
+	*p = ConsistencyProof{OldSize: oldSize, NewSize: newSize, Path: path}
+	return nil
+}
+
+// reader читает поля по порядку; первая ошибка останавливает разбор
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) kind(want byte) {
+	if b := r.byte(); r.err == nil && b != want {
+		r.err = fmt.Errorf("proof type 0x%02x, want 0x%02x", b, want)
+	}
+}
+
+func (r *reader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.data) == 0 {
+		r.err = fmt.Errorf("truncated proof")
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = fmt.Errorf("bad varint in proof")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *reader) count(limit int) int {
+	n := r.uvarint()
+	if r.err == nil && n > uint64(limit) {
+		r.err = fmt.Errorf("proof path of %d hashes exceeds %d", n, limit)
+		return 0
+	}
+	return int(n)
+}
+
+func (r *reader) hash() Hash {
+	var h Hash
+	if r.err != nil {
+		return h
+	}
+	if len(r.data) < HashSize {
+		r.err = fmt.Errorf("truncated proof")
+		return h
+	}
+	copy(h[:], r.data)
+	r.data = r.data[HashSize:]
+	return h
+}
+
+// done — ошибка разбора или лишние байты в конце
+func (r *reader) done() error {
+	if r.err == nil && len(r.data) > 0 {
+		r.err = fmt.Errorf("%d trailing bytes after proof", len(r.data))
+	}
+	if r.err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProof, r.err)
+	}
+	return nil
+}
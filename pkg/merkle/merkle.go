@@ -7,6 +7,61 @@ import (
 	"sort"
 )
 
+// Дерево Меркла в форме RFC 6962 / RFC 9162 (Certificate Transparency):
+//
+//   - лист:  H(0x00 || данные), внутренний узел: H(0x01 || левый || правый) —
+//     разные префиксы не дают выдать внутренний узел за лист (second preimage);
+//   - n листьев делятся на первые k (наибольшая степень двойки < n) и остальные;
+//     последний узел нечётного уровня не дублируется, а поднимается выше.
+//
+// При такой форме дерево из первых m листьев — часть дерева из n листьев,
+// поэтому кроме доказательств включения есть доказательства согласованности
+// (дерево только дописывалось, ничего не переписано).
+
+const (
+	LeafPrefix byte = 0x00 // префикс хеша листа
+	NodePrefix byte = 0x01 // префикс хеша внутреннего узла
+)
+
+// HashSize — размер хеша (SHA-256)
+const HashSize = sha256.Size
+
+// Hash — хеш листа или узла
+type Hash [HashSize]byte
+
+// String — hex-запись хеша
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// ParseHash разбирает hex-запись хеша
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return h, fmt.Errorf("hash is not hex: %w", err)
+	}
+	if len(b) != HashSize {
+		return h, fmt.Errorf("hash must be %d bytes, got %d", HashSize, len(b))
+	}
+	copy(h[:], b)
+	return h, nil
+}
+
+// LeafHash — хеш листа с данными data
+func LeafHash(data []byte) Hash {
+	return sha256.Sum256(append([]byte{LeafPrefix}, data...))
+}
+
+// NodeHash — хеш внутреннего узла
+func NodeHash(left, right Hash) Hash {
+	buf := make([]byte, 0, 1+2*HashSize)
+	buf = append(buf, NodePrefix)
+	buf = append(buf, left[:]...)
+	buf = append(buf, right[:]...)
+	return sha256.Sum256(buf)
+}
+
 // MerkleNode представляет узел дерева Меркла
 type MerkleNode struct {
 	Hash  string
@@ -15,57 +70,53 @@ type MerkleNode struct {
 	Data  string // ID связи или человека
 }
 
-// BuildTree строит дерево Меркла из списка хешей связей
+// BuildTree строит дерево Меркла из списка хешей связей (данные листьев)
 func BuildTree(hashes []string) *MerkleNode {
 	if len(hashes) == 0 {
 		return nil
 	}
-	
-	// Сортируем для детерминизма
-	sort.Strings(hashes)
-	
+
+	// Сортируем для детерминизма (копию — список вызывающего не трогаем)
+	sorted := append([]string(nil), hashes...)
+	sort.Strings(sorted)
+
 	// Создаём листовые узлы
-	var nodes []*MerkleNode
-	for _, h := range hashes {
-		nodes = append(nodes, &MerkleNode{Hash: h, Data: h})
+	nodes := make([]*MerkleNode, len(sorted))
+	for i, h := range sorted {
+		nodes[i] = &MerkleNode{Hash: LeafHash([]byte(h)).String(), Data: h}
 	}
-	
-	// Строим дерево по уровням
-	for len(nodes) > 1 {
-		var level []*MerkleNode
-		for i := 0; i < len(nodes); i += 2 {
-			left := nodes[i]
-			var right *MerkleNode
-			if i+1 < len(nodes) {
-				right = nodes[i+1]
-			} else {
-				// Нечётное количество: дублируем последний
-				right = left
-			}
-			combined := left.Hash + right.Hash
-			hash := sha256.Sum256([]byte(combined))
-			level = append(level, &MerkleNode{
-				Hash:  hex.EncodeToString(hash[:]),
-				Left:  left,
-				Right: right,
-			})
-		}
-		nodes = level
+	return buildNodes(nodes)
+}
+
+// buildNodes собирает поддерево над листьями (разбиение RFC 6962)
+func buildNodes(nodes []*MerkleNode) *MerkleNode {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	k := splitPoint(len(nodes))
+	left, right := buildNodes(nodes[:k]), buildNodes(nodes[k:])
+	return &MerkleNode{
+		Hash:  NodeHash(mustParse(left.Hash), mustParse(right.Hash)).String(),
+		Left:  left,
+		Right: right,
+	}
+}
+
+func mustParse(s string) Hash {
+	h, err := ParseHash(s)
+	if err != nil {
+		panic(err)
 	}
-	
-	return nodes[0]
+	return h
 }
 
-// Verify проверяет, входит ли хеш в дерево с данным root
-// rootHash и targetHash — строки (hex), proof — слайс строк (путь доказательства)
-func Verify(rootHash, targetHash string, proof []string) bool {
-	current := targetHash
-	for _, sibling := range proof {
-		combined := current + sibling
-		hash := sha256.Sum256([]byte(combined))
-		current = hex.EncodeToString(hash[:])
+// Verify проверяет, входит ли хеш связи targetHash в дерево с корнем rootHash (hex)
+func Verify(rootHash, targetHash string, proof *InclusionProof) bool {
+	root, err := ParseHash(rootHash)
+	if err != nil || proof == nil {
+		return false
 	}
-	return current == rootHash
+	return VerifyInclusion(root, LeafHash([]byte(targetHash)), proof) == nil
 }
 
 // HashRelation создаёт хеш для связи между двумя людьми
@@ -75,9 +126,38 @@ func HashRelation(fromID, toID, relationType string, timestamp int64) string {
 	return hex.EncodeToString(hash[:])
 }
 
-// GetMerkleProof возвращает упрощённое "доказательство" пути (заглушка для MVP)
-// В полной реализации здесь был бы обход дерева для сбора sibling-хешей
-func GetMerkleProof(root *MerkleNode, targetHash string) []string {
-	// MVP: возвращаем пустой proof — валидация будет полной при наличии root
-	return []string{}
+// GetMerkleProof строит доказательство включения хеша связи в дерево из BuildTree
+func GetMerkleProof(root *MerkleNode, targetHash string) (*InclusionProof, error) {
+	if root == nil {
+		return nil, fmt.Errorf("%w: empty tree", ErrNotFound)
+	}
+	proof := &InclusionProof{Size: uint64(countLeaves(root))}
+	if !collectPath(root, targetHash, proof) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, targetHash)
+	}
+	return proof, nil
+}
+
+// collectPath ищет лист и дописывает соседей от листа к корню
+func collectPath(n *MerkleNode, target string, proof *InclusionProof) bool {
+	if n.Left == nil && n.Right == nil {
+		return n.Data == target
+	}
+	if collectPath(n.Left, target, proof) {
+		proof.Path = append(proof.Path, ProofStep{Hash: mustParse(n.Right.Hash), Side: Right})
+		return true
+	}
+	if collectPath(n.Right, target, proof) {
+		proof.Index += uint64(countLeaves(n.Left))
+		proof.Path = append(proof.Path, ProofStep{Hash: mustParse(n.Left.Hash), Side: Left})
+		return true
+	}
+	return false
+}
+
+func countLeaves(n *MerkleNode) int {
+	if n.Left == nil && n.Right == nil {
+		return 1
+	}
+	return countLeaves(n.Left) + countLeaves(n.Right)
 }
@@ -1,6 +1,11 @@
 package merkle
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+)
 
 func TestBuildTree(t *testing.T) {
 	hashes := []string{"a", "b", "c"}
@@ -25,3 +30,158 @@ func TestHashRelation(t *testing.T) {
 		t.Error("Hash should be deterministic")
 	}
 }
+
+// Векторы RFC 6962 / certificate-transparency
+func TestHashes_Reference(t *testing.T) {
+	if got := rootOf(nil).String(); got != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("empty root %s", got)
+	}
+	if got := LeafHash(nil).String(); got != "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d" {
+		t.Errorf("empty leaf %s", got)
+	}
+	// Склейка двух листьев как один лист не даёт тот же корень
+	a, b := LeafHash([]byte("a")), LeafHash([]byte("b"))
+	forged := NewTree([][]byte{append(a[:], b[:]...)})
+	if forged.Root() == NewTree([][]byte{[]byte("a"), []byte("b")}).Root() {
+		t.Error("an inner node passes as a leaf")
+	}
+}
+
+func testTree(n int) *Tree {
+	tree := &Tree{}
+	for i := 0; i < n; i++ {
+		tree.Append([]byte(fmt.Sprintf("leaf-%d", i)))
+	}
+	return tree
+}
+
+func TestTree_InclusionProofs(t *testing.T) {
+	tree := testTree(21)
+	for size := uint64(1); size <= tree.Size(); size++ {
+		root, _ := tree.RootAt(size)
+		for i := uint64(0); i < size; i++ {
+			p, err := tree.InclusionProof(i, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			leaf := tree.leaves[i]
+			data, _ := p.MarshalBinary()
+			var decoded InclusionProof
+			if err := decoded.UnmarshalBinary(data); err != nil {
+				t.Fatalf("%d/%d: %v", i, size, err)
+			}
+			if err := VerifyInclusion(root, leaf, &decoded); err != nil {
+				t.Fatalf("%d/%d: %v", i, size, err)
+			}
+			if len(p.Path) == 0 {
+				continue
+			}
+			// Тот же путь с другой стороной соседа или для другого индекса не проходит
+			flipped := *p
+			flipped.Path = append([]ProofStep(nil), p.Path...)
+			flipped.Path[0].Side ^= 1
+			moved := *p
+			moved.Index = (i + 1) % size
+			for _, bad := range []*InclusionProof{&flipped, &moved} {
+				if err := VerifyInclusion(root, leaf, bad); !errors.Is(err, ErrInvalidProof) {
+					t.Fatalf("%d/%d: forged proof accepted: %+v", i, size, bad)
+				}
+			}
+		}
+	}
+	if _, err := tree.InclusionProof(5, 5); !errors.Is(err, ErrNotFound) {
+		t.Errorf("index outside the tree: %v", err)
+	}
+}
+
+func TestTree_ConsistencyProofs(t *testing.T) {
+	tree := testTree(21)
+	for m := uint64(1); m <= tree.Size(); m++ {
+		oldRoot, _ := tree.RootAt(m)
+		for n := m; n <= tree.Size(); n++ {
+			newRoot, _ := tree.RootAt(n)
+			p, err := tree.ConsistencyProof(m, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := p.MarshalBinary()
+			var decoded ConsistencyProof
+			if err := decoded.UnmarshalBinary(data); err != nil {
+				t.Fatalf("%d→%d: %v", m, n, err)
+			}
+			if err := VerifyConsistency(oldRoot, newRoot, &decoded); err != nil {
+				t.Fatalf("%d→%d: %v", m, n, err)
+			}
+			if m == n {
+				continue
+			}
+			// Переписанная история: другой старый корень
+			rewritten := testTree(int(m))
+			rewritten.leaves[0] = LeafHash([]byte("rewritten"))
+			if err := VerifyConsistency(rewritten.Root(), newRoot, p); err == nil {
+				t.Fatalf("%d→%d: rewritten history accepted", m, n)
+			}
+			if err := VerifyConsistency(newRoot, oldRoot, p); err == nil {
+				t.Fatalf("%d→%d: swapped roots accepted", m, n)
+			}
+		}
+	}
+}
+
+func TestGetMerkleProof(t *testing.T) {
+	hashes := []string{
+		HashRelation("u1", "u2", "MOTHER", 1),
+		HashRelation("u1", "u3", "FRIEND", 2),
+		HashRelation("u2", "u3", "SISTER", 3),
+		HashRelation("u3", "u4", "COLLEAGUE", 4),
+		HashRelation("u4", "u5", "FRIEND", 5),
+	}
+	root := BuildTree(hashes)
+
+	sorted := append([]string(nil), hashes...)
+	sort.Strings(sorted)
+	data := make([][]byte, len(sorted))
+	for i, h := range sorted {
+		data[i] = []byte(h)
+	}
+	if want := NewTree(data).Root().String(); root.Hash != want {
+		t.Fatalf("BuildTree root %s, want %s", root.Hash, want)
+	}
+
+	for _, h := range hashes {
+		proof, err := GetMerkleProof(root, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !Verify(root.Hash, h, proof) {
+			t.Errorf("proof for %s does not verify", h[:8])
+		}
+		if Verify(root.Hash, HashRelation("u9", "u9", "X", 9), proof) {
+			t.Error("proof verifies a foreign leaf")
+		}
+	}
+	if _, err := GetMerkleProof(root, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing leaf: %v", err)
+	}
+}
+
+func TestProof_UnmarshalRejectsGarbage(t *testing.T) {
+	p, _ := testTree(7).InclusionProof(3, 7)
+	data, _ := p.MarshalBinary()
+	cases := map[string][]byte{
+		"truncated": data[:len(data)-1],
+		"trailing":  append(append([]byte(nil), data...), 0),
+		"bad side":  append(append([]byte(nil), data[:4]...), append([]byte{7}, data[5:]...)...),
+		"huge path": {kindInclusion, 3, 7, 0xff, 0x01},
+	}
+	for name, b := range cases {
+		var got InclusionProof
+		if err := got.UnmarshalBinary(b); !errors.Is(err, ErrInvalidProof) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	var c ConsistencyProof
+	if err := c.UnmarshalBinary(data); !errors.Is(err, ErrInvalidProof) {
+		t.Errorf("inclusion proof parsed as consistency: %v", err)
+	}
+}
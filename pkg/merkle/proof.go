@@ -0,0 +1,251 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+var (
+	ErrNotFound     = errors.New("leaf not in tree")
+	ErrInvalidProof = errors.New("invalid merkle proof")
+)
+
+// Side — с какой стороны от текущего хеша стоит сосед
+type Side byte
+
+const (
+	Left  Side = 0 // сосед слева: H(0x01 || сосед || текущий)
+	Right Side = 1 // сосед справа: H(0x01 || текущий || сосед)
+)
+
+func (s Side) String() string {
+	if s == Left {
+		return "left"
+	}
+	return "right"
+}
+
+// ProofStep — соседний хеш на пути от листа к корню
+type ProofStep struct {
+	Hash Hash
+	Side Side
+}
+
+// InclusionProof — доказательство, что лист Index входит в дерево из Size листьев
+type InclusionProof struct {
+	Index uint64
+	Size  uint64
+	Path  []ProofStep // от листа к корню
+}
+
+// ConsistencyProof — доказательство, что дерево из OldSize листьев —
+// начало дерева из NewSize листьев
+type ConsistencyProof struct {
+	OldSize uint64
+	NewSize uint64
+	Path    []Hash
+}
+
+// Tree — дерево с упорядоченными листьями, только дописывается
+type Tree struct {
+	leaves []Hash
+}
+
+// NewTree строит дерево из данных листьев по порядку
+func NewTree(data [][]byte) *Tree {
+	t := &Tree{}
+	for _, d := range data {
+		t.Append(d)
+	}
+	return t
+}
+
+// Append дописывает лист и возвращает его индекс
+func (t *Tree) Append(data []byte) uint64 {
+	t.leaves = append(t.leaves, LeafHash(data))
+	return uint64(len(t.leaves) - 1)
+}
+
+// Size — число листьев
+func (t *Tree) Size() uint64 {
+	return uint64(len(t.leaves))
+}
+
+// Root — корень текущего дерева
+func (t *Tree) Root() Hash {
+	return rootOf(t.leaves)
+}
+
+// RootAt — корень дерева из первых size листьев
+func (t *Tree) RootAt(size uint64) (Hash, error) {
+	if size > t.Size() {
+		return Hash{}, fmt.Errorf("tree has %d leaves, asked for %d", t.Size(), size)
+	}
+	return rootOf(t.leaves[:size]), nil
+}
+
+// InclusionProof — доказательство включения листа index в дерево из первых size листьев
+func (t *Tree) InclusionProof(index, size uint64) (*InclusionProof, error) {
+	if size > t.Size() || index >= size {
+		return nil, fmt.Errorf("%w: leaf %d of %d (tree has %d)", ErrNotFound, index, size, t.Size())
+	}
+	return &InclusionProof{Index: index, Size: size, Path: inclusionPath(index, t.leaves[:size])}, nil
+}
+
+// ConsistencyProof — доказательство, что дерево из oldSize листьев — начало дерева из newSize
+func (t *Tree) ConsistencyProof(oldSize, newSize uint64) (*ConsistencyProof, error) {
+	if oldSize == 0 || oldSize > newSize || newSize > t.Size() {
+		return nil, fmt.Errorf("consistency between %d and %d leaves (tree has %d)", oldSize, newSize, t.Size())
+	}
+	p := &ConsistencyProof{OldSize: oldSize, NewSize: newSize}
+	if oldSize < newSize {
+		p.Path = subproof(oldSize, t.leaves[:newSize], true)
+	}
+	return p, nil
+}
+
+// rootOf — MTH из RFC 9162 (пустое дерево — хеш пустой строки)
+func rootOf(leaves []Hash) Hash {
+	switch len(leaves) {
+	case 0:
+		return emptyRoot
+	case 1:
+		return leaves[0]
+	}
+	k := splitPoint(len(leaves))
+	return NodeHash(rootOf(leaves[:k]), rootOf(leaves[k:]))
+}
+
+var emptyRoot = Hash(sha256.Sum256(nil))
+
+// splitPoint — наибольшая степень двойки, меньшая n (n > 1)
+func splitPoint(n int) int {
+	return 1 << (bits.Len(uint(n-1)) - 1)
+}
+
+// inclusionPath — PATH(m, D[n]) из RFC 9162 со сторонами соседей
+func inclusionPath(m uint64, leaves []Hash) []ProofStep {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	k := uint64(splitPoint(len(leaves)))
+	if m < k {
+		return append(inclusionPath(m, leaves[:k]), ProofStep{Hash: rootOf(leaves[k:]), Side: Right})
+	}
+	return append(inclusionPath(m-k, leaves[k:]), ProofStep{Hash: rootOf(leaves[:k]), Side: Left})
+}
+
+// subproof — SUBPROOF(m, D[n], b) из RFC 9162
+func subproof(m uint64, leaves []Hash, complete bool) []Hash {
+	n := uint64(len(leaves))
+	if m == n {
+		if complete {
+			return nil
+		}
+		return []Hash{rootOf(leaves)}
+	}
+	k := uint64(splitPoint(len(leaves)))
+	if m <= k {
+		return append(subproof(m, leaves[:k], complete), rootOf(leaves[k:]))
+	}
+	return append(subproof(m-k, leaves[k:], false), rootOf(leaves[:k]))
+}
+
+// Root вычисляет корень по хешу листа. Стороны соседей обязаны совпадать
+// с положением листа Index в дереве из Size листьев.
+func (p *InclusionProof) Root(leaf Hash) (Hash, error) {
+	if p.Index >= p.Size {
+		return Hash{}, fmt.Errorf("%w: index %d outside tree of %d", ErrInvalidProof, p.Index, p.Size)
+	}
+	fn, sn := p.Index, p.Size-1
+	r := leaf
+	for i, step := range p.Path {
+		if sn == 0 {
+			return Hash{}, fmt.Errorf("%w: path longer than the tree is deep", ErrInvalidProof)
+		}
+		if fn&1 == 1 || fn == sn {
+			if step.Side != Left {
+				return Hash{}, fmt.Errorf("%w: step %d: sibling must be on the left", ErrInvalidProof, i)
+			}
+			r = NodeHash(step.Hash, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			if step.Side != Right {
+				return Hash{}, fmt.Errorf("%w: step %d: sibling must be on the right", ErrInvalidProof, i)
+			}
+			r = NodeHash(r, step.Hash)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return Hash{}, fmt.Errorf("%w: path too short", ErrInvalidProof)
+	}
+	return r, nil
+}
+
+// VerifyInclusion проверяет, что лист с хешем leaf входит в дерево с корнем root
+func VerifyInclusion(root, leaf Hash, p *InclusionProof) error {
+	got, err := p.Root(leaf)
+	if err != nil {
+		return err
+	}
+	if got != root {
+		return fmt.Errorf("%w: root mismatch", ErrInvalidProof)
+	}
+	return nil
+}
+
+// VerifyConsistency проверяет, что дерево с корнем newRoot получено из дерева
+// с корнем oldRoot только дописыванием листьев
+func VerifyConsistency(oldRoot, newRoot Hash, p *ConsistencyProof) error {
+	switch {
+	case p.OldSize == 0 || p.OldSize > p.NewSize:
+		return fmt.Errorf("%w: sizes %d → %d", ErrInvalidProof, p.OldSize, p.NewSize)
+	case p.OldSize == p.NewSize:
+		if len(p.Path) != 0 || oldRoot != newRoot {
+			return fmt.Errorf("%w: same size, different trees", ErrInvalidProof)
+		}
+		return nil
+	case len(p.Path) == 0:
+		return fmt.Errorf("%w: empty path", ErrInvalidProof)
+	}
+
+	path := p.Path
+	if p.OldSize&(p.OldSize-1) == 0 {
+		// Старое дерево — полное поддерево нового: его корень начинает путь
+		path = append([]Hash{oldRoot}, path...)
+	}
+	fn, sn := p.OldSize-1, p.NewSize-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := path[0], path[0]
+	for _, c := range path[1:] {
+		if sn == 0 {
+			return fmt.Errorf("%w: path longer than the tree is deep", ErrInvalidProof)
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = NodeHash(c, fr)
+			sr = NodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = NodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || fr != oldRoot || sr != newRoot {
+		return fmt.Errorf("%w: roots do not match", ErrInvalidProof)
+	}
+	return nil
+}
@@ -0,0 +1,535 @@
+// Package outbox — очередь исходящих сообщений с доставкой «сохрани и перешли».
+//
+// Пиры mesh-сети часто офлайн, поэтому сообщение сначала сохраняется на диск,
+// а затем отправляется с повторами и экспоненциальной паузой, пока получатель
+// не подтвердит приём (envelope.TypeAck с nonce конверта) или не истечёт срок.
+//
+// Хранится открытый payload, а конверт запечатывается заново при каждой
+// попытке: окно повторов получателя отбрасывает конверты старше нескольких
+// минут. Nonce каждой попытки запоминается до отправки — подтверждение может
+// прийти раньше, чем Transmit вернёт управление.
+package outbox
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"ideal-core/pkg/envelope"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status — состояние доставки
+type Status string
+
+const (
+	StatusQueued    Status = "queued"    // ждёт первой отправки или повтора
+	StatusSent      Status = "sent"      // передано пиру, подтверждения ещё нет
+	StatusDelivered Status = "delivered" // получатель подтвердил приём
+	StatusFailed    Status = "failed"    // срок истёк или ошибка неисправима
+)
+
+const (
+	DefaultTTL            = 7 * 24 * time.Hour // срок доставки по умолчанию
+	DefaultRetryMin       = 5 * time.Second
+	DefaultRetryMax       = 30 * time.Minute
+	DefaultAttemptTimeout = 30 * time.Second
+	DefaultKeepFinished   = 7 * 24 * time.Hour // сколько хранить доставленные и неудачные
+
+	// maxNonces — сколько последних попыток помнить для сопоставления подтверждений
+	maxNonces = 16
+)
+
+var (
+	// ErrPermanent — оборачивает ошибки Transmit, при которых повторять бессмысленно
+	ErrPermanent = errors.New("permanent delivery error")
+	ErrExpired   = errors.New("message expired before delivery")
+)
+
+// Message — сообщение в очереди
+type Message struct {
+	ID          string        `json:"id"`
+	Recipient   string        `json:"recipient"` // ключ пира (hex)
+	Type        envelope.Type `json:"type"`
+	Payload     []byte        `json:"payload,omitempty"`
+	Status      Status        `json:"status"`
+	Attempts    int           `json:"attempts"`
+	LastError   string        `json:"last_error,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+	NextAttempt time.Time     `json:"next_attempt"`
+	SentAt      *time.Time    `json:"sent_at,omitempty"`
+	DeliveredAt *time.Time    `json:"delivered_at,omitempty"`
+	FailedAt    *time.Time    `json:"failed_at,omitempty"`
+	Nonces      []string      `json:"nonces,omitempty"` // nonce конвертов последних попыток
+}
+
+// Finished — доставлено или окончательно не удалось
+func (m Message) Finished() bool {
+	return m.Status == StatusDelivered || m.Status == StatusFailed
+}
+
+// Config — настройки очереди
+type Config struct {
+	Dir string
+	Key func() *crypto.KeyPair // ключ узла для запечатывания конвертов
+	// Transmit передаёт кадр пиру (подключаясь при необходимости)
+	Transmit func(ctx context.Context, peer ed25519.PublicKey, frame []byte) error
+
+	RetryMin       time.Duration // пауза после первой попытки, дальше удваивается
+	RetryMax       time.Duration
+	AttemptTimeout time.Duration // на одну передачу (включая подключение)
+	KeepFinished   time.Duration
+}
+
+// Outbox — персистентная очередь (outbox.json в каталоге данных)
+type Outbox struct {
+	cfg  Config
+	path string
+	wake chan struct{}
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+// New открывает очередь
+func New(cfg Config) (*Outbox, error) {
+	if cfg.RetryMin <= 0 {
+		cfg.RetryMin = DefaultRetryMin
+	}
+	if cfg.RetryMax <= 0 {
+		cfg.RetryMax = DefaultRetryMax
+	}
+	if cfg.AttemptTimeout <= 0 {
+		cfg.AttemptTimeout = DefaultAttemptTimeout
+	}
+	if cfg.KeepFinished <= 0 {
+		cfg.KeepFinished = DefaultKeepFinished
+	}
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return nil, err
+	}
+	o := &Outbox{cfg: cfg, path: filepath.Join(cfg.Dir, "outbox.json"), wake: make(chan struct{}, 1)}
+	data, err := os.ReadFile(o.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &o.messages); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", o.path, err)
+		}
+	}
+	return o, nil
+}
+
+// Enqueue ставит сообщение в очередь; ttl <= 0 — DefaultTTL
+func (o *Outbox) Enqueue(recipient ed25519.PublicKey, typ envelope.Type, payload []byte, ttl time.Duration, now time.Time) (Message, error) {
+	if len(recipient) != ed25519.PublicKeySize {
+		return Message{}, errors.New("invalid recipient key")
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Message{}, err
+	}
+	m := Message{
+		ID:          hex.EncodeToString(id),
+		Recipient:   hex.EncodeToString(recipient),
+		Type:        typ,
+		Payload:     append([]byte(nil), payload...),
+		Status:      StatusQueued,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+		NextAttempt: now,
+	}
+
+	o.mu.Lock()
+	o.messages = append(o.messages, m)
+	err := o.save()
+	o.mu.Unlock()
+	if err != nil {
+		return Message{}, err
+	}
+	o.Wake()
+	return m, nil
+}
+
+// Wake будит цикл доставки (новое сообщение, пир появился в сети)
+func (o *Outbox) Wake() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run доставляет сообщения до отмены ctx
+func (o *Outbox) Run(ctx context.Context) {
+	for {
+		o.Flush(ctx, time.Now())
+		o.Prune(time.Now())
+
+		wait := time.Until(o.nextDue())
+		if wait < 0 {
+			wait = 0
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-o.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// Flush делает по одной попытке для всех сообщений, срок повтора которых наступил.
+// Если пир недоступен, остальные его сообщения откладываются до того же времени,
+// чтобы не ждать таймаут подключения на каждом. Возвращает число попыток.
+func (o *Outbox) Flush(ctx context.Context, now time.Time) int {
+	attempts := 0
+	unreachable := make(map[string]time.Time) // пир → время следующей попытки
+	for _, m := range o.due(now) {
+		if ctx.Err() != nil {
+			break
+		}
+		if next, ok := unreachable[m.Recipient]; ok {
+			o.postpone(m.ID, next)
+			continue
+		}
+		ok, err := o.attempt(ctx, m.ID, now)
+		if ok {
+			attempts++
+		}
+		if err != nil && !errors.Is(err, ErrPermanent) {
+			if m, gerr := o.Get(m.ID); gerr == nil {
+				unreachable[m.Recipient] = m.NextAttempt
+			}
+		}
+	}
+	return attempts
+}
+
+func (o *Outbox) due(now time.Time) []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var out []Message
+	for _, m := range o.messages {
+		if !m.Finished() && !m.NextAttempt.After(now) {
+			out = append(out, m)
+		}
+	}
+	sort.SliceStable(out, func(a, b int) bool { return out[a].CreatedAt.Before(out[b].CreatedAt) })
+	return out
+}
+
+func (o *Outbox) postpone(id string, until time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if i := o.index(id); i >= 0 && o.messages[i].NextAttempt.Before(until) {
+		o.messages[i].NextAttempt = until
+		o.save()
+	}
+}
+
+func (o *Outbox) nextDue() time.Time {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	next := time.Now().Add(o.cfg.RetryMax)
+	for _, m := range o.messages {
+		if !m.Finished() && m.NextAttempt.Before(next) {
+			next = m.NextAttempt
+		}
+	}
+	return next
+}
+
+// attempt запечатывает и передаёт одно сообщение.
+// ok — попытка состоялась; err — ошибка передачи.
+func (o *Outbox) attempt(ctx context.Context, id string, now time.Time) (ok bool, err error) {
+	o.mu.Lock()
+	i := o.index(id)
+	if i < 0 || o.messages[i].Finished() {
+		o.mu.Unlock()
+		return false, nil
+	}
+	m := &o.messages[i]
+	if now.After(m.ExpiresAt) {
+		o.fail(m, ErrExpired, now)
+		o.save()
+		o.mu.Unlock()
+		return false, nil
+	}
+	recipient, _ := hex.DecodeString(m.Recipient)
+	env, err := envelope.Seal(o.cfg.Key(), recipient, m.Type, m.Payload, now)
+	if err != nil {
+		o.fail(m, err, now)
+		o.save()
+		o.mu.Unlock()
+		return false, nil
+	}
+	m.Attempts++
+	m.Nonces = append(m.Nonces, hex.EncodeToString(env.Nonce[:]))
+	if len(m.Nonces) > maxNonces {
+		m.Nonces = m.Nonces[len(m.Nonces)-maxNonces:]
+	}
+	// Следующая попытка — если не будет ни ошибки, ни подтверждения
+	m.NextAttempt = now.Add(o.backoff(m.Attempts))
+	o.save()
+	o.mu.Unlock()
+
+	tctx, cancel := context.WithTimeout(ctx, o.cfg.AttemptTimeout)
+	err = o.cfg.Transmit(tctx, recipient, env.Marshal())
+	cancel()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if i = o.index(id); i < 0 || o.messages[i].Finished() {
+		return true, err // удалено или уже подтверждено
+	}
+	m = &o.messages[i]
+	switch {
+	case errors.Is(err, ErrPermanent):
+		o.fail(m, err, now)
+	case err != nil:
+		m.LastError = err.Error()
+	default:
+		m.Status = StatusSent
+		m.LastError = ""
+		if m.SentAt == nil {
+			m.SentAt = &now
+		}
+	}
+	o.save()
+	return true, err
+}
+
+// backoff — пауза перед попыткой attempts+1: RetryMin·2^(attempts-1), не больше RetryMax
+func (o *Outbox) backoff(attempts int) time.Duration {
+	d := o.cfg.RetryMin
+	for i := 1; i < attempts && d < o.cfg.RetryMax; i++ {
+		d *= 2
+	}
+	return min(d, o.cfg.RetryMax)
+}
+
+func (o *Outbox) fail(m *Message, err error, now time.Time) {
+	m.Status = StatusFailed
+	m.LastError = err.Error()
+	m.FailedAt = &now
+}
+
+// Ack отмечает сообщение доставленным по nonce из подтверждения.
+// Подтверждение принимается только от получателя сообщения.
+func (o *Outbox) Ack(from ed25519.PublicKey, nonce []byte, now time.Time) (Message, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	sender, n := hex.EncodeToString(from), hex.EncodeToString(nonce)
+	for i := range o.messages {
+		m := &o.messages[i]
+		if m.Recipient != sender || !containsString(m.Nonces, n) {
+			continue
+		}
+		if m.Status != StatusDelivered {
+			m.Status = StatusDelivered
+			m.DeliveredAt = &now
+			m.LastError = ""
+			if m.SentAt == nil {
+				m.SentAt = &now
+			}
+			if err := o.save(); err != nil {
+				return Message{}, err
+			}
+		}
+		return *m, nil
+	}
+	return Message{}, fmt.Errorf("ack %s: %w", n, os.ErrNotExist)
+}
+
+// Get возвращает сообщение по ID
+func (o *Outbox) Get(id string) (Message, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if i := o.index(id); i >= 0 {
+		return o.messages[i], nil
+	}
+	return Message{}, fmt.Errorf("message %s: %w", id, os.ErrNotExist)
+}
+
+// List возвращает сообщения (новые первыми); status != "" — только с этим статусом
+func (o *Outbox) List(status Status) []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	out := []Message{}
+	for _, m := range o.messages {
+		if status == "" || m.Status == status {
+			out = append(out, m)
+		}
+	}
+	sort.SliceStable(out, func(a, b int) bool { return out[a].CreatedAt.After(out[b].CreatedAt) })
+	return out
+}
+
+// Retry возвращает неудачное или ожидающее сообщение в очередь с немедленной попыткой
+// (срок продлевается на ttl, если уже истёк)
+func (o *Outbox) Retry(id string, ttl time.Duration, now time.Time) (Message, error) {
+	o.mu.Lock()
+	i := o.index(id)
+	if i < 0 {
+		o.mu.Unlock()
+		return Message{}, fmt.Errorf("message %s: %w", id, os.ErrNotExist)
+	}
+	m := &o.messages[i]
+	if m.Status == StatusDelivered {
+		o.mu.Unlock()
+		return *m, nil
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if now.After(m.ExpiresAt) {
+		m.ExpiresAt = now.Add(ttl)
+	}
+	if m.Status == StatusFailed {
+		m.Status = StatusQueued
+		m.FailedAt = nil
+	}
+	m.NextAttempt = now
+	out := *m
+	err := o.save()
+	o.mu.Unlock()
+	if err != nil {
+		return Message{}, err
+	}
+	o.Wake()
+	return out, nil
+}
+
+// RetryPeer переносит ближайшую попытку для всех сообщений пира на now
+// (пир только что подключился — не ждём конца паузы)
+func (o *Outbox) RetryPeer(peer ed25519.PublicKey, now time.Time) {
+	o.mu.Lock()
+	key, changed := hex.EncodeToString(peer), false
+	for i := range o.messages {
+		m := &o.messages[i]
+		if m.Recipient == key && !m.Finished() && m.NextAttempt.After(now) {
+			m.NextAttempt = now
+			changed = true
+		}
+	}
+	if changed {
+		o.save()
+	}
+	o.mu.Unlock()
+	if changed {
+		o.Wake()
+	}
+}
+
+// Cancel удаляет сообщение из очереди
+func (o *Outbox) Cancel(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	i := o.index(id)
+	if i < 0 {
+		return fmt.Errorf("message %s: %w", id, os.ErrNotExist)
+	}
+	o.messages = append(o.messages[:i], o.messages[i+1:]...)
+	return o.save()
+}
+
+// CancelWhere удаляет из очереди недоставленные сообщения, для которых match
+// возвращает true (например, пакеты отозванного доступа); возвращает их число
+func (o *Outbox) CancelWhere(match func(Message) bool) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	kept := o.messages[:0]
+	removed := 0
+	for _, m := range o.messages {
+		if !m.Finished() && match(m) {
+			removed++
+			continue
+		}
+		kept = append(kept, m)
+	}
+	o.messages = kept
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, o.save()
+}
+
+// Prune удаляет завершённые сообщения старше KeepFinished
+func (o *Outbox) Prune(now time.Time) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	kept := o.messages[:0]
+	removed := 0
+	for _, m := range o.messages {
+		var done *time.Time
+		switch m.Status {
+		case StatusDelivered:
+			done = m.DeliveredAt
+		case StatusFailed:
+			done = m.FailedAt
+		}
+		if done != nil && now.Sub(*done) > o.cfg.KeepFinished {
+			removed++
+			continue
+		}
+		kept = append(kept, m)
+	}
+	o.messages = kept
+	if removed > 0 {
+		o.save()
+	}
+	return removed
+}
+
+// Stats — число сообщений по статусам
+func (o *Outbox) Stats() map[Status]int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	stats := map[Status]int{StatusQueued: 0, StatusSent: 0, StatusDelivered: 0, StatusFailed: 0}
+	for _, m := range o.messages {
+		stats[m.Status]++
+	}
+	return stats
+}
+
+func (o *Outbox) index(id string) int {
+	for i, m := range o.messages {
+		if m.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (o *Outbox) save() error {
+	data, err := json.MarshalIndent(o.messages, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(o.path, data, 0600)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package outbox
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"ideal-core/pkg/envelope"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePeer — получатель, который может быть офлайн
+type fakePeer struct {
+	mu     sync.Mutex
+	online bool
+	dials  int
+	nonces [][envelope.NonceSize]byte
+}
+
+func (p *fakePeer) transmit(ctx context.Context, peer ed25519.PublicKey, frame []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dials++
+	if !p.online {
+		return errors.New("connection refused")
+	}
+	env, err := envelope.Unmarshal(frame)
+	if err != nil {
+		return err
+	}
+	p.nonces = append(p.nonces, env.Nonce)
+	return nil
+}
+
+func newTestOutbox(t *testing.T, dir string, peer *fakePeer) *Outbox {
+	t.Helper()
+	kp, _ := crypto.GenerateKeyPair()
+	o, err := New(Config{
+		Dir:      dir,
+		Key:      func() *crypto.KeyPair { return kp },
+		Transmit: peer.transmit,
+		RetryMin: time.Second,
+		RetryMax: 8 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestOutbox_RetryUntilDelivered(t *testing.T) {
+	dir := t.TempDir()
+	peer := &fakePeer{}
+	o := newTestOutbox(t, dir, peer)
+	bob, _ := crypto.GenerateKeyPair()
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	m, err := o.Enqueue(bob.PublicKey, envelope.TypePing, []byte("hi"), time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Enqueue(bob.PublicKey, envelope.TypePing, []byte("second"), time.Hour, now)
+
+	// Офлайн: одна попытка на пира за проход, пауза растёт 1s, 2s, 4s, 8s, 8s
+	var waits []time.Duration
+	at := now
+	for i := 0; i < 5; i++ {
+		o.Flush(ctx, at)
+		got, _ := o.Get(m.ID)
+		waits = append(waits, got.NextAttempt.Sub(at))
+		at = got.NextAttempt
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second}
+	if fmt.Sprint(waits) != fmt.Sprint(want) {
+		t.Fatalf("backoff %v, want %v", waits, want)
+	}
+	if peer.dials != 5 {
+		t.Fatalf("offline peer dialed %d times, want 5 (one per flush)", peer.dials)
+	}
+
+	// Перезапуск: очередь переживает его
+	o = newTestOutbox(t, dir, peer)
+	if got := o.List(StatusQueued); len(got) != 2 || got[0].LastError == "" {
+		t.Fatalf("queue after restart: %+v", got)
+	}
+
+	peer.online = true
+	if n := o.Flush(ctx, at); n != 2 {
+		t.Fatalf("flushed %d messages, want 2", n)
+	}
+	if got, _ := o.Get(m.ID); got.Status != StatusSent {
+		t.Fatalf("status %s, want sent", got.Status)
+	}
+
+	// Подтверждение от постороннего не засчитывается
+	mallory, _ := crypto.GenerateKeyPair()
+	if _, err := o.Ack(mallory.PublicKey, peer.nonces[0][:], at); err == nil {
+		t.Fatal("ack from a non-recipient was accepted")
+	}
+	got, err := o.Ack(bob.PublicKey, peer.nonces[0][:], at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != m.ID || got.Status != StatusDelivered {
+		t.Fatalf("ack matched %s (%s), want %s delivered", got.ID, got.Status, m.ID)
+	}
+
+	// Неподтверждённое повторяется после паузы; доставленное — нет
+	peer.dials = 0
+	o.Flush(ctx, at.Add(time.Minute))
+	if peer.dials != 1 {
+		t.Fatalf("expected one resend of the unacknowledged message, got %d", peer.dials)
+	}
+}
+
+func TestOutbox_ExpiryAndPermanentErrors(t *testing.T) {
+	peer := &fakePeer{}
+	o := newTestOutbox(t, t.TempDir(), peer)
+	bob, _ := crypto.GenerateKeyPair()
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	m, _ := o.Enqueue(bob.PublicKey, envelope.TypePing, nil, time.Minute, now)
+	o.Flush(ctx, now.Add(2*time.Minute))
+	got, _ := o.Get(m.ID)
+	if got.Status != StatusFailed || got.LastError != ErrExpired.Error() {
+		t.Fatalf("expected expired failure, got %s (%s)", got.Status, got.LastError)
+	}
+
+	// Retry возвращает сообщение в очередь с новым сроком
+	peer.online = true
+	later := now.Add(3 * time.Minute)
+	if _, err := o.Retry(m.ID, time.Hour, later); err != nil {
+		t.Fatal(err)
+	}
+	o.Flush(ctx, later)
+	if got, _ := o.Get(m.ID); got.Status != StatusSent {
+		t.Fatalf("retried message status %s, want sent", got.Status)
+	}
+
+	o.cfg.Transmit = func(ctx context.Context, peer ed25519.PublicKey, frame []byte) error {
+		return fmt.Errorf("%w: recipient is not in the contact book", ErrPermanent)
+	}
+	p, _ := o.Enqueue(bob.PublicKey, envelope.TypePing, nil, 0, later)
+	o.Flush(ctx, later)
+	if got, _ := o.Get(p.ID); got.Status != StatusFailed || got.Attempts != 1 {
+		t.Fatalf("permanent error: status %s after %d attempts", got.Status, got.Attempts)
+	}
+
+	if n := o.Prune(later.Add(DefaultKeepFinished + time.Hour)); n != 1 {
+		t.Fatalf("pruned %d messages, want 1 (the failed one)", n)
+	}
+}
+
+func TestOutbox_CancelWhereDropsRevokedShareBatches(t *testing.T) {
+	dir := t.TempDir()
+	peer := &fakePeer{}
+	o := newTestOutbox(t, dir, peer)
+	bob, _ := crypto.GenerateKeyPair()
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	// Получатель офлайн: два пакета отзываемого доступа, один другого и уведомление об отзыве
+	o.Enqueue(bob.PublicKey, envelope.TypeJournalShare, []byte(`{"share_id":"a","entries":[]}`), 0, now)
+	o.Enqueue(bob.PublicKey, envelope.TypeJournalShare, []byte(`{"share_id":"a","entries":[]}`), 0, now)
+	other, _ := o.Enqueue(bob.PublicKey, envelope.TypeJournalShare, []byte(`{"share_id":"b","entries":[]}`), 0, now)
+	revoke, _ := o.Enqueue(bob.PublicKey, envelope.TypeJournalRevoke, []byte(`{"share_id":"a"}`), 0, now)
+	o.Flush(ctx, now)
+
+	n, err := o.CancelWhere(func(m Message) bool {
+		return m.Type == envelope.TypeJournalShare && strings.Contains(string(m.Payload), `"share_id":"a"`)
+	})
+	if err != nil || n != 2 {
+		t.Fatalf("cancelled %d messages (%v), want 2", n, err)
+	}
+
+	// После перезапуска и выхода получателя в сеть уходят только оставшиеся
+	o = newTestOutbox(t, dir, peer)
+	peer.online = true
+	if sent := o.Flush(ctx, now.Add(time.Minute)); sent != 2 {
+		t.Fatalf("sent %d messages, want 2", sent)
+	}
+	for _, m := range o.List("") {
+		if m.ID != other.ID && m.ID != revoke.ID {
+			t.Fatalf("cancelled message %s still queued", m.ID)
+		}
+	}
+}
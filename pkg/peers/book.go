@@ -0,0 +1,235 @@
+package peers
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// seenInterval — LastSeen пишется на диск не чаще, чем раз в этот интервал
+const seenInterval = time.Minute
+
+// Book — адресная книга (peers.json в каталоге данных)
+type Book struct {
+	mu   sync.Mutex
+	path string
+	data struct {
+		Peers  []Peer `json:"peers"`
+		Policy Policy `json:"policy"`
+	}
+	// persisted — LastSeen каждого пира на момент последней записи на диск
+	persisted map[string]time.Time
+}
+
+// NewBook открывает книгу в каталоге dataDir
+func NewBook(dataDir string) (*Book, error) {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, err
+	}
+	b := &Book{path: filepath.Join(dataDir, "peers.json"), persisted: make(map[string]time.Time)}
+	data, err := os.ReadFile(b.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &b.data); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", b.path, err)
+		}
+		for _, p := range b.data.Peers {
+			b.persisted[p.Key] = p.LastSeen
+		}
+	}
+	return b, nil
+}
+
+// Add добавляет пира; если ключ уже есть, обновляются имя и доверие
+func (b *Book) Add(p Peer) (Peer, error) {
+	if !p.Trust.Valid() {
+		return Peer{}, fmt.Errorf("%w %q", ErrBadTrust, p.Trust)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if i := b.index(p.Key); i >= 0 {
+		if p.Name != "" {
+			b.data.Peers[i].Name = p.Name
+		}
+		b.data.Peers[i].Trust = p.Trust
+		if p.PersonID != "" {
+			b.data.Peers[i].PersonID = p.PersonID
+		}
+		if p.Endpoint != "" {
+			b.data.Peers[i].Endpoint = p.Endpoint
+		}
+		return b.data.Peers[i], b.save()
+	}
+	b.data.Peers = append(b.data.Peers, p)
+	return p, b.save()
+}
+
+// Get возвращает пира по hex-ключу (os.ErrNotExist, если его нет)
+func (b *Book) Get(key string) (Peer, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := b.index(key)
+	if i < 0 {
+		return Peer{}, fmt.Errorf("peer %s: %w", key, os.ErrNotExist)
+	}
+	return b.data.Peers[i], nil
+}
+
+// ByAddress находит пира по Yggdrasil-адресу
+func (b *Book) ByAddress(addr string) (Peer, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, p := range b.data.Peers {
+		if p.Address == addr {
+			return p, nil
+		}
+	}
+	return Peer{}, fmt.Errorf("peer at %s: %w", addr, os.ErrNotExist)
+}
+
+// List возвращает пиров по имени; trust != "" — только с этим уровнем
+func (b *Book) List(trust Trust) []Peer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]Peer, 0, len(b.data.Peers))
+	for _, p := range b.data.Peers {
+		if trust == "" || p.Trust == trust {
+			out = append(out, p)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name) })
+	return out
+}
+
+// Update изменяет запись пира и сохраняет книгу
+func (b *Book) Update(key string, fn func(*Peer) error) (Peer, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := b.index(key)
+	if i < 0 {
+		return Peer{}, fmt.Errorf("peer %s: %w", key, os.ErrNotExist)
+	}
+	p := b.data.Peers[i]
+	if err := fn(&p); err != nil {
+		return Peer{}, err
+	}
+	if !p.Trust.Valid() {
+		return Peer{}, fmt.Errorf("%w %q", ErrBadTrust, p.Trust)
+	}
+	b.data.Peers[i] = p
+	return p, b.save()
+}
+
+// SetTrust меняет уровень доверия
+func (b *Book) SetTrust(key string, trust Trust) (Peer, error) {
+	return b.Update(key, func(p *Peer) error {
+		p.Trust = trust
+		return nil
+	})
+}
+
+// MarkVerified отмечает, что отпечаток сверен (verified=false снимает отметку)
+func (b *Book) MarkVerified(key string, verified bool, now time.Time) (Peer, error) {
+	return b.Update(key, func(p *Peer) error {
+		p.Verified = verified
+		p.VerifiedAt = time.Time{}
+		if verified {
+			p.VerifiedAt = now
+		}
+		return nil
+	})
+}
+
+// Remove удаляет пира (чтобы заблокировать, используйте SetTrust(TrustBlocked))
+func (b *Book) Remove(key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := b.index(key)
+	if i < 0 {
+		return fmt.Errorf("peer %s: %w", key, os.ErrNotExist)
+	}
+	b.data.Peers = append(b.data.Peers[:i], b.data.Peers[i+1:]...)
+	return b.save()
+}
+
+// Seen отмечает активность пира. На диск пишется не чаще раза в минуту,
+// чтобы поток сообщений не превращался в поток записей.
+func (b *Book) Seen(key ed25519.PublicKey, now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := b.index(hex.EncodeToString(key))
+	if i < 0 {
+		return nil
+	}
+	p := &b.data.Peers[i]
+	p.LastSeen = now
+	// Сравнивается с записанным: при частых сообщениях LastSeen в памяти
+	// обновляется всегда, и сравнение с ним не дало бы записать ни разу
+	if now.Sub(b.persisted[p.Key]) < seenInterval {
+		return nil
+	}
+	return b.save()
+}
+
+// Policy возвращает политику приёма
+func (b *Book) Policy() Policy {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.data.Policy
+}
+
+// SetPolicy сохраняет политику приёма
+func (b *Book) SetPolicy(p Policy) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data.Policy = p
+	return b.save()
+}
+
+// Allow решает по политике, принимать ли сообщение от ключа:
+// заблокированные отбрасываются всегда, неизвестные — если не AcceptUnknown
+func (b *Book) Allow(key ed25519.PublicKey) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := b.index(hex.EncodeToString(key))
+	switch {
+	case i < 0 && !b.data.Policy.AcceptUnknown:
+		return ErrUnknownPeer
+	case i >= 0 && b.data.Peers[i].Trust == TrustBlocked:
+		return ErrBlocked
+	}
+	return nil
+}
+
+func (b *Book) index(key string) int {
+	key = strings.ToLower(key)
+	for i, p := range b.data.Peers {
+		if p.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (b *Book) save() error {
+	data, err := json.MarshalIndent(b.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(b.path, data, 0600); err != nil {
+		return err
+	}
+	for _, p := range b.data.Peers {
+		b.persisted[p.Key] = p.LastSeen
+	}
+	return nil
+}
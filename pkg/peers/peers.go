@@ -0,0 +1,121 @@
+// Package peers — адресная книга узлов: ключ, Yggdrasil-адрес, имя,
+// связь с человеком из db, уровень доверия и проверка отпечатка.
+//
+// Пир идентифицируется ключом ed25519, а не IPv6: адрес выводится из ключа
+// (yggdrasil.AddrForKey), поэтому запись нельзя «переназначить» на чужой узел.
+// Policy решает, принимать ли сообщения от пира, до вызова обработчиков.
+package peers
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/yggdrasil"
+	"strings"
+	"time"
+)
+
+// Trust — уровень доверия к пиру
+type Trust string
+
+const (
+	TrustBlocked   Trust = "blocked"   // сообщения отбрасываются
+	TrustKnown     Trust = "known"     // принимаются, без доступа к личным данным
+	TrustTrusted   Trust = "trusted"   // близкий человек (доли восстановления, обмен)
+	TrustTherapist Trust = "therapist" // терапевт: получает то, чем с ним поделились
+	TrustDevice    Trust = "device"    // своё устройство той же идентичности (синхронизация дневника)
+)
+
+var (
+	ErrBlocked     = errors.New("peer is blocked")
+	ErrUnknownPeer = errors.New("peer is not in the contact book")
+	ErrBadTrust    = errors.New("unknown trust level")
+)
+
+// Valid сообщает, известен ли уровень
+func (t Trust) Valid() bool {
+	switch t {
+	case TrustBlocked, TrustKnown, TrustTrusted, TrustTherapist, TrustDevice:
+		return true
+	}
+	return false
+}
+
+// Peer — запись адресной книги
+type Peer struct {
+	Key        string    `json:"key"`                // ed25519 (hex)
+	Address    string    `json:"address"`            // Yggdrasil IPv6, выведенный из ключа
+	Endpoint   string    `json:"endpoint,omitempty"` // адрес для подключения вместо Address (LAN, -peer-listen)
+	Name       string    `json:"name"`
+	PersonID   string    `json:"person_id,omitempty"` // связь с db.Person
+	Trust      Trust     `json:"trust"`
+	AddedAt    time.Time `json:"added_at"`
+	LastSeen   time.Time `json:"last_seen,omitempty"`
+	Verified   bool      `json:"verified"` // отпечаток сверен лично
+	VerifiedAt time.Time `json:"verified_at,omitempty"`
+}
+
+// PublicKey возвращает ключ пира
+func (p Peer) PublicKey() ed25519.PublicKey {
+	k, _ := hex.DecodeString(p.Key)
+	return k
+}
+
+// DialAddr — адрес для подключения к пиру
+func (p Peer) DialAddr() string {
+	if p.Endpoint != "" {
+		return p.Endpoint
+	}
+	return p.Address
+}
+
+// NewPeer создаёт запись по ключу; адрес выводится из ключа
+func NewPeer(key ed25519.PublicKey, name string, trust Trust, now time.Time) (Peer, error) {
+	if !trust.Valid() {
+		return Peer{}, fmt.Errorf("%w %q", ErrBadTrust, trust)
+	}
+	addr, err := yggdrasil.AddrForKey(key)
+	if err != nil {
+		return Peer{}, err
+	}
+	return Peer{
+		Key:     hex.EncodeToString(key),
+		Address: addr.String(),
+		Name:    name,
+		Trust:   trust,
+		AddedAt: now,
+	}, nil
+}
+
+// Fingerprint — код для сверки двух ключей при личной встрече или по телефону.
+// Симметричен: у обеих сторон получается один и тот же код (12 групп по 5 цифр,
+// каждая — 5 байт SHA-512 по модулю 100000, как safety number в Signal).
+func Fingerprint(a, b ed25519.PublicKey) string {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	h := sha512.New()
+	h.Write([]byte("ideal-core/fingerprint/v1"))
+	h.Write(a)
+	h.Write(b)
+	sum := h.Sum(nil)
+
+	groups := make([]string, 12)
+	for i := range groups {
+		var chunk [8]byte
+		copy(chunk[3:], sum[i*5:i*5+5])
+		groups[i] = fmt.Sprintf("%05d", binary.BigEndian.Uint64(chunk[:])%100000)
+	}
+	return strings.Join(groups, " ")
+}
+
+// Policy — какие входящие сообщения принимать
+type Policy struct {
+	// AcceptUnknown — принимать сообщения от ключей, которых нет в книге
+	// (нужно, чтобы новый человек мог впервые написать)
+	AcceptUnknown bool `json:"accept_unknown"`
+}
@@ -0,0 +1,141 @@
+package peers
+
+import (
+	"context"
+	"errors"
+	"ideal-core/pkg/crypto"
+	"ideal-core/pkg/envelope"
+	"ideal-core/pkg/identity"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestFingerprint_Symmetric(t *testing.T) {
+	a, _ := crypto.GenerateKeyPair()
+	b, _ := crypto.GenerateKeyPair()
+	c, _ := crypto.GenerateKeyPair()
+
+	fp := Fingerprint(a.PublicKey, b.PublicKey)
+	if fp != Fingerprint(b.PublicKey, a.PublicKey) {
+		t.Fatal("fingerprint depends on argument order")
+	}
+	if fp == Fingerprint(a.PublicKey, c.PublicKey) {
+		t.Fatal("different key pairs share a fingerprint")
+	}
+	if !regexp.MustCompile(`^\d{5}( \d{5}){11}$`).MatchString(fp) {
+		t.Fatalf("unexpected format: %q", fp)
+	}
+}
+
+func TestBook_PersistAndUpdate(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC)
+	kp, _ := crypto.GenerateKeyPair()
+
+	book, err := NewBook(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPeer(kp.PublicKey, "Anna", TrustKnown, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Address != identity.DeriveYggdrasilIP(kp.PublicKey) {
+		t.Fatalf("address %s is not derived from the key", p.Address)
+	}
+	if _, err := book.Add(p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := book.SetTrust(p.Key, TrustTherapist); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := book.SetTrust(p.Key, "friend"); !errors.Is(err, ErrBadTrust) {
+		t.Fatalf("expected ErrBadTrust, got %v", err)
+	}
+	if _, err := book.MarkVerified(p.Key, true, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := book.Seen(kp.PublicKey, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewBook(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := reopened.ByAddress(p.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Trust != TrustTherapist || !got.Verified || !got.LastSeen.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected record after reopen: %+v", got)
+	}
+	// При сообщениях чаще seenInterval LastSeen всё равно записывается раз в интервал
+	for i := 1; i <= 90; i++ {
+		if err := reopened.Seen(kp.PublicKey, now.Add(time.Hour+time.Duration(i)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	again, err := NewBook(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := again.Get(p.Key); !got.LastSeen.Equal(now.Add(time.Hour + seenInterval)) {
+		t.Fatalf("last seen on disk %v, want %v", got.LastSeen, now.Add(time.Hour+seenInterval))
+	}
+	if list := reopened.List(TrustTrusted); len(list) != 0 {
+		t.Fatalf("trust filter returned %d peers", len(list))
+	}
+
+	if err := reopened.Remove(p.Key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Get(p.Key); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected ErrNotExist after Remove, got %v", err)
+	}
+}
+
+func TestBook_ReceivePolicy(t *testing.T) {
+	now := time.Now()
+	me, _ := crypto.GenerateKeyPair()
+	friend, _ := crypto.GenerateKeyPair()
+	stranger, _ := crypto.GenerateKeyPair()
+
+	book, _ := NewBook(t.TempDir())
+	p, _ := NewPeer(friend.PublicKey, "Friend", TrustTrusted, now)
+	book.Add(p)
+
+	r := envelope.NewReceiver(func() *crypto.KeyPair { return me })
+	r.SetPolicy(book.Allow)
+	delivered := 0
+	r.Handle(envelope.TypePing, func(ctx context.Context, msg envelope.Message) error {
+		delivered++
+		return nil
+	})
+	send := func(from *crypto.KeyPair) error {
+		env, _ := envelope.Seal(from, me.PublicKey, envelope.TypePing, []byte("hi"), time.Now())
+		return r.Process(context.Background(), env.Marshal())
+	}
+
+	if err := send(friend); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(stranger); !errors.Is(err, envelope.ErrRejected) || !errors.Is(err, ErrUnknownPeer) {
+		t.Fatalf("expected unknown peer to be rejected, got %v", err)
+	}
+
+	book.SetPolicy(Policy{AcceptUnknown: true})
+	if err := send(stranger); err != nil {
+		t.Fatalf("unknown peer should pass with AcceptUnknown: %v", err)
+	}
+
+	book.SetTrust(p.Key, TrustBlocked)
+	if err := send(friend); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("expected blocked peer to be rejected, got %v", err)
+	}
+	if delivered != 2 {
+		t.Fatalf("handler called %d times, want 2", delivered)
+	}
+}
@@ -0,0 +1,213 @@
+// Package questionnaire — стандартизированные опросники (PHQ-9, GAD-7, PSS-10)
+//
+// Зачем:
+// Свободные оценки интенсивности в дневнике удобны, но не сравнимы во времени.
+// Валидированные шкалы позволяют видеть прогресс за месяцы и проверять,
+// помогла ли конкретная фаза protocol36.
+//
+// Важно:
+// Это НЕ диагноз. Высокий балл — повод обратиться к специалисту.
+package questionnaire
+
+import (
+	"fmt"
+)
+
+// ID — идентификатор опросника
+type ID string
+
+const (
+	PHQ9  ID = "phq9"  // Депрессия (Patient Health Questionnaire-9)
+	GAD7  ID = "gad7"  // Тревога (Generalized Anxiety Disorder-7)
+	PSS10 ID = "pss10" // Воспринимаемый стресс (Perceived Stress Scale-10)
+)
+
+// Option — вариант ответа
+type Option struct {
+	Value int    `json:"value"`
+	Label string `json:"label"`
+}
+
+// Item — вопрос опросника
+type Item struct {
+	Number  int    `json:"number"` // с 1
+	Text    string `json:"text"`
+	Reverse bool   `json:"reverse,omitempty"` // обратный подсчёт (PSS: 4, 5, 7, 8)
+}
+
+// Band — диапазон баллов и его интерпретация
+type Band struct {
+	Min      int    `json:"min"`
+	Max      int    `json:"max"`
+	Severity string `json:"severity"` // minimal | mild | moderate | moderately_severe | severe | low | high
+	Label    string `json:"label"`
+	Alert    bool   `json:"alert"` // требует внимания специалиста
+}
+
+// ItemAlert — вопрос, любой ненулевой ответ на который поднимает тревогу
+type ItemAlert struct {
+	Number  int    `json:"number"`
+	Message string `json:"message"`
+}
+
+// Definition — описание опросника
+type Definition struct {
+	ID          ID          `json:"id"`
+	Title       string      `json:"title"`
+	Instruction string      `json:"instruction"`
+	Items       []Item      `json:"items"`
+	Options     []Option    `json:"options"`
+	Bands       []Band      `json:"bands"`
+	ItemAlerts  []ItemAlert `json:"item_alerts,omitempty"`
+}
+
+// Score — результат подсчёта
+type Score struct {
+	Total    int      `json:"total"`
+	Max      int      `json:"max"`
+	Severity string   `json:"severity"`
+	Label    string   `json:"label"`
+	Alerts   []string `json:"alerts,omitempty"`
+}
+
+// Варианты ответов
+var (
+	frequencyOptions = []Option{
+		{0, "Ни разу"},
+		{1, "Несколько дней"},
+		{2, "Более половины дней"},
+		{3, "Почти каждый день"},
+	}
+	stressOptions = []Option{
+		{0, "Никогда"},
+		{1, "Почти никогда"},
+		{2, "Иногда"},
+		{3, "Довольно часто"},
+		{4, "Очень часто"},
+	}
+)
+
+// crisisMessage — рекомендация при высоком риске
+const crisisMessage = "Пожалуйста, не оставайтесь с этим один на один: обратитесь к специалисту или на телефон доверия"
+
+// definitions — встроенные опросники
+var definitions = map[ID]Definition{
+	PHQ9: {
+		ID:          PHQ9,
+		Title:       "PHQ-9: шкала депрессии",
+		Instruction: "Как часто за последние 2 недели вас беспокоило следующее?",
+		Items: []Item{
+			{1, "Мало интереса или удовольствия от занятий", false},
+			{2, "Подавленность, депрессия или безнадёжность", false},
+			{3, "Трудности с засыпанием, прерывистый сон или слишком долгий сон", false},
+			{4, "Усталость или упадок сил", false},
+			{5, "Плохой аппетит или переедание", false},
+			{6, "Плохое мнение о себе, ощущение себя неудачником, который подвёл себя или семью", false},
+			{7, "Трудности с концентрацией внимания, например при чтении или просмотре телевизора", false},
+			{8, "Замедленность движений и речи, заметная другим, или, наоборот, суетливость и беспокойство", false},
+			{9, "Мысли о том, что лучше было бы умереть, или о причинении себе вреда", false},
+		},
+		Options: frequencyOptions,
+		Bands: []Band{
+			{0, 4, "minimal", "Минимальная", false},
+			{5, 9, "mild", "Лёгкая", false},
+			{10, 14, "moderate", "Умеренная", false},
+			{15, 19, "moderately_severe", "Умеренно тяжёлая", true},
+			{20, 27, "severe", "Тяжёлая", true},
+		},
+		ItemAlerts: []ItemAlert{
+			{9, "Есть мысли о смерти или самоповреждении. " + crisisMessage},
+		},
+	},
+	GAD7: {
+		ID:          GAD7,
+		Title:       "GAD-7: шкала тревоги",
+		Instruction: "Как часто за последние 2 недели вас беспокоило следующее?",
+		Items: []Item{
+			{1, "Нервозность, тревога или раздражительность", false},
+			{2, "Неспособность остановить или контролировать беспокойство", false},
+			{3, "Чрезмерное беспокойство о разных вещах", false},
+			{4, "Трудности с расслаблением", false},
+			{5, "Такое беспокойство, что трудно усидеть на месте", false},
+			{6, "Лёгкая раздражительность", false},
+			{7, "Страх, что может случиться что-то ужасное", false},
+		},
+		Options: frequencyOptions,
+		Bands: []Band{
+			{0, 4, "minimal", "Минимальная", false},
+			{5, 9, "mild", "Лёгкая", false},
+			{10, 14, "moderate", "Умеренная", false},
+			{15, 21, "severe", "Выраженная", true},
+		},
+	},
+	PSS10: {
+		ID:          PSS10,
+		Title:       "PSS-10: шкала воспринимаемого стресса",
+		Instruction: "Как часто за последний месяц...",
+		Items: []Item{
+			{1, "...вы были расстроены из-за чего-то неожиданного?", false},
+			{2, "...вам казалось, что вы не можете контролировать важные вещи в жизни?", false},
+			{3, "...вы чувствовали нервозность и стресс?", false},
+			{4, "...вы были уверены в своей способности справиться с личными проблемами?", true},
+			{5, "...вам казалось, что всё идёт так, как вы хотите?", true},
+			{6, "...вы понимали, что не справляетесь со всем, что нужно сделать?", false},
+			{7, "...вы могли справиться с раздражением?", true},
+			{8, "...вы чувствовали, что владеете ситуацией?", true},
+			{9, "...вы злились из-за того, что было вне вашего контроля?", false},
+			{10, "...вам казалось, что трудности накопились настолько, что вы не можете их преодолеть?", false},
+		},
+		Options: stressOptions,
+		Bands: []Band{
+			{0, 13, "low", "Низкий стресс", false},
+			{14, 26, "moderate", "Умеренный стресс", false},
+			{27, 40, "high", "Высокий стресс", true},
+		},
+	},
+}
+
+// Get возвращает описание опросника
+func Get(id ID) (Definition, bool) {
+	d, ok := definitions[id]
+	return d, ok
+}
+
+// All возвращает все опросники в фиксированном порядке
+func All() []Definition {
+	return []Definition{definitions[PHQ9], definitions[GAD7], definitions[PSS10]}
+}
+
+// ScoreAnswers проверяет ответы и считает балл
+func (d Definition) ScoreAnswers(answers []int) (Score, error) {
+	if len(answers) != len(d.Items) {
+		return Score{}, fmt.Errorf("%s: expected %d answers, got %d", d.ID, len(d.Items), len(answers))
+	}
+	minV, maxV := d.Options[0].Value, d.Options[len(d.Options)-1].Value
+
+	score := Score{Max: maxV * len(d.Items)}
+	for i, a := range answers {
+		if a < minV || a > maxV {
+			return Score{}, fmt.Errorf("%s: answer %d out of range %d-%d", d.ID, i+1, minV, maxV)
+		}
+		if d.Items[i].Reverse {
+			a = maxV - a
+		}
+		score.Total += a
+	}
+
+	for _, b := range d.Bands {
+		if score.Total >= b.Min && score.Total <= b.Max {
+			score.Severity = b.Severity
+			score.Label = b.Label
+			if b.Alert {
+				score.Alerts = append(score.Alerts, fmt.Sprintf("%s: %s (%d/%d) — рекомендуется обсудить результат со специалистом", d.Title, b.Label, score.Total, score.Max))
+			}
+			break
+		}
+	}
+	for _, ia := range d.ItemAlerts {
+		if answers[ia.Number-1] > 0 {
+			score.Alerts = append(score.Alerts, ia.Message)
+		}
+	}
+	return score, nil
+}
@@ -0,0 +1,106 @@
+package questionnaire
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScore_PHQ9(t *testing.T) {
+	def, _ := Get(PHQ9)
+
+	score, err := def.ScoreAnswers([]int{1, 1, 1, 1, 1, 1, 0, 0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score.Total != 6 || score.Severity != "mild" || len(score.Alerts) != 0 {
+		t.Errorf("Unexpected score: %+v", score)
+	}
+
+	// Тяжёлая депрессия + пункт 9 → две тревоги
+	score, _ = def.ScoreAnswers([]int{3, 3, 3, 3, 2, 2, 2, 2, 1})
+	if score.Total != 21 || score.Severity != "severe" {
+		t.Errorf("Expected severe 21, got %+v", score)
+	}
+	if len(score.Alerts) != 2 {
+		t.Errorf("Expected band + item-9 alerts, got %v", score.Alerts)
+	}
+
+	// Пункт 9 поднимает тревогу даже при низком общем балле
+	score, _ = def.ScoreAnswers([]int{0, 0, 0, 0, 0, 0, 0, 0, 1})
+	if len(score.Alerts) != 1 {
+		t.Errorf("Expected item-9 alert, got %v", score.Alerts)
+	}
+}
+
+func TestScore_PSS10_Reverse(t *testing.T) {
+	def, _ := Get(PSS10)
+	// Все ответы «Никогда»: прямые пункты дают 0, обратные (4, 5, 7, 8) — по 4
+	score, err := def.ScoreAnswers(make([]int, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score.Total != 16 || score.Severity != "moderate" {
+		t.Errorf("Expected 16 (moderate), got %+v", score)
+	}
+}
+
+func TestScore_Validation(t *testing.T) {
+	def, _ := Get(GAD7)
+	if _, err := def.ScoreAnswers([]int{1, 2, 3}); err == nil {
+		t.Error("Expected error for wrong answer count")
+	}
+	if _, err := def.ScoreAnswers([]int{0, 0, 0, 0, 0, 0, 4}); err == nil {
+		t.Error("Expected error for out-of-range answer")
+	}
+}
+
+func TestStore_HistoryAndPhases(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	submit := func(day int, answers []int, phase string) Response {
+		r, err := store.Submit(Response{QuestionnaireID: GAD7, PersonID: "me", Answers: answers, Phase: phase, Timestamp: base.AddDate(0, 0, day)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	first := submit(0, []int{3, 3, 3, 2, 2, 2, 2}, "Detox")
+	submit(5, []int{2, 2, 2, 2, 1, 1, 1}, "Detox")
+	submit(12, []int{1, 1, 1, 1, 1, 0, 0}, "Rewire")
+
+	if _, err := store.Submit(Response{QuestionnaireID: "unknown", Answers: []int{1}}); err == nil {
+		t.Error("Expected error for unknown questionnaire")
+	}
+	if len(first.Score.Alerts) == 0 {
+		t.Error("Expected alert for severe anxiety")
+	}
+	if err := store.LinkEntry(first.ID, "entry1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Перезагрузка из файла
+	reloaded, _ := NewStore(dir)
+	history := reloaded.History("me", GAD7)
+	if len(history) != 3 || history[0].ID != first.ID {
+		t.Fatalf("Unexpected history: %+v", history)
+	}
+	if got := reloaded.ByEntry("entry1"); len(got) != 1 {
+		t.Errorf("Expected 1 response linked to entry, got %d", len(got))
+	}
+	if got := reloaded.Alerts("me"); len(got) != 1 {
+		t.Errorf("Expected 1 alert response, got %d", len(got))
+	}
+
+	phases := reloaded.PhaseSummaries("me", GAD7)
+	if len(phases) != 2 || phases[0].Phase != "Detox" {
+		t.Fatalf("Unexpected phases: %+v", phases)
+	}
+	if phases[0].Count != 2 || phases[0].Change != 11-17 {
+		t.Errorf("Unexpected Detox summary: %+v", phases[0])
+	}
+}
@@ -0,0 +1,191 @@
+package questionnaire
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Response — заполненный опросник
+type Response struct {
+	ID              string    `json:"id"`
+	QuestionnaireID ID        `json:"questionnaire"`
+	PersonID        string    `json:"person_id"`
+	Timestamp       time.Time `json:"timestamp"`
+	Answers         []int     `json:"answers"`
+	Score           Score     `json:"score"`
+	EntryIDs        []string  `json:"entry_ids,omitempty"` // связанные записи дневника
+	Phase           string    `json:"phase,omitempty"`     // фаза protocol36 на момент заполнения
+}
+
+// PhaseSummary — динамика баллов внутри фазы protocol36
+type PhaseSummary struct {
+	Phase      string    `json:"phase"`
+	Count      int       `json:"count"`
+	FirstScore int       `json:"first_score"`
+	LastScore  int       `json:"last_score"`
+	AvgScore   float64   `json:"avg_score"`
+	Change     int       `json:"change"` // last - first; отрицательное = улучшение
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+}
+
+// Store — хранилище ответов (JSON-файл в каталоге данных)
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	responses []Response
+}
+
+// NewStore открывает хранилище в каталоге dataDir
+func NewStore(dataDir string) (*Store, error) {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, err
+	}
+	s := &Store{path: filepath.Join(dataDir, "questionnaires.json")}
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &s.responses); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", s.path, err)
+		}
+	}
+	return s, nil
+}
+
+// Submit считает балл и сохраняет ответ
+func (s *Store) Submit(r Response) (Response, error) {
+	def, ok := Get(r.QuestionnaireID)
+	if !ok {
+		return Response{}, fmt.Errorf("unknown questionnaire %q", r.QuestionnaireID)
+	}
+	score, err := def.ScoreAnswers(r.Answers)
+	if err != nil {
+		return Response{}, err
+	}
+	r.Score = score
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Response{}, err
+	}
+	r.ID = hex.EncodeToString(id)
+	if r.Timestamp.IsZero() {
+		r.Timestamp = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, r)
+	return r, s.save()
+}
+
+// History возвращает ответы человека по опроснику (все опросники, если qid пуст), старые первыми
+func (s *Store) History(personID string, qid ID) []Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []Response
+	for _, r := range s.responses {
+		if personID != "" && r.PersonID != personID {
+			continue
+		}
+		if qid != "" && r.QuestionnaireID != qid {
+			continue
+		}
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Timestamp.Before(result[j].Timestamp)
+	})
+	return result
+}
+
+// ByEntry возвращает ответы, связанные с записью дневника
+func (s *Store) ByEntry(entryID string) []Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []Response
+	for _, r := range s.responses {
+		for _, id := range r.EntryIDs {
+			if id == entryID {
+				result = append(result, r)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// LinkEntry привязывает запись дневника к ответу
+func (s *Store) LinkEntry(responseID, entryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.responses {
+		if s.responses[i].ID != responseID {
+			continue
+		}
+		for _, id := range s.responses[i].EntryIDs {
+			if id == entryID {
+				return nil
+			}
+		}
+		s.responses[i].EntryIDs = append(s.responses[i].EntryIDs, entryID)
+		return s.save()
+	}
+	return fmt.Errorf("response %s: %w", responseID, os.ErrNotExist)
+}
+
+// Alerts возвращает ответы с тревожными сигналами
+func (s *Store) Alerts(personID string) []Response {
+	var result []Response
+	for _, r := range s.History(personID, "") {
+		if len(r.Score.Alerts) > 0 {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// PhaseSummaries показывает, как менялся балл внутри каждой фазы protocol36.
+// Фазы упорядочены по первому заполнению.
+func (s *Store) PhaseSummaries(personID string, qid ID) []PhaseSummary {
+	var summaries []PhaseSummary
+	index := make(map[string]int)
+	for _, r := range s.History(personID, qid) {
+		if r.Phase == "" {
+			continue
+		}
+		i, ok := index[r.Phase]
+		if !ok {
+			index[r.Phase] = len(summaries)
+			summaries = append(summaries, PhaseSummary{Phase: r.Phase, FirstScore: r.Score.Total, From: r.Timestamp})
+			i = len(summaries) - 1
+		}
+		ps := &summaries[i]
+		ps.AvgScore = (ps.AvgScore*float64(ps.Count) + float64(r.Score.Total)) / float64(ps.Count+1)
+		ps.Count++
+		ps.LastScore = r.Score.Total
+		ps.Change = ps.LastScore - ps.FirstScore
+		ps.To = r.Timestamp
+	}
+	return summaries
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.responses, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
@@ -0,0 +1,256 @@
+// Package recovery — социальное восстановление: секрет (приватный ключ или
+// пароль бэкапа) делится по схеме Шамира между доверенными людьми, каждая
+// доля шифруется на ключ узла получателя.
+//
+// Поток:
+//  1. Владелец вызывает NewShareSet и рассылает SealedShare доверенным людям.
+//  2. При потере ключа доверенный человек открывает свою долю (OpenShare)
+//     и передаёт владельцу текстовую Piece (при встрече, по телефону).
+//  3. Владелец собирает k долей и вызывает Recover / RecoverKey.
+//
+// Доли ключа несут и историю ключей идентичности (она не секретна): без неё
+// восстановленный после ротаций ключ стал бы корнем новой идентичности.
+package recovery
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"strings"
+	"time"
+)
+
+// Kind — что именно разделено
+type Kind string
+
+const (
+	KindKey        Kind = "key"        // seed ed25519 ключа узла
+	KindPassphrase Kind = "passphrase" // пароль зашифрованного бэкапа
+)
+
+// piecePrefix — префикс текстовой доли (версия формата)
+const piecePrefix = "idshare1:"
+
+var ErrWrongKey = errors.New("recovered key does not match the owner key: shares are corrupted or from different sets")
+
+// Trustee — доверенный человек, получающий долю
+type Trustee struct {
+	PersonID  string            `json:"person_id"`
+	Name      string            `json:"name"`
+	PublicKey ed25519.PublicKey `json:"-"`
+}
+
+// Piece — открытая доля вместе с данными набора (то, что доверенный возвращает владельцу)
+type Piece struct {
+	SetID     string          `json:"set_id"`
+	Kind      Kind            `json:"kind"`
+	Owner     string          `json:"owner"` // hex ключа владельца на момент разделения
+	Threshold int             `json:"threshold"`
+	Total     int             `json:"total"`
+	Share     Share           `json:"share"`
+	History   json.RawMessage `json:"history,omitempty"` // identity.KeyHistory владельца (для KindKey)
+}
+
+// SealedShare — доля, зашифрованная на ключ доверенного человека
+type SealedShare struct {
+	SetID     string    `json:"set_id"`
+	PersonID  string    `json:"person_id"`
+	Name      string    `json:"name"`
+	Recipient string    `json:"recipient"` // hex ключа доверенного
+	Owner     string    `json:"owner"`     // hex ключа владельца (отправитель)
+	Index     int       `json:"index"`
+	Threshold int       `json:"threshold"`
+	Total     int       `json:"total"`
+	Box       []byte    `json:"box"` // crypto.SealForPeer(Piece в JSON)
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ShareSet — результат одного разделения
+type ShareSet struct {
+	ID        string        `json:"id"`
+	Kind      Kind          `json:"kind"`
+	Owner     string        `json:"owner"`
+	Threshold int           `json:"threshold"`
+	Total     int           `json:"total"`
+	Shares    []SealedShare `json:"shares"`
+	CreatedAt time.Time     `json:"created_at"`
+	Stale     bool          `json:"stale,omitempty"` // ключ с тех пор ротирован: доли восстановят выведенный ключ
+}
+
+// NewShareSet делит секрет между доверенными (по одной доле на каждого)
+// и шифрует каждую долю на ключ получателя
+func NewShareSet(owner *crypto.KeyPair, kind Kind, secret []byte, threshold int, trustees []Trustee, now time.Time) (*ShareSet, error) {
+	return newShareSet(owner, kind, secret, nil, threshold, trustees, now)
+}
+
+func newShareSet(owner *crypto.KeyPair, kind Kind, secret []byte, history json.RawMessage, threshold int, trustees []Trustee, now time.Time) (*ShareSet, error) {
+	if kind != KindKey && kind != KindPassphrase {
+		return nil, fmt.Errorf("unknown secret kind %q", kind)
+	}
+	seen := make(map[string]bool, len(trustees))
+	for _, t := range trustees {
+		if len(t.PublicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("trustee %s has no valid public key", t.PersonID)
+		}
+		k := hex.EncodeToString(t.PublicKey)
+		if seen[k] {
+			return nil, fmt.Errorf("trustee %s is listed twice", t.PersonID)
+		}
+		seen[k] = true
+	}
+	shares, err := Split(secret, len(trustees), threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	set := &ShareSet{
+		ID:        hex.EncodeToString(id),
+		Kind:      kind,
+		Owner:     owner.ToHex(),
+		Threshold: threshold,
+		Total:     len(trustees),
+		CreatedAt: now,
+	}
+	for i, t := range trustees {
+		piece := Piece{SetID: set.ID, Kind: kind, Owner: set.Owner, Threshold: threshold, Total: set.Total, Share: shares[i], History: history}
+		plain, err := json.Marshal(piece)
+		if err != nil {
+			return nil, err
+		}
+		box, err := owner.SealForPeer(t.PublicKey, nil, plain)
+		clear(plain)
+		clear(shares[i].Y)
+		if err != nil {
+			return nil, fmt.Errorf("seal share for %s: %w", t.PersonID, err)
+		}
+		set.Shares = append(set.Shares, SealedShare{
+			SetID:     set.ID,
+			PersonID:  t.PersonID,
+			Name:      t.Name,
+			Recipient: hex.EncodeToString(t.PublicKey),
+			Owner:     set.Owner,
+			Index:     int(shares[i].X),
+			Threshold: threshold,
+			Total:     set.Total,
+			Box:       box,
+			CreatedAt: now,
+		})
+	}
+	return set, nil
+}
+
+// SplitKey делит seed приватного ключа владельца; history — его история
+// ключей в JSON (identity.KeyHistory), она попадает в каждую долю
+func SplitKey(owner *crypto.KeyPair, history json.RawMessage, threshold int, trustees []Trustee, now time.Time) (*ShareSet, error) {
+	return newShareSet(owner, KindKey, owner.PrivateKey.Seed(), history, threshold, trustees, now)
+}
+
+// OpenShare расшифровывает долю, адресованную ключу trustee
+func OpenShare(trustee *crypto.KeyPair, s SealedShare) (Piece, error) {
+	if s.Recipient != trustee.ToHex() {
+		return Piece{}, fmt.Errorf("share is addressed to %s..., not to this key", prefix(s.Recipient))
+	}
+	owner, err := hex.DecodeString(s.Owner)
+	if err != nil || len(owner) != ed25519.PublicKeySize {
+		return Piece{}, errors.New("share has an invalid owner key")
+	}
+	plain, err := trustee.OpenFromPeer(owner, s.Box, nil)
+	if err != nil {
+		return Piece{}, err
+	}
+	defer clear(plain)
+	var p Piece
+	if err := json.Unmarshal(plain, &p); err != nil {
+		return Piece{}, fmt.Errorf("parse share: %w", err)
+	}
+	// Открытые метаданные не должны расходиться с зашифрованными
+	if p.SetID != s.SetID || p.Owner != s.Owner || int(p.Share.X) != s.Index {
+		return Piece{}, errors.New("share metadata does not match its sealed content")
+	}
+	return p, nil
+}
+
+// Encode возвращает текстовое представление доли для передачи владельцу
+func (p Piece) Encode() string {
+	data, _ := json.Marshal(p)
+	return piecePrefix + base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodePiece разбирает текстовую долю
+func DecodePiece(s string) (Piece, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, piecePrefix) {
+		return Piece{}, fmt.Errorf("share must start with %q", piecePrefix)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(s, piecePrefix))
+	if err != nil {
+		return Piece{}, fmt.Errorf("share is damaged: %w", err)
+	}
+	var p Piece
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Piece{}, fmt.Errorf("share is damaged: %w", err)
+	}
+	return p, nil
+}
+
+// Recover восстанавливает секрет из долей одного набора
+func Recover(pieces []Piece) (Kind, []byte, error) {
+	if len(pieces) == 0 {
+		return "", nil, ErrTooFewShares
+	}
+	first := pieces[0]
+	shares := make([]Share, 0, len(pieces))
+	for _, p := range pieces {
+		if p.SetID != first.SetID || p.Owner != first.Owner || p.Kind != first.Kind || p.Threshold != first.Threshold ||
+			!bytes.Equal(p.History, first.History) {
+			return "", nil, errors.New("shares belong to different sets")
+		}
+		shares = append(shares, p.Share)
+	}
+	if len(shares) < first.Threshold {
+		return "", nil, fmt.Errorf("%w: have %d, need %d", ErrTooFewShares, len(shares), first.Threshold)
+	}
+	secret, err := Combine(shares)
+	if err != nil {
+		return "", nil, err
+	}
+	return first.Kind, secret, nil
+}
+
+// RecoverKey восстанавливает ключ и проверяет, что он совпадает с ключом владельца
+func RecoverKey(pieces []Piece) (*crypto.KeyPair, error) {
+	kind, secret, err := Recover(pieces)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(secret)
+	if kind != KindKey {
+		return nil, fmt.Errorf("shares hold a %s, not a key", kind)
+	}
+	if len(secret) != ed25519.SeedSize {
+		return nil, ErrWrongKey
+	}
+	priv := ed25519.NewKeyFromSeed(secret)
+	kp := &crypto.KeyPair{PrivateKey: priv, PublicKey: priv.Public().(ed25519.PublicKey)}
+	if kp.ToHex() != pieces[0].Owner {
+		return nil, ErrWrongKey
+	}
+	return kp, nil
+}
+
+func prefix(key string) string {
+	if len(key) > 16 {
+		return key[:16]
+	}
+	return key
+}
@@ -0,0 +1,187 @@
+package recovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"ideal-core/pkg/crypto"
+	"testing"
+	"time"
+)
+
+func TestGFTables(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			if gfMul(byte(a), byte(b)) != gfMulSlow(byte(a), byte(b)) {
+				t.Fatalf("gfMul(%d,%d) mismatch", a, b)
+			}
+			if gfMul(gfDiv(byte(a), byte(b)), byte(b)) != byte(a) {
+				t.Fatalf("gfDiv(%d,%d) is not the inverse of gfMul", a, b)
+			}
+		}
+	}
+	// Пример из FIPS-197: {57} • {83} = {c1}
+	if gfMul(0x57, 0x83) != 0xc1 {
+		t.Fatalf("gfMul(0x57,0x83) = %#x, want 0xc1", gfMul(0x57, 0x83))
+	}
+}
+
+func TestSplitCombineAnySubset(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+	shares, err := Split(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Все сочетания по 3 и больше восстанавливают секрет
+	for mask := 0; mask < 1<<5; mask++ {
+		var subset []Share
+		for i := range shares {
+			if mask&(1<<i) != 0 {
+				subset = append(subset, shares[i])
+			}
+		}
+		if len(subset) < 3 {
+			continue
+		}
+		got, err := Combine(subset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, secret) {
+			t.Fatalf("subset %05b recovered %q", mask, got)
+		}
+	}
+	// Двух долей недостаточно
+	if got, _ := Combine(shares[:2]); bytes.Equal(got, secret) {
+		t.Fatal("two shares must not reveal the secret")
+	}
+}
+
+func TestSplitValidation(t *testing.T) {
+	for _, c := range []struct{ n, k int }{{3, 1}, {2, 3}, {256, 2}} {
+		if _, err := Split([]byte{1}, c.n, c.k); err == nil {
+			t.Errorf("Split(n=%d, k=%d) should fail", c.n, c.k)
+		}
+	}
+	shares, _ := Split([]byte{1, 2}, 3, 2)
+	if _, err := Combine([]Share{shares[0], shares[0]}); !errors.Is(err, ErrDuplicateShare) {
+		t.Fatalf("duplicate share: got %v", err)
+	}
+}
+
+func TestSocialRecoveryOfKey(t *testing.T) {
+	owner, _ := crypto.GenerateKeyPair()
+	people := make([]*crypto.KeyPair, 4)
+	trustees := make([]Trustee, 4)
+	for i := range people {
+		people[i], _ = crypto.GenerateKeyPair()
+		trustees[i] = Trustee{PersonID: string(rune('a' + i)), PublicKey: people[i].PublicKey}
+	}
+
+	history := json.RawMessage(`{"root":"` + owner.ToHex() + `"}`)
+	set, err := SplitKey(owner, history, 3, trustees, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Shares) != 4 || set.Threshold != 3 {
+		t.Fatalf("unexpected set: %d shares, threshold %d", len(set.Shares), set.Threshold)
+	}
+
+	// Чужой ключ не открывает долю
+	if _, err := OpenShare(people[1], set.Shares[0]); err == nil {
+		t.Fatal("share opened by the wrong person")
+	}
+
+	var pieces []Piece
+	for _, i := range []int{3, 0, 2} {
+		p, err := OpenShare(people[i], set.Shares[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Доля проходит через текст (передаётся владельцу вручную)
+		decoded, err := DecodePiece(p.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		pieces = append(pieces, decoded)
+	}
+
+	if _, err := RecoverKey(pieces[:2]); !errors.Is(err, ErrTooFewShares) {
+		t.Fatalf("two pieces: got %v, want ErrTooFewShares", err)
+	}
+	kp, err := RecoverKey(pieces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kp.ToHex() != owner.ToHex() {
+		t.Fatal("recovered a different key")
+	}
+	// История ключей возвращается вместе с ключом
+	if string(pieces[0].History) != string(history) {
+		t.Fatalf("history lost: %s", pieces[0].History)
+	}
+
+	// Повреждённая доля обнаруживается по ключу владельца
+	pieces[1].Share.Y[0] ^= 1
+	if _, err := RecoverKey(pieces); !errors.Is(err, ErrWrongKey) {
+		t.Fatalf("corrupted piece: got %v, want ErrWrongKey", err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, _ := crypto.GenerateKeyPair()
+	a, _ := crypto.GenerateKeyPair()
+	b, _ := crypto.GenerateKeyPair()
+	set, err := NewShareSet(owner, KindPassphrase, []byte("backup password"), 2,
+		[]Trustee{{PersonID: "a", PublicKey: a.PublicKey}, {PersonID: "b", PublicKey: b.PublicKey}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddSet(*set); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Hold(set.Shares[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := reopened.GetSet(set.ID); err != nil || got.Total != 2 {
+		t.Fatalf("GetSet: %+v, %v", got, err)
+	}
+	held, err := reopened.HeldFor(set.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, _ := OpenShare(a, held)
+	p2, _ := OpenShare(b, set.Shares[1])
+	kind, secret, err := Recover([]Piece{p1, p2})
+	if err != nil || kind != KindPassphrase || string(secret) != "backup password" {
+		t.Fatalf("Recover: %s %q %v", kind, secret, err)
+	}
+
+	// После ротации устаревают только наборы долей ключа (пароль бэкапа прежний)
+	keySet, err := SplitKey(owner, nil, 2, []Trustee{{PersonID: "a", PublicKey: a.PublicKey}, {PersonID: "b", PublicKey: b.PublicKey}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddSet(*keySet); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.MarkKeyStale(owner.ToHex()); err != nil || n != 1 {
+		t.Fatalf("MarkKeyStale: %d, %v", n, err)
+	}
+	reopened, _ = NewStore(dir)
+	for _, got := range reopened.Sets() {
+		if got.Stale != (got.ID == keySet.ID) {
+			t.Errorf("set %s (%s): stale=%v", got.ID, got.Kind, got.Stale)
+		}
+	}
+}
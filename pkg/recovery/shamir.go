@@ -0,0 +1,144 @@
+package recovery
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// Схема Шамира над GF(256)
+//
+// Каждый байт секрета — свободный член случайного многочлена степени k-1
+// над полем GF(2^8) с неприводимым многочленом x^8+x^4+x^3+x+1 (0x11b, как
+// в AES). Доля i — значения всех многочленов в точке x = i (1..255).
+// Любые k долей восстанавливают секрет интерполяцией Лагранжа в нуле,
+// k-1 долей не дают о нём никакой информации.
+
+const MaxShares = 255
+
+var (
+	ErrTooFewShares   = errors.New("not enough shares to recover the secret")
+	ErrDuplicateShare = errors.New("duplicate share index")
+)
+
+// Share — одна доля: точка X и значения многочленов в ней
+type Share struct {
+	X byte   `json:"x"`
+	Y []byte `json:"y"`
+}
+
+var expTable [510]byte
+var logTable [256]byte
+
+func init() {
+	// Генератор 3 порождает мультипликативную группу поля
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		expTable[i] = x
+		expTable[i+255] = x
+		logTable[x] = byte(i)
+		x = gfMulSlow(x, 3)
+	}
+}
+
+func gfMulSlow(a, b byte) byte {
+	var p byte
+	for b != 0 {
+		if b&1 != 0 {
+			p ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return p
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+int(logTable[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if b == 0 {
+		panic("recovery: division by zero in GF(256)")
+	}
+	if a == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+255-int(logTable[b])]
+}
+
+// Split делит секрет на n долей, любые k из которых восстанавливают его
+func Split(secret []byte, n, k int) ([]Share, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("secret is empty")
+	}
+	if k < 2 || k > n || n > MaxShares {
+		return nil, fmt.Errorf("need 2 <= threshold <= shares <= %d, got %d of %d", MaxShares, k, n)
+	}
+
+	shares := make([]Share, n)
+	for i := range shares {
+		shares[i] = Share{X: byte(i + 1), Y: make([]byte, len(secret))}
+	}
+	coeffs := make([]byte, k)
+	defer clear(coeffs)
+	for b, s := range secret {
+		coeffs[0] = s
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			// Схема Горнера
+			x, y := shares[i].X, byte(0)
+			for j := k - 1; j >= 0; j-- {
+				y = gfMul(y, x) ^ coeffs[j]
+			}
+			shares[i].Y[b] = y
+		}
+	}
+	return shares, nil
+}
+
+// Combine восстанавливает секрет из долей (их должно быть не меньше порога —
+// при меньшем числе результат будет случайным, поэтому порог проверяет вызывающий)
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, ErrTooFewShares
+	}
+	size := len(shares[0].Y)
+	seen := make(map[byte]bool, len(shares))
+	for _, s := range shares {
+		if s.X == 0 {
+			return nil, errors.New("share index must not be zero")
+		}
+		if seen[s.X] {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateShare, s.X)
+		}
+		seen[s.X] = true
+		if len(s.Y) != size {
+			return nil, errors.New("shares have different lengths")
+		}
+	}
+
+	secret := make([]byte, size)
+	for i, si := range shares {
+		// Базисный многочлен Лагранжа в нуле: prod x_j / (x_j - x_i); вычитание в GF(2^8) — XOR
+		basis := byte(1)
+		for j, sj := range shares {
+			if i != j {
+				basis = gfMul(basis, gfDiv(sj.X, sj.X^si.X))
+			}
+		}
+		for b := range secret {
+			secret[b] ^= gfMul(basis, si.Y[b])
+		}
+	}
+	return secret, nil
+}
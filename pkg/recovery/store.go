@@ -0,0 +1,139 @@
+package recovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store — наборы долей владельца и доли, доверенные нам другими (JSON-файл в каталоге данных).
+// Доли хранятся только в зашифрованном виде.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	data storeData
+}
+
+type storeData struct {
+	Sets []ShareSet    `json:"sets"` // разделения нашего секрета
+	Held []SealedShare `json:"held"` // доли чужих секретов, адресованные нам
+}
+
+// NewStore открывает хранилище в каталоге dataDir
+func NewStore(dataDir string) (*Store, error) {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, err
+	}
+	s := &Store{path: filepath.Join(dataDir, "recovery.json")}
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &s.data); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", s.path, err)
+		}
+	}
+	return s, nil
+}
+
+// AddSet сохраняет новый набор долей
+func (s *Store) AddSet(set ShareSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Sets = append(s.data.Sets, set)
+	return s.save()
+}
+
+// Sets возвращает все наборы (новые последними)
+func (s *Store) Sets() []ShareSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ShareSet(nil), s.data.Sets...)
+}
+
+// GetSet возвращает набор по ID
+func (s *Store) GetSet(id string) (ShareSet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, set := range s.data.Sets {
+		if set.ID == id {
+			return set, nil
+		}
+	}
+	return ShareSet{}, fmt.Errorf("share set %s: %w", id, os.ErrNotExist)
+}
+
+// DeleteSet удаляет набор (например, после ротации ключа он устарел)
+func (s *Store) DeleteSet(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, set := range s.data.Sets {
+		if set.ID == id {
+			s.data.Sets = append(s.data.Sets[:i], s.data.Sets[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("share set %s: %w", id, os.ErrNotExist)
+}
+
+// MarkKeyStale помечает наборы долей ключа retired (hex) устаревшими — после
+// ротации они восстанавливают выведенный ключ. Возвращает число помеченных.
+func (s *Store) MarkKeyStale(retired string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for i, set := range s.data.Sets {
+		if set.Kind == KindKey && set.Owner == retired && !set.Stale {
+			s.data.Sets[i].Stale = true
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, s.save()
+}
+
+// Hold сохраняет долю, которую нам доверили (повторная доставка заменяет прежнюю)
+func (s *Store) Hold(share SealedShare) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, h := range s.data.Held {
+		if h.SetID == share.SetID && h.Index == share.Index {
+			s.data.Held[i] = share
+			return s.save()
+		}
+	}
+	s.data.Held = append(s.data.Held, share)
+	return s.save()
+}
+
+// Held возвращает доверенные нам доли
+func (s *Store) Held() []SealedShare {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SealedShare(nil), s.data.Held...)
+}
+
+// HeldFor возвращает долю набора setID, если она у нас есть
+func (s *Store) HeldFor(setID string) (SealedShare, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, h := range s.data.Held {
+		if h.SetID == setID {
+			return h, nil
+		}
+	}
+	return SealedShare{}, fmt.Errorf("held share of set %s: %w", setID, os.ErrNotExist)
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
@@ -1,9 +1,14 @@
 package rl
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
+	"os"
+	"sort"
 	"sync"
 	"time"
 )
@@ -37,23 +42,56 @@ const (
 
 // Agent — Q-learning агент
 type Agent struct {
-	qTable       map[string]map[string]float64 // stateKey -> actionID -> Q-value
+	qTable       map[string]map[string]float64      // stateKey -> actionID -> Q-value
+	stats        map[string]map[string]*actionStats // stateKey -> actionID -> награды (для обмена опытом)
+	prior        *Prior                             // опыт других узлов (nil — без него)
 	mu           sync.RWMutex
 	learningRate float64
 	discount     float64
 	exploration  float64 // epsilon-greedy
 }
 
+// actionStats — сколько раз действие получало награду и их сумма
+type actionStats struct {
+	Count int     `json:"count"`
+	Sum   float64 `json:"sum"`
+}
+
 // NewAgent создаёт нового агента
 func NewAgent(lr, discount, exploration float64) *Agent {
 	return &Agent{
 		qTable:       make(map[string]map[string]float64),
+		stats:        make(map[string]map[string]*actionStats),
 		learningRate: lr,
 		discount:     discount,
 		exploration:  exploration,
 	}
 }
 
+// SetPrior подключает опыт других узлов: он задаёт начальное Q
+// для пар (состояние, действие), которых агент ещё не пробовал сам
+func (a *Agent) SetPrior(p *Prior) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.prior = p
+}
+
+// initialQ — Q до собственного опыта: оценка prior или 0
+func (a *Agent) initialQ(key, actionID string) float64 {
+	if a.prior == nil {
+		return 0
+	}
+	return a.prior.Q(HashState(key), actionID)
+}
+
+// q — текущая оценка действия
+func (a *Agent) q(key, actionID string) float64 {
+	if q, ok := a.qTable[key][actionID]; ok {
+		return q
+	}
+	return a.initialQ(key, actionID)
+}
+
 // stateKey генерирует строковый ключ из State (для таблицы Q)
 func stateKey(s State) string {
 	// Упрощённо: хэшируем векторы + чакры
@@ -78,7 +116,7 @@ func (a *Agent) ChooseAction(s State, available []Action) Action {
 	bestQ := -math.MaxFloat64
 	var best Action
 	for _, act := range available {
-		q := a.qTable[key][act.ID]
+		q := a.q(key, act.ID)
 		if q > bestQ {
 			bestQ = q
 			best = act
@@ -110,7 +148,7 @@ func (a *Agent) Learn(s State, action Action, reward Reward, nextS State) {
 	}
 
 	// Q-learning update: Q(s,a) += lr * (r + γ*max_a' Q(s',a') - Q(s,a))
-	currentQ := a.qTable[key][action.ID]
+	currentQ := a.q(key, action.ID)
 	
 	// Max Q for next state
 	maxNextQ := -math.MaxFloat64
@@ -125,19 +163,102 @@ func (a *Agent) Learn(s State, action Action, reward Reward, nextS State) {
 
 	newQ := currentQ + a.learningRate*(float64(reward)+a.discount*maxNextQ-currentQ)
 	a.qTable[key][action.ID] = newQ
+
+	// Статистика для обмена: награда ограничена диапазоном (чувствительность для DP)
+	if a.stats[key] == nil {
+		a.stats[key] = make(map[string]*actionStats)
+	}
+	st := a.stats[key][action.ID]
+	if st == nil {
+		st = &actionStats{}
+		a.stats[key][action.ID] = st
+	}
+	st.Count++
+	st.Sum += clipReward(float64(reward))
 }
 
-// ExportExperience экспортирует опыт для gossip-обмена (только агрегированные паттерны)
+// ExportExperience экспортирует опыт для gossip-обмена (только агрегированные паттерны).
+// Это точные значения — перед отправкой их нужно пропустить через Privatize.
 func (a *Agent) ExportExperience() []Experience {
-	// В реальности: отправлять только статистики, не сырые данные
-	return []Experience{}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	now := time.Now()
+	out := []Experience{}
+	for key, actions := range a.stats {
+		hash := HashState(key)
+		for id, st := range actions {
+			if st.Count == 0 {
+				continue
+			}
+			out = append(out, Experience{
+				StateHash: hash,
+				ActionID:  id,
+				AvgReward: st.Sum / float64(st.Count),
+				Count:     st.Count,
+				Timestamp: now,
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].StateHash != out[j].StateHash {
+			return out[i].StateHash < out[j].StateHash
+		}
+		return out[i].ActionID < out[j].ActionID
+	})
+	return out
 }
 
 // Experience — агрегированный опыт для обмена между узлами
 type Experience struct {
-	StateHash   string  // Хэш состояния (не сами данные)
-	ActionID    string
-	AvgReward   float64
-	Count       int
-	Timestamp   time.Time
+	StateHash string    `json:"state_hash"` // Хэш состояния (не сами данные)
+	ActionID  string    `json:"action_id"`
+	AvgReward float64   `json:"avg_reward"`
+	Count     int       `json:"count"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// HashState — идентификатор состояния для обмена: одинаков на всех узлах,
+// но не раскрывает векторы и чакры напрямую
+func HashState(key string) string {
+	sum := sha256.Sum256([]byte("ideal-core/rl/state/v1:" + key))
+	return hex.EncodeToString(sum[:12])
+}
+
+// agentSnapshot — сохраняемое состояние агента
+type agentSnapshot struct {
+	QTable map[string]map[string]float64      `json:"q_table"`
+	Stats  map[string]map[string]*actionStats `json:"stats"`
+}
+
+// SaveFile сохраняет Q-таблицу и статистику наград
+func (a *Agent) SaveFile(path string) error {
+	a.mu.RLock()
+	data, err := json.MarshalIndent(agentSnapshot{QTable: a.qTable, Stats: a.stats}, "", "  ")
+	a.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// LoadFile загружает состояние, сохранённое SaveFile (нет файла — os.ErrNotExist)
+func (a *Agent) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var snap agentSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if snap.QTable != nil {
+		a.qTable = snap.QTable
+	}
+	if snap.Stats != nil {
+		a.stats = snap.Stats
+	}
+	return nil
 }
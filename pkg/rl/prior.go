@@ -0,0 +1,378 @@
+package rl
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Обмен опытом между узлами.
+//
+// Узел периодически выпускает зашумлённый опыт (Release) и рассылает его
+// доверенным пирам. Полученный опыт становится «prior» — начальной оценкой
+// действий, которых агент ещё не пробовал сам. Выпуски содержат только новый
+// опыт, поэтому получатель складывает их по парам. Вес пары от одного пира
+// ограничен, чтобы один узел не перевешивал остальных и собственный опыт.
+
+const exchangeFile = "rl_exchange.json"
+
+const (
+	// MaxPeerWeight — наибольший вес одной пары от одного пира
+	MaxPeerWeight = 20
+	// PriorStrength — сколько наблюдений prior «стоит» против нуля:
+	// Q = avg · w / (w + PriorStrength)
+	PriorStrength = 10
+	// PriorMaxAge — пара пира, не пополнявшаяся дольше, не учитывается
+	PriorMaxAge = 30 * 24 * time.Hour
+	// MaxExperiences — наибольший размер одного выпуска пира
+	MaxExperiences = 5000
+)
+
+// ExperienceMessage — выпуск опыта для пиров (envelope.TypeExperience)
+type ExperienceMessage struct {
+	Epsilon     float64      `json:"epsilon"`
+	ReleasedAt  time.Time    `json:"released_at"`
+	Experiences []Experience `json:"experiences"`
+}
+
+// PeerExperience — накопленный опыт пира. Timestamp пары — когда она
+// последний раз пополнялась, ReceivedAt — время последнего выпуска.
+type PeerExperience struct {
+	Peer        string       `json:"peer"`
+	Experiences []Experience `json:"experiences"`
+	ReceivedAt  time.Time    `json:"received_at"`
+}
+
+// Prior — опыт других узлов
+type Prior struct {
+	mu    sync.RWMutex
+	peers map[string]PeerExperience
+	// index — StateHash → ActionID → вклад пиров (пересчитывается при Merge)
+	index map[string]map[string]priorCell
+}
+
+type priorCell struct {
+	Sum    float64 // Σ avg·w
+	Weight float64 // Σ w
+}
+
+// NewPrior создаёт пустой prior
+func NewPrior() *Prior {
+	return &Prior{peers: make(map[string]PeerExperience)}
+}
+
+// Merge добавляет выпуск пира к его накопленному опыту. Некорректные записи
+// отбрасываются, устаревшие пары заменяются новыми.
+func (p *Prior) Merge(peer string, exps []Experience, now time.Time) (int, error) {
+	if len(exps) > MaxExperiences {
+		return 0, fmt.Errorf("%d experiences from %s, at most %d accepted", len(exps), peer, MaxExperiences)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	type pair struct{ state, action string }
+	acc := make(map[pair]Experience)
+	for _, e := range p.peers[peer].Experiences {
+		if now.Sub(e.Timestamp) <= PriorMaxAge {
+			acc[pair{e.StateHash, e.ActionID}] = e
+		}
+	}
+	n := 0
+	for _, e := range exps {
+		if e.StateHash == "" || e.ActionID == "" || e.Count <= 0 || math.IsNaN(e.AvgReward) {
+			continue
+		}
+		k := pair{e.StateHash, e.ActionID}
+		if old, ok := acc[k]; ok {
+			sum := old.AvgReward*float64(old.Count) + clipReward(e.AvgReward)*float64(e.Count)
+			e.Count += old.Count
+			e.AvgReward = sum / float64(e.Count)
+		}
+		e.AvgReward = clipReward(e.AvgReward)
+		e.Timestamp = now
+		acc[k] = e
+		n++
+	}
+
+	merged := make([]Experience, 0, len(acc))
+	for _, e := range acc {
+		merged = append(merged, e)
+	}
+	// Свежие пары первыми: сверх MaxExperiences отбрасываются самые старые
+	sort.Slice(merged, func(a, b int) bool {
+		if !merged[a].Timestamp.Equal(merged[b].Timestamp) {
+			return merged[a].Timestamp.After(merged[b].Timestamp)
+		}
+		if merged[a].StateHash != merged[b].StateHash {
+			return merged[a].StateHash < merged[b].StateHash
+		}
+		return merged[a].ActionID < merged[b].ActionID
+	})
+	if len(merged) > MaxExperiences {
+		merged = merged[:MaxExperiences]
+	}
+	p.peers[peer] = PeerExperience{Peer: peer, Experiences: merged, ReceivedAt: now}
+	p.reindex(now)
+	return n, nil
+}
+
+// Forget удаляет опыт пира (например, после понижения доверия)
+func (p *Prior) Forget(peer string, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.peers, peer)
+	p.reindex(now)
+}
+
+// Estimate возвращает среднюю награду пиров для пары и её вес (0 — опыта нет)
+func (p *Prior) Estimate(stateHash, actionID string) (avg, weight float64) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c, ok := p.index[stateHash][actionID]
+	if !ok || c.Weight == 0 {
+		return 0, 0
+	}
+	return c.Sum / c.Weight, c.Weight
+}
+
+// Q — начальное Q для пары: оценка пиров, сжатая к нулю при малом весе
+func (p *Prior) Q(stateHash, actionID string) float64 {
+	avg, w := p.Estimate(stateHash, actionID)
+	return avg * w / (w + PriorStrength)
+}
+
+// Peers возвращает опыт пиров (недавно приславшие первыми)
+func (p *Prior) Peers() []PeerExperience {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	out := make([]PeerExperience, 0, len(p.peers))
+	for _, pe := range p.peers {
+		out = append(out, pe)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].ReceivedAt.After(out[b].ReceivedAt) })
+	return out
+}
+
+// Size — число пар (состояние, действие), для которых есть опыт пиров
+func (p *Prior) Size() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	n := 0
+	for _, actions := range p.index {
+		n += len(actions)
+	}
+	return n
+}
+
+func (p *Prior) reindex(now time.Time) {
+	p.index = make(map[string]map[string]priorCell)
+	for _, pe := range p.peers {
+		for _, e := range pe.Experiences {
+			if now.Sub(e.Timestamp) > PriorMaxAge {
+				continue
+			}
+			w := math.Min(float64(e.Count), MaxPeerWeight)
+			if p.index[e.StateHash] == nil {
+				p.index[e.StateHash] = make(map[string]priorCell)
+			}
+			c := p.index[e.StateHash][e.ActionID]
+			c.Sum += e.AvgReward * w
+			c.Weight += w
+			p.index[e.StateHash][e.ActionID] = c
+		}
+	}
+}
+
+// Exchange — бюджет приватности и prior узла (rl_exchange.json в каталоге данных)
+type Exchange struct {
+	mu     sync.Mutex
+	path   string
+	budget Budget
+	prior  *Prior
+	rng    *rand.Rand
+
+	// released — StateHash → ActionID → сколько опыта пары уже выпущено
+	released map[string]map[string]releasedStats
+}
+
+// releasedStats — точные счётчик и сумма наград пары на момент её выпуска
+type releasedStats struct {
+	Count int     `json:"count"`
+	Sum   float64 `json:"sum"`
+}
+
+// exchangeState — сохраняемое состояние Exchange
+type exchangeState struct {
+	Budget   Budget                              `json:"budget"`
+	Peers    []PeerExperience                    `json:"peers"`
+	Released map[string]map[string]releasedStats `json:"released,omitempty"`
+}
+
+// NewExchange открывает состояние обмена в каталоге dataDir. Лимит и окно
+// бюджета берутся из аргументов (0 — по умолчанию), траты — из файла.
+func NewExchange(dataDir string, limit float64, window time.Duration) (*Exchange, error) {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, err
+	}
+	x := &Exchange{
+		path:     filepath.Join(dataDir, exchangeFile),
+		budget:   NewBudget(limit, window),
+		prior:    NewPrior(),
+		rng:      NewNoiseSource(),
+		released: make(map[string]map[string]releasedStats),
+	}
+	data, err := os.ReadFile(x.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		var st exchangeState
+		if err := json.Unmarshal(data, &st); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", x.path, err)
+		}
+		x.budget.Spends = st.Budget.Spends
+		if st.Released != nil {
+			x.released = st.Released
+		}
+		for _, pe := range st.Peers {
+			x.prior.peers[pe.Peer] = pe
+		}
+		x.prior.reindex(time.Now())
+	}
+	return x, nil
+}
+
+// Prior возвращает опыт пиров (для Agent.SetPrior)
+func (x *Exchange) Prior() *Prior {
+	return x.prior
+}
+
+// Release выпускает опыт агента, накопленный после прошлого выпуска, с параметром
+// epsilon и списывает его из бюджета. Без нового опыта — ErrNoNewExperience.
+// Бюджет и отметка о выпуске сохраняются до выпуска: зашумлённые данные без
+// списания не появляются, и одно взаимодействие не выпускается дважды — даже
+// если шум скрыл пару целиком.
+func (x *Exchange) Release(a *Agent, epsilon float64, now time.Time) ([]Experience, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	fresh, current := x.fresh(a)
+	if len(fresh) == 0 {
+		return nil, ErrNoNewExperience
+	}
+	if err := x.budget.Spend(epsilon, now); err != nil {
+		return nil, err
+	}
+	for _, e := range fresh {
+		if x.released[e.StateHash] == nil {
+			x.released[e.StateHash] = make(map[string]releasedStats)
+		}
+		x.released[e.StateHash][e.ActionID] = current[e.StateHash+"/"+e.ActionID]
+	}
+	if err := x.save(); err != nil {
+		return nil, err
+	}
+	return Privatize(fresh, epsilon, x.rng)
+}
+
+// Pending — число пар с опытом, ещё не попавшим в выпуск
+func (x *Exchange) Pending(a *Agent) int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	fresh, _ := x.fresh(a)
+	return len(fresh)
+}
+
+// fresh возвращает опыт агента после прошлого выпуска и точные итоги этих пар
+func (x *Exchange) fresh(a *Agent) ([]Experience, map[string]releasedStats) {
+	out := []Experience{}
+	current := make(map[string]releasedStats)
+	for _, e := range a.ExportExperience() {
+		cur := releasedStats{Count: e.Count, Sum: e.AvgReward * float64(e.Count)}
+		prev := x.released[e.StateHash][e.ActionID]
+		if prev.Count > cur.Count {
+			// Статистика агента сброшена: прежний выпуск уже не вычесть
+			prev = releasedStats{}
+		}
+		n := cur.Count - prev.Count
+		if n == 0 {
+			continue
+		}
+		current[e.StateHash+"/"+e.ActionID] = cur
+		out = append(out, Experience{
+			StateHash: e.StateHash,
+			ActionID:  e.ActionID,
+			AvgReward: (cur.Sum - prev.Sum) / float64(n),
+			Count:     n,
+			Timestamp: e.Timestamp,
+		})
+	}
+	return out, current
+}
+
+// Merge принимает выпуск пира
+func (x *Exchange) Merge(peer string, exps []Experience, now time.Time) (int, error) {
+	n, err := x.prior.Merge(peer, exps, now)
+	if err != nil {
+		return 0, err
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return n, x.save()
+}
+
+// Forget удаляет опыт пира
+func (x *Exchange) Forget(peer string, now time.Time) error {
+	x.prior.Forget(peer, now)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.save()
+}
+
+// BudgetStatus — состояние бюджета
+type BudgetStatus struct {
+	Limit     float64       `json:"limit"`
+	Window    time.Duration `json:"window"`
+	Spent     float64       `json:"spent"`
+	Remaining float64       `json:"remaining"`
+	Spends    []Spend       `json:"spends"`
+}
+
+// Budget возвращает состояние бюджета на момент now
+func (x *Exchange) Budget(now time.Time) BudgetStatus {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	st := BudgetStatus{
+		Limit:     x.budget.Limit,
+		Window:    x.budget.Window,
+		Spent:     x.budget.Spent(now),
+		Remaining: x.budget.Remaining(now),
+		Spends:    []Spend{},
+	}
+	for _, s := range x.budget.Spends {
+		if now.Sub(s.At) < x.budget.Window {
+			st.Spends = append(st.Spends, s)
+		}
+	}
+	return st
+}
+
+// NextRelease — когда бюджета снова хватит на выпуск с epsilon
+func (x *Exchange) NextRelease(epsilon float64, now time.Time) time.Time {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.budget.NextAvailable(epsilon, now)
+}
+
+func (x *Exchange) save() error {
+	data, err := json.MarshalIndent(exchangeState{Budget: x.budget, Peers: x.prior.Peers(), Released: x.released}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(x.path, data, 0600)
+}
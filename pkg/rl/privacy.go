@@ -0,0 +1,182 @@
+package rl
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Дифференциальная приватность для обмена опытом.
+//
+// Единица приватности — одно взаимодействие (одна награда в Learn). Оно меняет
+// ровно одну пару (состояние, действие): счётчик на 1 и сумму наград не больше
+// чем на RewardRange. Поэтому на каждую пару хватает шума Лапласа с масштабом
+// 1/ε₁ для счётчика и RewardRange/ε₂ для суммы (ε₁ = ε₂ = ε/2), а выпуск целиком
+// стоит ε (параллельная композиция по парам). Всё, что дальше делается с
+// зашумлёнными числами (отсев, округление, пересылка нескольким пирам), бюджет
+// не тратит.
+//
+// Exchange выпускает только опыт, накопленный после прошлого выпуска, поэтому
+// каждое взаимодействие попадает ровно в один выпуск и теряет не больше ε за всё
+// время. Бюджет за окно ограничивает лишь частоту выпусков. Шум берётся из
+// crypto/rand: по предсказуемому генератору его можно вычесть.
+
+const (
+	// RewardMin, RewardMax — границы награды (Learn обрезает награду до них)
+	RewardMin = float64(RewardIgnored)
+	RewardMax = float64(RewardReturned)
+	// RewardRange — чувствительность суммы наград
+	RewardRange = RewardMax - RewardMin
+
+	// MinReleaseCount — пары с меньшим зашумлённым счётчиком не публикуются
+	MinReleaseCount = 3
+)
+
+// Значения по умолчанию для бюджета узла
+const (
+	DefaultEpsilon      = 0.5                // ε одного выпуска
+	DefaultBudgetLimit  = 3.5                // суммарный ε за окно
+	DefaultBudgetWindow = 7 * 24 * time.Hour // окно бюджета
+)
+
+var (
+	ErrBudgetExhausted = errors.New("privacy budget exhausted")
+	ErrNoNewExperience = errors.New("no new experience since the last release")
+)
+
+// cryptoSource — источник для math/rand поверх crypto/rand
+type cryptoSource struct{}
+
+func (cryptoSource) Uint64() uint64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("crypto/rand: %v", err))
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+func (s cryptoSource) Int63() int64 { return int64(s.Uint64() >> 1) }
+
+func (cryptoSource) Seed(int64) {}
+
+// NewNoiseSource — генератор шума для Privatize на crypto/rand
+func NewNoiseSource() *rand.Rand {
+	return rand.New(cryptoSource{})
+}
+
+// clipReward ограничивает награду диапазоном [RewardMin, RewardMax]
+func clipReward(r float64) float64 {
+	return math.Max(RewardMin, math.Min(RewardMax, r))
+}
+
+// laplace — шум Лапласа с масштабом b
+func laplace(rng *rand.Rand, b float64) float64 {
+	u := rng.Float64() - 0.5
+	for u == -0.5 {
+		u = rng.Float64() - 0.5
+	}
+	return -b * math.Copysign(math.Log(1-2*math.Abs(u)), u)
+}
+
+// Privatize зашумляет точный опыт (из ExportExperience) с параметром epsilon.
+// Пары с малым зашумлённым счётчиком отбрасываются, средняя награда
+// обрезается до допустимого диапазона.
+func Privatize(exps []Experience, epsilon float64, rng *rand.Rand) ([]Experience, error) {
+	if epsilon <= 0 || math.IsNaN(epsilon) || math.IsInf(epsilon, 0) {
+		return nil, fmt.Errorf("epsilon must be positive, got %v", epsilon)
+	}
+	half := epsilon / 2
+	out := []Experience{}
+	for _, e := range exps {
+		count := float64(e.Count) + laplace(rng, 1/half)
+		if count < MinReleaseCount {
+			continue
+		}
+		// Сумма сдвинутых наград: каждая в [0, RewardRange]
+		shifted := (clipReward(e.AvgReward)-RewardMin)*float64(e.Count) + laplace(rng, RewardRange/half)
+		avg := clipReward(shifted/count + RewardMin)
+		out = append(out, Experience{
+			StateHash: e.StateHash,
+			ActionID:  e.ActionID,
+			AvgReward: avg,
+			Count:     int(math.Round(count)),
+			Timestamp: e.Timestamp,
+		})
+	}
+	return out, nil
+}
+
+// Spend — одна трата бюджета
+type Spend struct {
+	Epsilon float64   `json:"epsilon"`
+	At      time.Time `json:"at"`
+}
+
+// Budget — бюджет приватности узла: не больше Limit за скользящее окно Window
+type Budget struct {
+	Limit  float64       `json:"limit"`
+	Window time.Duration `json:"window"`
+	Spends []Spend       `json:"spends"`
+}
+
+// NewBudget создаёт бюджет (нулевые значения заменяются значениями по умолчанию)
+func NewBudget(limit float64, window time.Duration) Budget {
+	if limit <= 0 {
+		limit = DefaultBudgetLimit
+	}
+	if window <= 0 {
+		window = DefaultBudgetWindow
+	}
+	return Budget{Limit: limit, Window: window}
+}
+
+// Spent — потрачено в текущем окне
+func (b *Budget) Spent(now time.Time) float64 {
+	total := 0.0
+	for _, s := range b.Spends {
+		if now.Sub(s.At) < b.Window {
+			total += s.Epsilon
+		}
+	}
+	return total
+}
+
+// Remaining — остаток бюджета в текущем окне
+func (b *Budget) Remaining(now time.Time) float64 {
+	return math.Max(0, b.Limit-b.Spent(now))
+}
+
+// NextAvailable — когда снова можно потратить epsilon (now, если уже можно)
+func (b *Budget) NextAvailable(epsilon float64, now time.Time) time.Time {
+	at := now
+	for _, s := range b.Spends {
+		if b.Remaining(at) >= epsilon-1e-9 {
+			break
+		}
+		if end := s.At.Add(b.Window); end.After(at) {
+			at = end
+		}
+	}
+	return at
+}
+
+// Spend списывает epsilon; при нехватке — ErrBudgetExhausted, ничего не списывая.
+// Старые траты за пределами окна забываются.
+func (b *Budget) Spend(epsilon float64, now time.Time) error {
+	if rem := b.Remaining(now); epsilon > rem+1e-9 {
+		return fmt.Errorf("%w: need ε=%.2f, %.2f of %.2f left until %s",
+			ErrBudgetExhausted, epsilon, rem, b.Limit, b.NextAvailable(epsilon, now).Format(time.RFC3339))
+	}
+	kept := b.Spends[:0]
+	for _, s := range b.Spends {
+		if now.Sub(s.At) < b.Window {
+			kept = append(kept, s)
+		}
+	}
+	b.Spends = append(kept, Spend{Epsilon: epsilon, At: now})
+	return nil
+}
@@ -0,0 +1,205 @@
+package rl
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestPrivatize_NoiseAndThreshold(t *testing.T) {
+	exps := []Experience{
+		{StateHash: "s1", ActionID: "a", AvgReward: 2, Count: 400},
+		{StateHash: "s2", ActionID: "a", AvgReward: 5, Count: 1},
+	}
+	rng := rand.New(rand.NewSource(1))
+
+	var sumAvg, sumCount float64
+	rare := 0
+	const rounds = 2000
+	for i := 0; i < rounds; i++ {
+		out, err := Privatize(exps, 1, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range out {
+			if e.AvgReward < RewardMin || e.AvgReward > RewardMax {
+				t.Fatalf("avg reward %v outside [%v, %v]", e.AvgReward, RewardMin, RewardMax)
+			}
+			if e.StateHash == "s2" {
+				rare++
+				continue
+			}
+			sumAvg += e.AvgReward
+			sumCount += float64(e.Count)
+		}
+	}
+	// Частая пара публикуется почти без искажений, единичная — лишь изредка
+	if got := sumAvg / rounds; math.Abs(got-2) > 0.05 {
+		t.Fatalf("mean released avg %.3f, want ≈2", got)
+	}
+	if got := sumCount / rounds; math.Abs(got-400) > 1 {
+		t.Fatalf("mean released count %.1f, want ≈400", got)
+	}
+	if rare > rounds/4 {
+		t.Fatalf("single-use pair released %d times of %d", rare, rounds)
+	}
+
+	if _, err := Privatize(exps, 0, rng); err == nil {
+		t.Fatal("zero epsilon accepted")
+	}
+}
+
+func TestBudget_Window(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := NewBudget(1, 24*time.Hour)
+	for i := 0; i < 2; i++ {
+		if err := b.Spend(0.5, now.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Spend(0.5, now.Add(2*time.Hour)); !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("expected ErrBudgetExhausted, got %v", err)
+	}
+	if got := b.NextAvailable(0.5, now.Add(2*time.Hour)); !got.Equal(now.Add(24 * time.Hour)) {
+		t.Fatalf("next available %v, want %v", got, now.Add(24*time.Hour))
+	}
+	if err := b.Spend(0.5, now.Add(24*time.Hour)); err != nil {
+		t.Fatalf("budget not restored after the window: %v", err)
+	}
+	if len(b.Spends) != 2 {
+		t.Fatalf("expired spends kept: %+v", b.Spends)
+	}
+}
+
+func TestExchange_PriorSeedsAgent(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now() // при загрузке опыт пиров старше PriorMaxAge отбрасывается
+	s := State{Vectors: [3][3]int{{1, 2, 3}}, Chakras: []int{4}}
+	calm, walk := Action{ID: "calm"}, Action{ID: "walk"}
+
+	// Узел A учится: «walk» возвращает клиентов, «calm» игнорируют
+	a := NewAgent(0.5, 0.9, 0)
+	for i := 0; i < 50; i++ {
+		a.Learn(s, walk, RewardReturned, s)
+		a.Learn(s, calm, RewardIgnored, s)
+	}
+	xa, err := NewExchange(dir+"/a", 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, err := xa.Release(a, 1, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(release) != 2 || release[0].StateHash != HashState(stateKey(s)) {
+		t.Fatalf("unexpected release: %+v", release)
+	}
+	// Выпущенный опыт второй раз не выпускается
+	if _, err := xa.Release(a, 0.5, now); !errors.Is(err, ErrNoNewExperience) {
+		t.Fatalf("expected ErrNoNewExperience, got %v", err)
+	}
+	a.Learn(s, walk, RewardReturned, s)
+	if _, err := xa.Release(a, 0.5, now); !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("expected ErrBudgetExhausted, got %v", err)
+	}
+	if n := xa.Pending(a); n != 1 {
+		t.Fatalf("pending pairs %d, want 1", n)
+	}
+
+	// Узел B без своего опыта выбирает то, что сработало у A
+	xb, err := NewExchange(dir+"/b", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := xb.Merge("alice", release, now); err != nil {
+		t.Fatal(err)
+	}
+	b := NewAgent(0.5, 0.9, 0)
+	b.SetPrior(xb.Prior())
+	if got := b.ChooseAction(s, []Action{calm, walk}); got.ID != "walk" {
+		t.Fatalf("chose %s, want walk from the prior", got.ID)
+	}
+	if q := xb.Prior().Q(HashState(stateKey(s)), "walk"); q <= 0 || q >= RewardMax {
+		t.Fatalf("prior Q %.2f should be shrunk towards zero", q)
+	}
+
+	// Prior и бюджет переживают перезапуск
+	xb2, err := NewExchange(dir+"/b", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xb2.Prior().Size() != 2 {
+		t.Fatalf("prior size after restart %d, want 2", xb2.Prior().Size())
+	}
+	xa2, _ := NewExchange(dir+"/a", 1, time.Hour)
+	if st := xa2.Budget(now); st.Spent != 1 {
+		t.Fatalf("spent after restart %.2f, want 1", st.Spent)
+	}
+
+	// Перезапуск агента сохраняет статистику
+	path := dir + "/agent.json"
+	if err := a.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	a2 := NewAgent(0.5, 0.9, 0)
+	if err := a2.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := a2.ExportExperience(); len(got) != 2 || got[1].Count != 51 || got[1].AvgReward != RewardMax {
+		t.Fatalf("unexpected experience after reload: %+v", got)
+	}
+}
+
+func TestExchange_ReleasesOnlyNewExperience(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	s := State{Vectors: [3][3]int{{1, 2, 3}}}
+	walk := Action{ID: "walk"}
+
+	a := NewAgent(0.5, 0.9, 0)
+	for i := 0; i < 100; i++ {
+		a.Learn(s, walk, RewardReturned, s)
+	}
+	x, err := NewExchange(dir+"/a", 20, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := x.Release(a, 5, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		a.Learn(s, walk, RewardIgnored, s)
+	}
+	// Отметка о выпуске переживает перезапуск
+	x, err = NewExchange(dir+"/a", 20, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := x.Release(a, 5, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 1 || len(second) != 1 {
+		t.Fatalf("unexpected releases: %+v, %+v", first, second)
+	}
+	if e := second[0]; math.Abs(float64(e.Count-200)) > 10 || math.Abs(e.AvgReward-float64(RewardIgnored)) > 0.5 {
+		t.Fatalf("second release %+v should cover only the 200 new interactions", e)
+	}
+
+	// Получатель складывает выпуски: (100·5 + 200·(−1)) / 300 ≈ 1
+	xb, err := NewExchange(dir+"/b", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range [][]Experience{first, second} {
+		if _, err := xb.Merge("alice", rel, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if avg, _ := xb.Prior().Estimate(first[0].StateHash, "walk"); math.Abs(avg-1) > 0.3 {
+		t.Fatalf("merged avg %.2f, want ≈1", avg)
+	}
+}
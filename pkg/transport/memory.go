@@ -0,0 +1,249 @@
+package transport
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Conditions — качество канала в одну сторону
+type Conditions struct {
+	Latency time.Duration // базовая задержка
+	Jitter  time.Duration // случайная добавка 0..Jitter (может переупорядочить кадры)
+	Loss    float64       // вероятность потери кадра, 0..1
+}
+
+// Network — узлы в одном процессе. Адрес узла — произвольная строка.
+type Network struct {
+	mu       sync.Mutex
+	rng      *rand.Rand
+	defaults Conditions
+	links    map[[2]string]Conditions // (от, к) → условия
+	nodes    map[string]*Memory
+
+	sent, dropped atomic.Int64
+}
+
+// NewNetwork создаёт сеть; seed делает потери и задержки воспроизводимыми
+func NewNetwork(seed int64) *Network {
+	return &Network{
+		rng:   rand.New(rand.NewSource(seed)),
+		links: make(map[[2]string]Conditions),
+		nodes: make(map[string]*Memory),
+	}
+}
+
+// SetConditions задаёт условия для всех каналов без собственных настроек
+func (n *Network) SetConditions(c Conditions) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.defaults = c
+}
+
+// SetLink задаёт условия канала from → to (Loss: 1 — разрыв в одну сторону)
+func (n *Network) SetLink(from, to string, c Conditions) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.links[[2]string{from, to}] = c
+}
+
+// Partition разрывает связь между a и b в обе стороны (Heal восстанавливает)
+func (n *Network) Partition(a, b string) {
+	n.SetLink(a, b, Conditions{Loss: 1})
+	n.SetLink(b, a, Conditions{Loss: 1})
+}
+
+// Heal возвращает каналам между a и b условия по умолчанию
+func (n *Network) Heal(a, b string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.links, [2]string{a, b})
+	delete(n.links, [2]string{b, a})
+}
+
+// Stats — отправлено и потеряно кадров
+func (n *Network) Stats() (sent, dropped int64) {
+	return n.sent.Load(), n.dropped.Load()
+}
+
+// Node создаёт узел с адресом addr
+func (n *Network) Node(addr string, key func() *crypto.KeyPair) *Memory {
+	return &Memory{
+		net:   n,
+		addr:  addr,
+		key:   key,
+		inbox: make(chan Packet, inboxSize),
+		peers: make(map[string]string),
+		done:  make(chan struct{}),
+	}
+}
+
+// delay решает судьбу кадра: задержка или потеря
+func (n *Network) delay(from, to string) (time.Duration, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	c, ok := n.links[[2]string{from, to}]
+	if !ok {
+		c = n.defaults
+	}
+	if c.Loss > 0 && n.rng.Float64() < c.Loss {
+		return 0, false
+	}
+	d := c.Latency
+	if c.Jitter > 0 {
+		d += time.Duration(n.rng.Int63n(int64(c.Jitter)))
+	}
+	return d, true
+}
+
+func (n *Network) lookup(addr string) *Memory {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.nodes[addr]
+}
+
+// Memory — узел сети Network
+type Memory struct {
+	net  *Network
+	addr string
+	key  func() *crypto.KeyPair
+
+	mu    sync.Mutex
+	peers map[string]string // ключ пира (hex) → адрес
+	inbox chan Packet
+	done  chan struct{}
+	once  sync.Once
+}
+
+func (m *Memory) Listen() error {
+	m.net.mu.Lock()
+	defer m.net.mu.Unlock()
+	if _, taken := m.net.nodes[m.addr]; taken {
+		return fmt.Errorf("address %s is already in use", m.addr)
+	}
+	m.net.nodes[m.addr] = m
+	return nil
+}
+
+func (m *Memory) Addr() string {
+	return m.addr
+}
+
+// Dial соединяет узлы (в обе стороны, как TCP-сессия). Рукопожатие не теряется.
+func (m *Memory) Dial(ctx context.Context, addr string) (ed25519.PublicKey, error) {
+	if m.isClosed() {
+		return nil, ErrClosed
+	}
+	peer := m.net.lookup(addr)
+	if peer == nil || peer.isClosed() {
+		return nil, fmt.Errorf("dial %s: connection refused", addr)
+	}
+	if _, ok := m.net.delay(m.addr, addr); !ok {
+		return nil, fmt.Errorf("dial %s: timeout", addr)
+	}
+	peerKey := peer.key().PublicKey
+	m.link(peerKey, addr)
+	peer.link(m.key().PublicKey, m.addr)
+	return peerKey, nil
+}
+
+func (m *Memory) link(key ed25519.PublicKey, addr string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.peers[hex.EncodeToString(key)] = addr
+}
+
+func (m *Memory) Send(ctx context.Context, peer ed25519.PublicKey, frame []byte) error {
+	if m.isClosed() {
+		return ErrClosed
+	}
+	m.mu.Lock()
+	addr, ok := m.peers[hex.EncodeToString(peer)]
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w %s", ErrNoSession, hex.EncodeToString(peer)[:16])
+	}
+	target := m.net.lookup(addr)
+	if target == nil || target.isClosed() {
+		m.mu.Lock()
+		delete(m.peers, hex.EncodeToString(peer))
+		m.mu.Unlock()
+		return fmt.Errorf("%w %s: peer went away", ErrNoSession, hex.EncodeToString(peer)[:16])
+	}
+
+	m.net.sent.Add(1)
+	d, ok := m.net.delay(m.addr, addr)
+	if !ok {
+		m.net.dropped.Add(1)
+		return nil // потеря незаметна отправителю, как в настоящей сети
+	}
+	pkt := Packet{From: m.key().PublicKey, Data: append([]byte(nil), frame...)}
+	if d == 0 {
+		target.deliver(pkt)
+	} else {
+		time.AfterFunc(d, func() { target.deliver(pkt) })
+	}
+	return nil
+}
+
+func (m *Memory) deliver(p Packet) {
+	if m.isClosed() {
+		m.net.dropped.Add(1)
+		return
+	}
+	select {
+	case m.inbox <- p:
+	default:
+		m.net.dropped.Add(1) // переполнение очереди получателя
+	}
+}
+
+func (m *Memory) Receive(ctx context.Context) (Packet, error) {
+	select {
+	case p := <-m.inbox:
+		return p, nil
+	case <-m.done:
+		return Packet{}, ErrClosed
+	case <-ctx.Done():
+		return Packet{}, ctx.Err()
+	}
+}
+
+func (m *Memory) Peers() []ed25519.PublicKey {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]ed25519.PublicKey, 0, len(m.peers))
+	for k := range m.peers {
+		b, _ := hex.DecodeString(k)
+		out = append(out, b)
+	}
+	return out
+}
+
+func (m *Memory) isClosed() bool {
+	select {
+	case <-m.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close снимает узел с сети; пиры узнают об этом при следующей отправке
+func (m *Memory) Close() error {
+	m.once.Do(func() {
+		close(m.done)
+		m.net.mu.Lock()
+		if m.net.nodes[m.addr] == m {
+			delete(m.net.nodes, m.addr)
+		}
+		m.net.mu.Unlock()
+	})
+	return nil
+}
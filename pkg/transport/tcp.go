@@ -0,0 +1,126 @@
+package transport
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"ideal-core/pkg/yggdrasil"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// TCP — транспорт поверх сессий yggdrasil.ConnManager
+type TCP struct {
+	m           *yggdrasil.ConnManager
+	inbox       chan Packet
+	defaultPort int
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// NewTCP создаёт транспорт, слушающий listenAddr ("127.0.0.1:0" в тестах)
+func NewTCP(key func() *crypto.KeyPair, listenAddr string) *TCP {
+	t := &TCP{inbox: make(chan Packet, inboxSize), closed: make(chan struct{})}
+	t.m = yggdrasil.NewConnManager(yggdrasil.ConnConfig{
+		ListenAddr: listenAddr,
+		Key:        key,
+		Handler: func(ctx context.Context, peer ed25519.PublicKey, frame []byte) {
+			// Блокирующая запись — естественное ограничение скорости пира
+			select {
+			case t.inbox <- Packet{From: peer, Data: frame}:
+			case <-ctx.Done():
+			}
+		},
+	})
+	return t
+}
+
+// NewYggdrasil — TCP на Yggdrasil-адресе узла; Dial принимает адрес без порта
+func NewYggdrasil(client *yggdrasil.Client, key func() *crypto.KeyPair) (*TCP, error) {
+	ip := client.GetLocalIPv6()
+	if ip == "" {
+		return nil, yggdrasil.ErrNoService
+	}
+	t := NewTCP(key, net.JoinHostPort(ip, strconv.Itoa(yggdrasil.DefaultPeerPort)))
+	t.defaultPort = yggdrasil.DefaultPeerPort
+	return t, nil
+}
+
+// Manager — менеджер соединений (для диагностики сессий)
+func (t *TCP) Manager() *yggdrasil.ConnManager {
+	return t.m
+}
+
+func (t *TCP) Listen() error {
+	return t.m.Listen()
+}
+
+func (t *TCP) Addr() string {
+	if a := t.m.Addr(); a != nil {
+		return a.String()
+	}
+	return ""
+}
+
+// Maintain держит соединение с addr, переподключаясь после обрыва
+func (t *TCP) Maintain(addr string) {
+	t.m.Maintain(t.withPort(addr))
+}
+
+func (t *TCP) Dial(ctx context.Context, addr string) (ed25519.PublicKey, error) {
+	peer, err := t.m.Connect(ctx, t.withPort(addr))
+	if errors.Is(err, yggdrasil.ErrManagerClosed) {
+		return nil, ErrClosed
+	}
+	return peer, err
+}
+
+func (t *TCP) withPort(addr string) string {
+	if t.defaultPort == 0 {
+		return addr
+	}
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	return net.JoinHostPort(addr, strconv.Itoa(t.defaultPort))
+}
+
+func (t *TCP) Send(ctx context.Context, peer ed25519.PublicKey, frame []byte) error {
+	err := t.m.Send(peer, frame)
+	if errors.Is(err, yggdrasil.ErrNoSession) {
+		return fmt.Errorf("%w %s", ErrNoSession, hex.EncodeToString(peer)[:16])
+	}
+	return err
+}
+
+func (t *TCP) Receive(ctx context.Context) (Packet, error) {
+	select {
+	case p := <-t.inbox:
+		return p, nil
+	case <-t.closed:
+		return Packet{}, ErrClosed
+	case <-ctx.Done():
+		return Packet{}, ctx.Err()
+	}
+}
+
+func (t *TCP) Peers() []ed25519.PublicKey {
+	sessions := t.m.Sessions()
+	peers := make([]ed25519.PublicKey, 0, len(sessions))
+	for _, s := range sessions {
+		if k, err := hex.DecodeString(s.Key); err == nil {
+			peers = append(peers, k)
+		}
+	}
+	return peers
+}
+
+func (t *TCP) Close() error {
+	t.closeOnce.Do(func() { close(t.closed) })
+	return t.m.Close()
+}
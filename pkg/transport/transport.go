@@ -0,0 +1,48 @@
+// Package transport — доставка кадров между узлами.
+//
+// Транспорт не знает о содержимом кадров (обычно это конверты envelope) и
+// лишь связывает их с ключом пира, подтверждённым при установке соединения.
+// Реализации:
+//   - TCP — сессии yggdrasil.ConnManager на любом адресе (loopback в тестах);
+//   - NewYggdrasil — тот же TCP на Yggdrasil-адресе узла;
+//   - Network/Memory — узлы в одном процессе с имитацией задержки и потерь,
+//     чтобы сценарии из нескольких узлов (обмен, синхронизация, gossip)
+//     проверялись в модульных тестах.
+package transport
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+)
+
+var (
+	ErrClosed    = errors.New("transport is closed")
+	ErrNoSession = errors.New("no session with peer")
+)
+
+// Packet — кадр от пира
+type Packet struct {
+	From ed25519.PublicKey
+	Data []byte
+}
+
+// Transport — соединения с пирами и обмен кадрами
+type Transport interface {
+	// Listen начинает принимать входящие соединения
+	Listen() error
+	// Addr — адрес, по которому к узлу могут подключиться другие
+	Addr() string
+	// Dial соединяется с узлом по адресу и возвращает его подтверждённый ключ
+	Dial(ctx context.Context, addr string) (ed25519.PublicKey, error)
+	// Send отправляет кадр по установленной сессии (ErrNoSession, если её нет)
+	Send(ctx context.Context, peer ed25519.PublicKey, frame []byte) error
+	// Receive ждёт следующий входящий кадр
+	Receive(ctx context.Context) (Packet, error)
+	// Peers — ключи пиров с активной сессией
+	Peers() []ed25519.PublicKey
+	Close() error
+}
+
+// inboxSize — очередь входящих кадров
+const inboxSize = 1024
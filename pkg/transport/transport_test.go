@@ -0,0 +1,218 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"ideal-core/pkg/envelope"
+	"testing"
+	"time"
+)
+
+type testNode struct {
+	t  Transport
+	kp *crypto.KeyPair
+}
+
+func newKey(t *testing.T) func() *crypto.KeyPair {
+	t.Helper()
+	kp, err := crypto.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func() *crypto.KeyPair { return kp }
+}
+
+// exchange — общий сценарий: три узла в кольце обмениваются конвертами
+func exchange(t *testing.T, nodes []testNode) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, n := range nodes {
+		if err := n.t.Listen(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { n.t.Close() })
+	}
+	for i, n := range nodes {
+		next := nodes[(i+1)%len(nodes)]
+		peer, err := n.t.Dial(ctx, next.t.Addr())
+		if err != nil {
+			t.Fatalf("dial %d→%d: %v", i, (i+1)%len(nodes), err)
+		}
+		if !peer.Equal(next.kp.PublicKey) {
+			t.Fatalf("dial %d returned wrong peer key", i)
+		}
+	}
+
+	for i, n := range nodes {
+		next := nodes[(i+1)%len(nodes)]
+		env, err := envelope.Seal(n.kp, next.kp.PublicKey, envelope.TypePing, []byte(fmt.Sprintf("hello from %d", i)), time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := n.t.Send(ctx, next.kp.PublicKey, env.Marshal()); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+
+	for i, n := range nodes {
+		prev := (i + len(nodes) - 1) % len(nodes)
+		pkt, err := n.t.Receive(ctx)
+		if err != nil {
+			t.Fatalf("receive %d: %v", i, err)
+		}
+		if !pkt.From.Equal(nodes[prev].kp.PublicKey) {
+			t.Fatalf("node %d: packet attributed to wrong peer", i)
+		}
+		r := envelope.NewReceiver(func() *crypto.KeyPair { return n.kp })
+		msg, err := r.Open(pkt.Data)
+		if err != nil {
+			t.Fatalf("node %d: %v", i, err)
+		}
+		if want := fmt.Sprintf("hello from %d", prev); string(msg.Payload) != want {
+			t.Fatalf("node %d got %q, want %q", i, msg.Payload, want)
+		}
+	}
+
+	// Каждый узел связан с двумя соседями
+	for i, n := range nodes {
+		if got := len(n.t.Peers()); got != 2 {
+			t.Fatalf("node %d has %d peers, want 2", i, got)
+		}
+	}
+}
+
+func TestTCP_Exchange(t *testing.T) {
+	var nodes []testNode
+	for i := 0; i < 3; i++ {
+		key := newKey(t)
+		nodes = append(nodes, testNode{t: NewTCP(key, "127.0.0.1:0"), kp: key()})
+	}
+	exchange(t, nodes)
+}
+
+func TestMemory_Exchange(t *testing.T) {
+	net := NewNetwork(1)
+	net.SetConditions(Conditions{Latency: time.Millisecond, Jitter: time.Millisecond})
+	var nodes []testNode
+	for i := 0; i < 3; i++ {
+		key := newKey(t)
+		nodes = append(nodes, testNode{t: net.Node(fmt.Sprintf("node-%d", i), key), kp: key()})
+	}
+	exchange(t, nodes)
+}
+
+func TestSend_NoSession(t *testing.T) {
+	stranger, _ := crypto.GenerateKeyPair()
+	ctx := context.Background()
+
+	tcp := NewTCP(newKey(t), "127.0.0.1:0")
+	if err := tcp.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	defer tcp.Close()
+	if err := tcp.Send(ctx, stranger.PublicKey, []byte("x")); !errors.Is(err, ErrNoSession) {
+		t.Fatalf("tcp: expected ErrNoSession, got %v", err)
+	}
+
+	mem := NewNetwork(1).Node("a", newKey(t))
+	if err := mem.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.Send(ctx, stranger.PublicKey, []byte("x")); !errors.Is(err, ErrNoSession) {
+		t.Fatalf("memory: expected ErrNoSession, got %v", err)
+	}
+	mem.Close()
+	if _, err := mem.Receive(ctx); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed after Close, got %v", err)
+	}
+}
+
+// memPair — два соединённых узла сети net
+func memPair(t *testing.T, net *Network) (a, b *Memory, bKey ed25519.PublicKey) {
+	t.Helper()
+	a, b = net.Node("a", newKey(t)), net.Node("b", newKey(t))
+	for _, n := range []*Memory{a, b} {
+		if err := n.Listen(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { n.Close() })
+	}
+	bKey, err := a.Dial(context.Background(), "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, b, bKey
+}
+
+func TestMemory_LossAndLatency(t *testing.T) {
+	net := NewNetwork(42)
+	a, b, bKey := memPair(t, net)
+	net.SetConditions(Conditions{Latency: 20 * time.Millisecond, Loss: 0.3})
+
+	const total = 500
+	start := time.Now()
+	for i := 0; i < total; i++ {
+		if err := a.Send(context.Background(), bKey, []byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sent, dropped := net.Stats()
+	if sent != total {
+		t.Fatalf("sent = %d, want %d", sent, total)
+	}
+	// 30% ± разумный разброс при фиксированном seed
+	if dropped < total/5 || dropped > total*2/5 {
+		t.Fatalf("dropped %d of %d, expected about 30%%", dropped, total)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := b.Receive(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("first frame arrived after %v, latency not applied", elapsed)
+	}
+	for got := 1; got < int(total-dropped); got++ {
+		if _, err := b.Receive(ctx); err != nil {
+			t.Fatalf("received %d of %d: %v", got, total-dropped, err)
+		}
+	}
+}
+
+func TestMemory_Partition(t *testing.T) {
+	net := NewNetwork(1)
+	a, b, bKey := memPair(t, net)
+	ctx := context.Background()
+
+	net.Partition("a", "b")
+	if err := a.Send(ctx, bKey, []byte("lost")); err != nil {
+		t.Fatal(err)
+	}
+	short, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := b.Receive(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected nothing during partition, got %v", err)
+	}
+
+	net.Heal("a", "b")
+	if err := a.Send(ctx, bKey, []byte("back")); err != nil {
+		t.Fatal(err)
+	}
+	pkt, err := b.Receive(ctx)
+	if err != nil || !bytes.Equal(pkt.Data, []byte("back")) {
+		t.Fatalf("after heal got %q, %v", pkt.Data, err)
+	}
+
+	// Закрытый пир обрывает сессию
+	b.Close()
+	if err := a.Send(ctx, bKey, []byte("gone")); !errors.Is(err, ErrNoSession) {
+		t.Fatalf("expected ErrNoSession after peer closed, got %v", err)
+	}
+}
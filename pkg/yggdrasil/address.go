@@ -0,0 +1,143 @@
+package yggdrasil
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"net"
+)
+
+// Адресация Yggdrasil (совместимо с yggdrasil-go, src/address):
+//
+//  1. Публичный ключ ed25519 побитово инвертируется.
+//  2. Считаются ведущие единицы (ones); первый ноль после них отбрасывается.
+//  3. Адрес = 0x02 | ones | следующие 112 бит инвертированного ключа.
+//  4. Подсеть /64 = адрес с префиксом 0x03 (0x02 | 0x01), первые 8 байт.
+//
+// Чем больше ведущих нулей в ключе, тем «сильнее» адрес (меньше коллизий
+// по префиксу) — поэтому узлы майнят ключи.
+
+const (
+	addressPrefix byte = 0x02 // 200::/7 — адреса узлов
+	subnetPrefix  byte = 0x03 // 300::/8 — маршрутизируемые /64 подсети узлов
+)
+
+// Address — IPv6-адрес узла Yggdrasil
+type Address [16]byte
+
+// Subnet — префикс /64 подсети узла
+type Subnet [8]byte
+
+// AddrForKey вычисляет адрес узла по публичному ключу
+func AddrForKey(publicKey ed25519.PublicKey) (Address, error) {
+	var addr Address
+	if len(publicKey) != ed25519.PublicKeySize {
+		return addr, fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(publicKey))
+	}
+	var buf [ed25519.PublicKeySize]byte
+	for i, b := range publicKey {
+		buf[i] = ^b
+	}
+
+	temp := make([]byte, 0, len(addr)-2)
+	done := false
+	var ones, bits byte
+	nBits := 0
+	for idx := 0; idx < 8*len(buf) && len(temp) < cap(temp); idx++ {
+		bit := (buf[idx/8] >> (7 - uint(idx%8))) & 1
+		if !done {
+			if bit != 0 {
+				ones++
+				continue
+			}
+			done = true // первый ноль — разделитель, в адрес не попадает
+			continue
+		}
+		bits = bits<<1 | bit
+		nBits++
+		if nBits == 8 {
+			temp = append(temp, bits)
+			bits, nBits = 0, 0
+		}
+	}
+
+	addr[0] = addressPrefix
+	addr[1] = ones
+	copy(addr[2:], temp)
+	return addr, nil
+}
+
+// SubnetForKey вычисляет /64 подсеть узла по публичному ключу
+func SubnetForKey(publicKey ed25519.PublicKey) (Subnet, error) {
+	var snet Subnet
+	addr, err := AddrForKey(publicKey)
+	if err != nil {
+		return snet, err
+	}
+	copy(snet[:], addr[:])
+	snet[0] = subnetPrefix
+	return snet, nil
+}
+
+// IsValid — адрес лежит в 200::/8 (адреса узлов)
+func (a Address) IsValid() bool {
+	return a[0] == addressPrefix
+}
+
+// IP возвращает адрес как net.IP
+func (a Address) IP() net.IP {
+	return net.IP(append([]byte(nil), a[:]...))
+}
+
+// String — каноническая запись IPv6 (например 200:848a:604f:...)
+func (a Address) String() string {
+	return a.IP().String()
+}
+
+// IsValid — подсеть лежит в 300::/8
+func (s Subnet) IsValid() bool {
+	return s[0] == subnetPrefix
+}
+
+// IPNet возвращает подсеть как net.IPNet /64
+func (s Subnet) IPNet() *net.IPNet {
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, s[:])
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(64, 128)}
+}
+
+// String — запись подсети в нотации CIDR (например 300:848a:604f:bb7e::/64)
+func (s Subnet) String() string {
+	return s.IPNet().String()
+}
+
+// ParseAddress разбирает строку IPv6 как адрес Yggdrasil
+func ParseAddress(s string) (Address, error) {
+	var addr Address
+	ip := net.ParseIP(s)
+	if ip == nil || ip.To4() != nil {
+		return addr, fmt.Errorf("not an IPv6 address: %q", s)
+	}
+	copy(addr[:], ip.To16())
+	if !addr.IsValid() {
+		return addr, fmt.Errorf("%s is not a Yggdrasil node address (200::/8)", s)
+	}
+	return addr, nil
+}
+
+// KeyMatchesAddress проверяет, что адрес (или адрес из подсети узла) принадлежит ключу.
+// Для адресов из 300::/8 сравниваются первые 64 бита.
+func KeyMatchesAddress(publicKey ed25519.PublicKey, ip net.IP) bool {
+	ip16 := ip.To16()
+	if ip16 == nil || ip.To4() != nil {
+		return false
+	}
+	switch ip16[0] {
+	case addressPrefix:
+		addr, err := AddrForKey(publicKey)
+		return err == nil && net.IP(addr[:]).Equal(ip16)
+	case subnetPrefix:
+		snet, err := SubnetForKey(publicKey)
+		return err == nil && snet.IPNet().Contains(ip16)
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package yggdrasil
+
+import (
+	"crypto/ed25519"
+	"net"
+	"testing"
+)
+
+// Вектор из yggdrasil-go (src/address/address_test.go)
+var refKey = ed25519.PublicKey{
+	189, 186, 207, 216, 34, 64, 222, 61, 205, 18, 57, 36, 203, 181, 82, 86,
+	251, 141, 171, 8, 170, 152, 227, 5, 82, 138, 184, 79, 65, 158, 110, 251,
+}
+
+func TestAddrForKey_Reference(t *testing.T) {
+	want := Address{2, 0, 132, 138, 96, 79, 187, 126, 67, 132, 101, 219, 141, 182, 104, 149}
+	addr, err := AddrForKey(refKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != want {
+		t.Errorf("AddrForKey: got %v, want %v", addr[:], want[:])
+	}
+	if addr.String() != "200:848a:604f:bb7e:4384:65db:8db6:6895" {
+		t.Errorf("Unexpected string form: %s", addr)
+	}
+
+	snet, _ := SubnetForKey(refKey)
+	if want := (Subnet{3, 0, 132, 138, 96, 79, 187, 126}); snet != want {
+		t.Errorf("SubnetForKey: got %v, want %v", snet[:], want[:])
+	}
+	if snet.String() != "300:848a:604f:bb7e::/64" {
+		t.Errorf("Unexpected subnet form: %s", snet)
+	}
+}
+
+func TestAddrForKey_LeadingOnes(t *testing.T) {
+	// Ключ 0x00 0x7f ...: после инверсии 0xff 0x80 → 9 единиц, затем разделитель
+	key := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	for i := range key {
+		key[i] = 0xff
+	}
+	key[0], key[1] = 0x00, 0x7f
+	addr, _ := AddrForKey(key)
+	if addr[0] != 0x02 || addr[1] != 9 {
+		t.Errorf("Expected 9 leading ones, got %v", addr[:2])
+	}
+	if addr[2] != 0 {
+		t.Errorf("Expected zero bits after separator, got %#x", addr[2])
+	}
+
+	if _, err := AddrForKey(key[:31]); err == nil {
+		t.Error("Expected error for short key")
+	}
+}
+
+func TestKeyMatchesAddress(t *testing.T) {
+	other, _, _ := ed25519.GenerateKey(nil)
+
+	if !KeyMatchesAddress(refKey, net.ParseIP("200:848a:604f:bb7e:4384:65db:8db6:6895")) {
+		t.Error("Reference address must match its key")
+	}
+	if !KeyMatchesAddress(refKey, net.ParseIP("300:848a:604f:bb7e::1")) {
+		t.Error("Host in node subnet must match")
+	}
+	if KeyMatchesAddress(other, net.ParseIP("200:848a:604f:bb7e:4384:65db:8db6:6895")) {
+		t.Error("Foreign key must not match")
+	}
+	if KeyMatchesAddress(refKey, net.ParseIP("fe80::1")) || KeyMatchesAddress(refKey, net.ParseIP("10.0.0.1")) {
+		t.Error("Non-Yggdrasil addresses must not match")
+	}
+
+	if _, err := ParseAddress("300:848a:604f:bb7e::1"); err == nil {
+		t.Error("Subnet address is not a node address")
+	}
+	if a, err := ParseAddress("200:848a:604f:bb7e:4384:65db:8db6:6895"); err != nil || !a.IsValid() {
+		t.Errorf("ParseAddress: %v", err)
+	}
+}
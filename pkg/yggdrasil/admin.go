@@ -0,0 +1,235 @@
+package yggdrasil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Клиент admin API Yggdrasil
+//
+// Протокол — JSON поверх unix- или TCP-сокета: запрос
+// {"request": "getSelf", "arguments": {...}, "keepalive": true},
+// ответ {"status": "success", "response": {...}} или
+// {"status": "error", "error": "..."}. С keepalive соединение остаётся
+// открытым для следующих запросов; запросы выполняются строго по очереди.
+
+// DefaultAdminTimeout — таймаут одного запроса, если в ctx нет дедлайна
+const DefaultAdminTimeout = 5 * time.Second
+
+// AdminError — ошибка, которую вернул сам Yggdrasil
+type AdminError struct {
+	Request string
+	Message string
+}
+
+func (e *AdminError) Error() string {
+	return fmt.Sprintf("yggdrasil %s: %s", e.Request, e.Message)
+}
+
+// SelfInfo — ответ getSelf
+type SelfInfo struct {
+	BuildName      string `json:"build_name"`
+	BuildVersion   string `json:"build_version"`
+	Key            string `json:"key"`
+	Address        string `json:"address"`
+	Subnet         string `json:"subnet"`
+	RoutingEntries uint64 `json:"routing_entries"`
+}
+
+// PeerInfo — элемент ответа getPeers
+type PeerInfo struct {
+	URI       string  `json:"remote"`
+	Up        bool    `json:"up"`
+	Inbound   bool    `json:"inbound"`
+	Address   string  `json:"address"`
+	Key       string  `json:"key"`
+	Port      uint64  `json:"port"`
+	Priority  uint64  `json:"priority"`
+	BytesRecv uint64  `json:"bytes_recvd"`
+	BytesSent uint64  `json:"bytes_sent"`
+	Uptime    float64 `json:"uptime"`  // секунды
+	Latency   float64 `json:"latency"` // наносекунды
+	LastError string  `json:"last_error,omitempty"`
+}
+
+// SessionInfo — элемент ответа getSessions
+type SessionInfo struct {
+	Address   string  `json:"address"`
+	Key       string  `json:"key"`
+	BytesRecv uint64  `json:"bytes_recvd"`
+	BytesSent uint64  `json:"bytes_sent"`
+	Uptime    float64 `json:"uptime"`
+}
+
+// AdminClient — соединение с admin-сокетом (переподключается при обрыве)
+type AdminClient struct {
+	network, address string
+
+	mu   sync.Mutex
+	conn net.Conn
+	dec  *json.Decoder
+}
+
+// NewAdminClient создаёт клиента. endpoint: "unix:///path", "tcp://host:port"
+// или просто путь к unix-сокету. Соединение открывается при первом запросе.
+func NewAdminClient(endpoint string) *AdminClient {
+	switch {
+	case strings.HasPrefix(endpoint, "unix://"):
+		return &AdminClient{network: "unix", address: strings.TrimPrefix(endpoint, "unix://")}
+	case strings.HasPrefix(endpoint, "tcp://"):
+		return &AdminClient{network: "tcp", address: strings.TrimPrefix(endpoint, "tcp://")}
+	default:
+		return &AdminClient{network: "unix", address: endpoint}
+	}
+}
+
+// Endpoint — адрес сокета в виде network://address
+func (a *AdminClient) Endpoint() string {
+	return a.network + "://" + a.address
+}
+
+type adminRequest struct {
+	Request   string      `json:"request"`
+	Arguments interface{} `json:"arguments,omitempty"`
+	KeepAlive bool        `json:"keepalive"`
+}
+
+type adminResponse struct {
+	Status   string          `json:"status"`
+	Error    string          `json:"error,omitempty"`
+	Response json.RawMessage `json:"response"`
+}
+
+// Call выполняет произвольный запрос admin API и разбирает ответ в out (может быть nil)
+func (a *AdminClient) Call(ctx context.Context, request string, args, out interface{}) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	resp, err := a.roundTrip(ctx, adminRequest{Request: request, Arguments: args, KeepAlive: true})
+	if err != nil {
+		// Сокет мог быть закрыт сервером (перезапуск Yggdrasil) — одна повторная попытка
+		a.closeLocked()
+		if ctx.Err() != nil {
+			return err
+		}
+		if resp, err = a.roundTrip(ctx, adminRequest{Request: request, Arguments: args, KeepAlive: true}); err != nil {
+			a.closeLocked()
+			return fmt.Errorf("yggdrasil admin %s: %w", request, err)
+		}
+	}
+	if resp.Status != "success" {
+		msg := resp.Error
+		if msg == "" {
+			msg = "status " + resp.Status
+		}
+		return &AdminError{Request: request, Message: msg}
+	}
+	if out == nil || len(resp.Response) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Response, out); err != nil {
+		return fmt.Errorf("yggdrasil admin %s: parse response: %w", request, err)
+	}
+	return nil
+}
+
+func (a *AdminClient) roundTrip(ctx context.Context, req adminRequest) (*adminResponse, error) {
+	if a.conn == nil {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, a.network, a.address)
+		if err != nil {
+			return nil, err
+		}
+		a.conn, a.dec = conn, json.NewDecoder(conn)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(DefaultAdminTimeout)
+	}
+	a.conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { a.conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if err := json.NewEncoder(a.conn).Encode(req); err != nil {
+		return nil, err
+	}
+	var resp adminResponse
+	if err := a.dec.Decode(&resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetSelf — сведения о локальном узле (адрес, подсеть, ключ)
+func (a *AdminClient) GetSelf(ctx context.Context) (*SelfInfo, error) {
+	var self SelfInfo
+	if err := a.Call(ctx, "getSelf", nil, &self); err != nil {
+		return nil, err
+	}
+	return &self, nil
+}
+
+// GetPeers — подключённые и настроенные пиры
+func (a *AdminClient) GetPeers(ctx context.Context) ([]PeerInfo, error) {
+	var resp struct {
+		Peers []PeerInfo `json:"peers"`
+	}
+	if err := a.Call(ctx, "getPeers", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Peers, nil
+}
+
+// GetSessions — активные сессии с другими узлами
+func (a *AdminClient) GetSessions(ctx context.Context) ([]SessionInfo, error) {
+	var resp struct {
+		Sessions []SessionInfo `json:"sessions"`
+	}
+	if err := a.Call(ctx, "getSessions", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Sessions, nil
+}
+
+type peerArgs struct {
+	URI       string `json:"uri"`
+	Interface string `json:"interface,omitempty"`
+}
+
+// AddPeer добавляет пира (uri вида tls://host:port); intf — сетевой интерфейс или ""
+func (a *AdminClient) AddPeer(ctx context.Context, uri, intf string) error {
+	if uri == "" {
+		return errors.New("peer uri is required")
+	}
+	return a.Call(ctx, "addPeer", peerArgs{URI: uri, Interface: intf}, nil)
+}
+
+// RemovePeer удаляет пира, добавленного AddPeer или из конфигурации
+func (a *AdminClient) RemovePeer(ctx context.Context, uri, intf string) error {
+	if uri == "" {
+		return errors.New("peer uri is required")
+	}
+	return a.Call(ctx, "removePeer", peerArgs{URI: uri, Interface: intf}, nil)
+}
+
+// Close закрывает соединение
+func (a *AdminClient) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.closeLocked()
+}
+
+func (a *AdminClient) closeLocked() error {
+	if a.conn == nil {
+		return nil
+	}
+	err := a.conn.Close()
+	a.conn, a.dec = nil, nil
+	return err
+}
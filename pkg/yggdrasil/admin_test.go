@@ -0,0 +1,167 @@
+package yggdrasil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// fakeAdmin — admin-сокет, отвечающий как Yggdrasil 0.5
+type fakeAdmin struct {
+	mu    sync.Mutex
+	peers []PeerInfo
+	conns int
+}
+
+func startFakeAdmin(t *testing.T) (*fakeAdmin, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ygg.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeAdmin{peers: []PeerInfo{{URI: "tls://1.2.3.4:443", Up: true, Address: "200:1::1", Key: "aa"}}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns++
+			f.mu.Unlock()
+			go f.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return f, path
+}
+
+func (f *fakeAdmin) serve(conn net.Conn) {
+	defer conn.Close()
+	dec, enc := json.NewDecoder(conn), json.NewEncoder(conn)
+	for {
+		var req struct {
+			Request   string          `json:"request"`
+			Arguments json.RawMessage `json:"arguments"`
+			KeepAlive bool            `json:"keepalive"`
+		}
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		var args peerArgs
+		json.Unmarshal(req.Arguments, &args)
+
+		f.mu.Lock()
+		var resp interface{}
+		status, msg := "success", ""
+		switch req.Request {
+		case "getSelf":
+			resp = SelfInfo{BuildName: "yggdrasil", BuildVersion: "0.5.12", Key: "bb", Address: "200:848a:604f:bb7e:4384:65db:8db6:6895", Subnet: "300:848a:604f:bb7e::/64"}
+		case "getPeers":
+			resp = map[string]interface{}{"peers": f.peers}
+		case "getSessions":
+			resp = map[string]interface{}{"sessions": []SessionInfo{{Address: "200:2::1", Key: "cc", BytesSent: 10}}}
+		case "addPeer":
+			f.peers = append(f.peers, PeerInfo{URI: args.URI})
+			resp = map[string]interface{}{}
+		case "removePeer":
+			status, msg = "error", "peer not found"
+			for i, p := range f.peers {
+				if p.URI == args.URI {
+					f.peers = append(f.peers[:i], f.peers[i+1:]...)
+					status, msg = "success", ""
+					break
+				}
+			}
+		default:
+			status, msg = "error", "unknown action"
+		}
+		f.mu.Unlock()
+
+		enc.Encode(map[string]interface{}{"status": status, "error": msg, "request": req, "response": resp})
+		if !req.KeepAlive {
+			return
+		}
+	}
+}
+
+func TestAdminClient(t *testing.T) {
+	f, path := startFakeAdmin(t)
+	a := NewAdminClient("unix://" + path)
+	defer a.Close()
+	ctx := context.Background()
+
+	self, err := a.GetSelf(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if self.Address != "200:848a:604f:bb7e:4384:65db:8db6:6895" || self.BuildVersion != "0.5.12" {
+		t.Fatalf("GetSelf: %+v", self)
+	}
+
+	if err := a.AddPeer(ctx, "tcp://5.6.7.8:1234", ""); err != nil {
+		t.Fatal(err)
+	}
+	peers, err := a.GetPeers(ctx)
+	if err != nil || len(peers) != 2 || peers[1].URI != "tcp://5.6.7.8:1234" || !peers[0].Up {
+		t.Fatalf("GetPeers: %+v, %v", peers, err)
+	}
+	if err := a.RemovePeer(ctx, "tcp://5.6.7.8:1234", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var adminErr *AdminError
+	if err := a.RemovePeer(ctx, "tcp://9.9.9.9:1", ""); !errors.As(err, &adminErr) || adminErr.Message != "peer not found" {
+		t.Fatalf("expected AdminError, got %v", err)
+	}
+	if err := a.Call(ctx, "explode", nil, nil); !errors.As(err, &adminErr) {
+		t.Fatalf("expected AdminError for unknown request, got %v", err)
+	}
+
+	sessions, err := a.GetSessions(ctx)
+	if err != nil || len(sessions) != 1 || sessions[0].BytesSent != 10 {
+		t.Fatalf("GetSessions: %+v, %v", sessions, err)
+	}
+
+	// Все запросы прошли по одному соединению (keepalive)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.conns != 1 {
+		t.Fatalf("expected 1 connection, got %d", f.conns)
+	}
+}
+
+func TestAdminClient_Reconnect(t *testing.T) {
+	f, path := startFakeAdmin(t)
+	a := NewAdminClient(path)
+	defer a.Close()
+	ctx := context.Background()
+
+	if _, err := a.GetSelf(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Сервер закрыл соединение (например, перезапуск) — клиент переподключается
+	a.mu.Lock()
+	a.conn.Close()
+	a.mu.Unlock()
+	if _, err := a.GetSelf(ctx); err != nil {
+		t.Fatalf("after reconnect: %v", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.conns != 2 {
+		t.Fatalf("expected 2 connections, got %d", f.conns)
+	}
+}
+
+func TestAdminClient_Unavailable(t *testing.T) {
+	a := NewAdminClient(filepath.Join(t.TempDir(), "missing.sock"))
+	if _, err := a.GetSelf(context.Background()); err == nil {
+		t.Fatal("expected error for missing socket")
+	}
+}
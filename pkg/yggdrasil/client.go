@@ -2,18 +2,20 @@ package yggdrasil
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
-	"time"
 )
 
-// Client обёртка над Yggdrasil-сокетом для продакшена
+// ErrNoService — локальный Yggdrasil не запущен
+var ErrNoService = errors.New("yggdrasil service is not available")
+
+// Client — управление локальным Yggdrasil через admin API.
+// Данные между узлами передаёт transport (TCP на Yggdrasil-адресе).
 type Client struct {
-	conn       net.Conn
+	admin      *AdminClient
 	nodeID     string
 	yggPath    string
 	hasService bool
@@ -28,17 +30,16 @@ func NewClient(nodeID, yggPath string, hasService bool) (*Client, error) {
 	}
 
 	if hasService {
-		// Подключаемся к локальному сокету Yggdrasil
-		socketPath := detectYggdrasilSocket()
-		conn, err := net.Dial("unix", socketPath)
+		// Проверяем admin-сокет локального Yggdrasil
+		client.admin = NewAdminClient(detectYggdrasilSocket())
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultAdminTimeout)
+		defer cancel()
+		self, err := client.admin.GetSelf(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to connect to yggdrasil socket: %w", err)
+			client.admin.Close()
+			return nil, fmt.Errorf("failed to connect to yggdrasil admin socket: %w", err)
 		}
-		client.conn = conn
-		fmt.Printf("✅ Connected to Yggdrasil at %s\n", socketPath)
-	} else {
-		// Fallback: эмулируем транспорт для локального тестирования
-		fmt.Println("⚠️  Running in fallback mode (no Yggdrasil service)")
+		fmt.Printf("✅ Connected to Yggdrasil %s at %s (%s)\n", self.BuildVersion, client.admin.Endpoint(), self.Address)
 	}
 
 	return client, nil
@@ -61,125 +62,59 @@ func detectYggdrasilSocket() string {
 	return "/var/run/yggdrasil.sock"
 }
 
-// Dial подключается к узлу по его Yggdrasil IPv6
-func (c *Client) Dial(ctx context.Context, ipv6 string) error {
-	if !c.hasService {
-		fmt.Printf("🔗 [FALLBACK] Dialing %s (Yggdrasil IPv6)...\n", ipv6)
-		time.Sleep(100 * time.Millisecond)
-		return nil
-	}
-
-	// В продакшене: реальное подключение через Yggdrasil
-	addr := fmt.Sprintf("[%s]:9001", ipv6) // Порт по умолчанию
-	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
-	if err != nil {
-		return fmt.Errorf("failed to dial %s: %w", addr, err)
-	}
-	c.conn = conn
-	return nil
-}
-
-// Send отправляет зашифрованное сообщение через Yggdrasil
-func (c *Client) Send(targetIPv6 string, payload []byte) error {
-	if !c.hasService {
-		fmt.Printf("📤 [FALLBACK] Sending %d bytes to %s\n", len(payload), targetIPv6)
-		return nil
-	}
-
-	if c.conn == nil {
-		return fmt.Errorf("not connected")
-	}
-
-	// В продакшене: шифрование + отправка через сокет
-	_, err := c.conn.Write(payload)
-	if err != nil {
-		return fmt.Errorf("send failed: %w", err)
-	}
-	return nil
+// Available сообщает, подключён ли клиент к локальному Yggdrasil
+func (c *Client) Available() bool {
+	return c.hasService
 }
 
-// Receive слушает входящие сообщения (запускать в горутине)
-func (c *Client) Receive(ctx context.Context, handler func([]byte) error) error {
-	if !c.hasService {
-		fmt.Println("📡 [FALLBACK] Listening for incoming messages...")
-		<-ctx.Done()
-		return ctx.Err()
-	}
-
-	if c.conn == nil {
-		return fmt.Errorf("not connected")
-	}
-
-	buf := make([]byte, 4096)
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			c.conn.SetReadDeadline(time.Now().Add(1 * time.Second))
-			n, err := c.conn.Read(buf)
-			if err != nil {
-				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-					continue
-				}
-				return err
-			}
-			if err := handler(buf[:n]); err != nil {
-				return err
-			}
-		}
-	}
+// Admin — клиент admin API (nil без Yggdrasil)
+func (c *Client) Admin() *AdminClient {
+	return c.admin
 }
 
-// GetLocalIPv6 возвращает IPv6 текущего узла через yggdrasilctl
+// GetLocalIPv6 возвращает IPv6 текущего узла (getSelf); "" без Yggdrasil
 func (c *Client) GetLocalIPv6() string {
 	if !c.hasService {
-		return fmt.Sprintf("200:dead:beef:%s::1", c.nodeID[:8])
+		return ""
 	}
 
-	// В продакшене: парсить вывод yggdrasilctl getself
-	cmd := exec.Command(strings.TrimSuffix(c.yggPath, "yggdrasil")+"ctl", "getself")
-	output, err := cmd.Output()
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultAdminTimeout)
+	defer cancel()
+	self, err := c.admin.GetSelf(ctx)
 	if err != nil {
-		return c.nodeID // Fallback
-	}
-
-	// Парсинг реального вывода (упрощённо)
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.Contains(line, "ip:") {
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				return strings.Trim(parts[1], `"`)
-			}
-		}
+		return ""
 	}
-	return c.nodeID
+	return self.Address
 }
 
-// Bootstrap подключается к известным пир-узлам для входа в сеть
+// Bootstrap подключается к известным пир-узлам для входа в сеть (addPeer)
 func (c *Client) Bootstrap(peers []string) error {
 	if !c.hasService {
-		for _, peer := range peers {
-			fmt.Printf("🌱 [FALLBACK] Bootstrapping with peer: %s\n", peer)
-		}
-		return nil
+		return ErrNoService
 	}
 
-	// В продакшене: использовать yggdrasilctl addpeer
+	var failed []string
 	for _, peer := range peers {
-		cmd := exec.Command(strings.TrimSuffix(c.yggPath, "yggdrasil")+"ctl", "addpeer", peer)
-		if err := cmd.Run(); err != nil {
-			fmt.Printf("⚠️  Failed to add peer %s: %v\n", peer, err)
+		peer = strings.TrimSpace(peer)
+		if peer == "" {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultAdminTimeout)
+		if err := c.admin.AddPeer(ctx, peer, ""); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", peer, err))
 		}
+		cancel()
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to add peers: %s", strings.Join(failed, "; "))
 	}
 	return nil
 }
 
-// Close закрывает соединение
+// Close закрывает соединение с admin-сокетом
 func (c *Client) Close() error {
-	if c.conn != nil {
-		return c.conn.Close()
+	if c.admin != nil {
+		return c.admin.Close()
 	}
 	return nil
 }
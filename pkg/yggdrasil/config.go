@@ -0,0 +1,492 @@
+package yggdrasil
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Конфиг локального Yggdrasil (yggdrasil.conf, формат yggdrasil-go 0.5).
+//
+// Узел может сгенерировать конфиг сам: пиры из -bootstrap, адреса для
+// входящих пиров, admin-сокет и, по желанию, ключ самого узла — тогда
+// Yggdrasil работает на ключе Node ID и приложение доступно по адресу,
+// который выводится из его ключа. Без своего ключа у сервиса свой адрес,
+// и приложение доступно только по нему (см. CheckReachability).
+
+// DefaultAdminListen — admin-сокет Yggdrasil по умолчанию
+const DefaultAdminListen = "unix:///var/run/yggdrasil.sock"
+
+// MinIfMTU — минимальный MTU TUN-интерфейса (минимум для IPv6)
+const MinIfMTU = 1280
+
+// ConfigPaths — где обычно лежит yggdrasil.conf
+var ConfigPaths = []string{
+	"/etc/yggdrasil.conf",
+	"/etc/yggdrasil/yggdrasil.conf",
+	"/usr/local/etc/yggdrasil.conf",
+	"/opt/homebrew/etc/yggdrasil.conf",
+}
+
+var (
+	peerSchemes   = []string{"tcp", "tls", "quic", "ws", "wss", "socks", "sockstls", "unix"}
+	listenSchemes = []string{"tcp", "tls", "quic", "ws", "unix"}
+)
+
+// MulticastInterface — правило поиска пиров в локальной сети
+type MulticastInterface struct {
+	Regex    string `json:"Regex"`
+	Beacon   bool   `json:"Beacon"`
+	Listen   bool   `json:"Listen"`
+	Port     uint16 `json:"Port"`
+	Priority uint64 `json:"Priority"`
+	Password string `json:"Password"`
+}
+
+// Config — yggdrasil.conf. Поля, которых нет в структуре, сохраняются как есть.
+type Config struct {
+	PrivateKey          string                 `json:"PrivateKey,omitempty"` // hex, 64 байта ed25519
+	PrivateKeyPath      string                 `json:"PrivateKeyPath,omitempty"`
+	Peers               []string               `json:"Peers"`
+	InterfacePeers      map[string][]string    `json:"InterfacePeers"`
+	Listen              []string               `json:"Listen"`
+	AdminListen         string                 `json:"AdminListen"`
+	MulticastInterfaces []MulticastInterface   `json:"MulticastInterfaces"`
+	AllowedPublicKeys   []string               `json:"AllowedPublicKeys"`
+	IfName              string                 `json:"IfName"`
+	IfMTU               uint64                 `json:"IfMTU"`
+	NodeInfoPrivacy     bool                   `json:"NodeInfoPrivacy"`
+	NodeInfo            map[string]interface{} `json:"NodeInfo"`
+
+	extra map[string]json.RawMessage
+}
+
+// ConfigOptions — что узел задаёт в конфиге
+type ConfigOptions struct {
+	Peers       []string           // пиры для входа в сеть (-bootstrap)
+	Listen      []string           // адреса для входящих пиров (пусто — не менять)
+	AdminListen string             // admin-сокет (пусто — не менять)
+	Key         ed25519.PrivateKey // ключ узла; nil — ключ сервиса (существующий или новый)
+}
+
+// ConfigChange — отличие поля конфига
+type ConfigChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// DefaultConfig — конфиг как у yggdrasil -genconf, без ключа
+func DefaultConfig() *Config {
+	return &Config{
+		Peers:               []string{},
+		InterfacePeers:      map[string][]string{},
+		Listen:              []string{},
+		AdminListen:         DefaultAdminListen,
+		MulticastInterfaces: []MulticastInterface{{Regex: ".*", Beacon: true, Listen: true}},
+		AllowedPublicKeys:   []string{},
+		IfName:              "auto",
+		IfMTU:               65535,
+		NodeInfo:            map[string]interface{}{},
+	}
+}
+
+// GenerateConfig собирает конфиг по опциям поверх base (существующего
+// конфига; nil — конфиг по умолчанию). Пиры base сохраняются, пиры из
+// опций добавляются. Без opts.Key остаётся ключ base или создаётся новый.
+func GenerateConfig(opts ConfigOptions, base *Config) (*Config, error) {
+	cfg := DefaultConfig()
+	if base != nil {
+		cfg = base.clone()
+	}
+	for _, peer := range opts.Peers {
+		if peer = strings.TrimSpace(peer); peer != "" && !containsString(cfg.Peers, peer) {
+			cfg.Peers = append(cfg.Peers, peer)
+		}
+	}
+	var listen []string
+	for _, l := range opts.Listen {
+		if l = strings.TrimSpace(l); l != "" {
+			listen = append(listen, l)
+		}
+	}
+	if len(listen) > 0 {
+		cfg.Listen = listen
+	}
+	if opts.AdminListen != "" {
+		cfg.AdminListen = opts.AdminListen
+	}
+	switch {
+	case opts.Key != nil:
+		if len(opts.Key) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("private key must be %d bytes, got %d", ed25519.PrivateKeySize, len(opts.Key))
+		}
+		cfg.PrivateKey = hex.EncodeToString(opts.Key)
+		cfg.PrivateKeyPath = ""
+	case cfg.PrivateKey == "" && cfg.PrivateKeyPath == "":
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		cfg.PrivateKey = hex.EncodeToString(priv)
+	}
+	return cfg, cfg.Validate()
+}
+
+// ParseConfig разбирает yggdrasil.conf (HJSON или JSON)
+func ParseConfig(data []byte) (*Config, error) {
+	v, err := parseHJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := v.(map[string]interface{}); !ok {
+		return nil, errors.New("config is not an object")
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	if err := json.Unmarshal(raw, cfg); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	for _, known := range configFields() {
+		delete(fields, known)
+	}
+	if len(fields) > 0 {
+		cfg.extra = fields
+	}
+	return cfg, nil
+}
+
+// LoadConfig читает yggdrasil.conf
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// FindConfig возвращает путь к существующему yggdrasil.conf или ""
+func FindConfig() string {
+	for _, path := range ConfigPaths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Marshal — конфиг в JSON (подмножество HJSON, Yggdrasil читает его как есть)
+func (c *Config) Marshal() ([]byte, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.extra) > 0 {
+		keys := make([]string, 0, len(c.extra))
+		for k := range c.extra {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var buf bytes.Buffer
+		buf.Write(data[:len(data)-1])
+		for _, k := range keys {
+			name, _ := json.Marshal(k)
+			fmt.Fprintf(&buf, ",%s:%s", name, c.extra[k])
+		}
+		buf.WriteByte('}')
+		data = buf.Bytes()
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// WriteFile сохраняет конфиг (0600: в нём закрытый ключ)
+func (c *Config) WriteFile(path string) error {
+	data, err := c.Marshal()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte("# Generated by ideal-core\n"), data...), 0600)
+}
+
+// PublicKey — публичный ключ из PrivateKey
+func (c *Config) PublicKey() (ed25519.PublicKey, error) {
+	if c.PrivateKey == "" {
+		if c.PrivateKeyPath != "" {
+			return nil, fmt.Errorf("key is stored in %s", c.PrivateKeyPath)
+		}
+		return nil, errors.New("no private key")
+	}
+	key, err := hex.DecodeString(c.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("private key is not hex: %w", err)
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("private key must be %d bytes, got %d", ed25519.PrivateKeySize, len(key))
+	}
+	priv := ed25519.PrivateKey(key)
+	pub := priv.Public().(ed25519.PublicKey)
+	if !bytes.Equal(ed25519.NewKeyFromSeed(priv.Seed()).Public().(ed25519.PublicKey), pub) {
+		return nil, errors.New("private key halves do not match (corrupted key)")
+	}
+	return pub, nil
+}
+
+// Address — адрес, который получит Yggdrasil с этим конфигом
+func (c *Config) Address() (Address, error) {
+	pub, err := c.PublicKey()
+	if err != nil {
+		return Address{}, err
+	}
+	return AddrForKey(pub)
+}
+
+// Validate проверяет конфиг так же, как его проверит Yggdrasil при запуске
+func (c *Config) Validate() error {
+	var errs []error
+	if c.PrivateKey != "" || c.PrivateKeyPath == "" {
+		if _, err := c.PublicKey(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, peer := range c.Peers {
+		if err := validateURI(peer, peerSchemes); err != nil {
+			errs = append(errs, fmt.Errorf("peer: %w", err))
+		}
+	}
+	for iface, peers := range c.InterfacePeers {
+		for _, peer := range peers {
+			if err := validateURI(peer, peerSchemes); err != nil {
+				errs = append(errs, fmt.Errorf("peer on %s: %w", iface, err))
+			}
+		}
+	}
+	for _, l := range c.Listen {
+		if err := validateURI(l, listenSchemes); err != nil {
+			errs = append(errs, fmt.Errorf("listen: %w", err))
+		}
+	}
+	if c.AdminListen != "" && c.AdminListen != "none" {
+		if err := validateURI(c.AdminListen, []string{"unix", "tcp"}); err != nil {
+			errs = append(errs, fmt.Errorf("admin listen: %w", err))
+		}
+	}
+	for _, mi := range c.MulticastInterfaces {
+		if _, err := regexp.Compile(mi.Regex); err != nil {
+			errs = append(errs, fmt.Errorf("multicast interface regex %q: %w", mi.Regex, err))
+		}
+	}
+	for _, k := range c.AllowedPublicKeys {
+		if b, err := hex.DecodeString(k); err != nil || len(b) != ed25519.PublicKeySize {
+			errs = append(errs, fmt.Errorf("allowed public key %q is not a %d-byte hex key", k, ed25519.PublicKeySize))
+		}
+	}
+	if c.IfName == "" {
+		errs = append(errs, errors.New("IfName is empty (use \"auto\" or \"none\")"))
+	}
+	if c.IfName != "none" && (c.IfMTU < MinIfMTU || c.IfMTU > 65535) {
+		errs = append(errs, fmt.Errorf("IfMTU %d is outside %d..65535", c.IfMTU, MinIfMTU))
+	}
+	return errors.Join(errs...)
+}
+
+// validateURI проверяет адрес пира или listen-адрес вида scheme://host:port
+func validateURI(s string, schemes []string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if !containsString(schemes, u.Scheme) {
+		return fmt.Errorf("%q: scheme must be one of %s", s, strings.Join(schemes, ", "))
+	}
+	if u.Scheme == "unix" {
+		if u.Path == "" {
+			return fmt.Errorf("%q: socket path is empty", s)
+		}
+		return nil
+	}
+	if _, port, err := net.SplitHostPort(u.Host); err != nil || port == "" {
+		return fmt.Errorf("%q: expected host:port", s)
+	}
+	return nil
+}
+
+// DiffConfig сравнивает два конфига по полям. Ключ показывается адресом,
+// который он даёт, а не самим ключом.
+func DiffConfig(from, to *Config) ([]ConfigChange, error) {
+	a, err := from.fields()
+	if err != nil {
+		return nil, err
+	}
+	b, err := to.fields()
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for k := range a {
+		names[k] = true
+	}
+	for k := range b {
+		names[k] = true
+	}
+	changes := []ConfigChange{}
+	for _, name := range sortedFields(names) {
+		if bytes.Equal(a[name], b[name]) {
+			continue
+		}
+		if name == "PrivateKey" {
+			changes = append(changes, ConfigChange{Field: name, Old: from.keySummary(), New: to.keySummary()})
+			continue
+		}
+		changes = append(changes, ConfigChange{Field: name, Old: string(a[name]), New: string(b[name])})
+	}
+	return changes, nil
+}
+
+// fields — поля конфига в каноническом JSON (для сравнения)
+func (c *Config) fields() (map[string]json.RawMessage, error) {
+	data, err := c.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for k, v := range m {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, v); err != nil {
+			return nil, err
+		}
+		m[k] = buf.Bytes()
+	}
+	return m, nil
+}
+
+func (c *Config) keySummary() string {
+	if c.PrivateKey == "" {
+		return ""
+	}
+	addr, err := c.Address()
+	if err != nil {
+		return "invalid key: " + err.Error()
+	}
+	return "key of " + addr.String()
+}
+
+func (c *Config) clone() *Config {
+	data, _ := c.Marshal()
+	cp, err := ParseConfig(data)
+	if err != nil {
+		return DefaultConfig()
+	}
+	return cp
+}
+
+// configFields — JSON-имена полей Config в порядке yggdrasil -genconf
+func configFields() []string {
+	return []string{
+		"PrivateKey", "PrivateKeyPath", "Peers", "InterfacePeers", "Listen", "AdminListen",
+		"MulticastInterfaces", "AllowedPublicKeys", "IfName", "IfMTU", "NodeInfoPrivacy", "NodeInfo",
+	}
+}
+
+// sortedFields — сначала известные поля по порядку, затем остальные по алфавиту
+func sortedFields(names map[string]bool) []string {
+	var out []string
+	for _, k := range configFields() {
+		if names[k] {
+			out = append(out, k)
+			delete(names, k)
+		}
+	}
+	var rest []string
+	for k := range names {
+		rest = append(rest, k)
+	}
+	sort.Strings(rest)
+	return append(out, rest...)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Reachability — по какому Yggdrasil-адресу на самом деле доступно приложение
+type Reachability struct {
+	AppAddress     string   `json:"app_address"`               // адрес ключа узла (Node ID)
+	ServiceAddress string   `json:"service_address,omitempty"` // адрес запущенного Yggdrasil (getSelf)
+	ConfigAddress  string   `json:"config_address,omitempty"`  // адрес ключа из yggdrasil.conf
+	Reachable      string   `json:"reachable,omitempty"`       // где приложение принимает пиров
+	SharedKey      bool     `json:"shared_key"`                // Yggdrasil работает на ключе узла
+	Problems       []string `json:"problems,omitempty"`
+}
+
+// CheckReachability сопоставляет адрес ключа узла, адрес запущенного
+// Yggdrasil (self == nil — сервис недоступен) и адрес из конфига (cfg может быть nil).
+// Приложение слушает на адресе сервиса, поэтому доступно только по нему.
+func CheckReachability(appKey ed25519.PublicKey, self *SelfInfo, cfg *Config) Reachability {
+	var r Reachability
+	if addr, err := AddrForKey(appKey); err == nil {
+		r.AppAddress = addr.String()
+	}
+	if cfg != nil {
+		if addr, err := cfg.Address(); err == nil {
+			r.ConfigAddress = addr.String()
+		} else {
+			r.Problems = append(r.Problems, fmt.Sprintf("yggdrasil.conf: %v", err))
+		}
+	}
+	if self == nil {
+		r.Problems = append(r.Problems, "Yggdrasil is not running: the app is not reachable over the mesh")
+		return r
+	}
+	if ip := net.ParseIP(self.Address); ip != nil {
+		r.ServiceAddress = ip.String()
+	} else {
+		r.ServiceAddress = self.Address
+	}
+	r.Reachable = r.ServiceAddress
+	r.SharedKey = r.ServiceAddress == r.AppAddress
+	if !r.SharedKey {
+		r.Problems = append(r.Problems, fmt.Sprintf(
+			"Yggdrasil uses its own key: the app is reachable at %s, not at its Node ID address %s",
+			r.ServiceAddress, r.AppAddress))
+	}
+	if r.ConfigAddress != "" && r.ConfigAddress != r.ServiceAddress {
+		r.Problems = append(r.Problems, fmt.Sprintf(
+			"yggdrasil.conf gives %s but the running service is at %s: restart Yggdrasil to apply the config",
+			r.ConfigAddress, r.ServiceAddress))
+	}
+	return r
+}
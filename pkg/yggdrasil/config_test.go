@@ -0,0 +1,132 @@
+package yggdrasil
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Фрагмент вывода yggdrasil -genconf (HJSON)
+const genconf = `{
+  # Your private key. DO NOT share this with anyone!
+  PrivateKey: %KEY%
+
+  # List of outbound peer connection strings (e.g. tls://a.b.c.d:e or
+  # socks://a.b.c.d:e/f.g.h.i:j).
+  Peers:
+  [
+    tls://ygg.example.net:443
+  ]
+
+  Listen: []
+
+  /* Listen address for admin connections. */
+  AdminListen: unix:///var/run/yggdrasil.sock
+
+  MulticastInterfaces:
+  [
+    {
+      Regex: .*
+      Beacon: true
+      Listen: true
+      Port: 0
+      Priority: 0
+      Password: ""
+    }
+  ]
+  AllowedPublicKeys: []
+  IfName: auto
+  IfMTU: 65535
+  NodeInfoPrivacy: false
+  NodeInfo: {}
+  LogLookups: false
+}`
+
+func TestConfig_GenerateDiffAndReachability(t *testing.T) {
+	_, serviceKey, _ := ed25519.GenerateKey(nil)
+	nodePub, nodeKey, _ := ed25519.GenerateKey(nil)
+	serviceHex := hex.EncodeToString(serviceKey)
+
+	existing, err := ParseConfig([]byte(strings.Replace(genconf, "%KEY%", serviceHex, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := existing.Validate(); err != nil {
+		t.Fatalf("genconf output rejected: %v", err)
+	}
+	if len(existing.Peers) != 1 || existing.Peers[0] != "tls://ygg.example.net:443" || existing.IfMTU != 65535 {
+		t.Fatalf("parsed %+v", existing)
+	}
+
+	cfg, err := GenerateConfig(ConfigOptions{
+		Peers:  []string{"tls://ygg.example.net:443", " tcp://[2001:db8::1]:9001"},
+		Listen: []string{"tls://[::]:0"},
+		Key:    nodeKey,
+	}, existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := DiffConfig(existing, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeAddr, _ := AddrForKey(nodePub)
+	got := map[string]ConfigChange{}
+	for _, c := range changes {
+		got[c.Field] = c
+	}
+	if len(changes) != 3 || got["PrivateKey"].New != "key of "+nodeAddr.String() || !strings.Contains(got["Peers"].New, "9001") {
+		t.Fatalf("diff %+v", changes)
+	}
+	if strings.Contains(got["PrivateKey"].Old+got["PrivateKey"].New, serviceHex[:16]) {
+		t.Fatal("diff leaks the private key")
+	}
+
+	// Запись и чтение: неизвестные поля сохраняются, различий нет
+	path := filepath.Join(t.TempDir(), "yggdrasil.conf")
+	if err := cfg.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	back, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes, _ := DiffConfig(cfg, back); len(changes) != 0 || string(back.extra["LogLookups"]) != "false" {
+		t.Fatalf("round trip changed %+v", changes)
+	}
+
+	// Сервис ещё на старом ключе: доступен по адресу сервиса, не по Node ID
+	svcAddr, _ := existing.Address()
+	r := CheckReachability(nodePub, &SelfInfo{Address: svcAddr.String()}, cfg)
+	if r.Reachable != svcAddr.String() || r.SharedKey || r.ConfigAddress != nodeAddr.String() || len(r.Problems) != 2 {
+		t.Fatalf("reachability %+v", r)
+	}
+	r = CheckReachability(nodePub, &SelfInfo{Address: nodeAddr.String()}, cfg)
+	if r.Reachable != nodeAddr.String() || !r.SharedKey || len(r.Problems) != 0 {
+		t.Fatalf("reachability with the node key %+v", r)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cfg, err := GenerateConfig(ConfigOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.Address(); err != nil {
+		t.Fatalf("no key generated: %v", err)
+	}
+	cfg.Peers = []string{"ygg.example.net:443", "tls://ygg.example.net"}
+	cfg.Listen = []string{"http://[::]:80"}
+	cfg.AdminListen = "/var/run/yggdrasil.sock"
+	cfg.IfMTU = 500
+	cfg.PrivateKey = cfg.PrivateKey[:64] + strings.Repeat("0", 64)
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("invalid config accepted")
+	}
+	if n := strings.Count(err.Error(), "\n") + 1; n != 6 {
+		t.Fatalf("want 6 problems, got %d:\n%v", n, err)
+	}
+}
@@ -0,0 +1,437 @@
+package yggdrasil
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"ideal-core/pkg/crypto"
+	"ideal-core/pkg/envelope"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// Менеджер соединений между узлами
+//
+// Слушает TCP на Yggdrasil-адресе узла (в тестах — на любом адресе),
+// держит по одной сессии на пира, у каждой сессии своя горутина чтения.
+// При установке соединения стороны обмениваются ключами и подписывают
+// случайные вызовы друг друга — так сессия знает, с каким ключом говорит.
+// Сессия без трафика дольше IdleTimeout закрывается; Maintain поддерживает
+// исходящее соединение, переподключаясь с экспоненциальной задержкой.
+
+// DefaultPeerPort — TCP-порт приложения на Yggdrasil-адресе
+const DefaultPeerPort = 9001
+
+const (
+	handshakeContext = "ideal-core/session/v1"
+	challengeSize    = 32
+)
+
+var (
+	ErrNoSession     = errors.New("no session with peer")
+	ErrManagerClosed = errors.New("connection manager is closed")
+)
+
+// FrameHandler получает кадры от пиров
+type FrameHandler func(ctx context.Context, peer ed25519.PublicKey, frame []byte)
+
+// ConnConfig — настройки менеджера
+type ConnConfig struct {
+	ListenAddr   string                 // "[200:...]:9001"; "127.0.0.1:0" в тестах
+	Key          func() *crypto.KeyPair // текущий ключ узла
+	Handler      FrameHandler
+	IdleTimeout  time.Duration // по умолчанию 5 минут
+	DialTimeout  time.Duration // по умолчанию 10 секунд
+	ReconnectMin time.Duration // по умолчанию 1 секунда
+	ReconnectMax time.Duration // по умолчанию 1 минута
+}
+
+// PeerSession — состояние сессии для API/диагностики
+type PeerSession struct {
+	Key          string    `json:"key"`
+	RemoteAddr   string    `json:"remote_addr"`
+	Inbound      bool      `json:"inbound"`
+	Established  time.Time `json:"established"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+type session struct {
+	conn     net.Conn
+	peer     ed25519.PublicKey
+	inbound  bool
+	started  time.Time
+	writeMu  sync.Mutex
+	mu       sync.Mutex
+	activity time.Time
+	done     chan struct{}
+	once     sync.Once
+}
+
+func (s *session) touch() {
+	s.mu.Lock()
+	s.activity = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *session) lastActivity() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.activity
+}
+
+func (s *session) alive() bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// preferred — соединение открыто стороной с меньшим ключом
+func preferred(inbound bool, us, peer ed25519.PublicKey) bool {
+	usSmaller := bytes.Compare(us, peer) < 0
+	return inbound != usSmaller
+}
+
+func (s *session) close() {
+	s.once.Do(func() {
+		s.conn.Close()
+		close(s.done)
+	})
+}
+
+// ConnManager — входящие и исходящие сессии с пирами
+type ConnManager struct {
+	cfg ConnConfig
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	ln       net.Listener
+	sessions map[string]*session
+}
+
+// NewConnManager создаёт менеджер; Listen запускает приём входящих соединений
+func NewConnManager(cfg ConnConfig) *ConnManager {
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 5 * time.Minute
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 10 * time.Second
+	}
+	if cfg.ReconnectMin <= 0 {
+		cfg.ReconnectMin = time.Second
+	}
+	if cfg.ReconnectMax < cfg.ReconnectMin {
+		cfg.ReconnectMax = time.Minute
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ConnManager{cfg: cfg, ctx: ctx, cancel: cancel, sessions: make(map[string]*session)}
+}
+
+// Listen открывает слушающий сокет и принимает соединения в фоне
+func (m *ConnManager) Listen() error {
+	ln, err := net.Listen("tcp", m.cfg.ListenAddr)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.ln = ln
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if m.ctx.Err() == nil {
+					log.Printf("⚠️  Peer listener stopped: %v", err)
+				}
+				return
+			}
+			m.wg.Add(1)
+			go func() {
+				defer m.wg.Done()
+				if _, err := m.start(conn, true); err != nil {
+					log.Printf("⚠️  Inbound handshake from %s failed: %v", conn.RemoteAddr(), err)
+				}
+			}()
+		}
+	}()
+	return nil
+}
+
+// Addr — фактический адрес слушателя (nil до Listen)
+func (m *ConnManager) Addr() net.Addr {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ln == nil {
+		return nil
+	}
+	return m.ln.Addr()
+}
+
+// Connect открывает исходящее соединение и возвращает ключ пира
+func (m *ConnManager) Connect(ctx context.Context, addr string) (ed25519.PublicKey, error) {
+	if m.ctx.Err() != nil {
+		return nil, ErrManagerClosed
+	}
+	s, err := m.dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return s.peer, nil
+}
+
+func (m *ConnManager) dial(ctx context.Context, addr string) (*session, error) {
+	d := net.Dialer{Timeout: m.cfg.DialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return m.start(conn, false)
+}
+
+// Maintain держит исходящее соединение с addr до Close, переподключаясь после обрыва
+func (m *ConnManager) Maintain(addr string) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		delay := m.cfg.ReconnectMin
+		for m.ctx.Err() == nil {
+			ctx, cancel := context.WithTimeout(m.ctx, m.cfg.DialTimeout)
+			s, err := m.dial(ctx, addr)
+			cancel()
+			if err == nil {
+				delay = m.cfg.ReconnectMin
+				select {
+				case <-s.done:
+				case <-m.ctx.Done():
+					return
+				}
+				continue
+			}
+			select {
+			case <-time.After(delay):
+			case <-m.ctx.Done():
+				return
+			}
+			if delay *= 2; delay > m.cfg.ReconnectMax {
+				delay = m.cfg.ReconnectMax
+			}
+		}
+	}()
+}
+
+// Send отправляет кадр пиру по существующей сессии
+func (m *ConnManager) Send(peer ed25519.PublicKey, frame []byte) error {
+	s := m.session(peer)
+	if s == nil {
+		return fmt.Errorf("%w %s", ErrNoSession, hex.EncodeToString(peer)[:16])
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.SetWriteDeadline(time.Now().Add(m.cfg.DialTimeout))
+	if err := envelope.WriteFrame(s.conn, frame); err != nil {
+		s.close()
+		return err
+	}
+	s.touch()
+	return nil
+}
+
+// Connected сообщает, есть ли сессия с пиром
+func (m *ConnManager) Connected(peer ed25519.PublicKey) bool {
+	return m.session(peer) != nil
+}
+
+// Sessions — текущие сессии
+func (m *ConnManager) Sessions() []PeerSession {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]PeerSession, 0, len(m.sessions))
+	for k, s := range m.sessions {
+		out = append(out, PeerSession{
+			Key:          k,
+			RemoteAddr:   s.conn.RemoteAddr().String(),
+			Inbound:      s.inbound,
+			Established:  s.started,
+			LastActivity: s.lastActivity(),
+		})
+	}
+	return out
+}
+
+// Close закрывает слушатель и все сессии и дожидается горутин
+func (m *ConnManager) Close() error {
+	m.cancel()
+	m.mu.Lock()
+	if m.ln != nil {
+		m.ln.Close()
+	}
+	for _, s := range m.sessions {
+		s.close()
+	}
+	m.mu.Unlock()
+	m.wg.Wait()
+	return nil
+}
+
+func (m *ConnManager) session(peer ed25519.PublicKey) *session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sessions[hex.EncodeToString(peer)]
+}
+
+// start выполняет рукопожатие, регистрирует сессию и запускает её чтение
+func (m *ConnManager) start(conn net.Conn, inbound bool) (*session, error) {
+	conn.SetDeadline(time.Now().Add(m.cfg.DialTimeout))
+	peer, err := handshake(conn, m.cfg.Key())
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	now := time.Now()
+	s := &session{conn: conn, peer: peer, inbound: inbound, started: now, activity: now, done: make(chan struct{})}
+	key := hex.EncodeToString(peer)
+
+	m.mu.Lock()
+	if m.ctx.Err() != nil {
+		m.mu.Unlock()
+		conn.Close()
+		return nil, ErrManagerClosed
+	}
+	// Одна сессия на пира. Если обе стороны соединились одновременно, обе
+	// оставляют соединение, открытое меньшим ключом; иначе новая сессия
+	// вытесняет старую (например, после обрыва, который мы ещё не заметили).
+	old := m.sessions[key]
+	if old != nil && old.alive() && preferred(old.inbound, m.cfg.Key().PublicKey, peer) && !preferred(inbound, m.cfg.Key().PublicKey, peer) {
+		m.mu.Unlock()
+		conn.Close()
+		return old, nil
+	}
+	m.sessions[key] = s
+	m.mu.Unlock()
+	if old != nil {
+		old.close()
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.read(s)
+	}()
+	return s, nil
+}
+
+func (m *ConnManager) read(s *session) {
+	defer func() {
+		s.close()
+		m.mu.Lock()
+		key := hex.EncodeToString(s.peer)
+		if m.sessions[key] == s {
+			delete(m.sessions, key)
+		}
+		m.mu.Unlock()
+	}()
+
+	r := &sessionReader{s: s, idle: m.cfg.IdleTimeout}
+	for {
+		frame, err := envelope.ReadFrame(r)
+		if err != nil {
+			return
+		}
+		s.touch()
+		if m.cfg.Handler != nil {
+			m.cfg.Handler(m.ctx, s.peer, frame)
+		}
+	}
+}
+
+// sessionReader читает поток сессии, сдвигая дедлайн внутри Read: таймаут при
+// недавней активности (в том числе нашей отправке) просто повторяет чтение,
+// не теряя уже прочитанную часть кадра. Ошибка таймаута доходит до ReadFrame,
+// только если простаивали обе стороны, — тогда сессия закрывается.
+type sessionReader struct {
+	s    *session
+	idle time.Duration
+}
+
+func (r *sessionReader) Read(p []byte) (int, error) {
+	for {
+		r.s.conn.SetReadDeadline(r.s.lastActivity().Add(r.idle))
+		n, err := r.s.conn.Read(p)
+		var ne net.Error
+		timeout := errors.As(err, &ne) && ne.Timeout()
+		if n > 0 {
+			r.s.touch()
+			if timeout {
+				err = nil
+			}
+			return n, err
+		}
+		if timeout && time.Since(r.s.lastActivity()) < r.idle {
+			continue
+		}
+		return n, err
+	}
+}
+
+// handshake: обе стороны шлют ключ и вызов, затем подпись над
+// (контекст || свой ключ || ключ пира || вызов пира). Возвращает ключ пира.
+func handshake(conn net.Conn, kp *crypto.KeyPair) (ed25519.PublicKey, error) {
+	challenge := make([]byte, challengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	if err := envelope.WriteFrame(conn, append(append([]byte(nil), kp.PublicKey...), challenge...)); err != nil {
+		return nil, err
+	}
+	hello, err := envelope.ReadFrame(conn)
+	if err != nil {
+		return nil, fmt.Errorf("read hello: %w", err)
+	}
+	if len(hello) != ed25519.PublicKeySize+challengeSize {
+		return nil, errors.New("malformed hello")
+	}
+	peer := ed25519.PublicKey(append([]byte(nil), hello[:ed25519.PublicKeySize]...))
+	peerChallenge := hello[ed25519.PublicKeySize:]
+	if bytes.Equal(peer, kp.PublicKey) {
+		return nil, errors.New("connected to self")
+	}
+
+	if err := envelope.WriteFrame(conn, kp.Sign(handshakeMessage(kp.PublicKey, peer, peerChallenge))); err != nil {
+		return nil, err
+	}
+	sig, err := envelope.ReadFrame(conn)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("read proof: %w", err)
+	}
+	if !crypto.Verify(peer, handshakeMessage(peer, kp.PublicKey, challenge), sig) {
+		return nil, errors.New("peer failed to prove its key")
+	}
+	return peer, nil
+}
+
+func handshakeMessage(signer, other ed25519.PublicKey, challenge []byte) []byte {
+	msg := []byte(handshakeContext)
+	msg = append(msg, signer...)
+	msg = append(msg, other...)
+	return append(msg, challenge...)
+}
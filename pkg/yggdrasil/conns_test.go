@@ -0,0 +1,228 @@
+package yggdrasil
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"ideal-core/pkg/crypto"
+	"ideal-core/pkg/envelope"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type received struct {
+	mu     sync.Mutex
+	frames []string
+	from   []ed25519.PublicKey
+}
+
+func (r *received) handler(ctx context.Context, peer ed25519.PublicKey, frame []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frames = append(r.frames, string(frame))
+	r.from = append(r.from, peer)
+}
+
+func (r *received) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.frames)
+}
+
+func newTestManager(t *testing.T, idle time.Duration) (*ConnManager, *crypto.KeyPair, *received) {
+	t.Helper()
+	kp, _ := crypto.GenerateKeyPair()
+	rec := &received{}
+	m := NewConnManager(ConnConfig{
+		ListenAddr:   "127.0.0.1:0",
+		Key:          func() *crypto.KeyPair { return kp },
+		Handler:      rec.handler,
+		IdleTimeout:  idle,
+		ReconnectMin: 20 * time.Millisecond,
+		ReconnectMax: 100 * time.Millisecond,
+	})
+	if err := m.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m, kp, rec
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConnManager_ExchangeFrames(t *testing.T) {
+	a, aKey, aRec := newTestManager(t, time.Minute)
+	b, bKey, bRec := newTestManager(t, time.Minute)
+
+	peer, err := a.Connect(context.Background(), b.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(peer, bKey.PublicKey) {
+		t.Fatal("Connect returned wrong peer key")
+	}
+	waitFor(t, "inbound session", func() bool { return b.Connected(aKey.PublicKey) })
+
+	if err := a.Send(bKey.PublicKey, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Send(aKey.PublicKey, []byte("hi back")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "frames", func() bool { return aRec.count() == 1 && bRec.count() == 1 })
+	if bRec.frames[0] != "hello" || !bytes.Equal(bRec.from[0], aKey.PublicKey) {
+		t.Fatalf("b received %q from %x", bRec.frames[0], bRec.from[0])
+	}
+	if aRec.frames[0] != "hi back" {
+		t.Fatalf("a received %q", aRec.frames[0])
+	}
+
+	stranger, _ := crypto.GenerateKeyPair()
+	if err := a.Send(stranger.PublicKey, []byte("x")); err == nil {
+		t.Fatal("expected ErrNoSession")
+	}
+}
+
+func TestConnManager_ManyPeers(t *testing.T) {
+	hub, hubKey, hubRec := newTestManager(t, time.Minute)
+	const n = 5
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		m, _, _ := newTestManager(t, time.Minute)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := m.Connect(context.Background(), hub.Addr().String()); err != nil {
+				t.Error(err)
+				return
+			}
+			m.Send(hubKey.PublicKey, []byte("ping"))
+		}()
+	}
+	wg.Wait()
+	waitFor(t, "all sessions", func() bool { return len(hub.Sessions()) == n && hubRec.count() == n })
+	for _, s := range hub.Sessions() {
+		if !s.Inbound {
+			t.Error("expected inbound sessions on hub")
+		}
+	}
+}
+
+func TestConnManager_IdleTimeout(t *testing.T) {
+	a, aKey, _ := newTestManager(t, 150*time.Millisecond)
+	b, bKey, _ := newTestManager(t, 150*time.Millisecond)
+	if _, err := a.Connect(context.Background(), b.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Отправка поддерживает сессию живой
+	for i := 0; i < 4; i++ {
+		time.Sleep(60 * time.Millisecond)
+		if err := a.Send(bKey.PublicKey, []byte("keepalive")); err != nil {
+			t.Fatalf("session dropped while active: %v", err)
+		}
+	}
+	waitFor(t, "idle close", func() bool { return !a.Connected(bKey.PublicKey) && !b.Connected(aKey.PublicKey) })
+}
+
+func TestConnManager_SlowFrameKeepsAlignment(t *testing.T) {
+	b, _, bRec := newTestManager(t, 150*time.Millisecond)
+	kp, _ := crypto.GenerateKeyPair()
+	conn, err := net.Dial("tcp", b.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := handshake(conn, kp); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "session", func() bool { return b.Connected(kp.PublicKey) })
+
+	// Половина кадра, пауза дольше IdleTimeout, пока b сам отправляет, затем остаток
+	var frame bytes.Buffer
+	envelope.WriteFrame(&frame, []byte("slow frame body"))
+	envelope.WriteFrame(&frame, []byte("second"))
+	data := frame.Bytes()
+	conn.Write(data[:10])
+	for i := 0; i < 4; i++ {
+		time.Sleep(60 * time.Millisecond)
+		if err := b.Send(kp.PublicKey, []byte("keepalive")); err != nil {
+			t.Fatalf("session dropped mid-frame while active: %v", err)
+		}
+	}
+	conn.Write(data[10:])
+
+	waitFor(t, "frames", func() bool { return bRec.count() == 2 })
+	if bRec.frames[0] != "slow frame body" || bRec.frames[1] != "second" {
+		t.Fatalf("frames misaligned: %q", bRec.frames)
+	}
+}
+
+func TestConnManager_Reconnect(t *testing.T) {
+	a, aKey, _ := newTestManager(t, time.Minute)
+	b, bKey, _ := newTestManager(t, time.Minute)
+
+	a.Maintain(b.Addr().String())
+	waitFor(t, "first connection", func() bool { return a.Connected(bKey.PublicKey) })
+	first := a.Sessions()[0].Established
+
+	// Пир обрывает соединение — Maintain переподключается
+	b.session(aKey.PublicKey).close()
+	waitFor(t, "reconnection", func() bool {
+		s := a.Sessions()
+		return len(s) == 1 && s[0].Established.After(first)
+	})
+	if err := a.Send(bKey.PublicKey, []byte("after reconnect")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConnManager_SimultaneousDialIsStable(t *testing.T) {
+	a, aKey, _ := newTestManager(t, time.Minute)
+	b, bKey, _ := newTestManager(t, time.Minute)
+
+	a.Maintain(b.Addr().String())
+	b.Maintain(a.Addr().String())
+	waitFor(t, "sessions", func() bool { return a.Connected(bKey.PublicKey) && b.Connected(aKey.PublicKey) })
+	time.Sleep(200 * time.Millisecond)
+	before := a.Sessions()[0].Established
+
+	// Соединения не должны вытеснять друг друга по кругу
+	time.Sleep(300 * time.Millisecond)
+	after := a.Sessions()
+	if len(after) != 1 || !after[0].Established.Equal(before) {
+		t.Fatalf("sessions keep flapping: %+v", after)
+	}
+}
+
+func TestConnManager_Close(t *testing.T) {
+	a, _, _ := newTestManager(t, time.Minute)
+	b, bKey, _ := newTestManager(t, time.Minute)
+	a.Maintain(b.Addr().String())
+	waitFor(t, "connection", func() bool { return a.Connected(bKey.PublicKey) })
+
+	done := make(chan struct{})
+	go func() {
+		a.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not return")
+	}
+	if _, err := a.Connect(context.Background(), b.Addr().String()); err != ErrManagerClosed {
+		t.Fatalf("expected ErrManagerClosed, got %v", err)
+	}
+}
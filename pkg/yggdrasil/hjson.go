@@ -0,0 +1,236 @@
+package yggdrasil
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Разбор yggdrasil.conf. Yggdrasil пишет конфиг в HJSON (yggdrasil -genconf):
+// комментарии #, // и /* */, ключи и строки без кавычек, запятые
+// необязательны, корневые скобки можно опустить. Поддерживается то
+// подмножество, которое встречается в конфигах Yggdrasil (без многострочных
+// строк '''); обычный JSON — частный случай.
+
+type hjsonParser struct {
+	s   string
+	pos int
+}
+
+// parseHJSON разбирает документ в значения encoding/json (числа — json.Number)
+func parseHJSON(data []byte) (interface{}, error) {
+	p := &hjsonParser{s: string(data)}
+	p.skip()
+	var v interface{}
+	var err error
+	if p.peek() == '{' || p.peek() == '[' {
+		v, err = p.value()
+	} else {
+		v, err = p.members(false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.skip()
+	if p.pos < len(p.s) {
+		return nil, p.errorf("unexpected %q after the end of the document", p.s[p.pos])
+	}
+	return v, nil
+}
+
+func (p *hjsonParser) errorf(format string, args ...interface{}) error {
+	line := 1 + strings.Count(p.s[:p.pos], "\n")
+	return fmt.Errorf("config line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *hjsonParser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+// skip пропускает пробелы, переводы строк и комментарии
+func (p *hjsonParser) skip() {
+	for p.pos < len(p.s) {
+		switch rest := p.s[p.pos:]; {
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r' || rest[0] == '\n':
+			p.pos++
+		case rest[0] == '#' || strings.HasPrefix(rest, "//"):
+			p.toEOL()
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				p.pos = len(p.s)
+				return
+			}
+			p.pos += end + 4
+		default:
+			return
+		}
+	}
+}
+
+// toEOL переходит к концу строки и возвращает пройденный текст
+func (p *hjsonParser) toEOL() string {
+	start := p.pos
+	for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func (p *hjsonParser) value() (interface{}, error) {
+	switch p.peek() {
+	case 0:
+		return nil, p.errorf("unexpected end of the document")
+	case '{':
+		p.pos++
+		return p.members(true)
+	case '[':
+		p.pos++
+		return p.array()
+	case '"', '\'':
+		return p.quoted()
+	case ',', ':', ']', '}':
+		return nil, p.errorf("unexpected %q", p.peek())
+	}
+	return p.quoteless(), nil
+}
+
+// members разбирает пары ключ: значение (braced — внутри { })
+func (p *hjsonParser) members(braced bool) (map[string]interface{}, error) {
+	obj := map[string]interface{}{}
+	for {
+		p.skip()
+		switch {
+		case p.pos >= len(p.s) && braced:
+			return nil, p.errorf("missing '}'")
+		case p.pos >= len(p.s):
+			return obj, nil
+		case braced && p.peek() == '}':
+			p.pos++
+			return obj, nil
+		}
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		p.skip()
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = v
+		p.skip()
+		if p.peek() == ',' {
+			p.pos++
+		}
+	}
+}
+
+func (p *hjsonParser) key() (string, error) {
+	var key string
+	if c := p.peek(); c == '"' || c == '\'' {
+		v, err := p.quoted()
+		if err != nil {
+			return "", err
+		}
+		key = v.(string)
+	} else {
+		start := p.pos
+		for p.pos < len(p.s) && !strings.ContainsRune(" \t\r\n:,{}[]", rune(p.s[p.pos])) {
+			p.pos++
+		}
+		key = p.s[start:p.pos]
+	}
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+	if key == "" || p.peek() != ':' {
+		return "", p.errorf("expected a key followed by ':'")
+	}
+	p.pos++
+	return key, nil
+}
+
+func (p *hjsonParser) array() ([]interface{}, error) {
+	arr := []interface{}{}
+	for {
+		p.skip()
+		if p.pos >= len(p.s) {
+			return nil, p.errorf("missing ']'")
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return arr, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+		p.skip()
+		if p.peek() == ',' {
+			p.pos++
+		}
+	}
+}
+
+// quoted — строка в двойных (экранирование как в JSON) или одинарных кавычках
+func (p *hjsonParser) quoted() (interface{}, error) {
+	q := p.s[p.pos]
+	start := p.pos
+	for p.pos++; p.pos < len(p.s); p.pos++ {
+		switch p.s[p.pos] {
+		case '\\':
+			p.pos++
+		case '\n':
+			return nil, p.errorf("unterminated string")
+		case q:
+			p.pos++
+			raw := p.s[start:p.pos]
+			if q == '\'' {
+				raw = strconv.Quote(strings.ReplaceAll(raw[1:len(raw)-1], `\'`, `'`))
+			}
+			var s string
+			if err := json.Unmarshal([]byte(raw), &s); err != nil {
+				return nil, p.errorf("bad string %s: %v", raw, err)
+			}
+			return s, nil
+		}
+	}
+	return nil, p.errorf("unterminated string")
+}
+
+// quoteless — литерал (число, true, false, null) или строка без кавычек до конца строки
+func (p *hjsonParser) quoteless() interface{} {
+	start := p.pos
+	line := p.toEOL()
+	token := line
+	if i := strings.IndexAny(token, ",]} \t\r#"); i >= 0 {
+		token = token[:i]
+	}
+	if rest := strings.TrimSpace(line[len(token):]); rest == "" || strings.ContainsAny(rest[:1], ",]}#") || strings.HasPrefix(rest, "//") {
+		var literal interface{}
+		switch token {
+		case "true":
+			literal = true
+		case "false":
+			literal = false
+		case "null":
+			p.pos = start + len(token)
+			return nil
+		default:
+			if _, err := strconv.ParseFloat(token, 64); err == nil {
+				literal = json.Number(token)
+			}
+		}
+		if literal != nil {
+			p.pos = start + len(token)
+			return literal
+		}
+	}
+	return strings.TrimSpace(line)
+}